@@ -18,6 +18,8 @@ package auth
 
 import (
 	"fmt"
+	net_http "net/http"
+	"net/url"
 	"sort"
 	"strings"
 
@@ -101,8 +103,9 @@ func (b *BceV1Signer) Sign(req *http.Request, cred *BceCredentials, opt *SignOpt
 		opt.ExpireSeconds)
 	signKey := util.HmacSha256Hex(secretAccessKey, signKeyInfo)
 	canonicalUri := getCanonicalURIPath(req.Uri())
-	canonicalQueryString := getCanonicalQueryString(req.Params())
-	canonicalHeaders, signedHeadersArr := getCanonicalHeaders(req.Headers(), opt.HeadersToSign)
+	canonicalQueryString := getCanonicalQueryString(req.MultiParams())
+	canonicalHeaders, signedHeadersArr := getCanonicalHeaders(req.MultiHeaders(),
+		opt.HeadersToSign)
 
 	// Generate signed headers string
 	signedHeaders := ""
@@ -136,35 +139,38 @@ func getCanonicalURIPath(path string) string {
 	return "/" + canonical_path
 }
 
-func getCanonicalQueryString(params map[string]string) string {
+func getCanonicalQueryString(params url.Values) string {
 	if len(params) == 0 {
 		return ""
 	}
 
+	// Every value of a repeated key is an individual item of the canonical query string
 	result := make([]string, 0, len(params))
-	for k, v := range params {
+	for k, values := range params {
 		if strings.ToLower(k) == strings.ToLower(http.AUTHORIZATION) {
 			continue
 		}
-		item := ""
-		if len(v) == 0 {
-			item = fmt.Sprintf("%s=", util.UriEncode(k, true))
-		} else {
-			item = fmt.Sprintf("%s=%s", util.UriEncode(k, true), util.UriEncode(v, true))
+		for _, v := range values {
+			item := ""
+			if len(v) == 0 {
+				item = fmt.Sprintf("%s=", util.UriEncode(k, true))
+			} else {
+				item = fmt.Sprintf("%s=%s", util.UriEncode(k, true), util.UriEncode(v, true))
+			}
+			result = append(result, item)
 		}
-		result = append(result, item)
 	}
 	sort.Strings(result)
 	return strings.Join(result, "&")
 }
 
-func getCanonicalHeaders(headers map[string]string,
+func getCanonicalHeaders(headers net_http.Header,
 	headersToSign map[string]struct{}) (string, []string) {
 	canonicalHeaders := make([]string, 0, len(headers))
 	signHeaders := make([]string, 0, len(headersToSign))
-	for k, v := range headers {
+	for k, values := range headers {
 		headKey := strings.ToLower(k)
-		if headKey == strings.ToLower(http.AUTHORIZATION) {
+		if headKey == strings.ToLower(http.AUTHORIZATION) || len(values) == 0 {
 			continue
 		}
 		_, headExists := headersToSign[headKey]
@@ -172,9 +178,13 @@ func getCanonicalHeaders(headers map[string]string,
 			(strings.HasPrefix(headKey, http.BCE_PREFIX) &&
 				(headKey != http.BCE_REQUEST_ID)) {
 
-			headVal := strings.TrimSpace(v)
-			encoded := util.UriEncode(headKey, true) + ":" + util.UriEncode(headVal, true)
-			canonicalHeaders = append(canonicalHeaders, encoded)
+			// Every value of a repeated header is an individual canonical header line, while
+			// the header name is only signed once
+			for _, v := range values {
+				headVal := strings.TrimSpace(v)
+				encoded := util.UriEncode(headKey, true) + ":" + util.UriEncode(headVal, true)
+				canonicalHeaders = append(canonicalHeaders, encoded)
+			}
 			signHeaders = append(signHeaders, headKey)
 		}
 	}
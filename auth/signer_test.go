@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/http"
+)
+
+func TestCanonicalQueryStringMultiValues(t *testing.T) {
+	req := &http.Request{}
+	req.SetParam("b", "2")
+	req.AddParam("a", "y")
+	req.AddParam("a", "x")
+	req.SetParam("acl", "")
+
+	expected := "a=x&a=y&acl=&b=2"
+	if actual := getCanonicalQueryString(req.MultiParams()); actual != expected {
+		t.Errorf("expect %s but %s", expected, actual)
+	}
+	if actual := req.QueryString(); actual != "a=y&a=x&acl=&b=2" {
+		t.Errorf("unexpected query string %s", actual)
+	}
+}
+
+func TestCanonicalHeadersMultiValues(t *testing.T) {
+	req := &http.Request{}
+	req.SetHeader(http.HOST, "bj.bcebos.com")
+	req.AddHeader("x-bce-meta-tag", "b")
+	req.AddHeader("x-bce-meta-tag", "a")
+	req.SetHeader(http.BCE_REQUEST_ID, "id")
+	req.SetHeader("X-Custom", "ignored")
+
+	canonical, signed := getCanonicalHeaders(req.MultiHeaders(), DEFAULT_HEADERS_TO_SIGN)
+	expected := "host:bj.bcebos.com\nx-bce-meta-tag:a\nx-bce-meta-tag:b"
+	if canonical != expected {
+		t.Errorf("expect %q but %q", expected, canonical)
+	}
+	if len(signed) != 2 || signed[0] != "host" || signed[1] != "x-bce-meta-tag" {
+		t.Errorf("unexpected signed headers %v", signed)
+	}
+	if values := req.HeaderValues("x-bce-meta-tag"); len(values) != 2 {
+		t.Errorf("expect 2 values but %v", values)
+	}
+	if req.Headers()["x-bce-meta-tag"] != "b" {
+		t.Errorf("expect the first value but %v", req.Headers())
+	}
+}
@@ -19,6 +19,8 @@ package bce
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/kougazhang/bce-sdk-go/util"
 )
 
 // RequestBuilder holds config data for bce request.
@@ -33,6 +35,7 @@ type RequestBuilder struct {
 	headers     map[string]string // optional
 	body        interface{}       // optional
 	result      interface{}       // optional
+	idempotent  *bool             // optional
 }
 
 // create RequestBuilder with the client.
@@ -69,6 +72,20 @@ func (b *RequestBuilder) WithQueryParamFilter(key, value string) *RequestBuilder
 	return b.WithQueryParam(key, value)
 }
 
+// set the idempotent token, a new one is generated if the given token is blank.
+func (b *RequestBuilder) WithClientToken(token string) *RequestBuilder {
+	if len(token) == 0 {
+		token = util.NewUUID()
+	}
+	return b.WithQueryParam(CLIENT_TOKEN, token)
+}
+
+// mark whether the request can be replayed safely by the retry policy.
+func (b *RequestBuilder) WithIdempotent(idempotent bool) *RequestBuilder {
+	b.idempotent = &idempotent
+	return b
+}
+
 func (b *RequestBuilder) WithQueryParams(params map[string]string) *RequestBuilder {
 	if b.queryParams == nil {
 		b.queryParams = params
@@ -155,6 +172,9 @@ func (b *RequestBuilder) buildBceRequest() (*BceRequest, error) {
 	if b.queryParams != nil {
 		req.SetParams(b.queryParams)
 	}
+	if b.idempotent != nil {
+		req.SetIdempotent(*b.idempotent)
+	}
 	if b.body != nil {
 		bodyBytes, err := json.Marshal(b.body)
 		if err != nil {
@@ -42,6 +42,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/kougazhang/bce-sdk-go/auth"
@@ -129,7 +132,7 @@ func (c *BceClient) SendRequest(req *BceRequest, resp *BceResponse) error {
 		httpResp, err := http.Execute(&req.Request)
 
 		if err != nil {
-			if c.Config.Retry.ShouldRetry(err, retries) {
+			if c.shouldRetry(req, err, retries) {
 				delay_in_mills := c.Config.Retry.GetDelayBeforeNextRetryInMillis(err, retries)
 				time.Sleep(delay_in_mills)
 			} else {
@@ -150,12 +153,12 @@ func (c *BceClient) SendRequest(req *BceRequest, resp *BceResponse) error {
 
 		log.Infof("receive http response: status: %s, debugId: %s, requestId: %s, elapsed: %v",
 			resp.StatusText(), resp.DebugId(), resp.RequestId(), resp.ElapsedTime())
-		for k, v := range resp.Headers() {
-			log.Debugf("%s=%s", k, v)
+		for k, v := range resp.MultiHeaders() {
+			log.Debugf("%s=%s", k, strings.Join(v, ","))
 		}
 		if resp.IsFail() {
 			err := resp.ServiceError()
-			if c.Config.Retry.ShouldRetry(err, retries) {
+			if c.shouldRetry(req, err, retries) {
 				delay_in_mills := c.Config.Retry.GetDelayBeforeNextRetryInMillis(err, retries)
 				time.Sleep(delay_in_mills)
 			} else {
@@ -199,7 +202,7 @@ func (c *BceClient) SendRequestFromBytes(req *BceRequest, resp *BceResponse, con
 		defer req.Request.Body().Close() // Manually close the ReadCloser body for retry
 		httpResp, err := http.Execute(&req.Request)
 		if err != nil {
-			if c.Config.Retry.ShouldRetry(err, retries) {
+			if c.shouldRetry(req, err, retries) {
 				delay_in_mills := c.Config.Retry.GetDelayBeforeNextRetryInMillis(err, retries)
 				time.Sleep(delay_in_mills)
 			} else {
@@ -215,12 +218,12 @@ func (c *BceClient) SendRequestFromBytes(req *BceRequest, resp *BceResponse, con
 		resp.ParseResponse()
 		log.Infof("receive http response: status: %s, debugId: %s, requestId: %s, elapsed: %v",
 			resp.StatusText(), resp.DebugId(), resp.RequestId(), resp.ElapsedTime())
-		for k, v := range resp.Headers() {
-			log.Debugf("%s=%s", k, v)
+		for k, v := range resp.MultiHeaders() {
+			log.Debugf("%s=%s", k, strings.Join(v, ","))
 		}
 		if resp.IsFail() {
 			err := resp.ServiceError()
-			if c.Config.Retry.ShouldRetry(err, retries) {
+			if c.shouldRetry(req, err, retries) {
 				delay_in_mills := c.Config.Retry.GetDelayBeforeNextRetryInMillis(err, retries)
 				time.Sleep(delay_in_mills)
 			} else {
@@ -234,6 +237,28 @@ func (c *BceClient) SendRequestFromBytes(req *BceRequest, resp *BceResponse, con
 	}
 }
 
+// shouldRetry - decide whether to replay the request after the given error. A non-idempotent
+// request is only replayed when the connection could not be established, since the service may
+// have performed it otherwise.
+func (c *BceClient) shouldRetry(req *BceRequest, err BceError, retries int) bool {
+	if !c.Config.Retry.ShouldRetry(err, retries) {
+		return false
+	}
+	if !req.IsIdempotent() && !isDialError(err) {
+		log.Warnf("give up retrying the non-idempotent request: %v", err)
+		return false
+	}
+	return true
+}
+
+func isDialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
 func (c *BceClient) GetBceClientConfig() *BceClientConfiguration {
 	return c.Config
 }
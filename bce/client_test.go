@@ -0,0 +1,71 @@
+package bce
+
+import (
+	net_http "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/http"
+)
+
+func newRetryTestClient(t *testing.T) (*BceClient, *[]string, func()) {
+	tokens := []string{}
+	server := httptest.NewServer(net_http.HandlerFunc(
+		func(w net_http.ResponseWriter, r *net_http.Request) {
+			tokens = append(tokens, r.URL.Query().Get(CLIENT_TOKEN))
+			w.WriteHeader(net_http.StatusInternalServerError)
+		}))
+	client, err := NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = NewBackOffRetryPolicy(2, 10, 1)
+	return client, &tokens, server.Close
+}
+
+func TestRetryKeepsClientToken(t *testing.T) {
+	client, tokens, clean := newRetryTestClient(t)
+	defer clean()
+
+	err := NewRequestBuilder(client).WithURL("/v2/instance").WithMethod(http.POST).
+		WithClientToken("").Do()
+	if err == nil {
+		t.Fatal("expect error of internal server error")
+	}
+	if len(*tokens) != 3 || (*tokens)[0] == "" {
+		t.Fatalf("unexpected tokens %v", *tokens)
+	}
+	for _, token := range *tokens {
+		if token != (*tokens)[0] {
+			t.Errorf("token changed between retries: %v", *tokens)
+		}
+	}
+}
+
+func TestNoRetryForNonIdempotentRequest(t *testing.T) {
+	client, tokens, clean := newRetryTestClient(t)
+	defer clean()
+
+	req := &BceRequest{}
+	req.SetUri("/v2/instance")
+	req.SetMethod(http.POST)
+	if !req.IsIdempotent() {
+		t.Errorf("expect POST to be idempotent by default")
+	}
+	client.SendRequest(req, &BceResponse{})
+	if len(*tokens) != 3 {
+		t.Errorf("expect retries of POST by default but sent %d times", len(*tokens))
+	}
+
+	*tokens = (*tokens)[:0]
+	req = &BceRequest{}
+	req.SetUri("/v2/instance")
+	req.SetMethod(http.POST)
+	req.SetIdempotent(false)
+	if err := client.SendRequest(req, &BceResponse{}); err == nil {
+		t.Fatal("expect error of internal server error")
+	}
+	if len(*tokens) != 1 {
+		t.Errorf("expect no retry but sent %d times", len(*tokens))
+	}
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// profile.go - load the client configuration from the shared config file and environment
+
+package bce
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/auth"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+// Constants of the environment variables and defaults used by the config loader
+const (
+	ENV_CONFIG_FILE                  = "BCE_CONFIG_FILE"
+	ENV_PROFILE                      = "BCE_PROFILE"
+	ENV_ACCESS_KEY_ID                = "BCE_ACCESS_KEY_ID"
+	ENV_SECRET_ACCESS_KEY            = "BCE_SECRET_ACCESS_KEY"
+	ENV_SESSION_TOKEN                = "BCE_SESSION_TOKEN"
+	ENV_REGION                       = "BCE_REGION"
+	ENV_ENDPOINT                     = "BCE_ENDPOINT"
+	ENV_PROXY_URL                    = "BCE_PROXY_URL"
+	ENV_CONNECTION_TIMEOUT_IN_MILLIS = "BCE_CONNECTION_TIMEOUT_IN_MILLIS"
+	ENV_MAX_ERROR_RETRY              = "BCE_MAX_ERROR_RETRY"
+	ENV_LOG_LEVEL                    = "BCE_LOG_LEVEL"
+
+	// ENV_SERVICE_ENDPOINT_FORMAT is formatted with the upper case service name, eg. BCE_BOS_ENDPOINT
+	ENV_SERVICE_ENDPOINT_FORMAT = "BCE_%s_ENDPOINT"
+
+	DEFAULT_PROFILE     = "default"
+	DEFAULT_CONFIG_FILE = ".bce/config.json" // relative to the home directory of the current user
+)
+
+// RetryProfile defines the retry policy of a profile. The no retry policy is used if the
+// `MaxErrorRetry` is 0, the fields with zero value use the value of `DEFAULT_RETRY_POLICY`.
+type RetryProfile struct {
+	MaxErrorRetry        *int  `json:"maxErrorRetry,omitempty"`
+	MaxDelayInMillis     int64 `json:"maxDelayInMillis,omitempty"`
+	BaseIntervalInMillis int64 `json:"baseIntervalInMillis,omitempty"`
+}
+
+// Profile defines a named group of settings in the shared config file.
+type Profile struct {
+	AccessKeyId               string            `json:"accessKeyId,omitempty"`
+	SecretAccessKey           string            `json:"secretAccessKey,omitempty"`
+	SessionToken              string            `json:"sessionToken,omitempty"`
+	Region                    string            `json:"region,omitempty"`
+	Endpoint                  string            `json:"endpoint,omitempty"`
+	Endpoints                 map[string]string `json:"endpoints,omitempty"` // keyed by service name
+	ProxyUrl                  string            `json:"proxyUrl,omitempty"`
+	ConnectionTimeoutInMillis int               `json:"connectionTimeoutInMillis,omitempty"`
+	Retry                     *RetryProfile     `json:"retry,omitempty"`
+	LogLevel                  string            `json:"logLevel,omitempty"`
+}
+
+// ConfigFile defines the structure of the shared config file, for example:
+//     {
+//         "profiles": {
+//             "default": {
+//                 "accessKeyId": "ak",
+//                 "secretAccessKey": "sk",
+//                 "region": "bj",
+//                 "endpoints": {"bos": "bj.bcebos.com"},
+//                 "retry": {"maxErrorRetry": 3},
+//                 "logLevel": "warn"
+//             }
+//         }
+//     }
+type ConfigFile struct {
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// ConfigLoader loads the BceClientConfiguration of a service from the shared config file and the
+// environment variables. The precedence from high to low is:
+//     1. the `BCE_*` environment variables, eg. BCE_ACCESS_KEY_ID, BCE_BOS_ENDPOINT, BCE_ENDPOINT
+//     2. the selected profile of the config file, the `endpoints` entry of the service is
+//        prior to the `endpoint` of the profile
+//     3. the SDK defaults, the endpoint is built by the service and region if not given
+// The profile is selected by the `Profile` field, then BCE_PROFILE, and then "default". The config
+// file is selected by the `FilePath` field, then BCE_CONFIG_FILE, and then ~/.bce/config.json, it
+// is an error only if the explicitly selected file or profile does not exist.
+type ConfigLoader struct {
+	FilePath string
+	Profile  string
+	Service  string // the lower case service name, eg. bos, bcc, used to choose the endpoint
+
+	// LookupEnv is used to read the environment variables including HOME, default is os.LookupEnv
+	// and the home directory is given by os.UserHomeDir
+	LookupEnv func(string) (string, bool)
+}
+
+// LoadConfiguration - load the client configuration of the service with the default config file
+// and the environment variables
+//
+// PARAMS:
+//     - profile: the profile name, empty to use BCE_PROFILE or the default profile
+//     - service: the lower case service name, eg. bos, bcc
+// RETURNS:
+//     - *BceClientConfiguration: the loaded configuration
+//     - error: nil if ok otherwise the specific error
+func LoadConfiguration(profile, service string) (*BceClientConfiguration, error) {
+	loader := &ConfigLoader{Profile: profile, Service: service}
+	return loader.Load()
+}
+
+// LoadConfigFile - parse the shared config file of the given path
+//
+// PARAMS:
+//     - path: the config file path
+// RETURNS:
+//     - *ConfigFile: the parsed config file
+//     - error: nil if ok otherwise the specific error
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	result := &ConfigFile{}
+	if err := json.NewDecoder(fp).Decode(result); err != nil {
+		return nil, NewBceClientError(fmt.Sprintf("invalid config file %s: %v", path, err))
+	}
+	return result, nil
+}
+
+// Load - resolve the client configuration by the precedence rules of the loader. The log level of
+// the profile is not applied, use LoadLogLevel to get it.
+//
+// RETURNS:
+//     - *BceClientConfiguration: the loaded configuration
+//     - error: nil if ok otherwise the specific error
+func (l *ConfigLoader) Load() (*BceClientConfiguration, error) {
+	profile, err := l.loadProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Override the profile with the environment variables
+	l.overrideString(&profile.AccessKeyId, ENV_ACCESS_KEY_ID)
+	l.overrideString(&profile.SecretAccessKey, ENV_SECRET_ACCESS_KEY)
+	l.overrideString(&profile.SessionToken, ENV_SESSION_TOKEN)
+	l.overrideString(&profile.Region, ENV_REGION)
+	l.overrideString(&profile.ProxyUrl, ENV_PROXY_URL)
+	if val, ok := l.lookupEnv(ENV_CONNECTION_TIMEOUT_IN_MILLIS); ok {
+		millis, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, NewBceClientError(fmt.Sprintf("invalid %s: %s",
+				ENV_CONNECTION_TIMEOUT_IN_MILLIS, val))
+		}
+		profile.ConnectionTimeoutInMillis = millis
+	}
+	if val, ok := l.lookupEnv(ENV_MAX_ERROR_RETRY); ok {
+		maxRetry, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, NewBceClientError(fmt.Sprintf("invalid %s: %s", ENV_MAX_ERROR_RETRY, val))
+		}
+		if profile.Retry == nil {
+			profile.Retry = &RetryProfile{}
+		}
+		profile.Retry.MaxErrorRetry = &maxRetry
+	}
+
+	conf := &BceClientConfiguration{
+		Endpoint:  l.resolveEndpoint(profile),
+		ProxyUrl:  profile.ProxyUrl,
+		Region:    profile.Region,
+		UserAgent: DEFAULT_USER_AGENT,
+		SignOption: &auth.SignOptions{
+			HeadersToSign: auth.DEFAULT_HEADERS_TO_SIGN,
+			ExpireSeconds: auth.DEFAULT_EXPIRE_SECONDS},
+		Retry:                     DEFAULT_RETRY_POLICY,
+		ConnectionTimeoutInMillis: DEFAULT_CONNECTION_TIMEOUT_IN_MILLIS,
+	}
+	if len(conf.Region) == 0 {
+		conf.Region = DEFAULT_REGION
+	}
+	if profile.ConnectionTimeoutInMillis > 0 {
+		conf.ConnectionTimeoutInMillis = profile.ConnectionTimeoutInMillis
+	}
+	if profile.Retry != nil {
+		conf.Retry = profile.Retry.policy()
+	}
+
+	if len(profile.AccessKeyId) != 0 || len(profile.SecretAccessKey) != 0 {
+		if len(profile.SessionToken) != 0 {
+			conf.Credentials, err = auth.NewSessionBceCredentials(profile.AccessKeyId,
+				profile.SecretAccessKey, profile.SessionToken)
+		} else {
+			conf.Credentials, err = auth.NewBceCredentials(profile.AccessKeyId,
+				profile.SecretAccessKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
+
+// LoadLogLevel - resolve the log level by the logLevel of the profile and BCE_LOG_LEVEL, the level
+// is left to the caller to apply by log.SetLogLevel
+//
+// RETURNS:
+//     - log.Level: the log level
+//     - bool: whether the log level is specified
+//     - error: nil if ok otherwise the specific error
+func (l *ConfigLoader) LoadLogLevel() (log.Level, bool, error) {
+	profile, err := l.loadProfile()
+	if err != nil {
+		return 0, false, err
+	}
+	l.overrideString(&profile.LogLevel, ENV_LOG_LEVEL)
+	if len(profile.LogLevel) == 0 {
+		return 0, false, nil
+	}
+	level, err := parseLogLevel(profile.LogLevel)
+	if err != nil {
+		return 0, false, err
+	}
+	return level, true, nil
+}
+
+func (l *ConfigLoader) lookupEnv(key string) (string, bool) {
+	lookup := l.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	val, ok := lookup(key)
+	if !ok || len(val) == 0 {
+		return "", false
+	}
+	return val, true
+}
+
+// homeDir returns the home directory of the current user, it is read from HOME if the LookupEnv
+// is injected so that the environment is fully replaced by it
+func (l *ConfigLoader) homeDir() string {
+	if l.LookupEnv != nil {
+		home, _ := l.lookupEnv("HOME")
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+func (l *ConfigLoader) overrideString(field *string, key string) {
+	if val, ok := l.lookupEnv(key); ok {
+		*field = val
+	}
+}
+
+func (l *ConfigLoader) loadProfile() (*Profile, error) {
+	name, explicitProfile := l.Profile, true
+	if len(name) == 0 {
+		name, explicitProfile = l.lookupEnv(ENV_PROFILE)
+		if !explicitProfile {
+			name = DEFAULT_PROFILE
+		}
+	}
+
+	path, explicitFile := l.FilePath, true
+	if len(path) == 0 {
+		path, explicitFile = l.lookupEnv(ENV_CONFIG_FILE)
+	}
+	if !explicitFile {
+		home := l.homeDir()
+		if len(home) == 0 {
+			return &Profile{}, nil
+		}
+		path = filepath.Join(home, DEFAULT_CONFIG_FILE)
+	}
+
+	file, err := LoadConfigFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicitFile && !explicitProfile {
+			return &Profile{}, nil
+		}
+		return nil, err
+	}
+	profile, ok := file.Profiles[name]
+	if !ok || profile == nil {
+		if explicitProfile {
+			return nil, NewBceClientError(fmt.Sprintf("profile %s not found in %s", name, path))
+		}
+		return &Profile{}, nil
+	}
+	result := *profile
+	return &result, nil
+}
+
+func (l *ConfigLoader) resolveEndpoint(profile *Profile) string {
+	service := strings.ToLower(l.Service)
+	if len(service) != 0 {
+		if val, ok := l.lookupEnv(fmt.Sprintf(ENV_SERVICE_ENDPOINT_FORMAT,
+			strings.ToUpper(service))); ok {
+			return val
+		}
+	}
+	if val, ok := l.lookupEnv(ENV_ENDPOINT); ok {
+		return val
+	}
+	if val, ok := profile.Endpoints[service]; ok && len(service) != 0 {
+		return val
+	}
+	if len(profile.Endpoint) != 0 {
+		return profile.Endpoint
+	}
+	if len(service) == 0 {
+		return ""
+	}
+	region := profile.Region
+	if len(region) == 0 {
+		region = DEFAULT_REGION
+	}
+	if service == "bos" {
+		return region + ".bcebos.com"
+	}
+	return service + "." + region + "." + DEFAULT_DOMAIN
+}
+
+func (r *RetryProfile) policy() RetryPolicy {
+	maxRetry := DEFAULT_RETRY_POLICY.maxErrorRetry
+	if r.MaxErrorRetry != nil {
+		maxRetry = *r.MaxErrorRetry
+	}
+	if maxRetry <= 0 {
+		return NewNoRetryPolicy()
+	}
+	maxDelay, base := DEFAULT_RETRY_POLICY.maxDelayInMillis, DEFAULT_RETRY_POLICY.baseIntervalInMillis
+	if r.MaxDelayInMillis > 0 {
+		maxDelay = r.MaxDelayInMillis
+	}
+	if r.BaseIntervalInMillis > 0 {
+		base = r.BaseIntervalInMillis
+	}
+	return NewBackOffRetryPolicy(maxRetry, maxDelay, base)
+}
+
+func parseLogLevel(level string) (log.Level, error) {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return log.DEBUG, nil
+	case "INFO":
+		return log.INFO, nil
+	case "WARN", "WARNING":
+		return log.WARN, nil
+	case "ERROR":
+		return log.ERROR, nil
+	case "FATAL":
+		return log.FATAL, nil
+	case "PANIC":
+		return log.PANIC, nil
+	}
+	return log.DEBUG, NewBceClientError("invalid log level: " + level)
+}
@@ -0,0 +1,166 @@
+package bce
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+const testConfigFile = `{
+    "profiles": {
+        "default": {
+            "accessKeyId": "ak",
+            "secretAccessKey": "sk",
+            "region": "gz",
+            "endpoints": {"bos": "custom.bcebos.com"},
+            "connectionTimeoutInMillis": 5000,
+            "retry": {"maxErrorRetry": 0}
+        },
+        "sts": {
+            "accessKeyId": "tmp-ak",
+            "secretAccessKey": "tmp-sk",
+            "sessionToken": "token",
+            "endpoint": "bcc.su.baidubce.com",
+            "logLevel": "error"
+        }
+    }
+}`
+
+func newTestLoader(t *testing.T, env map[string]string) (*ConfigLoader, func()) {
+	dir, err := ioutil.TempDir("", "bce-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(testConfigFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loader := &ConfigLoader{
+		FilePath: path,
+		LookupEnv: func(key string) (string, bool) {
+			val, ok := env[key]
+			return val, ok
+		},
+	}
+	return loader, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadDefaultProfile(t *testing.T) {
+	loader, clean := newTestLoader(t, map[string]string{})
+	defer clean()
+
+	loader.Service = "bos"
+	conf, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Endpoint != "custom.bcebos.com" || conf.Region != "gz" {
+		t.Errorf("unexpected endpoint or region: %s, %s", conf.Endpoint, conf.Region)
+	}
+	if conf.Credentials.AccessKeyId != "ak" || conf.ConnectionTimeoutInMillis != 5000 {
+		t.Errorf("unexpected config: %v", conf)
+	}
+	if _, ok := conf.Retry.(*NoRetryPolicy); !ok {
+		t.Errorf("expect no retry policy but %T", conf.Retry)
+	}
+
+	loader.Service = "vpc"
+	conf, err = loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Endpoint != "vpc.gz.baidubce.com" {
+		t.Errorf("unexpected default endpoint: %s", conf.Endpoint)
+	}
+}
+
+func TestLoadEnvironmentPrecedence(t *testing.T) {
+	loader, clean := newTestLoader(t, map[string]string{
+		ENV_PROFILE:           "sts",
+		ENV_SECRET_ACCESS_KEY: "env-sk",
+		ENV_MAX_ERROR_RETRY:   "5",
+		"BCE_BCC_ENDPOINT":    "bcc.bj.baidubce.com",
+	})
+	defer clean()
+
+	loader.Service = "bcc"
+	conf, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred := conf.Credentials
+	if cred.AccessKeyId != "tmp-ak" || cred.SecretAccessKey != "env-sk" || cred.SessionToken != "token" {
+		t.Errorf("unexpected credentials: %v", cred)
+	}
+	if conf.Endpoint != "bcc.bj.baidubce.com" || conf.Region != DEFAULT_REGION {
+		t.Errorf("unexpected endpoint or region: %s, %s", conf.Endpoint, conf.Region)
+	}
+	if policy, ok := conf.Retry.(*BackOffRetryPolicy); !ok || policy.maxErrorRetry != 5 {
+		t.Errorf("unexpected retry policy: %v", conf.Retry)
+	}
+}
+
+func TestLoadLogLevel(t *testing.T) {
+	loader, clean := newTestLoader(t, map[string]string{})
+	defer clean()
+
+	if _, ok, err := loader.LoadLogLevel(); ok || err != nil {
+		t.Errorf("expect no log level of the default profile: %v", err)
+	}
+	loader.Profile = "sts"
+	if level, ok, err := loader.LoadLogLevel(); !ok || err != nil || level != log.ERROR {
+		t.Errorf("unexpected log level %v of the profile: %v", level, err)
+	}
+	loader.LookupEnv = func(key string) (string, bool) {
+		if key == ENV_LOG_LEVEL {
+			return "warn", true
+		}
+		return "", false
+	}
+	if level, ok, err := loader.LoadLogLevel(); !ok || err != nil || level != log.WARN {
+		t.Errorf("unexpected log level %v of the environment: %v", level, err)
+	}
+	loader.LookupEnv = func(key string) (string, bool) { return "verbose", key == ENV_LOG_LEVEL }
+	if _, _, err := loader.LoadLogLevel(); err == nil {
+		t.Errorf("expect error of invalid log level")
+	}
+}
+
+func TestLoadHomeConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bce-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, DEFAULT_CONFIG_FILE)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(testConfigFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &ConfigLoader{Service: "bos", LookupEnv: func(key string) (string, bool) {
+		return dir, key == "HOME"
+	}}
+	conf, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Endpoint != "custom.bcebos.com" || conf.Credentials.AccessKeyId != "ak" {
+		t.Errorf("expect the config file in the home directory, got %s", conf.Endpoint)
+	}
+}
+
+func TestLoadMissingProfile(t *testing.T) {
+	loader, clean := newTestLoader(t, map[string]string{})
+	defer clean()
+
+	loader.Profile = "missing"
+	if _, err := loader.Load(); err == nil {
+		t.Errorf("expect error for the missing profile")
+	}
+}
@@ -176,11 +176,15 @@ func NewBodyFromSizedReader(r io.Reader, size int64) (*Body, error) {
 	return body, nil
 }
 
+// CLIENT_TOKEN is the query parameter name of the idempotent token supported by BCE services
+const CLIENT_TOKEN = "clientToken"
+
 // BceRequest defines the request structure for accessing BCE services
 type BceRequest struct {
 	http.Request
 	requestId   string
 	clientError *BceClientError
+	idempotent  *bool
 }
 
 func (b *BceRequest) RequestId() string { return b.requestId }
@@ -191,6 +195,30 @@ func (b *BceRequest) ClientError() *BceClientError { return b.clientError }
 
 func (b *BceRequest) SetClientError(err *BceClientError) { b.clientError = err }
 
+// SetClientToken - set the idempotent token of the request. A new token is generated by UUID if
+// the given one is empty, so that all retries of the same logical call share the same token.
+//
+// PARAMS:
+//     - token: the idempotent token given by the user, may be empty
+// RETURNS:
+//     - string: the token actually sent
+func (b *BceRequest) SetClientToken(token string) string {
+	if len(token) == 0 {
+		token = util.NewUUID()
+	}
+	b.SetParam(CLIENT_TOKEN, token)
+	return token
+}
+
+// IsIdempotent returns whether the request can be replayed safely by the retry policy. All the
+// requests are idempotent unless marked explicitly, the non-idempotent calls without the token
+// support are marked by `SetIdempotent(false)`.
+func (b *BceRequest) IsIdempotent() bool {
+	return b.idempotent == nil || *b.idempotent
+}
+
+func (b *BceRequest) SetIdempotent(val bool) { b.idempotent = &val }
+
 func (b *BceRequest) SetBody(body *Body) { // override SetBody derived from http.Request
 	b.Request.SetBody(body.Stream())
 	b.SetLength(body.Size()) // set field of "net/http.Request.ContentLength"
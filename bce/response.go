@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	net_http "net/http"
 	"strings"
 	"time"
 
@@ -65,6 +66,16 @@ func (r *BceResponse) Headers() map[string]string {
 	return r.response.GetHeaders()
 }
 
+// HeaderValues returns all the values of the given response header.
+func (r *BceResponse) HeaderValues(key string) []string {
+	return r.response.GetHeaderValues(key)
+}
+
+// MultiHeaders returns all the response headers including the repeated ones.
+func (r *BceResponse) MultiHeaders() net_http.Header {
+	return r.response.GetMultiHeaders()
+}
+
 func (r *BceResponse) Body() io.ReadCloser {
 	return r.response.Body()
 }
@@ -127,14 +127,8 @@ func Execute(request *Request) (*Response, error) {
 		RawQuery: request.QueryString()}
 	httpRequest.URL = internalUrl
 
-	// Set the request headers
-	internalHeader := make(http.Header)
-	for k, v := range request.Headers() {
-		val := make([]string, 0, 1)
-		val = append(val, v)
-		internalHeader[k] = val
-	}
-	httpRequest.Header = internalHeader
+	// Set the request headers, all the values of the repeated headers are kept
+	httpRequest.Header = request.MultiHeaders()
 
 	if request.Body() != nil {
 		if request.Length() > 0 {
@@ -19,6 +19,9 @@ package http
 import (
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -37,6 +40,11 @@ type Request struct {
 	headers  map[string]string
 	params   map[string]string
 
+	// The values after the first one of the repeated headers and query parameters, they are
+	// added by AddHeader and AddParam
+	extraHeaders map[string][]string
+	extraParams  map[string][]string
+
 	// Optional body and length fields to set the body stream and content length
 	body   io.ReadCloser
 	length int64
@@ -102,12 +110,27 @@ func (r *Request) SetPort(port int) {
 	r.port = port
 }
 
+// Headers returns the first value of every header. The returned map is owned by the request and
+// changes on it take effect on the request, use the `HeaderValues` or `MultiHeaders` to get the
+// values added by `AddHeader`.
 func (r *Request) Headers() map[string]string {
 	return r.headers
 }
 
+// SetHeaders replaces all the headers with the given single-valued ones.
 func (r *Request) SetHeaders(headers map[string]string) {
 	r.headers = headers
+	r.extraHeaders = nil
+}
+
+// MultiHeaders returns a copy of all the headers including the values added by `AddHeader`.
+func (r *Request) MultiHeaders() http.Header {
+	return multiValues(r.headers, r.extraHeaders)
+}
+
+// SetMultiHeaders replaces all the headers with the given multi-valued ones.
+func (r *Request) SetMultiHeaders(headers http.Header) {
+	r.headers, r.extraHeaders = splitValues(headers)
 }
 
 func (r *Request) Header(key string) string {
@@ -117,19 +140,51 @@ func (r *Request) Header(key string) string {
 	return ""
 }
 
+// HeaderValues returns all the values of the given header key.
+func (r *Request) HeaderValues(key string) []string {
+	return values(r.headers, r.extraHeaders, key)
+}
+
 func (r *Request) SetHeader(key, value string) {
 	if r.headers == nil {
 		r.headers = make(map[string]string)
 	}
 	r.headers[key] = value
+	delete(r.extraHeaders, key)
+}
+
+// AddHeader appends the value to the given header key instead of replacing it.
+func (r *Request) AddHeader(key, value string) {
+	r.headers, r.extraHeaders = addValue(r.headers, r.extraHeaders, key, value)
+}
+
+func (r *Request) DelHeader(key string) {
+	delete(r.headers, key)
+	delete(r.extraHeaders, key)
 }
 
+// Params returns the first value of every query parameter. The returned map is owned by the
+// request and changes on it take effect on the request, use the `ParamValues` or `MultiParams`
+// to get the values added by `AddParam`.
 func (r *Request) Params() map[string]string {
 	return r.params
 }
 
+// SetParams replaces all the query parameters with the given single-valued ones.
 func (r *Request) SetParams(params map[string]string) {
 	r.params = params
+	r.extraParams = nil
+}
+
+// MultiParams returns a copy of all the query parameters including the values added by
+// `AddParam`.
+func (r *Request) MultiParams() url.Values {
+	return url.Values(multiValues(r.params, r.extraParams))
+}
+
+// SetMultiParams replaces all the query parameters with the given multi-valued ones.
+func (r *Request) SetMultiParams(params url.Values) {
+	r.params, r.extraParams = splitValues(params)
 }
 
 func (r *Request) Param(key string) string {
@@ -139,17 +194,41 @@ func (r *Request) Param(key string) string {
 	return ""
 }
 
+// ParamValues returns all the values of the given query parameter key.
+func (r *Request) ParamValues(key string) []string {
+	return values(r.params, r.extraParams, key)
+}
+
 func (r *Request) SetParam(key, value string) {
 	if r.params == nil {
 		r.params = make(map[string]string)
 	}
 	r.params[key] = value
+	delete(r.extraParams, key)
+}
+
+// AddParam appends the value to the given query parameter key instead of replacing it.
+func (r *Request) AddParam(key, value string) {
+	r.params, r.extraParams = addValue(r.params, r.extraParams, key, value)
+}
+
+func (r *Request) DelParam(key string) {
+	delete(r.params, key)
+	delete(r.extraParams, key)
 }
 
 func (r *Request) QueryString() string {
-	buf := make([]string, 0, len(r.params))
-	for k, v := range r.params {
-		buf = append(buf, util.UriEncode(k, true)+"="+util.UriEncode(v, true))
+	params := r.MultiParams()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf := make([]string, 0, len(params))
+	for _, k := range keys {
+		for _, v := range params[k] {
+			buf = append(buf, util.UriEncode(k, true)+"="+util.UriEncode(v, true))
+		}
 	}
 	return strings.Join(buf, "&")
 }
@@ -213,9 +292,64 @@ func (r *Request) GenerateUrl(addPort bool) string {
 
 func (r *Request) String() string {
 	header := make([]string, 0, len(r.headers))
-	for k, v := range r.headers {
-		header = append(header, "\t"+k+"="+v)
+	for k, v := range r.MultiHeaders() {
+		for _, val := range v {
+			header = append(header, "\t"+k+"="+val)
+		}
 	}
 	return fmt.Sprintf("\t%s %s\n%v",
 		r.method, r.GenerateUrl(false), strings.Join(header, "\n"))
 }
+
+// values returns the first value and the extra values of the key, the extra values are ignored if
+// the key has been removed from the first values
+func values(first map[string]string, extra map[string][]string, key string) []string {
+	v, ok := first[key]
+	if !ok {
+		return nil
+	}
+	return append([]string{v}, extra[key]...)
+}
+
+func multiValues(first map[string]string, extra map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(first))
+	for k := range first {
+		result[k] = values(first, extra, k)
+	}
+	return result
+}
+
+func splitValues(all map[string][]string) (map[string]string, map[string][]string) {
+	first := make(map[string]string, len(all))
+	var extra map[string][]string
+	for k, v := range all {
+		if len(v) == 0 {
+			continue
+		}
+		first[k] = v[0]
+		if len(v) > 1 {
+			if extra == nil {
+				extra = make(map[string][]string)
+			}
+			extra[k] = append([]string(nil), v[1:]...)
+		}
+	}
+	return first, extra
+}
+
+func addValue(first map[string]string, extra map[string][]string, key, value string) (
+	map[string]string, map[string][]string) {
+	if first == nil {
+		first = make(map[string]string)
+	}
+	if _, ok := first[key]; !ok {
+		first[key] = value
+		delete(extra, key)
+		return first, extra
+	}
+	if extra == nil {
+		extra = make(map[string][]string)
+	}
+	extra[key] = append(extra[key], value)
+	return first, extra
+}
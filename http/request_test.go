@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	req := &Request{}
+	req.SetHeader("x-bce-meta-tag", "a")
+	req.AddHeader("x-bce-meta-tag", "b")
+	req.AddHeader("x-bce-meta-tag", "c")
+	req.AddHeader(HOST, "bj.bcebos.com")
+
+	// the returned map is the live single-valued view of the headers
+	req.Headers()[CONTENT_TYPE] = "text/plain"
+	if req.Header(CONTENT_TYPE) != "text/plain" || req.Headers()["x-bce-meta-tag"] != "a" {
+		t.Errorf("unexpected headers %v", req.Headers())
+	}
+	values := req.HeaderValues("x-bce-meta-tag")
+	if !reflect.DeepEqual(values, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected header values %v", values)
+	}
+	expected := http.Header{
+		"x-bce-meta-tag": {"a", "b", "c"},
+		HOST:             {"bj.bcebos.com"},
+		CONTENT_TYPE:     {"text/plain"},
+	}
+	multi := req.MultiHeaders()
+	if !reflect.DeepEqual(multi, expected) {
+		t.Errorf("unexpected multi headers %v", multi)
+	}
+	// the multi-valued headers are a copy
+	multi["x-bce-meta-tag"] = nil
+	if len(req.HeaderValues("x-bce-meta-tag")) != 3 {
+		t.Errorf("expect the request unchanged by the copy")
+	}
+
+	req.SetHeader("x-bce-meta-tag", "d")
+	if values := req.HeaderValues("x-bce-meta-tag"); !reflect.DeepEqual(values, []string{"d"}) {
+		t.Errorf("expect the added values replaced, got %v", values)
+	}
+	req.AddHeader("x-bce-meta-tag", "e")
+	delete(req.Headers(), "x-bce-meta-tag")
+	if values := req.HeaderValues("x-bce-meta-tag"); values != nil {
+		t.Errorf("expect the added values dropped with the deleted header, got %v", values)
+	}
+
+	req.SetMultiHeaders(http.Header{"x-bce-acl": {"private", "public-read"}})
+	if req.Header("x-bce-acl") != "private" || len(req.HeaderValues("x-bce-acl")) != 2 ||
+		len(req.Headers()) != 1 {
+		t.Errorf("unexpected headers %v", req.MultiHeaders())
+	}
+	req.DelHeader("x-bce-acl")
+	if len(req.MultiHeaders()) != 0 {
+		t.Errorf("expect no header, got %v", req.MultiHeaders())
+	}
+}
+
+func TestRequestParams(t *testing.T) {
+	req := &Request{}
+	req.AddParam("tag", "b")
+	req.AddParam("tag", "a")
+	req.Params()["acl"] = ""
+	req.SetParam("maxKeys", "10")
+
+	if values := req.ParamValues("tag"); !reflect.DeepEqual(values, []string{"b", "a"}) {
+		t.Errorf("unexpected param values %v", values)
+	}
+	if req.Param("tag") != "b" || len(req.Params()) != 3 || len(req.MultiParams()["tag"]) != 2 {
+		t.Errorf("unexpected params %v", req.MultiParams())
+	}
+	if qs := req.QueryString(); qs != "acl=&maxKeys=10&tag=b&tag=a" {
+		t.Errorf("unexpected query string %s", qs)
+	}
+
+	req.DelParam("tag")
+	req.SetParams(map[string]string{"uploads": ""})
+	if qs := req.QueryString(); qs != "uploads=" {
+		t.Errorf("unexpected query string %s", qs)
+	}
+}
@@ -56,6 +56,13 @@ func (r *Response) GetHeader(name string) string {
 	return r.httpResponse.Header.Get(name)
 }
 
+// GetHeaderValues returns all the values of the given response header.
+func (r *Response) GetHeaderValues(name string) []string {
+	return r.httpResponse.Header[http.CanonicalHeaderKey(name)]
+}
+
+// GetHeaders returns the first value of every response header. Use the `GetMultiHeaders` to get
+// all of them, for example multiple `Set-Cookie` headers.
 func (r *Response) GetHeaders() map[string]string {
 	header := r.httpResponse.Header
 	ret := make(map[string]string, len(header))
@@ -65,6 +72,11 @@ func (r *Response) GetHeaders() map[string]string {
 	return ret
 }
 
+// GetMultiHeaders returns all the response headers with `net/http.Header` semantics.
+func (r *Response) GetMultiHeaders() http.Header {
+	return r.httpResponse.Header
+}
+
 func (r *Response) ContentLength() int64 {
 	return r.httpResponse.ContentLength
 }
@@ -44,7 +44,7 @@ func (c *Client) CreateLoadBalancer(args *CreateLoadBalancerArgs) (*CreateLoadBa
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppBlbUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -67,7 +67,7 @@ func (c *Client) UpdateLoadBalancer(blbId string, args *UpdateLoadBalancerArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppBlbUriWithId(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -41,7 +41,7 @@ func (c *Client) CreateAppIpGroup(blbId string, args *CreateAppIpGroupArgs) (*Cr
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppIpGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -64,7 +64,7 @@ func (c *Client) UpdateAppIpGroup(blbId string, args *UpdateAppIpGroupArgs) erro
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -118,7 +118,7 @@ func (c *Client) DeleteAppIpGroup(blbId string, args *DeleteAppIpGroupArgs) erro
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("delete", "").
 		WithBody(args).
 		Do()
@@ -147,7 +147,7 @@ func (c *Client) CreateAppIpGroupBackendPolicy(blbId string, args *CreateAppIpGr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppIpGroupBackendPolicyUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -171,7 +171,7 @@ func (c *Client) UpdateAppIpGroupBackendPolicy (blbId string, args *UpdateAppIpG
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupBackendPolicyUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -191,7 +191,7 @@ func (c *Client) DeleteAppIpGroupBackendPolicy(blbId string, args *DeleteAppIpGr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupBackendPolicyUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("delete", "").
 		WithBody(args).
 		Do()
@@ -212,7 +212,7 @@ func (c *Client) CreateAppIpGroupMember(blbId string, args *CreateAppIpGroupMemb
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppIpGroupMemberUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -232,7 +232,7 @@ func (c *Client) UpdateAppIpGroupMember(blbId string, args *UpdateAppIpGroupMemb
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupMemberUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -282,7 +282,7 @@ func (c *Client) DeleteAppIpGroupMember(blbId string, args *DeleteAppIpGroupMemb
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppIpGroupMemberUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("delete", "").
 		WithBody(args).
 		Do()
@@ -41,7 +41,7 @@ func (c *Client) CreateAppServerGroup(blbId string, args *CreateAppServerGroupAr
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppServerGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -64,7 +64,7 @@ func (c *Client) UpdateAppServerGroup(blbId string, args *UpdateAppServerGroupAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppServerGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -118,7 +118,7 @@ func (c *Client) DeleteAppServerGroup(blbId string, args *DeleteAppServerGroupAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppServerGroupUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("delete", "").
 		WithBody(args).
 		Do()
@@ -149,7 +149,7 @@ func (c *Client) CreateAppServerGroupPort(blbId string, args *CreateAppServerGro
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppServerGroupPortUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -172,7 +172,7 @@ func (c *Client) UpdateAppServerGroupPort(blbId string, args *UpdateAppServerGro
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppServerGroupPortUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -192,7 +192,7 @@ func (c *Client) DeleteAppServerGroupPort(blbId string, args *DeleteAppServerGro
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppServerGroupPortUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("batchdelete", "").
 		WithBody(args).
 		Do()
@@ -213,7 +213,7 @@ func (c *Client) CreateBlbRs(blbId string, args *CreateBlbRsArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getBlbRsUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -233,7 +233,7 @@ func (c *Client) UpdateBlbRs(blbId string, args *UpdateBlbRsArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getBlbRsUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -283,7 +283,7 @@ func (c *Client) DeleteBlbRs(blbId string, args *DeleteBlbRsArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getBlbRsUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("batchdelete", "").
 		WithBody(args).
 		Do()
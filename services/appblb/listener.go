@@ -47,7 +47,7 @@ func (c *Client) CreateAppTCPListener(blbId string, args *CreateAppTCPListenerAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppTCPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -75,7 +75,7 @@ func (c *Client) CreateAppUDPListener(blbId string, args *CreateAppUDPListenerAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppUDPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -103,7 +103,7 @@ func (c *Client) CreateAppHTTPListener(blbId string, args *CreateAppHTTPListener
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppHTTPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -135,7 +135,7 @@ func (c *Client) CreateAppHTTPSListener(blbId string, args *CreateAppHTTPSListen
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppHTTPSListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -167,7 +167,7 @@ func (c *Client) CreateAppSSLListener(blbId string, args *CreateAppSSLListenerAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAppSSLListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -188,7 +188,7 @@ func (c *Client) UpdateAppTCPListener(blbId string, args *UpdateAppTCPListenerAr
 		WithMethod(http.PUT).
 		WithURL(getAppTCPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -213,7 +213,7 @@ func (c *Client) UpdateAppUDPListener(blbId string, args *UpdateAppUDPListenerAr
 		WithMethod(http.PUT).
 		WithURL(getAppUDPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -238,7 +238,7 @@ func (c *Client) UpdateAppHTTPListener(blbId string, args *UpdateAppHTTPListener
 		WithMethod(http.PUT).
 		WithURL(getAppHTTPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -267,7 +267,7 @@ func (c *Client) UpdateAppHTTPSListener(blbId string, args *UpdateAppHTTPSListen
 		WithMethod(http.PUT).
 		WithURL(getAppHTTPSListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -296,7 +296,7 @@ func (c *Client) UpdateAppSSLListener(blbId string, args *UpdateAppSSLListenerAr
 		WithMethod(http.PUT).
 		WithURL(getAppSSLListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -518,7 +518,7 @@ func (c *Client) DeleteAppListeners(blbId string, args *DeleteAppListenersArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getAppListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("batchdelete", "").
 		WithBody(args).
 		Do()
@@ -547,7 +547,7 @@ func (c *Client) CreatePolicys(blbId string, args *CreatePolicysArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getPolicysUrl(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -609,7 +609,7 @@ func (c *Client) DeletePolicys(blbId string, args *DeletePolicysArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getPolicysUrl(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("batchdelete", "").
 		WithBody(args).
 		Do()
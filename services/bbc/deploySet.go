@@ -39,9 +39,7 @@ func CreateDeploySet(cli bce.Client, clientToken string, reqBody *bce.Body) (*Cr
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -41,9 +41,7 @@ func CreateImageFromInstanceId(cli bce.Client, clientToken string, reqBody *bce.
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
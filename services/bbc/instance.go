@@ -46,9 +46,7 @@ func CreateInstance(cli bce.Client, args *CreateInstanceArgs, reqBody *bce.Body)
 	req.SetBody(reqBody)
 	req.SetHeader("x-request-token", requestToken)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -438,9 +436,7 @@ func ModifyInstanceDesc(cli bce.Client, instanceId string, clientToken string, r
 	req.SetMethod(http.PUT)
 	req.SetParam("updateDesc", "")
 	req.SetBody(reqBody)
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -671,9 +667,7 @@ func BatchAddIp(cli bce.Client, args *BatchAddIpArgs, reqBody *bce.Body) (*Batch
 	req.SetMethod(http.PUT)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -708,9 +702,7 @@ func BatchAddIpCrossSubnet(cli bce.Client, args *BatchAddIpCrossSubnetArgs, reqB
 	req.SetMethod(http.PUT)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -744,9 +736,7 @@ func BatchDelIp(cli bce.Client, args *BatchDelIpArgs, reqBody *bce.Body) error {
 	req.SetMethod(http.PUT)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -39,8 +39,8 @@ func CreateAutoSnapshotPolicy(cli bce.Client, args *CreateASPArgs) (*CreateASPRe
 	req.SetUri(getASPUri())
 	req.SetMethod(http.POST)
 
-	if args != nil && len(args.ClientToken) != 0 {
-		req.SetParam("clientToken", args.ClientToken)
+	if args != nil {
+		req.SetClientToken(args.ClientToken)
 	}
 
 	jsonBytes, err := json.Marshal(args)
@@ -39,9 +39,7 @@ func CreateCDSVolume(cli bce.Client, args *CreateCDSVolumeArgs) (*CreateCDSVolum
 	req.SetUri(getVolumeUri())
 	req.SetMethod(http.POST)
 
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -83,9 +81,7 @@ func CreateCDSVolumeV3(cli bce.Client, args *CreateCDSVolumeV3Args) (*CreateCDSV
 	req.SetUri(getVolumeV3Uri())
 	req.SetMethod(http.POST)
 
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -432,9 +428,7 @@ func ResizeCDSVolume(cli bce.Client, volumeId string, args *ResizeCSDVolumeArgs)
 	req.SetUri(getVolumeUriWithId(volumeId))
 	req.SetMethod(http.PUT)
 
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 	req.SetParam("resize", "")
 
 	jsonBytes, err := json.Marshal(args)
@@ -513,9 +507,7 @@ func PurchaseReservedCDSVolume(cli bce.Client, volumeId string, args *PurchaseRe
 	req.SetUri(getVolumeUriWithId(volumeId))
 	req.SetMethod(http.PUT)
 
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 	req.SetParam("purchaseReserved", "")
 
 	jsonBytes, err := json.Marshal(args)
@@ -670,9 +662,7 @@ func AutoRenewCDSVolume(cli bce.Client, args *AutoRenewCDSVolumeArgs) error {
 	req := &bce.BceRequest{}
 	req.SetUri(getAutoRenewVolumeUri())
 	req.SetMethod(http.POST)
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -709,9 +699,7 @@ func CancelAutoRenewCDSVolume(cli bce.Client, args *CancelAutoRenewCDSVolumeArgs
 	req := &bce.BceRequest{}
 	req.SetUri(getCancelAutoRenewVolumeUri())
 	req.SetMethod(http.POST)
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
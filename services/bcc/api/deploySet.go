@@ -38,9 +38,7 @@ func CreateDeploySet(cli bce.Client, clientToken string, reqBody *bce.Body) (*Cr
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -110,9 +108,7 @@ func ModifyDeploySet(cli bce.Client, deploySetId string, clientToken string, req
 	req.SetParam("modifyAttribute", "")
 
 	// Send request and get response
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -193,9 +189,7 @@ func UpdateInstanceDeploy(cli bce.Client, clientToken string, reqBody *bce.Body)
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -217,9 +211,7 @@ func DelInstanceDeploy(cli bce.Client, clientToken string, reqBody *bce.Body) er
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
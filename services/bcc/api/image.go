@@ -40,9 +40,7 @@ func CreateImage(cli bce.Client, args *CreateImageArgs) (*CreateImageResult, err
 	req.SetUri(getImageUri())
 	req.SetMethod(http.POST)
 
-	if args.ClientToken != "" {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -193,6 +191,7 @@ func RemoteCopyImage(cli bce.Client, imageId string, args *RemoteCopyImageArgs)
 	req := &bce.BceRequest{}
 	req.SetUri(getImageUriWithId(imageId))
 	req.SetMethod(http.POST)
+	req.SetIdempotent(false)
 
 	req.SetParam("remoteCopy", "")
 
@@ -233,6 +232,7 @@ func RemoteCopyImageReturnImageIds(cli bce.Client, imageId string, args *RemoteC
 	req := &bce.BceRequest{}
 	req.SetUri(getImageUriWithId(imageId))
 	req.SetMethod(http.POST)
+	req.SetIdempotent(false)
 
 	req.SetParam("remoteCopy", "")
 
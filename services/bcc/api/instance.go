@@ -43,9 +43,7 @@ func CreateInstance(cli bce.Client, args *CreateInstanceArgs, reqBody *bce.Body)
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 	req.SetHeader("x-request-token", requestToken)
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -82,9 +80,7 @@ func CreateInstanceBySpec(cli bce.Client, args *CreateInstanceBySpecArgs, reqBod
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 	req.SetHeader("x-request-token", requestToken)
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -121,9 +117,7 @@ func CreateInstanceV3(cli bce.Client, args *CreateInstanceV3Args, reqBody *bce.B
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 	req.SetHeader("x-request-token", requestToken)
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -512,9 +506,7 @@ func ResizeInstance(cli bce.Client, instanceId, clientToken string, reqBody *bce
 	req.SetParam("resize", "")
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -912,9 +904,7 @@ func InstancePurchaseReserved(cli bce.Client, instanceId, relatedRenewFlag, clie
 	req.SetParam("relatedRenewFlag", relatedRenewFlag)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1026,9 +1016,7 @@ func BatchAddIp(cli bce.Client, args *BatchAddIpArgs, reqBody *bce.Body) (*Batch
 	req.SetMethod(http.PUT)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1062,9 +1050,7 @@ func BatchDelIp(cli bce.Client, args *BatchDelIpArgs, reqBody *bce.Body) error {
 	req.SetMethod(http.PUT)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1095,9 +1081,7 @@ func ResizeInstanceBySpec(cli bce.Client, instanceId, clientToken string, reqBod
 	req.SetParam("resize", "")
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1295,9 +1279,7 @@ func CreateBidInstance(cli bce.Client, clientToken string, reqBody *bce.Body) (*
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1330,9 +1312,7 @@ func CancelBidOrder(cli bce.Client, clientToken string, reqBody *bce.Body) (*Cre
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
 
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
@@ -1365,9 +1345,7 @@ func GetBidInstancePrice(cli bce.Client, clientToken string, reqBody *bce.Body)
 	req.SetUri(getBidInstancePriceUri())
 	req.SetMethod(http.POST)
 	req.SetBody(reqBody)
-	if clientToken != "" {
-		req.SetParam("clientToken", clientToken)
-	}
+	req.SetClientToken(clientToken)
 
 	// Send request and get response
 	resp := &bce.BceResponse{}
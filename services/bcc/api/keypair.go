@@ -32,9 +32,7 @@ func CreateKeypair(cli bce.Client, args *CreateKeypairArgs) (*KeypairResult, err
 	req.SetMethod(http.POST)
 	//req.SetParam("create", "")
 
-	if len(args.ClientToken) != 0 {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -68,9 +66,7 @@ func ImportKeypair(cli bce.Client, args *ImportKeypairArgs) (*KeypairResult, err
 	req.SetMethod(http.PUT)
 	req.SetParam("import", "")
 
-	if len(args.ClientToken) != 0 {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// securityGroupPlan.go - the security group rule normalization and reconciliation plan
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	SECURITY_GROUP_DIRECTION_INGRESS = "ingress"
+	SECURITY_GROUP_DIRECTION_EGRESS  = "egress"
+
+	SECURITY_GROUP_ETHERTYPE_IPV4 = "IPv4"
+	SECURITY_GROUP_ETHERTYPE_IPV6 = "IPv6"
+
+	SECURITY_GROUP_PROTOCOL_ALL  = "all"
+	SECURITY_GROUP_PROTOCOL_TCP  = "tcp"
+	SECURITY_GROUP_PROTOCOL_UDP  = "udp"
+	SECURITY_GROUP_PROTOCOL_ICMP = "icmp"
+
+	SECURITY_GROUP_PEER_ALL  = "all"
+	SECURITY_GROUP_PORT_ALL  = "1-65535"
+	SECURITY_GROUP_PORT_MAX  = 65535
+	securityGroupKeySplitter = "|"
+)
+
+// NormalizeSecurityGroupRule - normalize the rule so that rules with the same semantics compare
+// equal: the direction, protocol and ethertype are canonicalized, the CIDR of the peer is reduced
+// to its network address and the port range is written in the shortest form.
+//
+// PARAMS:
+//     - rule: the rule to be normalized
+// RETURNS:
+//     - SecurityGroupRuleModel: the normalized rule which only keeps the fields of its identity
+//     - error: nil if success otherwise the specific error
+func NormalizeSecurityGroupRule(rule SecurityGroupRuleModel) (SecurityGroupRuleModel, error) {
+	result := SecurityGroupRuleModel{}
+
+	result.Direction = strings.ToLower(strings.TrimSpace(rule.Direction))
+	if result.Direction != SECURITY_GROUP_DIRECTION_INGRESS &&
+		result.Direction != SECURITY_GROUP_DIRECTION_EGRESS {
+		return result, fmt.Errorf("invalid direction of security group rule: %q", rule.Direction)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(rule.Ethertype)) {
+	case "", "ipv4":
+		result.Ethertype = SECURITY_GROUP_ETHERTYPE_IPV4
+	case "ipv6":
+		result.Ethertype = SECURITY_GROUP_ETHERTYPE_IPV6
+	default:
+		return result, fmt.Errorf("invalid ethertype of security group rule: %q", rule.Ethertype)
+	}
+
+	result.Protocol = strings.ToLower(strings.TrimSpace(rule.Protocol))
+	switch result.Protocol {
+	case "":
+		result.Protocol = SECURITY_GROUP_PROTOCOL_ALL
+	case SECURITY_GROUP_PROTOCOL_ALL, SECURITY_GROUP_PROTOCOL_TCP, SECURITY_GROUP_PROTOCOL_UDP,
+		SECURITY_GROUP_PROTOCOL_ICMP:
+	default:
+		return result, fmt.Errorf("invalid protocol of security group rule: %q", rule.Protocol)
+	}
+
+	if result.Protocol == SECURITY_GROUP_PROTOCOL_TCP ||
+		result.Protocol == SECURITY_GROUP_PROTOCOL_UDP {
+		portRange, err := normalizePortRange(rule.PortRange)
+		if err != nil {
+			return result, err
+		}
+		result.PortRange = portRange
+	}
+
+	ip, groupId := rule.SourceIp, rule.SourceGroupId
+	if result.Direction == SECURITY_GROUP_DIRECTION_EGRESS {
+		ip, groupId = rule.DestIp, rule.DestGroupId
+	}
+	groupId = strings.TrimSpace(groupId)
+	if len(groupId) == 0 {
+		cidr, err := normalizeCidr(ip, result.Ethertype)
+		if err != nil {
+			return result, err
+		}
+		ip = cidr
+	} else {
+		ip = ""
+	}
+	if result.Direction == SECURITY_GROUP_DIRECTION_INGRESS {
+		result.SourceIp, result.SourceGroupId = ip, groupId
+	} else {
+		result.DestIp, result.DestGroupId = ip, groupId
+	}
+	return result, nil
+}
+
+func normalizePortRange(portRange string) (string, error) {
+	portRange = strings.TrimSpace(portRange)
+	if len(portRange) == 0 {
+		return SECURITY_GROUP_PORT_ALL, nil
+	}
+	parts := strings.SplitN(portRange, "-", 2)
+	ports := make([]int, len(parts))
+	for i, part := range parts {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || port < 1 || port > SECURITY_GROUP_PORT_MAX {
+			return "", fmt.Errorf("invalid port range of security group rule: %q", portRange)
+		}
+		ports[i] = port
+	}
+	if len(ports) == 1 || ports[0] == ports[1] {
+		return strconv.Itoa(ports[0]), nil
+	}
+	if ports[0] > ports[1] {
+		return "", fmt.Errorf("invalid port range of security group rule: %q", portRange)
+	}
+	return fmt.Sprintf("%d-%d", ports[0], ports[1]), nil
+}
+
+func normalizeCidr(cidr, ethertype string) (string, error) {
+	cidr = strings.TrimSpace(cidr)
+	if len(cidr) == 0 || strings.ToLower(cidr) == SECURITY_GROUP_PEER_ALL {
+		return SECURITY_GROUP_PEER_ALL, nil
+	}
+	if !strings.Contains(cidr, "/") {
+		if ethertype == SECURITY_GROUP_ETHERTYPE_IPV6 {
+			cidr += "/128"
+		} else {
+			cidr += "/32"
+		}
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid cidr of security group rule: %q", cidr)
+	}
+	if (network.IP.To4() != nil) != (ethertype == SECURITY_GROUP_ETHERTYPE_IPV4) {
+		return "", fmt.Errorf("cidr %q does not match the ethertype %s", cidr, ethertype)
+	}
+	if ones, _ := network.Mask.Size(); ones == 0 {
+		return SECURITY_GROUP_PEER_ALL, nil
+	}
+	return network.String(), nil
+}
+
+// SecurityGroupRuleKey - get the identity of the rule, rules with the same key are equal
+//
+// PARAMS:
+//     - rule: the rule to get the key
+// RETURNS:
+//     - string: the key of the rule
+//     - error: nil if success otherwise the specific error
+func SecurityGroupRuleKey(rule SecurityGroupRuleModel) (string, error) {
+	normalized, err := NormalizeSecurityGroupRule(rule)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{normalized.Direction, normalized.Ethertype, normalized.Protocol,
+		normalized.PortRange, normalized.SourceIp, normalized.SourceGroupId, normalized.DestIp,
+		normalized.DestGroupId}, securityGroupKeySplitter), nil
+}
+
+// Equal returns whether the two rules have the same semantics, the remark is not compared.
+func (r SecurityGroupRuleModel) Equal(other SecurityGroupRuleModel) bool {
+	key, err := SecurityGroupRuleKey(r)
+	if err != nil {
+		return false
+	}
+	otherKey, err := SecurityGroupRuleKey(other)
+	return err == nil && key == otherKey
+}
+
+// SecurityGroupPlan defines the changes to make the live security group match the desired one.
+// The Create field is set if the security group does not exist yet, otherwise the rules in ToAdd
+// should be authorized before the rules in ToRemove are revoked.
+type SecurityGroupPlan struct {
+	SecurityGroupId string
+	Create          *CreateSecurityGroupArgs
+	ToAdd           []SecurityGroupRuleModel
+	ToRemove        []SecurityGroupRuleModel
+}
+
+// IsEmpty returns whether the live security group is already up to date.
+func (p *SecurityGroupPlan) IsEmpty() bool {
+	return p.Create == nil && len(p.ToAdd) == 0 && len(p.ToRemove) == 0
+}
+
+func (p *SecurityGroupPlan) String() string {
+	if p.IsEmpty() {
+		return fmt.Sprintf("security group %s is up to date", p.SecurityGroupId)
+	}
+	lines := []string{}
+	if p.Create != nil {
+		lines = append(lines, fmt.Sprintf("create security group %s", p.Create.Name))
+		for _, rule := range p.Create.Rules {
+			lines = append(lines, "  + "+describeSecurityGroupRule(rule))
+		}
+		return strings.Join(lines, "\n")
+	}
+	lines = append(lines, fmt.Sprintf("update security group %s", p.SecurityGroupId))
+	for _, rule := range p.ToAdd {
+		lines = append(lines, "  + "+describeSecurityGroupRule(rule))
+	}
+	for _, rule := range p.ToRemove {
+		lines = append(lines, "  - "+describeSecurityGroupRule(rule))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeSecurityGroupRule(rule SecurityGroupRuleModel) string {
+	normalized, err := NormalizeSecurityGroupRule(rule)
+	if err != nil {
+		return fmt.Sprintf("%+v", rule)
+	}
+	peer, prep := normalized.SourceIp+normalized.SourceGroupId, "from"
+	if normalized.Direction == SECURITY_GROUP_DIRECTION_EGRESS {
+		peer, prep = normalized.DestIp+normalized.DestGroupId, "to"
+	}
+	desc := fmt.Sprintf("%s %s %s", normalized.Direction, normalized.Ethertype, normalized.Protocol)
+	if len(normalized.PortRange) != 0 {
+		desc += " " + normalized.PortRange
+	}
+	return desc + " " + prep + " " + peer
+}
+
+// PlanSecurityGroup - compute the rules to add and remove to reconcile the live security group
+// with the desired one. Rules are compared by their normalized identity, so duplicates in the
+// desired rules are only added once and live rules differing only in the remark are kept.
+//
+// PARAMS:
+//     - desired: the desired security group
+//     - live: the live security group, nil if it does not exist yet
+// RETURNS:
+//     - *SecurityGroupPlan: the plan to apply
+//     - error: nil if success otherwise the specific error
+func PlanSecurityGroup(desired, live *SecurityGroupModel) (*SecurityGroupPlan, error) {
+	if desired == nil {
+		return nil, fmt.Errorf("the desired security group can not be nil")
+	}
+	desiredKeys := make(map[string]struct{}, len(desired.Rules))
+	desiredRules := make([]SecurityGroupRuleModel, 0, len(desired.Rules))
+	for _, rule := range desired.Rules {
+		key, err := SecurityGroupRuleKey(rule)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := desiredKeys[key]; ok {
+			continue
+		}
+		desiredKeys[key] = struct{}{}
+		desiredRules = append(desiredRules, rule)
+	}
+
+	if live == nil {
+		if len(desired.Name) == 0 {
+			return nil, fmt.Errorf("the name of the security group to create can not be empty")
+		}
+		return &SecurityGroupPlan{
+			Create: &CreateSecurityGroupArgs{
+				Name:  desired.Name,
+				Desc:  desired.Desc,
+				VpcId: desired.VpcId,
+				Rules: desiredRules,
+				Tags:  desired.Tags,
+			},
+		}, nil
+	}
+
+	plan := &SecurityGroupPlan{SecurityGroupId: live.Id}
+	liveKeys := make(map[string]struct{}, len(live.Rules))
+	for _, rule := range live.Rules {
+		key, err := SecurityGroupRuleKey(rule)
+		if err != nil {
+			return nil, fmt.Errorf("live rule of security group %s: %v", live.Id, err)
+		}
+		if _, ok := desiredKeys[key]; !ok {
+			plan.ToRemove = append(plan.ToRemove, rule)
+		}
+		liveKeys[key] = struct{}{}
+	}
+	for _, rule := range desiredRules {
+		key, _ := SecurityGroupRuleKey(rule)
+		if _, ok := liveKeys[key]; !ok {
+			plan.ToAdd = append(plan.ToAdd, rule)
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSecurityGroupRule(t *testing.T) {
+	equal := [][2]SecurityGroupRuleModel{
+		{{Direction: "ingress", Protocol: "tcp", PortRange: "80-80", SourceIp: "10.0.0.1/8"},
+			{Direction: "INGRESS", Protocol: "TCP", PortRange: "80", SourceIp: "10.0.0.0/8",
+				Ethertype: "IPv4", Remark: "web"}},
+		{{Direction: "ingress", Protocol: "tcp", SourceIp: ""},
+			{Direction: "ingress", Protocol: "tcp", PortRange: "1-65535", SourceIp: "0.0.0.0/0"}},
+		{{Direction: "egress", Protocol: "icmp", PortRange: "22", DestIp: "192.168.1.1"},
+			{Direction: "egress", Protocol: "icmp", DestIp: "192.168.1.1/32"}},
+		{{Direction: "egress", DestGroupId: "g-1", DestIp: "10.0.0.0/8"},
+			{Direction: "egress", Protocol: "all", DestGroupId: "g-1"}},
+	}
+	for i, pair := range equal {
+		if !pair[0].Equal(pair[1]) {
+			t.Errorf("case %d: expect rules to be equal", i)
+		}
+	}
+	if (SecurityGroupRuleModel{Direction: "ingress", Protocol: "tcp", PortRange: "80"}).Equal(
+		SecurityGroupRuleModel{Direction: "ingress", Protocol: "udp", PortRange: "80"}) {
+		t.Errorf("expect rules of different protocols to be different")
+	}
+
+	invalid := []SecurityGroupRuleModel{
+		{Direction: "inbound"},
+		{Direction: "ingress", Protocol: "gre"},
+		{Direction: "ingress", Protocol: "tcp", PortRange: "90-80"},
+		{Direction: "ingress", Protocol: "udp", PortRange: "70000"},
+		{Direction: "ingress", SourceIp: "10.0.0.256/8"},
+		{Direction: "ingress", Ethertype: "IPv6", SourceIp: "10.0.0.0/8"},
+	}
+	for i, rule := range invalid {
+		if _, err := NormalizeSecurityGroupRule(rule); err == nil {
+			t.Errorf("case %d: expect error of invalid rule %+v", i, rule)
+		}
+	}
+}
+
+func TestPlanSecurityGroup(t *testing.T) {
+	ssh := SecurityGroupRuleModel{Direction: "ingress", Protocol: "tcp", PortRange: "22",
+		SourceIp: "10.0.0.0/8"}
+	web := SecurityGroupRuleModel{Direction: "ingress", Protocol: "tcp", PortRange: "443"}
+	out := SecurityGroupRuleModel{Direction: "egress", Protocol: "all"}
+	desired := &SecurityGroupModel{Name: "sg", Rules: []SecurityGroupRuleModel{ssh, web, web, out}}
+
+	plan, err := PlanSecurityGroup(desired, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Create == nil || len(plan.Create.Rules) != 3 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	live := &SecurityGroupModel{Id: "g-1", Name: "sg", Rules: []SecurityGroupRuleModel{
+		{Direction: "ingress", Protocol: "tcp", PortRange: "22-22", SourceIp: "10.1.2.3/8",
+			Ethertype: "IPv4", SecurityGroupId: "g-1"},
+		{Direction: "ingress", Protocol: "tcp", PortRange: "80", SecurityGroupId: "g-1"},
+		{Direction: "egress", Protocol: "all", Ethertype: "IPv4", DestIp: "all", SecurityGroupId: "g-1"},
+	}}
+	plan, err = PlanSecurityGroup(desired, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.ToAdd) != 1 || plan.ToAdd[0].PortRange != "443" ||
+		len(plan.ToRemove) != 1 || plan.ToRemove[0].PortRange != "80" {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if !strings.Contains(plan.String(), "+ ingress IPv4 tcp 443 from all") {
+		t.Errorf("unexpected plan output:\n%s", plan)
+	}
+
+	live.Rules = append(live.Rules[:1], live.Rules[2], web)
+	if plan, _ := PlanSecurityGroup(desired, live); !plan.IsEmpty() {
+		t.Errorf("expect empty plan but %+v", plan)
+	}
+}
@@ -39,9 +39,7 @@ func CreateSnapshot(cli bce.Client, args *CreateSnapshotArgs) (*CreateSnapshotRe
 	req.SetUri(getSnapshotUri())
 	req.SetMethod(http.POST)
 
-	if len(args.ClientToken) != 0 {
-		req.SetParam("clientToken", args.ClientToken)
-	}
+	req.SetClientToken(args.ClientToken)
 
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
@@ -90,6 +88,9 @@ func ListSnapshot(cli bce.Client, queryArgs *ListSnapshotArgs) (*ListSnapshotRes
 		if queryArgs.MaxKeys != 0 {
 			req.SetParam("maxKeys", strconv.Itoa(queryArgs.MaxKeys))
 		}
+		if len(queryArgs.VolumeId) != 0 {
+			req.SetParam("volumeId", queryArgs.VolumeId)
+		}
 	}
 
 	if queryArgs == nil || queryArgs.MaxKeys == 0 {
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// snapshotRetention.go - the retention rules of the snapshots of a volume
+
+package api
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	SNAPSHOT_CREATE_METHOD_AUTO = "auto"
+
+	SNAPSHOT_KEEP_REASON_LATEST   = "latest"
+	SNAPSHOT_KEEP_REASON_LAST     = "last"
+	SNAPSHOT_KEEP_REASON_WITHIN   = "within"
+	SNAPSHOT_KEEP_REASON_DAILY    = "daily"
+	SNAPSHOT_KEEP_REASON_WEEKLY   = "weekly"
+	SNAPSHOT_KEEP_REASON_MONTHLY  = "monthly"
+	SNAPSHOT_KEEP_REASON_STATUS   = "status"
+	SNAPSHOT_KEEP_REASON_AUTO     = "auto"
+	SNAPSHOT_KEEP_REASON_IMAGE    = "image"
+	SNAPSHOT_KEEP_REASON_BAD_TIME = "unknownTime"
+
+	snapshotCreateTimeLayout       = "2006-01-02T15:04:05Z"
+	snapshotCreateTimeLayoutNoZone = "2006-01-02 15:04:05"
+)
+
+// SnapshotRetentionPolicy defines which snapshots of a volume are kept, a snapshot is kept if it
+// is selected by any of the rules. The daily, weekly and monthly rules keep the newest snapshot of
+// each of the latest N days, ISO weeks and months which have snapshots.
+type SnapshotRetentionPolicy struct {
+	KeepLast    int
+	KeepWithin  time.Duration
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+
+	// MaxAge overrides the rules above, the snapshots older than it are deleted if positive
+	MaxAge time.Duration
+}
+
+// SnapshotRetentionDecision is the decision on one snapshot, the snapshot is deleted if no reason
+// to keep it.
+type SnapshotRetentionDecision struct {
+	Snapshot SnapshotModel
+	Time     time.Time
+	Reasons  []string
+}
+
+func (d *SnapshotRetentionDecision) Keep() bool {
+	return len(d.Reasons) != 0
+}
+
+// ParseSnapshotCreateTime - parse the create time of the snapshot
+//
+// PARAMS:
+//     - snapshot: the snapshot to parse
+// RETURNS:
+//     - time.Time: the create time in UTC
+//     - error: nil if success otherwise the specific error
+func ParseSnapshotCreateTime(snapshot *SnapshotModel) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, snapshotCreateTimeLayout, snapshotCreateTimeLayoutNoZone} {
+		if t, err := time.Parse(layout, snapshot.CreateTime); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid create time %q of snapshot %s", snapshot.CreateTime, snapshot.Id)
+}
+
+// PlanSnapshotRetention - decide which snapshots of one volume to keep by the policy. Besides the
+// policy, the newest available snapshot, the snapshots not available yet, the ones created by the
+// auto snapshot policy and the ones used by images are always kept.
+//
+// PARAMS:
+//     - snapshots: the snapshots of one volume
+//     - policy: the retention policy
+//     - now: the current time, the days, weeks and months are counted in its location
+// RETURNS:
+//     - []SnapshotRetentionDecision: the decisions from the newest snapshot to the oldest
+func PlanSnapshotRetention(snapshots []SnapshotModel, policy *SnapshotRetentionPolicy,
+	now time.Time) []SnapshotRetentionDecision {
+	if policy == nil {
+		policy = &SnapshotRetentionPolicy{}
+	}
+	decisions := make([]SnapshotRetentionDecision, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		decision := SnapshotRetentionDecision{Snapshot: snapshot}
+		t, err := ParseSnapshotCreateTime(&snapshot)
+		if err != nil {
+			decision.Reasons = append(decision.Reasons, SNAPSHOT_KEEP_REASON_BAD_TIME)
+		}
+		decision.Time = t
+		decisions = append(decisions, decision)
+	}
+	sort.SliceStable(decisions, func(i, j int) bool { return decisions[i].Time.After(decisions[j].Time) })
+
+	latest := false
+	days, weeks, months := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	last := 0
+	for i := range decisions {
+		d := &decisions[i]
+		s := &d.Snapshot
+		if s.CreateMethod == SNAPSHOT_CREATE_METHOD_AUTO {
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_AUTO)
+		}
+		if len(s.TemplateId) != 0 {
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_IMAGE)
+		}
+		switch s.Status {
+		case SnapshotStatusAvailable:
+		case SnapshotStatusCreatedFailed:
+			continue
+		default:
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_STATUS)
+			continue
+		}
+		if d.Time.IsZero() {
+			continue
+		}
+		if !latest {
+			latest = true
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_LATEST)
+		}
+		if policy.MaxAge > 0 && now.Sub(d.Time) > policy.MaxAge {
+			continue
+		}
+
+		if last < policy.KeepLast {
+			last++
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_LAST)
+		}
+		if policy.KeepWithin > 0 && now.Sub(d.Time) <= policy.KeepWithin {
+			d.Reasons = append(d.Reasons, SNAPSHOT_KEEP_REASON_WITHIN)
+		}
+		local := d.Time.In(now.Location())
+		year, week := local.ISOWeek()
+		buckets := []struct {
+			seen   map[string]bool
+			key    string
+			limit  int
+			reason string
+		}{
+			{days, local.Format("2006-01-02"), policy.KeepDaily, SNAPSHOT_KEEP_REASON_DAILY},
+			{weeks, fmt.Sprintf("%d-%02d", year, week), policy.KeepWeekly, SNAPSHOT_KEEP_REASON_WEEKLY},
+			{months, local.Format("2006-01"), policy.KeepMonthly, SNAPSHOT_KEEP_REASON_MONTHLY},
+		}
+		for _, b := range buckets {
+			if !b.seen[b.key] && len(b.seen) < b.limit {
+				b.seen[b.key] = true
+				d.Reasons = append(d.Reasons, b.reason)
+			}
+		}
+	}
+	return decisions
+}
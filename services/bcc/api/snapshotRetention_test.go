@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func retentionSnapshot(id string, t time.Time) SnapshotModel {
+	return SnapshotModel{Id: id, CreateTime: t.Format(time.RFC3339), Status: SnapshotStatusAvailable,
+		CreateMethod: "manual"}
+}
+
+func keptSnapshots(decisions []SnapshotRetentionDecision) map[string][]string {
+	kept := map[string][]string{}
+	for _, d := range decisions {
+		if d.Keep() {
+			kept[d.Snapshot.Id] = d.Reasons
+		}
+	}
+	return kept
+}
+
+func TestPlanSnapshotRetention(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	snapshots := []SnapshotModel{}
+	// one snapshot every 12 hours over 90 days
+	for i := 0; i < 180; i++ {
+		snapshots = append(snapshots, retentionSnapshot(fmt.Sprintf("s-%03d", i),
+			now.Add(-time.Duration(i)*12*time.Hour)))
+	}
+	policy := &SnapshotRetentionPolicy{KeepLast: 2, KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 3}
+	decisions := PlanSnapshotRetention(snapshots, policy, now)
+	if len(decisions) != len(snapshots) || decisions[0].Snapshot.Id != "s-000" {
+		t.Fatalf("expect decisions from the newest, got %d first %s", len(decisions),
+			decisions[0].Snapshot.Id)
+	}
+	kept := keptSnapshots(decisions)
+
+	// s-000,s-001 by last, one per day for 7 days, 4 weeks and 3 months overlapping the days
+	for _, id := range []string{"s-000", "s-001", "s-002", "s-012"} {
+		if _, ok := kept[id]; !ok {
+			t.Errorf("expect %s to be kept", id)
+		}
+	}
+	if _, ok := kept["s-003"]; ok {
+		t.Errorf("expect s-003 not kept: %v", kept["s-003"])
+	}
+	daily, monthly := 0, 0
+	for _, reasons := range kept {
+		for _, r := range reasons {
+			if r == SNAPSHOT_KEEP_REASON_DAILY {
+				daily++
+			}
+			if r == SNAPSHOT_KEEP_REASON_MONTHLY {
+				monthly++
+			}
+		}
+	}
+	if daily != 7 || monthly != 3 {
+		t.Errorf("expect 7 daily and 3 monthly, got %d and %d", daily, monthly)
+	}
+	if len(kept) > 2+7+4+3 {
+		t.Errorf("too many snapshots kept: %d", len(kept))
+	}
+}
+
+func TestPlanSnapshotRetentionSafety(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-100 * 24 * time.Hour)
+	snapshots := []SnapshotModel{
+		retentionSnapshot("latest", old),
+		retentionSnapshot("older", old.Add(-time.Hour)),
+		retentionSnapshot("image", old.Add(-2*time.Hour)),
+		retentionSnapshot("auto", old.Add(-3*time.Hour)),
+		retentionSnapshot("creating", now),
+		retentionSnapshot("failed", now.Add(-time.Minute)),
+		{Id: "badTime", CreateTime: "yesterday", Status: SnapshotStatusAvailable},
+	}
+	snapshots[2].TemplateId = "m-1"
+	snapshots[3].CreateMethod = SNAPSHOT_CREATE_METHOD_AUTO
+	snapshots[4].Status = SnapshotStatusCreating
+	snapshots[5].Status = SnapshotStatusCreatedFailed
+
+	kept := keptSnapshots(PlanSnapshotRetention(snapshots,
+		&SnapshotRetentionPolicy{KeepWithin: time.Hour, MaxAge: 30 * 24 * time.Hour}, now))
+	expected := map[string]string{
+		"latest":   SNAPSHOT_KEEP_REASON_LATEST,
+		"image":    SNAPSHOT_KEEP_REASON_IMAGE,
+		"auto":     SNAPSHOT_KEEP_REASON_AUTO,
+		"creating": SNAPSHOT_KEEP_REASON_STATUS,
+		"badTime":  SNAPSHOT_KEEP_REASON_BAD_TIME,
+	}
+	if len(kept) != len(expected) {
+		t.Errorf("expect %d kept, got %v", len(expected), kept)
+	}
+	for id, reason := range expected {
+		if len(kept[id]) == 0 || kept[id][0] != reason {
+			t.Errorf("expect %s kept for %s, got %v", id, reason, kept[id])
+		}
+	}
+}
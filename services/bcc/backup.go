@@ -0,0 +1,563 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// backup.go - the backup plan of the CDS volumes with snapshots and remote image copies
+
+package bcc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/model"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+const (
+	DEFAULT_BACKUP_WAIT_TIMEOUT  = 30 * time.Minute
+	DEFAULT_BACKUP_POLL_INTERVAL = 10 * time.Second
+	BACKUP_SNAPSHOT_TIME_LAYOUT  = "20060102-150405"
+
+	backupSnapshotListMaxKeys = 1000
+	backupVolumeListMaxKeys   = 1000
+)
+
+// BackupPlan defines a backup of the selected volumes. Every run creates one snapshot named
+// "<Name>-<time>" of each volume, the time is the same for all the volumes of a run. Only the
+// snapshots with this name prefix are managed by the retention policy of the plan.
+type BackupPlan struct {
+	Name string
+
+	// The volumes are the union of VolumeIds, the volumes bound to all the VolumeTags in the zone
+	// and the volumes attached to the instances selected by Instances
+	VolumeIds           []string
+	VolumeTags          []model.TagModel
+	ZoneName            string
+	Instances           *FleetSelector
+	IncludeSystemVolume bool
+
+	// Retention prunes the old snapshots of the plan if not nil, DryRun only reports the decisions
+	Retention *api.SnapshotRetentionPolicy
+	DryRun    bool
+
+	// CopyImageIds are the custom images copied to the CopyToRegions, the status of the copies is
+	// tracked with the client of the region in RegionClients if provided
+	CopyImageIds  []string
+	CopyToRegions []string
+	RegionClients map[string]*Client
+
+	Parallelism  int
+	WaitTimeout  time.Duration
+	PollInterval time.Duration
+}
+
+// BackupSnapshotResult is the snapshot created for one volume.
+type BackupSnapshotResult struct {
+	VolumeId     string
+	InstanceId   string
+	SnapshotId   string
+	SnapshotName string
+	Status       api.SnapshotStatus
+	Error        error
+}
+
+// BackupPruneResult is the retention result of one volume, Skipped is the reason if not pruned.
+type BackupPruneResult struct {
+	VolumeId  string
+	Decisions []api.SnapshotRetentionDecision
+	Deleted   []string
+	Skipped   string
+	Error     error
+}
+
+// BackupImageCopyResult is the copy of one image to one region.
+type BackupImageCopyResult struct {
+	ImageId       string
+	Region        string
+	RemoteImageId string
+	Status        api.ImageStatus // empty if the copy is not tracked
+	Error         error
+}
+
+// BackupReport is the report of one run of the backup plan.
+type BackupReport struct {
+	Plan        string
+	Time        time.Time
+	Snapshots   []BackupSnapshotResult
+	Prunes      []BackupPruneResult
+	ImageCopies []BackupImageCopyResult
+}
+
+// Failed returns the count of the failed snapshots, prunes and image copies.
+func (r *BackupReport) Failed() int {
+	failed := 0
+	for _, res := range r.Snapshots {
+		if res.Error != nil {
+			failed++
+		}
+	}
+	for _, res := range r.Prunes {
+		if res.Error != nil {
+			failed++
+		}
+	}
+	for _, res := range r.ImageCopies {
+		if res.Error != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (r *BackupReport) String() string {
+	deleted := 0
+	for _, res := range r.Prunes {
+		deleted += len(res.Deleted)
+	}
+	lines := []string{fmt.Sprintf("backup %s at %s: %d snapshot(s), %d deleted, %d image copies, %d failed",
+		r.Plan, r.Time.Format(BACKUP_SNAPSHOT_TIME_LAYOUT), len(r.Snapshots), deleted,
+		len(r.ImageCopies), r.Failed())}
+	for _, res := range r.Snapshots {
+		if res.Error != nil {
+			lines = append(lines, fmt.Sprintf("  snapshot %s: %v", res.VolumeId, res.Error))
+		}
+	}
+	for _, res := range r.Prunes {
+		if res.Error != nil {
+			lines = append(lines, fmt.Sprintf("  prune %s: %v", res.VolumeId, res.Error))
+		}
+	}
+	for _, res := range r.ImageCopies {
+		if res.Error != nil {
+			lines = append(lines, fmt.Sprintf("  copy %s to %s: %v", res.ImageId, res.Region, res.Error))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BackupSnapshotName returns the name of the snapshots created by the plan at the time.
+func BackupSnapshotName(planName string, t time.Time) string {
+	return planName + "-" + t.UTC().Format(BACKUP_SNAPSHOT_TIME_LAYOUT)
+}
+
+type backupVolume struct {
+	volumeId   string
+	instanceId string
+}
+
+// RunBackupPlan - run the backup plan once: create the snapshots, wait for them, prune the old
+// snapshots by the retention policy and copy the images to the remote regions
+//
+// PARAMS:
+//     - plan: the backup plan
+// RETURNS:
+//     - *BackupReport: the report of the run
+//     - error: nil if the volumes are selected otherwise the specific error
+func (c *Client) RunBackupPlan(plan *BackupPlan) (*BackupReport, error) {
+	if plan == nil || len(plan.Name) == 0 {
+		return nil, fmt.Errorf("the name of the backup plan is required")
+	}
+	opts := *plan
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DEFAULT_FLEET_PARALLELISM
+	}
+	if opts.WaitTimeout <= 0 {
+		opts.WaitTimeout = DEFAULT_BACKUP_WAIT_TIMEOUT
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DEFAULT_BACKUP_POLL_INTERVAL
+	}
+
+	volumes, err := c.selectBackupVolumes(&opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &BackupReport{Plan: opts.Name, Time: time.Now().UTC()}
+	name := BackupSnapshotName(opts.Name, report.Time)
+
+	// the volumes of one instance are snapshotted one after another without waiting, so that the
+	// snapshots are as close in time as possible
+	byInstance := map[string][]int{}
+	keys := []string{}
+	report.Snapshots = make([]BackupSnapshotResult, len(volumes))
+	for i, v := range volumes {
+		report.Snapshots[i] = BackupSnapshotResult{VolumeId: v.volumeId, InstanceId: v.instanceId,
+			SnapshotName: name}
+		key := v.instanceId
+		if len(key) == 0 {
+			key = v.volumeId
+		}
+		if _, ok := byInstance[key]; !ok {
+			keys = append(keys, key)
+		}
+		byInstance[key] = append(byInstance[key], i)
+	}
+	runBackupTasks(len(keys), opts.Parallelism, func(i int) {
+		for _, index := range byInstance[keys[i]] {
+			c.createBackupSnapshot(&report.Snapshots[index], report.Time)
+		}
+		for _, index := range byInstance[keys[i]] {
+			c.waitBackupSnapshot(&report.Snapshots[index], &opts)
+		}
+	})
+
+	if opts.Retention != nil {
+		report.Prunes = make([]BackupPruneResult, len(volumes))
+		runBackupTasks(len(volumes), opts.Parallelism, func(i int) {
+			report.Prunes[i] = c.pruneBackupSnapshots(volumes[i].volumeId, &opts, report.Time)
+		})
+	}
+
+	for _, imageId := range opts.CopyImageIds {
+		report.ImageCopies = append(report.ImageCopies, c.copyBackupImage(imageId, &opts)...)
+	}
+	runBackupTasks(len(report.ImageCopies), opts.Parallelism, func(i int) {
+		c.trackBackupImageCopy(&report.ImageCopies[i], &opts)
+	})
+	log.Infof("%s", report)
+	return report, nil
+}
+
+func runBackupTasks(count, parallelism int, task func(i int)) {
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, parallelism)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+			task(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (c *Client) selectBackupVolumes(plan *BackupPlan) ([]backupVolume, error) {
+	result := []backupVolume{}
+	seen := map[string]bool{}
+	add := func(volume *api.VolumeModel) {
+		if seen[volume.Id] || (volume.IsSystemVolume && !plan.IncludeSystemVolume) {
+			return
+		}
+		seen[volume.Id] = true
+		v := backupVolume{volumeId: volume.Id}
+		if len(volume.Attachments) != 0 {
+			v.instanceId = volume.Attachments[0].InstanceId
+		}
+		result = append(result, v)
+	}
+
+	for _, volumeId := range plan.VolumeIds {
+		detail, err := c.GetCDSVolumeDetail(volumeId)
+		if err != nil {
+			return nil, err
+		}
+		if detail.Volume != nil {
+			add(detail.Volume)
+		}
+	}
+	if len(plan.VolumeTags) != 0 {
+		err := c.listBackupVolumes(&api.ListCDSVolumeArgs{ZoneName: plan.ZoneName},
+			func(volume *api.VolumeModel) {
+				if hasAllTags(volume.Tags, plan.VolumeTags) {
+					add(volume)
+				}
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if plan.Instances != nil {
+		instances, err := c.SelectInstances(plan.Instances)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			err := c.listBackupVolumes(&api.ListCDSVolumeArgs{InstanceId: instance.InstanceId}, add)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) listBackupVolumes(args *api.ListCDSVolumeArgs, visit func(*api.VolumeModel)) error {
+	args.MaxKeys = backupVolumeListMaxKeys
+	for {
+		listResult, err := c.ListCDSVolume(args)
+		if err != nil {
+			return err
+		}
+		for i := range listResult.Volumes {
+			visit(&listResult.Volumes[i])
+		}
+		if !listResult.IsTruncated || len(listResult.NextMarker) == 0 {
+			return nil
+		}
+		args.Marker = listResult.NextMarker
+	}
+}
+
+func hasAllTags(tags, expected []model.TagModel) bool {
+	for _, e := range expected {
+		found := false
+		for _, tag := range tags {
+			if tag.TagKey == e.TagKey && (len(e.TagValue) == 0 || tag.TagValue == e.TagValue) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Client) createBackupSnapshot(res *BackupSnapshotResult, t time.Time) {
+	desc := fmt.Sprintf("backup of %s at %s", res.VolumeId, t.Format(time.RFC3339))
+	if len(res.InstanceId) != 0 {
+		desc = fmt.Sprintf("backup of %s on %s at %s", res.VolumeId, res.InstanceId, t.Format(time.RFC3339))
+	}
+	result, err := c.CreateSnapshot(&api.CreateSnapshotArgs{
+		VolumeId:     res.VolumeId,
+		SnapshotName: res.SnapshotName,
+		Description:  desc,
+	})
+	if err != nil {
+		res.Error = err
+		return
+	}
+	res.SnapshotId = result.SnapshotId
+	res.Status = api.SnapshotStatusCreating
+}
+
+func (c *Client) waitBackupSnapshot(res *BackupSnapshotResult, plan *BackupPlan) {
+	if res.Error != nil {
+		return
+	}
+	deadline := time.Now().Add(plan.WaitTimeout)
+	for {
+		detail, err := c.GetSnapshotDetail(res.SnapshotId)
+		if err != nil {
+			res.Error = err
+			return
+		}
+		res.Status = detail.Snapshot.Status
+		switch res.Status {
+		case api.SnapshotStatusAvailable:
+			return
+		case api.SnapshotStatusCreatedFailed, api.SnapshotStatusNotAvailable:
+			res.Error = fmt.Errorf("snapshot %s of volume %s turns into status %s", res.SnapshotId,
+				res.VolumeId, res.Status)
+			return
+		}
+		if time.Now().Add(plan.PollInterval).After(deadline) {
+			res.Error = fmt.Errorf("wait for snapshot %s of volume %s timeout, current status %s",
+				res.SnapshotId, res.VolumeId, res.Status)
+			return
+		}
+		time.Sleep(plan.PollInterval)
+	}
+}
+
+func (c *Client) listVolumeSnapshots(volumeId string) ([]api.SnapshotModel, error) {
+	args := &api.ListSnapshotArgs{VolumeId: volumeId, MaxKeys: backupSnapshotListMaxKeys}
+	result := []api.SnapshotModel{}
+	for {
+		listResult, err := c.ListSnapshot(args)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range listResult.Snapshots {
+			if snapshot.VolumeId == volumeId {
+				result = append(result, snapshot)
+			}
+		}
+		if !listResult.IsTruncated || len(listResult.NextMarker) == 0 {
+			return result, nil
+		}
+		args.Marker = listResult.NextMarker
+	}
+}
+
+// countChainSnapshots returns the count of the snapshots in the snapshot chains of the volume.
+func (c *Client) countChainSnapshots(volumeId string) (int, error) {
+	result, err := c.ListSnapshotChain(&api.ListSnapshotChainArgs{VolumeId: volumeId,
+		PageSize: backupSnapshotListMaxKeys})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, chain := range result.Snapchains {
+		if chain.VolumeId == volumeId {
+			count += chain.ManualSnapCount + chain.AutoSnapCount
+		}
+	}
+	return count, nil
+}
+
+// pruneBackupSnapshots applies the retention policy to the snapshots of the plan on the volume.
+// The retention may delete the snapshots in the middle of the snapshot chain, whose data is then
+// merged into the chain by the service. So nothing is deleted while a snapshot is being created or
+// the chain disagrees with the listed snapshots, and the snapshots are deleted one at a time from
+// the oldest, each waiting for the chain to drop the previous one.
+func (c *Client) pruneBackupSnapshots(volumeId string, plan *BackupPlan,
+	now time.Time) BackupPruneResult {
+	res := BackupPruneResult{VolumeId: volumeId}
+	snapshots, err := c.listVolumeSnapshots(volumeId)
+	if err != nil {
+		res.Error = err
+		return res
+	}
+	owned := []api.SnapshotModel{}
+	for _, snapshot := range snapshots {
+		if snapshot.Status == api.SnapshotStatusCreating {
+			res.Skipped = fmt.Sprintf("snapshot %s is being created", snapshot.Id)
+			return res
+		}
+		if strings.HasPrefix(snapshot.Name, plan.Name+"-") {
+			owned = append(owned, snapshot)
+		}
+	}
+	res.Decisions = api.PlanSnapshotRetention(owned, plan.Retention, now)
+	if plan.DryRun {
+		res.Skipped = "dry run"
+		return res
+	}
+
+	prune := []api.SnapshotModel{}
+	for _, d := range res.Decisions {
+		if !d.Keep() {
+			prune = append(prune, d.Snapshot)
+		}
+	}
+	if len(prune) == 0 {
+		return res
+	}
+	chained, err := c.countChainSnapshots(volumeId)
+	if err != nil {
+		res.Error = fmt.Errorf("list snapshot chain: %v", err)
+		return res
+	}
+	if chained != len(snapshots) {
+		res.Skipped = fmt.Sprintf("snapshot chain has %d snapshot(s) but %d listed, it may be changing",
+			chained, len(snapshots))
+		return res
+	}
+
+	sort.SliceStable(prune, func(i, j int) bool { return prune[i].CreateTime < prune[j].CreateTime })
+	for _, snapshot := range prune {
+		if err := c.DeleteSnapshot(snapshot.Id); err != nil {
+			res.Error = fmt.Errorf("delete snapshot %s: %v", snapshot.Id, err)
+			return res
+		}
+		res.Deleted = append(res.Deleted, snapshot.Id)
+		chained--
+		if res.Error = c.waitSnapshotChain(volumeId, chained, plan); res.Error != nil {
+			return res
+		}
+	}
+	return res
+}
+
+// waitSnapshotChain waits for the snapshot chains of the volume to shrink to the expected count.
+func (c *Client) waitSnapshotChain(volumeId string, expected int, plan *BackupPlan) error {
+	deadline := time.Now().Add(plan.WaitTimeout)
+	for {
+		count, err := c.countChainSnapshots(volumeId)
+		if err != nil {
+			return fmt.Errorf("list snapshot chain: %v", err)
+		}
+		if count <= expected {
+			return nil
+		}
+		if time.Now().Add(plan.PollInterval).After(deadline) {
+			return fmt.Errorf("wait for snapshot chain of volume %s to shrink to %d timeout, current %d",
+				volumeId, expected, count)
+		}
+		time.Sleep(plan.PollInterval)
+	}
+}
+
+func (c *Client) copyBackupImage(imageId string, plan *BackupPlan) []BackupImageCopyResult {
+	results := make([]BackupImageCopyResult, len(plan.CopyToRegions))
+	for i, region := range plan.CopyToRegions {
+		results[i] = BackupImageCopyResult{ImageId: imageId, Region: region}
+	}
+	if len(results) == 0 {
+		return results
+	}
+	copyResult, err := c.RemoteCopyImageReturnImageIds(imageId,
+		&api.RemoteCopyImageArgs{DestRegion: plan.CopyToRegions})
+	for i := range results {
+		res := &results[i]
+		if err != nil {
+			res.Error = err
+			continue
+		}
+		res.Error = fmt.Errorf("no copy result of region %s", res.Region)
+		for _, remote := range copyResult.RemoteCopyImages {
+			if remote.Region != res.Region {
+				continue
+			}
+			res.RemoteImageId, res.Error = remote.ImageId, nil
+			if len(remote.ErrMsg) != 0 || len(remote.ImageId) == 0 {
+				res.Error = fmt.Errorf("copy image %s to %s failed: [Code: %s; Message: %s]",
+					imageId, res.Region, remote.Code, remote.ErrMsg)
+			}
+		}
+	}
+	return results
+}
+
+func (c *Client) trackBackupImageCopy(res *BackupImageCopyResult, plan *BackupPlan) {
+	client, ok := plan.RegionClients[res.Region]
+	if res.Error != nil || !ok || client == nil {
+		return
+	}
+	deadline := time.Now().Add(plan.WaitTimeout)
+	for {
+		detail, err := client.GetImageDetail(res.RemoteImageId)
+		if err != nil {
+			res.Error = err
+			return
+		}
+		if detail.Image != nil {
+			res.Status = detail.Image.Status
+		}
+		switch res.Status {
+		case api.ImageStatusAvailable:
+			return
+		case api.ImageStatusCreateFailed, api.ImageStatusError:
+			res.Error = fmt.Errorf("image %s in %s turns into status %s", res.RemoteImageId,
+				res.Region, res.Status)
+			return
+		}
+		if time.Now().Add(plan.PollInterval).After(deadline) {
+			res.Error = fmt.Errorf("wait for image %s in %s timeout, current status %s",
+				res.RemoteImageId, res.Region, res.Status)
+			return
+		}
+		time.Sleep(plan.PollInterval)
+	}
+}
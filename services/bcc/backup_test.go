@@ -0,0 +1,340 @@
+package bcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/model"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+)
+
+// fakeBackup serves the volume, snapshot and image APIs used by the backup plan
+type fakeBackup struct {
+	mutex     sync.Mutex
+	now       time.Time
+	volumes   []api.VolumeModel
+	instances []api.InstanceModelV3
+	snapshots map[string][]api.SnapshotModel // volume -> snapshots
+	polls     map[string]int                 // snapshot -> polls before available
+	chainLag  int                            // polls before the chain drops a deleted snapshot
+	lagging   map[string]int                 // volume -> deleted snapshots still in the chain
+	created   []string
+	deleted   []string
+	copies    map[string]string // region -> remote image id, empty for failure
+	images    map[string]api.ImageStatus
+}
+
+func newFakeBackup() *fakeBackup {
+	f := &fakeBackup{
+		now:       time.Now(),
+		snapshots: map[string][]api.SnapshotModel{},
+		polls:     map[string]int{},
+		lagging:   map[string]int{},
+		copies:    map[string]string{},
+		images:    map[string]api.ImageStatus{},
+	}
+	attach := func(instanceId string) []api.VolumeAttachmentModel {
+		return []api.VolumeAttachmentModel{{InstanceId: instanceId}}
+	}
+	f.volumes = []api.VolumeModel{
+		{Id: "v-sys", IsSystemVolume: true, Attachments: attach("i-db1")},
+		{Id: "v-db1", Attachments: attach("i-db1")},
+		{Id: "v-db1-log", Attachments: attach("i-db1")},
+		{Id: "v-tagged", Tags: []model.TagModel{{TagKey: "backup", TagValue: "daily"}}},
+		{Id: "v-other", Tags: []model.TagModel{{TagKey: "backup", TagValue: "weekly"}}},
+	}
+	f.instances = []api.InstanceModelV3{{InstanceId: "i-db1"}}
+	return f
+}
+
+func (f *fakeBackup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	query, uri, id := r.URL.Query(), r.URL.Path, path.Base(r.URL.Path)
+	encode := func(v interface{}) { json.NewEncoder(w).Encode(v) }
+	switch {
+	case r.Method == http.MethodGet && uri == "/v2/volume":
+		result := &api.ListCDSVolumeResult{}
+		for _, v := range f.volumes {
+			if instanceId := query.Get("instanceId"); len(instanceId) == 0 ||
+				(len(v.Attachments) != 0 && v.Attachments[0].InstanceId == instanceId) {
+				result.Volumes = append(result.Volumes, v)
+			}
+		}
+		encode(result)
+	case r.Method == http.MethodGet && strings.HasPrefix(uri, "/v2/volume/"):
+		for i := range f.volumes {
+			if f.volumes[i].Id == id {
+				encode(&api.GetVolumeDetailResult{Volume: &f.volumes[i]})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"code":"NoSuchObject","message":"volume %s not found"}`, id)
+	case r.Method == http.MethodPost && uri == "/v3/instance/list":
+		encode(&api.LogicMarkerResultResponseV3{Instances: f.instances})
+	case r.Method == http.MethodPost && uri == "/v2/snapshot":
+		args := &api.CreateSnapshotArgs{}
+		json.NewDecoder(r.Body).Decode(args)
+		snapshotId := "s-" + args.VolumeId
+		f.created = append(f.created, args.VolumeId)
+		f.snapshots[args.VolumeId] = append(f.snapshots[args.VolumeId], api.SnapshotModel{Id: snapshotId,
+			Name: args.SnapshotName, VolumeId: args.VolumeId, Status: api.SnapshotStatusCreating,
+			CreateTime: time.Now().UTC().Format(time.RFC3339), CreateMethod: "manual"})
+		encode(&api.CreateSnapshotResult{SnapshotId: snapshotId})
+	case r.Method == http.MethodGet && uri == "/v2/snapshot":
+		// the snapshots of all the volumes are listed if the volumeId is missing
+		result := &api.ListSnapshotResult{}
+		for volumeId, snapshots := range f.snapshots {
+			if volumeId == query.Get("volumeId") || len(query.Get("volumeId")) == 0 {
+				result.Snapshots = append(result.Snapshots, snapshots...)
+			}
+		}
+		encode(result)
+	case r.Method == http.MethodGet && uri == "/v2/snapshot/chain":
+		volumeId := query.Get("volumeId")
+		count := len(f.snapshots[volumeId]) + f.lagging[volumeId]
+		if f.lagging[volumeId] > 0 {
+			f.lagging[volumeId]--
+		}
+		encode(&api.ListSnapshotChainResult{Snapchains: []api.SnapchainModel{
+			{VolumeId: volumeId, ManualSnapCount: count}}})
+	case r.Method == http.MethodGet && strings.HasPrefix(uri, "/v2/snapshot/"):
+		volumeId := strings.TrimPrefix(id, "s-")
+		for i, s := range f.snapshots[volumeId] {
+			if s.Id != id {
+				continue
+			}
+			if f.polls[id] > 0 {
+				f.polls[id]--
+			} else if s.Status == api.SnapshotStatusCreating {
+				f.snapshots[volumeId][i].Status = api.SnapshotStatusAvailable
+			}
+			encode(&api.GetSnapshotDetailResult{Snapshot: f.snapshots[volumeId][i]})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodDelete && strings.HasPrefix(uri, "/v2/snapshot/"):
+		for volumeId, snapshots := range f.snapshots {
+			for i, s := range snapshots {
+				if s.Id == id {
+					f.snapshots[volumeId] = append(snapshots[:i:i], snapshots[i+1:]...)
+					f.lagging[volumeId] += f.chainLag
+					f.deleted = append(f.deleted, id)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPost && strings.HasPrefix(uri, "/v2/image/"):
+		args := &api.RemoteCopyImageArgs{}
+		json.NewDecoder(r.Body).Decode(args)
+		result := &api.RemoteCopyImageResult{}
+		for _, region := range args.DestRegion {
+			remote := api.RemoteCopyImageModel{Region: region, ImageId: f.copies[region]}
+			if len(remote.ImageId) == 0 {
+				remote.Code, remote.ErrMsg = "QuotaExceeded", "image quota exceeded"
+			}
+			result.RemoteCopyImages = append(result.RemoteCopyImages, remote)
+		}
+		encode(result)
+	case r.Method == http.MethodGet && strings.HasPrefix(uri, "/v2/image/"):
+		encode(&api.GetImageDetailResult{Image: &api.ImageModel{Id: id, Status: f.images[id]}})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"code":"Unexpected","message":"unexpected request %s %s"}`, r.Method, uri)
+	}
+}
+
+func newBackupTestClient(t *testing.T, f *fakeBackup) (*Client, func()) {
+	server := httptest.NewServer(f)
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+// addOldSnapshots adds the available snapshots of the plan created 1 to n days ago
+func (f *fakeBackup) addOldSnapshots(volumeId, plan string, n int) {
+	for i := 1; i <= n; i++ {
+		t := f.now.Add(-time.Duration(i) * 24 * time.Hour)
+		f.snapshots[volumeId] = append(f.snapshots[volumeId], api.SnapshotModel{
+			Id: fmt.Sprintf("s-%s-%d", volumeId, i), Name: BackupSnapshotName(plan, t), VolumeId: volumeId,
+			Status: api.SnapshotStatusAvailable, CreateTime: t.UTC().Format(time.RFC3339),
+			CreateMethod: "manual"})
+	}
+}
+
+func TestSelectBackupVolumes(t *testing.T) {
+	cases := []struct {
+		plan     BackupPlan
+		expected []string
+	}{
+		{BackupPlan{VolumeIds: []string{"v-other", "v-sys"}}, []string{"v-other"}},
+		{BackupPlan{VolumeTags: []model.TagModel{{TagKey: "backup", TagValue: "daily"}}}, []string{"v-tagged"}},
+		{BackupPlan{VolumeTags: []model.TagModel{{TagKey: "backup"}}}, []string{"v-tagged", "v-other"}},
+		{BackupPlan{Instances: &FleetSelector{}}, []string{"v-db1", "v-db1-log"}},
+		{BackupPlan{Instances: &FleetSelector{}, IncludeSystemVolume: true, VolumeIds: []string{"v-db1"}},
+			[]string{"v-db1", "v-sys", "v-db1-log"}},
+	}
+	client, clean := newBackupTestClient(t, newFakeBackup())
+	defer clean()
+	for i, c := range cases {
+		volumes, err := client.selectBackupVolumes(&c.plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, v := range volumes {
+			ids = append(ids, v.volumeId)
+		}
+		if strings.Join(ids, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("case %d: unexpected volumes %v", i, ids)
+		}
+	}
+	_, err := client.RunBackupPlan(&BackupPlan{Name: "daily", VolumeIds: []string{"v-x"}})
+	if err == nil {
+		t.Errorf("expect error of unknown volume")
+	}
+}
+
+func TestRunBackupPlan(t *testing.T) {
+	fake := newFakeBackup()
+	fake.polls["s-v-db1-log"] = 2
+	fake.addOldSnapshots("v-db1", "daily", 5)
+	fake.addOldSnapshots("v-db1-log", "daily", 5)
+	fake.addOldSnapshots("v-other", "daily", 5)
+	fake.chainLag = 1
+	client, clean := newBackupTestClient(t, fake)
+	defer clean()
+
+	report, err := client.RunBackupPlan(&BackupPlan{
+		Name:         "daily",
+		Instances:    &FleetSelector{},
+		Retention:    &api.SnapshotRetentionPolicy{KeepLast: 3},
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed() != 0 || len(report.Snapshots) != 2 {
+		t.Fatalf("unexpected report %s", report)
+	}
+	for _, res := range report.Snapshots {
+		if res.Status != api.SnapshotStatusAvailable || res.InstanceId != "i-db1" ||
+			res.SnapshotName != BackupSnapshotName("daily", report.Time) {
+			t.Errorf("unexpected snapshot %+v", res)
+		}
+	}
+	// the volumes of the instance are snapshotted before waiting for any of them
+	if strings.Join(fake.created, ",") != "v-db1,v-db1-log" {
+		t.Errorf("unexpected creations %v", fake.created)
+	}
+
+	// the new snapshot and 2 old ones are kept, the rest are deleted from the oldest
+	for _, prune := range report.Prunes {
+		expected := []string{}
+		for i := 5; i >= 3; i-- {
+			expected = append(expected, fmt.Sprintf("s-%s-%d", prune.VolumeId, i))
+		}
+		if prune.Error != nil || strings.Join(prune.Deleted, ",") != strings.Join(expected, ",") {
+			t.Errorf("unexpected prune %+v", prune)
+		}
+	}
+	// the snapshots of the volumes out of the plan are kept
+	sort.Strings(fake.deleted)
+	if len(fake.deleted) != 6 || len(fake.snapshots["v-other"]) != 5 {
+		t.Errorf("unexpected deleted snapshots %v", fake.deleted)
+	}
+}
+
+func TestRunBackupPlanPruneSafety(t *testing.T) {
+	plan := &BackupPlan{Name: "daily", VolumeIds: []string{"v-db1"},
+		Retention:   &api.SnapshotRetentionPolicy{KeepLast: 1},
+		WaitTimeout: 50 * time.Millisecond, PollInterval: time.Millisecond}
+	run := func(fake *fakeBackup, plan *BackupPlan) BackupPruneResult {
+		client, clean := newBackupTestClient(t, fake)
+		defer clean()
+		report, err := client.RunBackupPlan(plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report.Prunes[0]
+	}
+
+	fake := newFakeBackup()
+	fake.addOldSnapshots("v-db1", "daily", 3)
+	fake.snapshots["v-db1"][2].Status = api.SnapshotStatusCreating
+	if res := run(fake, plan); len(res.Skipped) == 0 || len(fake.deleted) != 0 {
+		t.Errorf("expect skipped for the snapshot being created: %+v", res)
+	}
+
+	// the chain still has a snapshot deleted before
+	fake = newFakeBackup()
+	fake.addOldSnapshots("v-db1", "daily", 3)
+	fake.lagging["v-db1"] = 5
+	if res := run(fake, plan); !strings.Contains(res.Skipped, "chain") || len(fake.deleted) != 0 {
+		t.Errorf("expect skipped for the changing chain: %+v", res)
+	}
+
+	fake = newFakeBackup()
+	fake.addOldSnapshots("v-db1", "daily", 3)
+	dryRun := *plan
+	dryRun.DryRun = true
+	if res := run(fake, &dryRun); res.Skipped != "dry run" || len(res.Decisions) != 4 ||
+		len(fake.deleted) != 0 {
+		t.Errorf("expect only decisions of dry run: %+v", res)
+	}
+
+	// the chain never drops the deleted snapshot
+	fake = newFakeBackup()
+	fake.addOldSnapshots("v-db1", "daily", 3)
+	fake.chainLag = 1000
+	res := run(fake, plan)
+	if res.Error == nil || len(res.Deleted) != 1 || res.Deleted[0] != "s-v-db1-3" {
+		t.Errorf("expect timeout after the first deletion: %+v", res)
+	}
+}
+
+func TestRunBackupPlanImageCopies(t *testing.T) {
+	fake := newFakeBackup()
+	fake.copies["gz"] = "m-gz"
+	fake.images["m-gz"] = api.ImageStatusAvailable
+	client, clean := newBackupTestClient(t, fake)
+	defer clean()
+
+	report, err := client.RunBackupPlan(&BackupPlan{
+		Name:          "daily",
+		CopyImageIds:  []string{"m-1"},
+		CopyToRegions: []string{"gz", "su"},
+		RegionClients: map[string]*Client{"gz": client},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.ImageCopies) != 2 || report.Failed() != 1 {
+		t.Fatalf("unexpected report %s", report)
+	}
+	gz, su := report.ImageCopies[0], report.ImageCopies[1]
+	if gz.RemoteImageId != "m-gz" || gz.Status != api.ImageStatusAvailable || gz.Error != nil {
+		t.Errorf("unexpected copy %+v", gz)
+	}
+	if su.Error == nil || !strings.Contains(su.Error.Error(), "QuotaExceeded") {
+		t.Errorf("unexpected copy %+v", su)
+	}
+
+	if _, err := client.RunBackupPlan(&BackupPlan{}); err == nil {
+		t.Errorf("expect error of unnamed plan")
+	}
+}
@@ -0,0 +1,394 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// bid.go - pick the cheapest bidding flavor and zone and create the bidding instances
+
+package bcc
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+const (
+	BID_MODEL_MARKET = "market"
+	BID_MODEL_CUSTOM = "custom"
+)
+
+// BidCandidate is a flavor in a zone to bid for.
+type BidCandidate struct {
+	ZoneName           string
+	InstanceType       api.InstanceType
+	CpuCount           int
+	MemoryCapacityInGB int
+	Spec               string // filled if listed by ListBidFlavor, used to look up the stock
+}
+
+func (c BidCandidate) String() string {
+	return fmt.Sprintf("%s/%s/%dC%dG", c.ZoneName, c.InstanceType, c.CpuCount, c.MemoryCapacityInGB)
+}
+
+// BidInstanceRequest defines the bidding instances to create and the candidates to choose from.
+type BidInstanceRequest struct {
+	// Template is the arguments to create the instances. The zone, instance type, cpu and memory
+	// are replaced by the chosen candidate and the payment timing is set to bidding.
+	Template api.CreateInstanceArgs
+
+	// SubnetIds is the subnet to use in each zone, Template.SubnetId is used for the missing zones
+	SubnetIds map[string]string
+
+	// Candidates to choose from. If empty, they are listed by ListBidFlavor and filtered by the
+	// non-empty conditions below.
+	Candidates            []BidCandidate
+	ZoneNames             []string
+	InstanceTypes         []api.InstanceType
+	MinCpuCount           int
+	MaxCpuCount           int
+	MinMemoryCapacityInGB int
+	MaxMemoryCapacityInGB int
+
+	// MaxPrice is the highest acceptable price of one instance, no limit if not positive. The
+	// candidates quoted above it are skipped, and unless the template has its own bid model, the
+	// instances are created with the custom bid model bidding at it.
+	MaxPrice float64
+
+	// CheckStock skips the candidates out of stock before trying to create
+	CheckStock bool
+
+	// MaxAttempts limits the number of creation attempts, all the candidates if not positive
+	MaxAttempts int
+}
+
+// BidAttempt records how a candidate was evaluated and tried.
+type BidAttempt struct {
+	Candidate BidCandidate
+	Price     float64 // the price of one instance, valid if PriceError is nil
+	Stock     int     // -1 if not checked
+
+	PriceError  error
+	SkipReason  string
+	CreateError error
+	InstanceIds []string
+}
+
+// Tried returns whether the creation of the candidate was attempted.
+func (a *BidAttempt) Tried() bool {
+	return a.CreateError != nil || len(a.InstanceIds) != 0
+}
+
+// BidReport reports the candidates in the order of price and which one is chosen.
+type BidReport struct {
+	Attempts []BidAttempt
+	Chosen   *BidAttempt // nil if no candidate succeeded
+}
+
+func (r *BidReport) String() string {
+	lines := make([]string, 0, len(r.Attempts)+1)
+	if r.Chosen != nil {
+		lines = append(lines, fmt.Sprintf("chosen %s at %v: %v", r.Chosen.Candidate, r.Chosen.Price,
+			r.Chosen.InstanceIds))
+	} else {
+		lines = append(lines, "no candidate succeeded")
+	}
+	for _, a := range r.Attempts {
+		state := "untried"
+		switch {
+		case a.PriceError != nil:
+			state = fmt.Sprintf("price error: %v", a.PriceError)
+		case len(a.SkipReason) != 0:
+			state = "skipped: " + a.SkipReason
+		case a.CreateError != nil:
+			state = fmt.Sprintf("create error: %v", a.CreateError)
+		case len(a.InstanceIds) != 0:
+			state = "created"
+		}
+		lines = append(lines, fmt.Sprintf("  %s price=%v stock=%d %s", a.Candidate, a.Price, a.Stock, state))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ListBidCandidates - list the bidding flavors in all zones and filter them by the request
+//
+// PARAMS:
+//     - req: the conditions to filter the flavors, the Candidates of it is returned if not empty
+// RETURNS:
+//     - []BidCandidate: the candidates matching the conditions
+//     - error: nil if success otherwise the specific error
+func (c *Client) ListBidCandidates(req *BidInstanceRequest) ([]BidCandidate, error) {
+	if req == nil {
+		return nil, fmt.Errorf("the bid instance request should not be nil")
+	}
+	if len(req.Candidates) != 0 {
+		return req.Candidates, nil
+	}
+	flavors, err := c.ListBidFlavor()
+	if err != nil {
+		return nil, err
+	}
+	result := []BidCandidate{}
+	for _, zone := range flavors.ZoneResources {
+		if len(req.ZoneNames) != 0 && !containsString(req.ZoneNames, zone.ZoneName) {
+			continue
+		}
+		for _, resource := range zone.BccResources {
+			if len(req.InstanceTypes) != 0 && !containsInstanceType(req.InstanceTypes, resource.InstanceType) {
+				continue
+			}
+			for _, flavor := range resource.Flavors {
+				if !inRange(flavor.CpuCount, req.MinCpuCount, req.MaxCpuCount) ||
+					!inRange(flavor.MemoryCapacityInGB, req.MinMemoryCapacityInGB, req.MaxMemoryCapacityInGB) {
+					continue
+				}
+				result = append(result, BidCandidate{
+					ZoneName:           zone.ZoneName,
+					InstanceType:       resource.InstanceType,
+					CpuCount:           flavor.CpuCount,
+					MemoryCapacityInGB: flavor.MemoryCapacityInGB,
+					Spec:               flavor.Spec,
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+// QuoteBidCandidates - query the bidding price of each candidate and sort them by price
+//
+// PARAMS:
+//     - req: the request of the bidding instances
+// RETURNS:
+//     - *BidReport: the candidates sorted by price, the ones failed to quote or above the max price
+//       are at the end with the PriceError or SkipReason
+//     - error: nil if success otherwise the specific error
+func (c *Client) QuoteBidCandidates(req *BidInstanceRequest) (*BidReport, error) {
+	candidates, err := c.ListBidCandidates(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no bidding flavor matches the request")
+	}
+
+	report := &BidReport{Attempts: make([]BidAttempt, len(candidates))}
+	for i, candidate := range candidates {
+		attempt := &report.Attempts[i]
+		attempt.Candidate, attempt.Stock = candidate, -1
+		attempt.Price, attempt.PriceError = c.quoteBidCandidate(&req.Template, candidate)
+		if attempt.PriceError == nil && req.MaxPrice > 0 && attempt.Price > req.MaxPrice {
+			attempt.SkipReason = fmt.Sprintf("price %v exceeds the max price %v", attempt.Price, req.MaxPrice)
+		}
+	}
+	sort.SliceStable(report.Attempts, func(i, j int) bool {
+		a, b := &report.Attempts[i], &report.Attempts[j]
+		if usableBidAttempt(a) != usableBidAttempt(b) {
+			return usableBidAttempt(a)
+		}
+		return a.Price < b.Price
+	})
+	return report, nil
+}
+
+// CreateCheapestBidInstance - create the bidding instances with the cheapest candidate, fall back to
+// the next one if the candidate is out of stock or rejected by the service
+//
+// PARAMS:
+//     - req: the request of the bidding instances
+// RETURNS:
+//     - *BidReport: the report of all the candidates and the chosen one
+//     - error: nil if the instances are created otherwise the last error
+func (c *Client) CreateCheapestBidInstance(req *BidInstanceRequest) (*BidReport, error) {
+	report, err := c.QuoteBidCandidates(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stocks map[string]int
+	if req.CheckStock {
+		if all, err := c.GetAllStocks(); err == nil {
+			stocks = make(map[string]int, len(all.BccStocks))
+			for _, s := range all.BccStocks {
+				stocks[s.ZoneName+"/"+s.Spec] += s.InventoryQuantity
+			}
+		} else {
+			log.Warnf("get all stocks failed, check the stock of each candidate: %v", err)
+		}
+	}
+
+	count := req.Template.PurchaseCount
+	if count <= 0 {
+		count = 1
+	}
+	attempts := 0
+	var lastErr error
+	for i := range report.Attempts {
+		attempt := &report.Attempts[i]
+		if !usableBidAttempt(attempt) {
+			continue
+		}
+		if req.MaxAttempts > 0 && attempts >= req.MaxAttempts {
+			break
+		}
+		if req.CheckStock {
+			attempt.Stock = c.bidCandidateStock(&req.Template, attempt.Candidate, stocks)
+			if attempt.Stock >= 0 && attempt.Stock < count {
+				attempt.SkipReason = fmt.Sprintf("stock %d is less than %d", attempt.Stock, count)
+				continue
+			}
+		}
+
+		attempts++
+		args := req.Template
+		applyBidCandidate(&args, attempt.Candidate, req.MaxPrice)
+		// a token shared by the candidates would dedupe the creation with the failed one
+		args.ClientToken = bidClientToken(req.Template.ClientToken, attempt.Candidate)
+		if subnetId, ok := req.SubnetIds[attempt.Candidate.ZoneName]; ok {
+			args.SubnetId = subnetId
+		}
+		result, err := c.CreateBidInstance(&args)
+		if err == nil {
+			attempt.InstanceIds = result.InstanceIds
+			report.Chosen = attempt
+			return report, nil
+		}
+		attempt.CreateError, lastErr = err, err
+		if _, ok := err.(*bce.BceServiceError); !ok {
+			// the instance may have been created if the response is lost, never create another one
+			return report, err
+		}
+		log.Warnf("create bidding instance with %s failed, try the next candidate: %v",
+			attempt.Candidate, err)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no bidding candidate is available within the price and stock")
+	}
+	return report, lastErr
+}
+
+func (c *Client) quoteBidCandidate(template *api.CreateInstanceArgs,
+	candidate BidCandidate) (float64, error) {
+	args := &api.GetBidInstancePriceArgs{
+		InstanceType:          candidate.InstanceType,
+		CpuCount:              candidate.CpuCount,
+		MemoryCapacityInGB:    candidate.MemoryCapacityInGB,
+		RootDiskSizeInGb:      template.RootDiskSizeInGb,
+		RootDiskStorageType:   template.RootDiskStorageType,
+		CreateCdsList:         template.CreateCdsList,
+		PurchaseCount:         1,
+		ImageId:               template.ImageId,
+		BidModel:              template.BidModel,
+		BidPrice:              template.BidPrice,
+		NetWorkCapacityInMbps: template.NetWorkCapacityInMbps,
+		ZoneName:              candidate.ZoneName,
+		InternetChargeType:    template.InternetChargeType,
+	}
+	if len(args.BidModel) == 0 {
+		args.BidModel = BID_MODEL_MARKET
+	}
+	result, err := c.GetBidInstancePrice(args)
+	if err != nil {
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(result.PerMoney, 64)
+	if err != nil {
+		if price, err = strconv.ParseFloat(result.Money, 64); err != nil {
+			return 0, fmt.Errorf("invalid bidding price %q: %v", result.Money, err)
+		}
+	}
+	return price, nil
+}
+
+// bidCandidateStock returns the stock of the candidate, -1 if unknown
+func (c *Client) bidCandidateStock(template *api.CreateInstanceArgs, candidate BidCandidate,
+	stocks map[string]int) int {
+	if stock, ok := stocks[candidate.ZoneName+"/"+candidate.Spec]; ok && len(candidate.Spec) != 0 {
+		return stock
+	}
+	result, err := c.GetInstanceCreateStock(&api.CreateInstanceStockArgs{
+		EphemeralDisks:     template.EphemeralDisks,
+		ZoneName:           candidate.ZoneName,
+		CardCount:          template.CardCount,
+		InstanceType:       candidate.InstanceType,
+		CpuCount:           candidate.CpuCount,
+		MemoryCapacityInGB: candidate.MemoryCapacityInGB,
+		GpuCard:            template.GpuCard,
+	})
+	if err != nil {
+		log.Warnf("get stock of %s failed: %v", candidate, err)
+		return -1
+	}
+	return result.Count
+}
+
+// applyBidCandidate sets the candidate to the arguments, the bid is capped at the max price by the
+// custom bid model unless the template has its own bid model
+func applyBidCandidate(args *api.CreateInstanceArgs, candidate BidCandidate, maxPrice float64) {
+	args.ZoneName = candidate.ZoneName
+	args.InstanceType = candidate.InstanceType
+	args.CpuCount = candidate.CpuCount
+	args.MemoryCapacityInGB = candidate.MemoryCapacityInGB
+	args.Billing.PaymentTiming = api.PaymentTimingBidding
+	if len(args.BidModel) != 0 {
+		return
+	}
+	args.BidModel = BID_MODEL_MARKET
+	if maxPrice > 0 {
+		args.BidModel = BID_MODEL_CUSTOM
+		args.BidPrice = strconv.FormatFloat(maxPrice, 'f', -1, 64)
+	}
+}
+
+// bidClientToken derives the token of the candidate from the token of the template, so that a
+// retried request deduplicates the creation with the same candidate only. An empty token is
+// generated for each request.
+func bidClientToken(token string, candidate BidCandidate) string {
+	if len(token) == 0 {
+		return ""
+	}
+	sum := sha1.Sum([]byte(candidate.String() + "/" + candidate.Spec))
+	return token + "-" + hex.EncodeToString(sum[:4])
+}
+
+func usableBidAttempt(a *BidAttempt) bool {
+	return a.PriceError == nil && len(a.SkipReason) == 0
+}
+
+func inRange(value, min, max int) bool {
+	return (min <= 0 || value >= min) && (max <= 0 || value <= max)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInstanceType(values []api.InstanceType, value api.InstanceType) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package bcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+)
+
+// fakeBid serves the bidding APIs, the created instances are named after their zones
+type fakeBid struct {
+	prices  map[string]string // zone/cpu -> price
+	stocks  map[string]int    // zone/spec -> stock
+	fails   map[string]bool   // zone -> whether the creation is rejected or its response lost
+	created []api.CreateInstanceArgs
+	tokens  []string
+}
+
+func (f *fakeBid) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v2/instance/bidFlavor":
+		flavors := []api.Flavor{
+			{CpuCount: 1, MemoryCapacityInGB: 2, Spec: "bcc.g1.small"},
+			{CpuCount: 2, MemoryCapacityInGB: 8, Spec: "bcc.g1.medium"},
+			{CpuCount: 8, MemoryCapacityInGB: 32, Spec: "bcc.g1.large"},
+		}
+		json.NewEncoder(w).Encode(&api.ListBidFlavorResult{ZoneResources: []api.ZoneResource{
+			{ZoneName: "zoneA", BccResources: []api.BccResource{
+				{InstanceType: api.InstanceTypeN3, Flavors: flavors},
+				{InstanceType: api.InstanceTypeN4, Flavors: flavors},
+			}},
+			{ZoneName: "zoneB", BccResources: []api.BccResource{
+				{InstanceType: api.InstanceTypeN3, Flavors: flavors},
+			}},
+		}})
+	case "/v2/instance/bidPrice":
+		args := &api.GetBidInstancePriceArgs{}
+		json.NewDecoder(r.Body).Decode(args)
+		price, ok := f.prices[fmt.Sprintf("%s/%d", args.ZoneName, args.CpuCount)]
+		if !ok || args.BidModel != BID_MODEL_MARKET {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"NoPrice","message":"no price"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(&api.GetBidInstancePriceResult{PerMoney: price})
+	case "/v2/instance/getAllStocks":
+		result := &api.GetAllStocksResult{}
+		for key, stock := range f.stocks {
+			parts := strings.SplitN(key, "/", 2)
+			result.BccStocks = append(result.BccStocks, api.BccStock{ZoneName: parts[0], Spec: parts[1],
+				InventoryQuantity: stock})
+		}
+		json.NewEncoder(w).Encode(result)
+	case "/v2/instance/bid":
+		args := api.CreateInstanceArgs{}
+		json.NewDecoder(r.Body).Decode(&args)
+		f.created = append(f.created, args)
+		f.tokens = append(f.tokens, r.URL.Query().Get("clientToken"))
+		rejected, ok := f.fails[args.ZoneName]
+		if ok && rejected {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"NoStock","message":"no stock"}`)
+			return
+		}
+		if ok {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		json.NewEncoder(w).Encode(&api.CreateInstanceResult{InstanceIds: []string{"i-" + args.ZoneName}})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"code":"Unexpected","message":"unexpected request %s"}`, r.URL.Path)
+	}
+}
+
+func newBidTestClient(t *testing.T, f *fakeBid) (*Client, func()) {
+	server := httptest.NewServer(f)
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func TestListBidCandidates(t *testing.T) {
+	cases := []struct {
+		req      BidInstanceRequest
+		expected []string
+	}{
+		{BidInstanceRequest{ZoneNames: []string{"zoneB"}},
+			[]string{"zoneB/N3/1C2G", "zoneB/N3/2C8G", "zoneB/N3/8C32G"}},
+		{BidInstanceRequest{InstanceTypes: []api.InstanceType{api.InstanceTypeN4}, MinCpuCount: 2},
+			[]string{"zoneA/N4/2C8G", "zoneA/N4/8C32G"}},
+		{BidInstanceRequest{MaxCpuCount: 2, MinMemoryCapacityInGB: 4, MaxMemoryCapacityInGB: 8},
+			[]string{"zoneA/N3/2C8G", "zoneA/N4/2C8G", "zoneB/N3/2C8G"}},
+		{BidInstanceRequest{Candidates: []BidCandidate{{ZoneName: "zoneC", InstanceType: api.InstanceTypeN3,
+			CpuCount: 4, MemoryCapacityInGB: 16}}}, []string{"zoneC/N3/4C16G"}},
+	}
+	client, clean := newBidTestClient(t, &fakeBid{})
+	defer clean()
+	for i, c := range cases {
+		candidates, err := client.ListBidCandidates(&c.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, candidate := range candidates {
+			names = append(names, candidate.String())
+		}
+		if strings.Join(names, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("case %d: unexpected candidates %v", i, names)
+		}
+	}
+}
+
+func TestQuoteBidCandidates(t *testing.T) {
+	fake := &fakeBid{prices: map[string]string{"zoneA/2": "0.3", "zoneB/2": "0.1", "zoneA/8": "0.9"}}
+	client, clean := newBidTestClient(t, fake)
+	defer clean()
+	report, err := client.QuoteBidCandidates(&BidInstanceRequest{MinCpuCount: 2,
+		InstanceTypes: []api.InstanceType{api.InstanceTypeN3}, MaxPrice: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		candidate string
+		price     float64
+		state     string
+	}{
+		{"zoneB/N3/2C8G", 0.1, ""},
+		{"zoneA/N3/2C8G", 0.3, ""},
+		{"zoneB/N3/8C32G", 0, "price"},
+		{"zoneA/N3/8C32G", 0.9, "skip"},
+	}
+	if len(report.Attempts) != len(cases) {
+		t.Fatalf("unexpected report %s", report)
+	}
+	for i, c := range cases {
+		a := report.Attempts[i]
+		state := ""
+		if a.PriceError != nil {
+			state = "price"
+		} else if len(a.SkipReason) != 0 {
+			state = "skip"
+		}
+		if a.Candidate.String() != c.candidate || a.Price != c.price || state != c.state {
+			t.Errorf("attempt %d: unexpected %s price=%v state=%q", i, a.Candidate, a.Price, state)
+		}
+	}
+}
+
+func TestCreateCheapestBidInstance(t *testing.T) {
+	template := api.CreateInstanceArgs{ClientToken: "token", PurchaseCount: 2}
+	cases := []struct {
+		name        string
+		fails       map[string]bool
+		stocks      map[string]int
+		checkStock  bool
+		maxAttempts int
+		created     []string
+		chosen      string
+		failed      bool
+	}{
+		{name: "cheapest", created: []string{"zoneB"}, chosen: "zoneB"},
+		{name: "fallback on service error", fails: map[string]bool{"zoneB": true},
+			created: []string{"zoneB", "zoneA"}, chosen: "zoneA"},
+		{name: "no fallback on lost response", fails: map[string]bool{"zoneB": false},
+			created: []string{"zoneB"}, failed: true},
+		{name: "max attempts", fails: map[string]bool{"zoneB": true}, maxAttempts: 1,
+			created: []string{"zoneB"}, failed: true},
+		{name: "skip out of stock", checkStock: true,
+			stocks:  map[string]int{"zoneB/bcc.g1.medium": 1, "zoneA/bcc.g1.medium": 5},
+			created: []string{"zoneA"}, chosen: "zoneA"},
+	}
+	for _, c := range cases {
+		fake := &fakeBid{prices: map[string]string{"zoneA/2": "0.3", "zoneB/2": "0.1"}, fails: c.fails,
+			stocks: c.stocks}
+		client, clean := newBidTestClient(t, fake)
+		report, err := client.CreateCheapestBidInstance(&BidInstanceRequest{Template: template,
+			MinCpuCount: 2, MaxCpuCount: 2, InstanceTypes: []api.InstanceType{api.InstanceTypeN3},
+			CheckStock: c.checkStock, MaxAttempts: c.maxAttempts})
+		clean()
+		if (err != nil) != c.failed {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		zones := []string{}
+		for i, args := range fake.created {
+			zones = append(zones, args.ZoneName)
+			if args.Billing.PaymentTiming != api.PaymentTimingBidding || args.BidModel != BID_MODEL_MARKET {
+				t.Errorf("%s: unexpected args %+v", c.name, args)
+			}
+			if !strings.HasPrefix(fake.tokens[i], "token-") {
+				t.Errorf("%s: expect the token derived from the template, got %q", c.name, fake.tokens[i])
+			}
+		}
+		if len(fake.tokens) == 2 && fake.tokens[0] == fake.tokens[1] {
+			t.Errorf("%s: expect a token for each candidate, got %v", c.name, fake.tokens)
+		}
+		if strings.Join(zones, ",") != strings.Join(c.created, ",") {
+			t.Errorf("%s: unexpected creations %v", c.name, zones)
+		}
+		chosen := ""
+		if report != nil && report.Chosen != nil {
+			chosen = report.Chosen.Candidate.ZoneName
+		}
+		if chosen != c.chosen {
+			t.Errorf("%s: unexpected chosen %q", c.name, chosen)
+		}
+	}
+}
+
+func TestCreateCheapestBidInstanceMaxPrice(t *testing.T) {
+	fake := &fakeBid{prices: map[string]string{"zoneA/2": "0.3", "zoneB/2": "0.1"}}
+	client, clean := newBidTestClient(t, fake)
+	defer clean()
+
+	req := &BidInstanceRequest{MinCpuCount: 2, MaxCpuCount: 2, MaxPrice: 0.25,
+		InstanceTypes: []api.InstanceType{api.InstanceTypeN3}}
+	if _, err := client.CreateCheapestBidInstance(req); err != nil {
+		t.Fatal(err)
+	}
+	args := fake.created[0]
+	if args.BidModel != BID_MODEL_CUSTOM || args.BidPrice != "0.25" || len(fake.tokens[0]) == 0 {
+		t.Errorf("expect the bid capped at the max price, got %+v", args)
+	}
+
+	// the bid model of the template is kept
+	req.Template.BidModel, req.Template.BidPrice = BID_MODEL_MARKET, ""
+	fake.created = nil
+	if _, err := client.CreateCheapestBidInstance(req); err != nil {
+		t.Fatal(err)
+	}
+	if args := fake.created[0]; args.BidModel != BID_MODEL_MARKET || len(args.BidPrice) != 0 {
+		t.Errorf("unexpected args %+v", args)
+	}
+}
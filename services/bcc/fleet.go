@@ -0,0 +1,402 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// fleet.go - the fleet operations on a group of BCC instances
+
+package bcc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/model"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+const (
+	DEFAULT_FLEET_PARALLELISM   = 5
+	DEFAULT_FLEET_WAIT_TIMEOUT  = 10 * time.Minute
+	DEFAULT_FLEET_POLL_INTERVAL = 5 * time.Second
+	DEFAULT_FLEET_LEAVE_TIMEOUT = 30 * time.Second
+)
+
+// FleetSelector defines the conditions to select the instances, all the non-empty conditions
+// must be satisfied. The zone, the only instance id and the first tag with value are filtered by
+// ListServersByMarkerV3, the other conditions are matched on the listed instances.
+type FleetSelector struct {
+	ZoneName string
+	VpcId    string
+
+	// Tags must all be bound to the instance, a tag with empty value matches any value of the key
+	Tags []model.TagModel
+
+	// NamePattern is a shell pattern of the instance name, such as "web-*"
+	NamePattern string
+
+	InstanceIds []string
+	Status      api.InstanceStatus
+}
+
+func (s *FleetSelector) listArgs() *api.ListServerRequestV3Args {
+	args := &api.ListServerRequestV3Args{ZoneName: s.ZoneName}
+	if len(s.InstanceIds) == 1 {
+		args.InstanceId = s.InstanceIds[0]
+	}
+	for _, tag := range s.Tags {
+		if len(tag.TagValue) != 0 {
+			args.Tag = tag
+			break
+		}
+	}
+	return args
+}
+
+func (s *FleetSelector) match(instance *api.InstanceModelV3) (bool, error) {
+	if len(s.VpcId) != 0 && instance.VpcId != s.VpcId {
+		return false, nil
+	}
+	if len(s.Status) != 0 && instance.Status != s.Status {
+		return false, nil
+	}
+	if len(s.InstanceIds) != 0 {
+		found := false
+		for _, id := range s.InstanceIds {
+			found = found || id == instance.InstanceId
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if len(s.NamePattern) != 0 {
+		matched, err := path.Match(s.NamePattern, instance.InstanceName)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	for _, expected := range s.Tags {
+		found := false
+		for _, tag := range instance.Tags {
+			if tag.TagKey == expected.TagKey &&
+				(len(expected.TagValue) == 0 || tag.TagValue == expected.TagValue) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SelectInstances - list the instances by ListServersByMarkerV3 and select the ones matching the
+// selector
+//
+// PARAMS:
+//     - selector: the conditions to select the instances
+// RETURNS:
+//     - []api.InstanceModelV3: the selected instances
+//     - error: nil if success otherwise the specific error
+func (c *Client) SelectInstances(selector *FleetSelector) ([]api.InstanceModelV3, error) {
+	if selector == nil {
+		selector = &FleetSelector{}
+	}
+	args := selector.listArgs()
+	result := []api.InstanceModelV3{}
+	for {
+		listResult, err := c.ListServersByMarkerV3(args)
+		if err != nil {
+			return nil, err
+		}
+		for i := range listResult.Instances {
+			matched, err := selector.match(&listResult.Instances[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid name pattern %q: %v", selector.NamePattern, err)
+			}
+			if matched {
+				result = append(result, listResult.Instances[i])
+			}
+		}
+		if !listResult.IsTruncated || len(listResult.NextMarker) == 0 {
+			break
+		}
+		args.Marker = listResult.NextMarker
+	}
+	return result, nil
+}
+
+// FleetOperation defines the operation applied to each instance of the fleet. The TargetStatus is
+// the status to wait for after the operation is accepted, empty if there is nothing to wait for,
+// and RestoreStatus takes the status of the instance right before the operation as the target.
+// Some operations make the instance leave the target status and come back, such as the reboot, so
+// with LeavesTargetStatus the wait does not finish until the instance is seen in another status or
+// the LeaveTimeout of the options passes, since a quick one may finish between two polls.
+type FleetOperation struct {
+	Name               string
+	TargetStatus       api.InstanceStatus
+	RestoreStatus      bool
+	LeavesTargetStatus bool
+	Apply              func(c *Client, instanceId string) error
+}
+
+func StartInstanceOperation() *FleetOperation {
+	return &FleetOperation{Name: "start", TargetStatus: api.InstanceStatusRunning,
+		Apply: func(c *Client, instanceId string) error { return c.StartInstance(instanceId) }}
+}
+
+func StopInstanceOperation(forceStop bool) *FleetOperation {
+	return &FleetOperation{Name: "stop", TargetStatus: api.InstanceStatusStopped,
+		Apply: func(c *Client, instanceId string) error { return c.StopInstance(instanceId, forceStop) }}
+}
+
+func RebootInstanceOperation(forceStop bool) *FleetOperation {
+	return &FleetOperation{Name: "reboot", TargetStatus: api.InstanceStatusRunning,
+		LeavesTargetStatus: true,
+		Apply: func(c *Client, instanceId string) error { return c.RebootInstance(instanceId, forceStop) }}
+}
+
+func ResizeInstanceBySpecOperation(args *api.ResizeInstanceArgs) *FleetOperation {
+	return &FleetOperation{Name: "resize", RestoreStatus: true, LeavesTargetStatus: true,
+		Apply: func(c *Client, instanceId string) error {
+			// every instance needs its own client token, which is generated if empty
+			resizeArgs := *args
+			resizeArgs.ClientToken = ""
+			return c.ResizeInstanceBySpec(instanceId, &resizeArgs)
+		}}
+}
+
+func BindInstanceToTagsOperation(tags []model.TagModel) *FleetOperation {
+	return &FleetOperation{Name: "bindTags",
+		Apply: func(c *Client, instanceId string) error {
+			return c.BindInstanceToTags(instanceId, &api.BindTagsRequest{ChangeTags: tags})
+		}}
+}
+
+func ModifyDeletionProtectionOperation(enabled bool) *FleetOperation {
+	args := &api.DeletionProtectionArgs{}
+	if enabled {
+		args.DeletionProtection = 1
+	}
+	return &FleetOperation{Name: "modifyDeletionProtection",
+		Apply: func(c *Client, instanceId string) error { return c.ModifyDeletionProtection(instanceId, args) }}
+}
+
+// FleetOptions defines how the operation is rolled out over the fleet. The instances are split into
+// batches of BatchSize, at most Parallelism instances in a batch are operated at the same time and
+// the next batch starts after all instances of the current one finish and PauseBetweenBatches.
+type FleetOptions struct {
+	Parallelism         int
+	BatchSize           int // all the instances in one batch if not positive
+	PauseBetweenBatches time.Duration
+
+	// WaitForStatus makes each instance wait for the target status of the operation
+	WaitForStatus bool
+	WaitTimeout   time.Duration
+	PollInterval  time.Duration
+
+	// LeaveTimeout bounds the wait for the instance to leave the target status if the operation
+	// LeavesTargetStatus, the instance seen in the target status after it is regarded as done
+	LeaveTimeout time.Duration
+
+	// StopOnFailure skips the remaining batches once an instance fails
+	StopOnFailure bool
+}
+
+// FleetResult is the result of the operation on one instance.
+type FleetResult struct {
+	InstanceId   string
+	InstanceName string
+	Batch        int
+	Status       api.InstanceStatus // the last observed status if waited
+	Skipped      bool
+	Error        error
+	Elapsed      time.Duration
+}
+
+// FleetReport is the per-instance report of a fleet operation.
+type FleetReport struct {
+	Operation string
+	Results   []FleetResult
+}
+
+// Failed returns the results of the instances failed to operate.
+func (r *FleetReport) Failed() []FleetResult {
+	result := []FleetResult{}
+	for _, res := range r.Results {
+		if res.Error != nil {
+			result = append(result, res)
+		}
+	}
+	return result
+}
+
+// Succeeded returns the results of the instances operated successfully.
+func (r *FleetReport) Succeeded() []FleetResult {
+	result := []FleetResult{}
+	for _, res := range r.Results {
+		if res.Error == nil && !res.Skipped {
+			result = append(result, res)
+		}
+	}
+	return result
+}
+
+func (r *FleetReport) String() string {
+	skipped := 0
+	for _, res := range r.Results {
+		if res.Skipped {
+			skipped++
+		}
+	}
+	lines := []string{fmt.Sprintf("%s: %d succeeded, %d failed, %d skipped", r.Operation,
+		len(r.Succeeded()), len(r.Failed()), skipped)}
+	for _, res := range r.Failed() {
+		lines = append(lines, fmt.Sprintf("  %s(%s): %v", res.InstanceId, res.InstanceName, res.Error))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RunFleetOperation - apply the operation to the instances batch by batch with bounded concurrency
+//
+// PARAMS:
+//     - instances: the instances to operate, usually returned by SelectInstances
+//     - operation: the operation to apply
+//     - options: the options of the rollout, the default ones are used if nil
+// RETURNS:
+//     - *FleetReport: the per-instance result report
+func (c *Client) RunFleetOperation(instances []api.InstanceModelV3, operation *FleetOperation,
+	options *FleetOptions) *FleetReport {
+	opts := FleetOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DEFAULT_FLEET_PARALLELISM
+	}
+	if opts.BatchSize <= 0 || opts.BatchSize > len(instances) {
+		opts.BatchSize = len(instances)
+	}
+	if opts.WaitTimeout <= 0 {
+		opts.WaitTimeout = DEFAULT_FLEET_WAIT_TIMEOUT
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DEFAULT_FLEET_POLL_INTERVAL
+	}
+	if opts.LeaveTimeout <= 0 {
+		opts.LeaveTimeout = DEFAULT_FLEET_LEAVE_TIMEOUT
+	}
+
+	report := &FleetReport{Operation: operation.Name, Results: make([]FleetResult, len(instances))}
+	for i, instance := range instances {
+		report.Results[i] = FleetResult{
+			InstanceId:   instance.InstanceId,
+			InstanceName: instance.InstanceName,
+			Batch:        i / opts.BatchSize,
+			Skipped:      true,
+		}
+	}
+
+	for start := 0; start < len(instances); start += opts.BatchSize {
+		end := start + opts.BatchSize
+		if end > len(instances) {
+			end = len(instances)
+		}
+		if start > 0 && opts.PauseBetweenBatches > 0 {
+			time.Sleep(opts.PauseBetweenBatches)
+		}
+		log.Infof("fleet %s: batch %d with %d instance(s)", operation.Name, start/opts.BatchSize,
+			end-start)
+
+		var wg sync.WaitGroup
+		tokens := make(chan struct{}, opts.Parallelism)
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			tokens <- struct{}{}
+			go func(res *FleetResult) {
+				defer func() {
+					<-tokens
+					wg.Done()
+				}()
+				c.runFleetInstance(res, operation, &opts)
+			}(&report.Results[i])
+		}
+		wg.Wait()
+
+		if opts.StopOnFailure && len(report.Failed()) != 0 {
+			log.Warnf("fleet %s: stop after batch %d for failures", operation.Name,
+				start/opts.BatchSize)
+			break
+		}
+	}
+	return report
+}
+
+func (c *Client) runFleetInstance(res *FleetResult, operation *FleetOperation, opts *FleetOptions) {
+	begin := time.Now()
+	defer func() { res.Elapsed = time.Since(begin) }()
+	res.Skipped = false
+	wait := opts.WaitForStatus && (len(operation.TargetStatus) != 0 || operation.RestoreStatus)
+	target := operation.TargetStatus
+	if wait && operation.RestoreStatus {
+		detail, err := c.GetInstanceDetail(res.InstanceId)
+		if err != nil {
+			res.Error = err
+			return
+		}
+		target = detail.Instance.Status
+	}
+	if res.Error = operation.Apply(c, res.InstanceId); res.Error != nil || !wait {
+		return
+	}
+	res.Status, res.Error = c.waitFleetInstance(res.InstanceId, target, operation.LeavesTargetStatus,
+		opts)
+}
+
+// waitFleetInstance waits for the instance to reach the target status. If the instance leaves the
+// target status for a while, it must be seen in another status, or still in the target status
+// after the LeaveTimeout, before the target status counts.
+func (c *Client) waitFleetInstance(instanceId string, target api.InstanceStatus, leaves bool,
+	opts *FleetOptions) (api.InstanceStatus, error) {
+	begin := time.Now()
+	deadline := begin.Add(opts.WaitTimeout)
+	left := !leaves
+	for {
+		detail, err := c.GetInstanceDetail(instanceId)
+		if err != nil {
+			return "", err
+		}
+		status := detail.Instance.Status
+		if status == api.InstanceStatusError {
+			return status, fmt.Errorf("instance %s turns into status %s", instanceId, status)
+		}
+		if !left && status == target && time.Since(begin) >= opts.LeaveTimeout {
+			log.Infof("instance %s is not seen leaving %s in %s, regard it as done", instanceId,
+				target, opts.LeaveTimeout)
+			left = true
+		}
+		left = left || status != target
+		if left && status == target {
+			return status, nil
+		}
+		if time.Now().Add(opts.PollInterval).After(deadline) {
+			return status, fmt.Errorf("wait for instance %s to be %s timeout, current status %s",
+				instanceId, target, status)
+		}
+		time.Sleep(opts.PollInterval)
+	}
+}
@@ -0,0 +1,299 @@
+package bcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/model"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+)
+
+// fakeFleet serves the instance APIs used by the fleet operations
+type fakeFleet struct {
+	mutex     sync.Mutex
+	instances []api.InstanceModelV3
+	listArgs  []api.ListServerRequestV3Args
+	active    int
+	maxActive int
+	applied   []string
+	failed    map[string]bool
+	statuses  map[string][]api.InstanceStatus
+	polls     map[string]int
+}
+
+func newFakeFleet(n int) *fakeFleet {
+	f := &fakeFleet{
+		failed:   map[string]bool{},
+		statuses: map[string][]api.InstanceStatus{},
+		polls:    map[string]int{},
+	}
+	for i := 0; i < n; i++ {
+		f.instances = append(f.instances, api.InstanceModelV3{InstanceId: fmt.Sprintf("i-%d", i),
+			InstanceName: fmt.Sprintf("web-%d", i), Status: api.InstanceStatusRunning})
+	}
+	return f
+}
+
+func (f *fakeFleet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := path.Base(r.URL.Path)
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v3/instance/list":
+		args := api.ListServerRequestV3Args{}
+		json.NewDecoder(r.Body).Decode(&args)
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.listArgs = append(f.listArgs, args)
+		// two instances a page
+		start := 0
+		fmt.Sscanf(args.Marker, "%d", &start)
+		result := &api.LogicMarkerResultResponseV3{}
+		for i := start; i < len(f.instances) && i < start+2; i++ {
+			result.Instances = append(result.Instances, f.instances[i])
+		}
+		if start+2 < len(f.instances) {
+			result.IsTruncated, result.NextMarker = true, fmt.Sprintf("%d", start+2)
+		}
+		json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet:
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.polls[id]++
+		statuses := f.statuses[id]
+		if len(statuses) > 1 {
+			f.statuses[id] = statuses[1:]
+		}
+		json.NewEncoder(w).Encode(&api.GetInstanceDetailResult{
+			Instance: api.InstanceModel{InstanceId: id, Status: statuses[0]}})
+	case r.Method == http.MethodPut:
+		f.mutex.Lock()
+		f.active++
+		if f.active > f.maxActive {
+			f.maxActive = f.active
+		}
+		f.applied = append(f.applied, id)
+		f.mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.active--
+		if f.failed[id] {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"code":"Instance.InvalidStatus","message":"invalid status"}`)
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newFleetTestClient(t *testing.T, f *fakeFleet) (*Client, func()) {
+	server := httptest.NewServer(f)
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func TestSelectInstances(t *testing.T) {
+	fake := newFakeFleet(5)
+	fake.instances[1].Tags = []model.TagModel{{TagKey: "env", TagValue: "prod"}, {TagKey: "role"}}
+	fake.instances[4].Tags = fake.instances[1].Tags
+	fake.instances[4].InstanceName = "db-4"
+	client, clean := newFleetTestClient(t, fake)
+	defer clean()
+
+	instances, err := client.SelectInstances(&FleetSelector{ZoneName: "cn-bj-a", NamePattern: "web-*",
+		Tags: []model.TagModel{{TagKey: "role"}, {TagKey: "env", TagValue: "prod"}}})
+	if err != nil || len(instances) != 1 || instances[0].InstanceId != "i-1" {
+		t.Fatalf("unexpected instances %+v %v", instances, err)
+	}
+	if len(fake.listArgs) != 3 || fake.listArgs[2].Marker != "4" {
+		t.Fatalf("expect all the 3 pages listed, got %+v", fake.listArgs)
+	}
+	// the zone and the first tag with value are filtered by the server
+	args := fake.listArgs[0]
+	if args.ZoneName != "cn-bj-a" || args.Tag.TagKey != "env" || args.Tag.TagValue != "prod" {
+		t.Errorf("unexpected list args %+v", args)
+	}
+
+	if _, err := client.SelectInstances(&FleetSelector{NamePattern: "[web"}); err == nil {
+		t.Errorf("expect error of invalid name pattern")
+	}
+}
+
+func TestRunFleetOperationBatches(t *testing.T) {
+	fake := newFakeFleet(7)
+	client, clean := newFleetTestClient(t, fake)
+	defer clean()
+
+	begin := time.Now()
+	report := client.RunFleetOperation(fake.instances, StartInstanceOperation(),
+		&FleetOptions{Parallelism: 2, BatchSize: 3, PauseBetweenBatches: 50 * time.Millisecond})
+	if len(report.Succeeded()) != 7 || len(report.Failed()) != 0 {
+		t.Fatalf("unexpected report %s", report)
+	}
+	if fake.maxActive != 2 {
+		t.Errorf("expect at most 2 instances at the same time, got %d", fake.maxActive)
+	}
+	// the batches run one after another
+	for i, id := range fake.applied {
+		var index int
+		fmt.Sscanf(id, "i-%d", &index)
+		if index/3 != i/3 {
+			t.Errorf("instance %s applied out of its batch: %v", id, fake.applied)
+		}
+	}
+	if report.Results[6].Batch != 2 {
+		t.Errorf("unexpected batch %d of the last instance", report.Results[6].Batch)
+	}
+	if elapsed := time.Since(begin); elapsed < 100*time.Millisecond {
+		t.Errorf("expect pauses between the 3 batches, elapsed %s", elapsed)
+	}
+}
+
+func TestRunFleetOperationStopOnFailure(t *testing.T) {
+	fake := newFakeFleet(5)
+	fake.failed["i-1"] = true
+	client, clean := newFleetTestClient(t, fake)
+	defer clean()
+
+	report := client.RunFleetOperation(fake.instances, StartInstanceOperation(),
+		&FleetOptions{BatchSize: 2, StopOnFailure: true})
+	if len(report.Failed()) != 1 || report.Failed()[0].InstanceId != "i-1" ||
+		len(report.Succeeded()) != 1 || len(fake.applied) != 2 {
+		t.Fatalf("unexpected report %s", report)
+	}
+	if realErr, ok := report.Failed()[0].Error.(*bce.BceServiceError); !ok ||
+		realErr.Code != "Instance.InvalidStatus" {
+		t.Errorf("unexpected error %v", report.Failed()[0].Error)
+	}
+	for _, res := range report.Results[2:] {
+		if !res.Skipped {
+			t.Errorf("expect %s skipped", res.InstanceId)
+		}
+	}
+
+	fake.applied = nil
+	report = client.RunFleetOperation(fake.instances, StartInstanceOperation(),
+		&FleetOptions{BatchSize: 2})
+	if len(report.Failed()) != 1 || len(report.Succeeded()) != 4 {
+		t.Errorf("expect all the batches without StopOnFailure: %s", report)
+	}
+}
+
+func TestRunFleetOperationWait(t *testing.T) {
+	fake := newFakeFleet(1)
+	client, clean := newFleetTestClient(t, fake)
+	defer clean()
+	opts := &FleetOptions{WaitForStatus: true, PollInterval: 5 * time.Millisecond,
+		LeaveTimeout: 50 * time.Millisecond, WaitTimeout: time.Second}
+
+	// the instance is still running right after the reboot is accepted
+	fake.statuses["i-0"] = []api.InstanceStatus{api.InstanceStatusRunning, api.InstanceStatusRunning,
+		api.InstanceStatusStarting, api.InstanceStatusRunning}
+	res := client.RunFleetOperation(fake.instances, RebootInstanceOperation(false), opts).Results[0]
+	if res.Error != nil || res.Status != api.InstanceStatusRunning || fake.polls["i-0"] != 4 {
+		t.Errorf("unexpected result %+v, polls %d", res, fake.polls["i-0"])
+	}
+
+	// the reboot finishes before the first poll
+	fake.statuses["i-0"] = []api.InstanceStatus{api.InstanceStatusRunning}
+	res = client.RunFleetOperation(fake.instances, RebootInstanceOperation(false), opts).Results[0]
+	if res.Error != nil || res.Status != api.InstanceStatusRunning {
+		t.Errorf("expect the quick reboot done after the leave timeout, got %+v", res)
+	}
+
+	// the resized instance returns to the status before the operation
+	fake.polls["i-0"] = 0
+	fake.statuses["i-0"] = []api.InstanceStatus{api.InstanceStatusStopped, api.InstanceStatusStopped,
+		api.InstanceStatusScaling, api.InstanceStatusStopped}
+	operation := ResizeInstanceBySpecOperation(&api.ResizeInstanceArgs{CpuCount: 2})
+	res = client.RunFleetOperation(fake.instances, operation, opts).Results[0]
+	if res.Error != nil || res.Status != api.InstanceStatusStopped || fake.polls["i-0"] != 4 {
+		t.Errorf("expect the resized instance stopped, got %+v, polls %d", res, fake.polls["i-0"])
+	}
+
+	fake.statuses["i-0"] = []api.InstanceStatus{api.InstanceStatusStopping, api.InstanceStatusError}
+	res = client.RunFleetOperation(fake.instances, StopInstanceOperation(false), opts).Results[0]
+	if res.Error == nil || res.Status != api.InstanceStatusError {
+		t.Errorf("expect error status, got %+v", res)
+	}
+
+	fake.statuses["i-0"] = []api.InstanceStatus{api.InstanceStatusStarting}
+	res = client.RunFleetOperation(fake.instances, StartInstanceOperation(),
+		&FleetOptions{WaitForStatus: true, PollInterval: 5 * time.Millisecond,
+			WaitTimeout: 30 * time.Millisecond}).Results[0]
+	if res.Error == nil || res.Status != api.InstanceStatusStarting {
+		t.Errorf("expect timeout, got %+v", res)
+	}
+}
+
+func TestFleetSelectorMatch(t *testing.T) {
+	instance := &api.InstanceModelV3{
+		InstanceId:   "i-1",
+		InstanceName: "web-01",
+		VpcId:        "vpc-1",
+		Status:       api.InstanceStatusRunning,
+		Tags:         []model.TagModel{{TagKey: "env", TagValue: "prod"}, {TagKey: "role", TagValue: "web"}},
+	}
+	cases := []struct {
+		selector FleetSelector
+		expected bool
+	}{
+		{FleetSelector{}, true},
+		{FleetSelector{VpcId: "vpc-1", NamePattern: "web-*"}, true},
+		{FleetSelector{VpcId: "vpc-2"}, false},
+		{FleetSelector{NamePattern: "db-*"}, false},
+		{FleetSelector{Status: api.InstanceStatusStopped}, false},
+		{FleetSelector{InstanceIds: []string{"i-2", "i-1"}}, true},
+		{FleetSelector{InstanceIds: []string{"i-2"}}, false},
+		{FleetSelector{Tags: []model.TagModel{{TagKey: "env", TagValue: "prod"}, {TagKey: "role"}}}, true},
+		{FleetSelector{Tags: []model.TagModel{{TagKey: "env", TagValue: "test"}}}, false},
+		{FleetSelector{Tags: []model.TagModel{{TagKey: "owner"}}}, false},
+	}
+	for i, c := range cases {
+		matched, err := c.selector.match(instance)
+		if err != nil || matched != c.expected {
+			t.Errorf("case %d: expect %v, got %v %v", i, c.expected, matched, err)
+		}
+	}
+	if _, err := (&FleetSelector{NamePattern: "[web"}).match(instance); err == nil {
+		t.Errorf("expect error of invalid name pattern")
+	}
+}
+
+func TestResizeInstanceBySpecOperationClientToken(t *testing.T) {
+	tokens := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.URL.Query().Get("clientToken"))
+	}))
+	defer server.Close()
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := &api.ResizeInstanceArgs{CpuCount: 2, MemoryCapacityInGB: 4, ClientToken: "shared"}
+	operation := ResizeInstanceBySpecOperation(args)
+	for _, id := range []string{"i-1", "i-2"} {
+		if err := operation.Apply(client, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tokens) != 2 || tokens[0] == "" || tokens[0] == "shared" || tokens[0] == tokens[1] {
+		t.Errorf("expect a new client token for each instance, got %v", tokens)
+	}
+	if args.ClientToken != "shared" || !operation.RestoreStatus {
+		t.Errorf("unexpected operation %+v of args %+v", operation, args)
+	}
+}
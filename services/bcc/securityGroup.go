@@ -0,0 +1,136 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// securityGroup.go - the declarative security group reconciliation of the BCC service
+
+package bcc
+
+import (
+	"fmt"
+
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+)
+
+// FindSecurityGroup - find the live security group by id, or by name and vpc id if the id of the
+// given model is empty
+//
+// PARAMS:
+//     - desired: the security group to find
+// RETURNS:
+//     - *api.SecurityGroupModel: the live security group, nil if not found
+//     - error: nil if success otherwise the specific error
+func (c *Client) FindSecurityGroup(desired *api.SecurityGroupModel) (*api.SecurityGroupModel, error) {
+	args := &api.ListSecurityGroupArgs{VpcId: desired.VpcId}
+	var found *api.SecurityGroupModel
+	for {
+		result, err := c.ListSecurityGroup(args)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.SecurityGroups {
+			group := &result.SecurityGroups[i]
+			if len(desired.Id) != 0 {
+				if group.Id == desired.Id {
+					return group, nil
+				}
+				continue
+			}
+			if group.Name != desired.Name {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("more than one security group named %s", desired.Name)
+			}
+			found = group
+		}
+		if !result.IsTruncated || len(result.NextMarker) == 0 {
+			break
+		}
+		args.Marker = result.NextMarker
+	}
+	if found == nil && len(desired.Id) != 0 {
+		return nil, fmt.Errorf("security group %s not found", desired.Id)
+	}
+	return found, nil
+}
+
+// PlanSecurityGroup - compute the plan to reconcile the live security group with the desired one
+// without changing anything
+//
+// PARAMS:
+//     - desired: the desired security group, located by id or by name and vpc id
+// RETURNS:
+//     - *api.SecurityGroupPlan: the plan of rule additions and removals
+//     - error: nil if success otherwise the specific error
+func (c *Client) PlanSecurityGroup(desired *api.SecurityGroupModel) (*api.SecurityGroupPlan, error) {
+	if desired == nil {
+		return nil, fmt.Errorf("the desired security group can not be nil")
+	}
+	live, err := c.FindSecurityGroup(desired)
+	if err != nil {
+		return nil, err
+	}
+	return api.PlanSecurityGroup(desired, live)
+}
+
+// ApplySecurityGroupPlan - apply the plan: create the security group if needed, otherwise
+// authorize the new rules before revoking the stale ones so that the allowed traffic is never
+// interrupted when a rule is replaced
+//
+// PARAMS:
+//     - plan: the plan computed by PlanSecurityGroup
+// RETURNS:
+//     - string: the id of the security group
+//     - error: nil if success otherwise the specific error
+func (c *Client) ApplySecurityGroupPlan(plan *api.SecurityGroupPlan) (string, error) {
+	if plan.Create != nil {
+		result, err := c.CreateSecurityGroup(plan.Create)
+		if err != nil {
+			return "", err
+		}
+		plan.SecurityGroupId = result.SecurityGroupId
+		return result.SecurityGroupId, nil
+	}
+	for i := range plan.ToAdd {
+		args := &api.AuthorizeSecurityGroupArgs{Rule: &plan.ToAdd[i]}
+		if err := c.AuthorizeSecurityGroupRule(plan.SecurityGroupId, args); err != nil {
+			return plan.SecurityGroupId, err
+		}
+	}
+	for i := range plan.ToRemove {
+		args := &api.RevokeSecurityGroupArgs{Rule: &plan.ToRemove[i]}
+		if err := c.RevokeSecurityGroupRule(plan.SecurityGroupId, args); err != nil {
+			return plan.SecurityGroupId, err
+		}
+	}
+	return plan.SecurityGroupId, nil
+}
+
+// ReconcileSecurityGroup - make the live security group match the desired one idempotently
+//
+// PARAMS:
+//     - desired: the desired security group, located by id or by name and vpc id
+//     - dryRun: only compute the plan without applying it if true
+// RETURNS:
+//     - *api.SecurityGroupPlan: the plan computed, its SecurityGroupId is set after applied
+//     - error: nil if success otherwise the specific error
+func (c *Client) ReconcileSecurityGroup(desired *api.SecurityGroupModel,
+	dryRun bool) (*api.SecurityGroupPlan, error) {
+	plan, err := c.PlanSecurityGroup(desired)
+	if err != nil || dryRun || plan.IsEmpty() {
+		return plan, err
+	}
+	_, err = c.ApplySecurityGroupPlan(plan)
+	return plan, err
+}
@@ -0,0 +1,173 @@
+package bcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/bcc/api"
+)
+
+// fakeSecurityGroups serves the security group APIs with one group per page of the listing
+type fakeSecurityGroups struct {
+	lock   sync.Mutex
+	groups []api.SecurityGroupModel
+	fail   string // the port range of the rule failing to authorize
+	calls  []string
+}
+
+func (s *fakeSecurityGroups) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := r.URL.Query()
+	body, _ := ioutil.ReadAll(r.Body)
+	id := strings.TrimPrefix(r.URL.Path, "/v2/securityGroup/")
+	switch {
+	case r.Method == http.MethodGet:
+		result := &api.ListSecurityGroupResult{}
+		for i, group := range s.groups {
+			if group.VpcId != query.Get("vpcId") || group.Id <= query.Get("marker") {
+				continue
+			}
+			result.SecurityGroups = []api.SecurityGroupModel{group}
+			if i+1 < len(s.groups) {
+				result.IsTruncated, result.NextMarker = true, group.Id
+			}
+			break
+		}
+		json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPost:
+		args := &api.CreateSecurityGroupArgs{}
+		json.Unmarshal(body, args)
+		group := api.SecurityGroupModel{Id: fmt.Sprintf("g-%d", len(s.groups)+1), Name: args.Name,
+			VpcId: args.VpcId, Rules: args.Rules}
+		s.groups = append(s.groups, group)
+		s.calls = append(s.calls, "create "+group.Name)
+		json.NewEncoder(w).Encode(&api.CreateSecurityGroupResult{SecurityGroupId: group.Id})
+	case r.Method == http.MethodPut:
+		args := &api.AuthorizeSecurityGroupArgs{}
+		json.Unmarshal(body, args)
+		action := "revoke"
+		if _, ok := query["authorizeRule"]; ok {
+			action = "authorize"
+		}
+		if action == "authorize" && args.Rule.PortRange == s.fail {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"SecurityGroup.RuleQuotaExceeded","message":"too many rules"}`)
+			return
+		}
+		s.calls = append(s.calls, fmt.Sprintf("%s %s %s", action, id, args.Rule.PortRange))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newSecurityGroupTestClient(t *testing.T, s *fakeSecurityGroups) (*Client, func()) {
+	server := httptest.NewServer(s)
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func testSecurityGroupRule(portRange string) api.SecurityGroupRuleModel {
+	return api.SecurityGroupRuleModel{Direction: "ingress", Protocol: "tcp", PortRange: portRange,
+		SourceIp: "0.0.0.0/0"}
+}
+
+func TestFindSecurityGroup(t *testing.T) {
+	s := &fakeSecurityGroups{groups: []api.SecurityGroupModel{
+		{Id: "g-1", Name: "web", VpcId: "vpc-1"},
+		{Id: "g-2", Name: "db", VpcId: "vpc-1"},
+		{Id: "g-3", Name: "db", VpcId: "vpc-1"},
+	}}
+	client, clean := newSecurityGroupTestClient(t, s)
+	defer clean()
+
+	group, err := client.FindSecurityGroup(&api.SecurityGroupModel{Id: "g-2", VpcId: "vpc-1"})
+	if err != nil || group.Name != "db" {
+		t.Errorf("expect the group found on the second page, got %+v %v", group, err)
+	}
+	group, err = client.FindSecurityGroup(&api.SecurityGroupModel{Name: "cache", VpcId: "vpc-1"})
+	if err != nil || group != nil {
+		t.Errorf("expect no group named cache, got %+v %v", group, err)
+	}
+	_, err = client.FindSecurityGroup(&api.SecurityGroupModel{Name: "db", VpcId: "vpc-1"})
+	if err == nil {
+		t.Errorf("expect error of the duplicated name")
+	}
+	_, err = client.FindSecurityGroup(&api.SecurityGroupModel{Id: "g-9", VpcId: "vpc-1"})
+	if err == nil {
+		t.Errorf("expect error of the missing id")
+	}
+}
+
+func TestReconcileSecurityGroup(t *testing.T) {
+	s := &fakeSecurityGroups{}
+	client, clean := newSecurityGroupTestClient(t, s)
+	defer clean()
+
+	desired := &api.SecurityGroupModel{Name: "web", VpcId: "vpc-1",
+		Rules: []api.SecurityGroupRuleModel{testSecurityGroupRule("80"), testSecurityGroupRule("443")}}
+	plan, err := client.ReconcileSecurityGroup(desired, false)
+	if err != nil || plan.SecurityGroupId != "g-1" || len(s.calls) != 1 || s.calls[0] != "create web" {
+		t.Fatalf("expect the group created, got %+v %v, calls %v", plan, err, s.calls)
+	}
+
+	// the live group is up to date, nothing is sent
+	s.calls = nil
+	desired.Rules[0].PortRange = "80-80"
+	if plan, err := client.ReconcileSecurityGroup(desired, false); err != nil || !plan.IsEmpty() ||
+		len(s.calls) != 0 {
+		t.Errorf("expect no change, got %+v %v, calls %v", plan, err, s.calls)
+	}
+
+	desired.Rules = []api.SecurityGroupRuleModel{testSecurityGroupRule("8080"),
+		testSecurityGroupRule("443")}
+	if _, err := client.ReconcileSecurityGroup(desired, true); err != nil || len(s.calls) != 0 {
+		t.Errorf("expect nothing sent by dry run, got %v, calls %v", err, s.calls)
+	}
+	if _, err := client.ReconcileSecurityGroup(desired, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "authorize g-1 8080,revoke g-1 80"
+	if strings.Join(s.calls, ",") != expected {
+		t.Errorf("expect the new rule authorized before revoking, got %v", s.calls)
+	}
+}
+
+func TestApplySecurityGroupPlanPartialFailure(t *testing.T) {
+	s := &fakeSecurityGroups{fail: "8443"}
+	client, clean := newSecurityGroupTestClient(t, s)
+	defer clean()
+
+	plan := &api.SecurityGroupPlan{
+		SecurityGroupId: "g-1",
+		ToAdd: []api.SecurityGroupRuleModel{testSecurityGroupRule("8080"),
+			testSecurityGroupRule("8443")},
+		ToRemove: []api.SecurityGroupRuleModel{testSecurityGroupRule("80")},
+	}
+	id, err := client.ApplySecurityGroupPlan(plan)
+	realErr, ok := err.(*bce.BceServiceError)
+	if id != "g-1" || !ok || realErr.Code != "SecurityGroup.RuleQuotaExceeded" {
+		t.Fatalf("expect the service error of the second rule, got %s %v", id, err)
+	}
+	// the stale rule is kept as the replacement is not complete
+	if strings.Join(s.calls, ",") != "authorize g-1 8080" {
+		t.Errorf("expect no revocation after the failure, got %v", s.calls)
+	}
+
+	s.calls = nil
+	id, err = client.ApplySecurityGroupPlan(&api.SecurityGroupPlan{SecurityGroupId: "g-1"})
+	if err != nil || id != "g-1" || len(s.calls) != 0 {
+		t.Errorf("expect nothing sent for the empty plan, got %s %v, calls %v", id, err, s.calls)
+	}
+}
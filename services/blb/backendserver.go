@@ -46,7 +46,7 @@ func (c *Client) AddBackendServers(blbId string, args *AddBackendServersArgs) er
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getBackendServerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -71,7 +71,7 @@ func (c *Client) UpdateBackendServers(blbId string, args *UpdateBackendServersAr
 		WithMethod(http.PUT).
 		WithURL(getBackendServerUri(blbId)).
 		WithQueryParam("update", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -158,7 +158,7 @@ func (c *Client) RemoveBackendServers(blbId string, args *RemoveBackendServersAr
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getBackendServerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
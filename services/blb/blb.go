@@ -46,7 +46,7 @@ func (c *Client) CreateLoadBalancer(args *CreateLoadBalancerArgs) (*CreateLoadBa
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getBlbUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -70,7 +70,7 @@ func (c *Client) UpdateLoadBalancer(blbId string, args *UpdateLoadBalancerArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getBlbUriWithId(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -51,7 +51,7 @@ func (c *Client) CreateTCPListener(blbId string, args *CreateTCPListenerArgs) er
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getTCPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -87,7 +87,7 @@ func (c *Client) CreateUDPListener(blbId string, args *CreateUDPListenerArgs) er
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getUDPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -119,7 +119,7 @@ func (c *Client) CreateHTTPListener(blbId string, args *CreateHTTPListenerArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getHTTPListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -155,7 +155,7 @@ func (c *Client) CreateHTTPSListener(blbId string, args *CreateHTTPSListenerArgs
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getHTTPSListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -191,7 +191,7 @@ func (c *Client) CreateSSLListener(blbId string, args *CreateSSLListenerArgs) er
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getSSLListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -212,7 +212,7 @@ func (c *Client) UpdateTCPListener(blbId string, args *UpdateTCPListenerArgs) er
 		WithMethod(http.PUT).
 		WithURL(getTCPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -233,7 +233,7 @@ func (c *Client) UpdateUDPListener(blbId string, args *UpdateUDPListenerArgs) er
 		WithMethod(http.PUT).
 		WithURL(getUDPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -254,7 +254,7 @@ func (c *Client) UpdateHTTPListener(blbId string, args *UpdateHTTPListenerArgs)
 		WithMethod(http.PUT).
 		WithURL(getHTTPListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -275,7 +275,7 @@ func (c *Client) UpdateHTTPSListener(blbId string, args *UpdateHTTPSListenerArgs
 		WithMethod(http.PUT).
 		WithURL(getHTTPSListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -296,7 +296,7 @@ func (c *Client) UpdateSSLListener(blbId string, args *UpdateSSLListenerArgs) er
 		WithMethod(http.PUT).
 		WithURL(getSSLListenerUri(blbId)).
 		WithQueryParam("listenerPort", strconv.Itoa(int(args.ListenerPort))).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -518,7 +518,7 @@ func (c *Client) DeleteListeners(blbId string, args *DeleteListenersArgs) error
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getListenerUri(blbId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("batchdelete", "").
 		WithBody(args).
 		Do()
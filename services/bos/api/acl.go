@@ -0,0 +1,432 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// acl.go - the ACL grant builder and the local access evaluator
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+const (
+	ACL_GRANTEE_ALL = "*"
+
+	PERMISSION_FULL_CONTROL  = "FULL_CONTROL"
+	PERMISSION_READ          = "READ"
+	PERMISSION_WRITE         = "WRITE"
+	PERMISSION_LIST          = "LIST"
+	PERMISSION_MODIFY        = "MODIFY"
+	PERMISSION_GET_OBJECT    = "GetObject"
+	PERMISSION_PUT_OBJECT    = "PutObject"
+	PERMISSION_DELETE_OBJECT = "DeleteObject"
+	PERMISSION_LIST_OBJECTS  = "ListObjects"
+)
+
+// ACL_PERMISSION_IMPLIES defines the permissions implied by each permission of a grant
+var ACL_PERMISSION_IMPLIES = map[string][]string{
+	PERMISSION_FULL_CONTROL: {PERMISSION_FULL_CONTROL, PERMISSION_READ, PERMISSION_WRITE,
+		PERMISSION_LIST, PERMISSION_MODIFY, PERMISSION_GET_OBJECT, PERMISSION_PUT_OBJECT,
+		PERMISSION_DELETE_OBJECT, PERMISSION_LIST_OBJECTS},
+	PERMISSION_READ: {PERMISSION_READ, PERMISSION_LIST, PERMISSION_GET_OBJECT,
+		PERMISSION_LIST_OBJECTS},
+	PERMISSION_LIST: {PERMISSION_LIST, PERMISSION_LIST_OBJECTS},
+	PERMISSION_WRITE: {PERMISSION_WRITE, PERMISSION_MODIFY, PERMISSION_PUT_OBJECT,
+		PERMISSION_DELETE_OBJECT},
+	PERMISSION_MODIFY:        {PERMISSION_MODIFY, PERMISSION_PUT_OBJECT},
+	PERMISSION_GET_OBJECT:    {PERMISSION_GET_OBJECT},
+	PERMISSION_PUT_OBJECT:    {PERMISSION_PUT_OBJECT},
+	PERMISSION_DELETE_OBJECT: {PERMISSION_DELETE_OBJECT},
+	PERMISSION_LIST_OBJECTS:  {PERMISSION_LIST_OBJECTS},
+}
+
+// GrantBuilder builds a single ACL grant, the first error occurs in the chain is returned by the
+// `Build` method.
+type GrantBuilder struct {
+	grant GrantType
+	err   error
+}
+
+// NewGrant - create an empty grant builder
+func NewGrant() *GrantBuilder {
+	return &GrantBuilder{}
+}
+
+// ToUsers - grant to the given user ids
+func (b *GrantBuilder) ToUsers(ids ...string) *GrantBuilder {
+	for _, id := range ids {
+		b.grant.Grantee = append(b.grant.Grantee, GranteeType{Id: id})
+	}
+	return b
+}
+
+// ToEveryone - grant to all the users including the anonymous ones
+func (b *GrantBuilder) ToEveryone() *GrantBuilder {
+	return b.ToUsers(ACL_GRANTEE_ALL)
+}
+
+// Allow - add the permissions of the grant
+func (b *GrantBuilder) Allow(permissions ...string) *GrantBuilder {
+	for _, p := range permissions {
+		if _, ok := ACL_PERMISSION_IMPLIES[p]; !ok && b.err == nil {
+			b.err = bce.NewBceClientError("unknown acl permission: " + p)
+		}
+	}
+	b.grant.Permission = append(b.grant.Permission, permissions...)
+	return b
+}
+
+// OnBucket - apply the grant to the bucket itself and all of its objects
+func (b *GrantBuilder) OnBucket(bucket string) *GrantBuilder {
+	b.grant.Resource = append(b.grant.Resource, bucket, bucket+"/*")
+	return b
+}
+
+// OnPrefix - apply the grant to the objects with the given prefix
+func (b *GrantBuilder) OnPrefix(bucket, prefix string) *GrantBuilder {
+	b.grant.Resource = append(b.grant.Resource, bucket+"/"+prefix+"*")
+	return b
+}
+
+// OnResources - apply the grant to the raw resources, eg. `bucket/dir/*.jpg`
+func (b *GrantBuilder) OnResources(resources ...string) *GrantBuilder {
+	b.grant.Resource = append(b.grant.Resource, resources...)
+	return b
+}
+
+// ExceptResources - apply the grant to all but the given resources
+func (b *GrantBuilder) ExceptResources(resources ...string) *GrantBuilder {
+	b.grant.NotResource = append(b.grant.NotResource, resources...)
+	return b
+}
+
+// FromIps - only allow the requests from the given IPs, CIDRs or wildcards like `192.168.1.*`
+func (b *GrantBuilder) FromIps(ips ...string) *GrantBuilder {
+	for _, ip := range ips {
+		if !validAclIp(ip) && b.err == nil {
+			b.err = bce.NewBceClientError("invalid acl ip address: " + ip)
+		}
+	}
+	b.grant.Condition.IpAddress = append(b.grant.Condition.IpAddress, ips...)
+	return b
+}
+
+// WithRefererLike - only allow the requests with the referer matching the wildcard patterns
+func (b *GrantBuilder) WithRefererLike(patterns ...string) *GrantBuilder {
+	b.grant.Condition.Referer.StringLike = append(b.grant.Condition.Referer.StringLike,
+		patterns...)
+	return b
+}
+
+// WithRefererEquals - only allow the requests with exactly the given referers
+func (b *GrantBuilder) WithRefererEquals(referers ...string) *GrantBuilder {
+	b.grant.Condition.Referer.StringEquals = append(b.grant.Condition.Referer.StringEquals,
+		referers...)
+	return b
+}
+
+// Build - validate and return the built grant
+//
+// RETURNS:
+//     - *GrantType: the built grant
+//     - error: nil if the grant is valid otherwise the specific error
+func (b *GrantBuilder) Build() (*GrantType, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	grant := b.grant
+	if err := ValidateGrant(&grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// AclBuilder builds the bucket or object ACL with several grants.
+type AclBuilder struct {
+	grants []GrantType
+	err    error
+}
+
+// NewAclBuilder - create an empty ACL builder
+func NewAclBuilder() *AclBuilder {
+	return &AclBuilder{}
+}
+
+// Grant - add the grant built by the given builder
+func (b *AclBuilder) Grant(grant *GrantBuilder) *AclBuilder {
+	built, err := grant.Build()
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+	b.grants = append(b.grants, *built)
+	return b
+}
+
+// BuildBucketAcl - return the args of PutBucketAclFromStruct
+func (b *AclBuilder) BuildBucketAcl() (*PutBucketAclArgs, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.grants) == 0 {
+		return nil, bce.NewBceClientError("acl should have at least one grant")
+	}
+	return &PutBucketAclArgs{AccessControlList: append([]GrantType(nil), b.grants...)}, nil
+}
+
+// BuildObjectAcl - return the args of PutObjectAclFromStruct, the grants of an object ACL can
+// not specify the resources
+func (b *AclBuilder) BuildObjectAcl() (*PutObjectAclArgs, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.grants) == 0 {
+		return nil, bce.NewBceClientError("acl should have at least one grant")
+	}
+	for _, g := range b.grants {
+		if len(g.Resource) != 0 || len(g.NotResource) != 0 {
+			return nil, bce.NewBceClientError("object acl grant should not specify resources")
+		}
+	}
+	return &PutObjectAclArgs{AccessControlList: append([]GrantType(nil), b.grants...)}, nil
+}
+
+// ValidateGrant - check the grantees, permissions, resources and conditions of the grant
+//
+// PARAMS:
+//     - grant: the grant to be checked
+// RETURNS:
+//     - error: nil if the grant is valid otherwise the specific error
+func ValidateGrant(grant *GrantType) error {
+	if len(grant.Grantee) == 0 {
+		return bce.NewBceClientError("acl grant should have at least one grantee")
+	}
+	for _, g := range grant.Grantee {
+		if len(g.Id) == 0 {
+			return bce.NewBceClientError("acl grantee id should not be empty")
+		}
+	}
+	if len(grant.Permission) == 0 {
+		return bce.NewBceClientError("acl grant should have at least one permission")
+	}
+	for _, p := range grant.Permission {
+		if _, ok := ACL_PERMISSION_IMPLIES[p]; !ok {
+			return bce.NewBceClientError("unknown acl permission: " + p)
+		}
+	}
+	if len(grant.Resource) != 0 && len(grant.NotResource) != 0 {
+		return bce.NewBceClientError("acl grant can not have both resource and notResource")
+	}
+	for _, ip := range grant.Condition.IpAddress {
+		if !validAclIp(ip) {
+			return bce.NewBceClientError("invalid acl ip address: " + ip)
+		}
+	}
+	return nil
+}
+
+// AclAccessRequest defines the access to be evaluated against an ACL.
+type AclAccessRequest struct {
+	Principal  string // the user id, empty for the anonymous user
+	Permission string // one of the PERMISSION_* constants
+	Bucket     string
+	Object     string // the object key, empty for the access to the bucket itself
+	SourceIp   string
+	Referer    string
+}
+
+// AclDecision defines the result of the local access evaluation.
+type AclDecision struct {
+	Allowed bool
+	Reason  string
+	Grant   *GrantType // the first grant allows the access, nil if not allowed or by the owner
+}
+
+// EvaluateBucketAcl - check whether the bucket ACL allows the access locally
+//
+// PARAMS:
+//     - acl: the bucket ACL, eg. from GetBucketAcl
+//     - req: the access to be evaluated
+// RETURNS:
+//     - *AclDecision: the evaluation result
+func EvaluateBucketAcl(acl *GetBucketAclResult, req *AclAccessRequest) *AclDecision {
+	if len(req.Principal) != 0 && req.Principal == acl.Owner.Id {
+		return &AclDecision{Allowed: true, Reason: "principal is the bucket owner"}
+	}
+	return evaluateGrants(acl.AccessControlList, req, true)
+}
+
+// EvaluateObjectAcl - check whether the object ACL allows the access locally, the resources of
+// the grants are ignored since they all apply to the object
+//
+// PARAMS:
+//     - acl: the object ACL, eg. from GetObjectAcl
+//     - req: the access to be evaluated
+// RETURNS:
+//     - *AclDecision: the evaluation result
+func EvaluateObjectAcl(acl *GetObjectAclResult, req *AclAccessRequest) *AclDecision {
+	return evaluateGrants(acl.AccessControlList, req, false)
+}
+
+func evaluateGrants(grants []GrantType, req *AclAccessRequest, checkResource bool) *AclDecision {
+	if _, ok := ACL_PERMISSION_IMPLIES[req.Permission]; !ok {
+		return &AclDecision{Reason: "unknown permission " + req.Permission}
+	}
+	reasons := make([]string, 0, len(grants))
+	for i := range grants {
+		grant := &grants[i]
+		reason := ""
+		switch {
+		case !aclGranteeMatch(grant.Grantee, req.Principal):
+			reason = "grantee not matched"
+		case !aclPermissionMatch(grant.Permission, req.Permission):
+			reason = "permission not granted"
+		case checkResource && !aclResourceMatch(grant, req.Bucket, req.Object):
+			reason = "resource not matched"
+		case !aclIpMatch(grant.Condition.IpAddress, req.SourceIp):
+			reason = "source ip not allowed"
+		case !aclRefererMatch(&grant.Condition.Referer, req.Referer):
+			reason = "referer not allowed"
+		default:
+			return &AclDecision{
+				Allowed: true,
+				Reason:  fmt.Sprintf("allowed by grant %d", i),
+				Grant:   grant,
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("grant %d: %s", i, reason))
+	}
+	if len(reasons) == 0 {
+		return &AclDecision{Reason: "no grant in acl"}
+	}
+	return &AclDecision{Reason: strings.Join(reasons, "; ")}
+}
+
+func aclGranteeMatch(grantees []GranteeType, principal string) bool {
+	for _, g := range grantees {
+		if g.Id == ACL_GRANTEE_ALL || (len(principal) != 0 && g.Id == principal) {
+			return true
+		}
+	}
+	return false
+}
+
+func aclPermissionMatch(granted []string, permission string) bool {
+	for _, p := range granted {
+		for _, implied := range ACL_PERMISSION_IMPLIES[p] {
+			if implied == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func aclResourceMatch(grant *GrantType, bucket, object string) bool {
+	target := bucket
+	if len(object) != 0 {
+		target = bucket + "/" + object
+	}
+	if len(grant.NotResource) != 0 {
+		for _, res := range grant.NotResource {
+			if aclWildcardMatch(res, target) {
+				return false
+			}
+		}
+		return true
+	}
+	if len(grant.Resource) == 0 { // the grant applies to the whole bucket by default
+		return true
+	}
+	for _, res := range grant.Resource {
+		if aclWildcardMatch(res, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func aclIpMatch(allowed []string, sourceIp string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ip := net.ParseIP(sourceIp)
+	if ip == nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		if strings.Contains(pattern, "/") {
+			if _, cidr, err := net.ParseCIDR(pattern); err == nil && cidr.Contains(ip) {
+				return true
+			}
+		} else if aclWildcardMatch(pattern, sourceIp) {
+			return true
+		}
+	}
+	return false
+}
+
+func aclRefererMatch(cond *AclRefererType, referer string) bool {
+	if len(cond.StringLike) == 0 && len(cond.StringEquals) == 0 {
+		return true
+	}
+	for _, val := range cond.StringEquals {
+		if val == referer {
+			return true
+		}
+	}
+	for _, pattern := range cond.StringLike {
+		if aclWildcardMatch(pattern, referer) {
+			return true
+		}
+	}
+	return false
+}
+
+func validAclIp(ip string) bool {
+	if strings.Contains(ip, "/") {
+		_, _, err := net.ParseCIDR(ip)
+		return err == nil
+	}
+	if strings.Contains(ip, "*") {
+		return net.ParseIP(strings.Replace(ip, "*", "0", -1)) != nil
+	}
+	return net.ParseIP(ip) != nil
+}
+
+// aclWildcardMatch matches the value with the pattern in which `*` matches any characters
+func aclWildcardMatch(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx == -1 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
@@ -0,0 +1,59 @@
+package api
+
+import "testing"
+
+func TestAclBuilder(t *testing.T) {
+	acl, err := NewAclBuilder().
+		Grant(NewGrant().ToEveryone().Allow(PERMISSION_READ).OnPrefix("bucket", "public/").
+			FromIps("192.168.0.0/16", "10.0.0.*").WithRefererLike("http://*.example.com/*")).
+		Grant(NewGrant().ToUsers("user-a").Allow(PERMISSION_WRITE).OnBucket("bucket")).
+		BuildBucketAcl()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acl.AccessControlList) != 2 {
+		t.Fatalf("unexpected acl %+v", acl)
+	}
+	if _, err := NewAclBuilder().Grant(NewGrant().ToUsers("a").Allow("READ_ALL")).
+		BuildBucketAcl(); err == nil {
+		t.Errorf("expect error of unknown permission")
+	}
+	if _, err := NewAclBuilder().Grant(NewGrant().ToUsers("a").Allow(PERMISSION_READ).
+		FromIps("300.1.1.1")).BuildBucketAcl(); err == nil {
+		t.Errorf("expect error of invalid ip")
+	}
+	if _, err := NewAclBuilder().Grant(NewGrant().ToUsers("a").Allow(PERMISSION_READ).
+		OnPrefix("bucket", "")).BuildObjectAcl(); err == nil {
+		t.Errorf("expect error of resource in object acl")
+	}
+
+	result := &GetBucketAclResult{
+		AccessControlList: acl.AccessControlList,
+		Owner:             AclOwnerType{Id: "owner"},
+	}
+	cases := []struct {
+		req     AclAccessRequest
+		allowed bool
+	}{
+		{AclAccessRequest{Permission: PERMISSION_GET_OBJECT, Bucket: "bucket", Object: "public/a.jpg",
+			SourceIp: "192.168.3.4", Referer: "http://www.example.com/index.html"}, true},
+		{AclAccessRequest{Permission: PERMISSION_GET_OBJECT, Bucket: "bucket", Object: "public/a.jpg",
+			SourceIp: "10.0.0.8", Referer: "http://www.example.com/"}, true},
+		{AclAccessRequest{Permission: PERMISSION_GET_OBJECT, Bucket: "bucket", Object: "public/a.jpg",
+			SourceIp: "172.16.0.1", Referer: "http://www.example.com/"}, false},
+		{AclAccessRequest{Permission: PERMISSION_GET_OBJECT, Bucket: "bucket", Object: "public/a.jpg",
+			SourceIp: "192.168.3.4", Referer: "http://evil.com/"}, false},
+		{AclAccessRequest{Permission: PERMISSION_GET_OBJECT, Bucket: "bucket", Object: "private/a",
+			SourceIp: "192.168.3.4", Referer: "http://www.example.com/"}, false},
+		{AclAccessRequest{Principal: "user-a", Permission: PERMISSION_DELETE_OBJECT,
+			Bucket: "bucket", Object: "any"}, true},
+		{AclAccessRequest{Principal: "user-a", Permission: PERMISSION_READ, Bucket: "bucket"}, false},
+		{AclAccessRequest{Principal: "owner", Permission: PERMISSION_FULL_CONTROL, Bucket: "bucket"}, true},
+	}
+	for i, c := range cases {
+		decision := EvaluateBucketAcl(result, &c.req)
+		if decision.Allowed != c.allowed {
+			t.Errorf("case %d: expect %v but %v: %s", i, c.allowed, decision.Allowed, decision.Reason)
+		}
+	}
+}
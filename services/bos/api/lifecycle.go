@@ -0,0 +1,321 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// lifecycle.go - the lifecycle rule builder, validator and local dry-run evaluator
+
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+const (
+	LIFECYCLE_ACTION_TRANSITION             = "Transition"
+	LIFECYCLE_ACTION_DELETE_OBJECT          = "DeleteObject"
+	LIFECYCLE_ACTION_ABORT_MULTIPART_UPLOAD = "AbortMultipartUpload"
+
+	LIFECYCLE_LAST_MODIFIED = "$(lastModified)"
+)
+
+var lifecycleDaysPattern = regexp.MustCompile(`^\$\(lastModified\)\+P(\d+)D$`)
+
+// LifecycleRuleBuilder builds a typed lifecycle rule, the first error occurs in the chain is
+// returned by the `Build` method.
+type LifecycleRuleBuilder struct {
+	rule LifecycleRuleType
+	err  error
+}
+
+// NewLifecycleRule - create a builder of the enabled lifecycle rule with the given id
+func NewLifecycleRule(id string) *LifecycleRuleBuilder {
+	return &LifecycleRuleBuilder{rule: LifecycleRuleType{Id: id, Status: STATUS_ENABLED}}
+}
+
+func (b *LifecycleRuleBuilder) Enabled() *LifecycleRuleBuilder {
+	b.rule.Status = STATUS_ENABLED
+	return b
+}
+
+func (b *LifecycleRuleBuilder) Disabled() *LifecycleRuleBuilder {
+	b.rule.Status = STATUS_DISABLED
+	return b
+}
+
+// WithPrefix - apply the rule to the objects with the given prefix, empty for the whole bucket
+func (b *LifecycleRuleBuilder) WithPrefix(bucket, prefix string) *LifecycleRuleBuilder {
+	b.rule.Resource = append(b.rule.Resource, bucket+"/"+prefix+"*")
+	return b
+}
+
+// WithResource - apply the rule to the raw resource, eg. `bucket/prefix*`
+func (b *LifecycleRuleBuilder) WithResource(resource ...string) *LifecycleRuleBuilder {
+	b.rule.Resource = append(b.rule.Resource, resource...)
+	return b
+}
+
+// AfterDays - the rule takes effect the given days after the last modified time of the object
+func (b *LifecycleRuleBuilder) AfterDays(days int) *LifecycleRuleBuilder {
+	if days < 0 && b.err == nil {
+		b.err = bce.NewBceClientError("lifecycle days should not be negative")
+	}
+	b.rule.Condition.Time.DateGreaterThan = fmt.Sprintf("%s+P%dD", LIFECYCLE_LAST_MODIFIED, days)
+	return b
+}
+
+// AfterDate - the rule takes effect after the given date, it must be 00:00:00 of the UTC time
+func (b *LifecycleRuleBuilder) AfterDate(date time.Time) *LifecycleRuleBuilder {
+	b.rule.Condition.Time.DateGreaterThan = date.UTC().Format(util.ISO8601Format)
+	return b
+}
+
+// Transition - transit the matched objects to the given storage class
+func (b *LifecycleRuleBuilder) Transition(storageClass string) *LifecycleRuleBuilder {
+	b.rule.Action = LifecycleActionType{
+		Name:         LIFECYCLE_ACTION_TRANSITION,
+		StorageClass: storageClass,
+	}
+	return b
+}
+
+// DeleteObject - delete the matched objects
+func (b *LifecycleRuleBuilder) DeleteObject() *LifecycleRuleBuilder {
+	b.rule.Action = LifecycleActionType{Name: LIFECYCLE_ACTION_DELETE_OBJECT}
+	return b
+}
+
+// AbortMultipartUpload - abort the matched unfinished multipart uploads
+func (b *LifecycleRuleBuilder) AbortMultipartUpload() *LifecycleRuleBuilder {
+	b.rule.Action = LifecycleActionType{Name: LIFECYCLE_ACTION_ABORT_MULTIPART_UPLOAD}
+	return b
+}
+
+// Build - validate and return the built lifecycle rule
+//
+// RETURNS:
+//     - *LifecycleRuleType: the built rule
+//     - error: nil if the rule is valid otherwise the specific error
+func (b *LifecycleRuleBuilder) Build() (*LifecycleRuleType, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	rule := b.rule
+	rule.Resource = append([]string(nil), b.rule.Resource...)
+	if err := ValidateLifecycleRule(&rule, ""); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ValidateLifecycleRule - check the status, resources, time condition and action of the rule
+//
+// PARAMS:
+//     - rule: the lifecycle rule to be checked
+//     - bucket: the bucket which the rule belongs to, empty to skip checking the resource bucket
+// RETURNS:
+//     - error: nil if the rule is valid otherwise the specific error
+func ValidateLifecycleRule(rule *LifecycleRuleType, bucket string) error {
+	invalid := func(format string, args ...interface{}) error {
+		return bce.NewBceClientError(fmt.Sprintf("invalid lifecycle rule %q: ", rule.Id) +
+			fmt.Sprintf(format, args...))
+	}
+	if rule.Status != STATUS_ENABLED && rule.Status != STATUS_DISABLED {
+		return invalid("status should be %s or %s", STATUS_ENABLED, STATUS_DISABLED)
+	}
+	if len(rule.Resource) == 0 {
+		return invalid("resource should not be empty")
+	}
+	for _, res := range rule.Resource {
+		pos := strings.Index(res, "/")
+		if pos <= 0 {
+			return invalid("resource %q should be in the form of bucket/prefix*", res)
+		}
+		if len(bucket) != 0 && res[:pos] != bucket {
+			return invalid("resource %q does not belong to bucket %s", res, bucket)
+		}
+		if idx := strings.Index(res, "*"); idx != -1 && idx != len(res)-1 {
+			return invalid("wildcard of resource %q is only supported at the end", res)
+		}
+	}
+	if _, _, err := parseLifecycleTime(rule.Condition.Time.DateGreaterThan); err != nil {
+		return invalid("%v", err)
+	}
+	switch rule.Action.Name {
+	case LIFECYCLE_ACTION_TRANSITION:
+		level, ok := VALID_STORAGE_CLASS_TYPE[rule.Action.StorageClass]
+		if !ok || level == VALID_STORAGE_CLASS_TYPE[STORAGE_CLASS_STANDARD] {
+			return invalid("transition storage class should be one of %s, %s and %s",
+				STORAGE_CLASS_STANDARD_IA, STORAGE_CLASS_COLD, STORAGE_CLASS_ARCHIVE)
+		}
+	case LIFECYCLE_ACTION_DELETE_OBJECT, LIFECYCLE_ACTION_ABORT_MULTIPART_UPLOAD:
+		if len(rule.Action.StorageClass) != 0 {
+			return invalid("storage class is only supported by the %s action",
+				LIFECYCLE_ACTION_TRANSITION)
+		}
+	default:
+		return invalid("unknown action %q", rule.Action.Name)
+	}
+	return nil
+}
+
+// ValidateLifecycleRules - check every rule and the uniqueness of the rule ids
+//
+// PARAMS:
+//     - args: the lifecycle rules to be put
+//     - bucket: the bucket which the rules belong to, empty to skip checking the resource bucket
+// RETURNS:
+//     - error: nil if the rules are valid otherwise the specific error
+func ValidateLifecycleRules(args *PutBucketLifecycleArgs, bucket string) error {
+	if args == nil || len(args.Rule) == 0 {
+		return bce.NewBceClientError("lifecycle rules should not be empty")
+	}
+	ids := make(map[string]struct{}, len(args.Rule))
+	for i := range args.Rule {
+		rule := &args.Rule[i]
+		if len(rule.Id) != 0 {
+			if _, ok := ids[rule.Id]; ok {
+				return bce.NewBceClientError("duplicate lifecycle rule id: " + rule.Id)
+			}
+			ids[rule.Id] = struct{}{}
+		}
+		if err := ValidateLifecycleRule(rule, bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LifecycleRuleReport defines the dry-run result of a single lifecycle rule.
+type LifecycleRuleReport struct {
+	Rule LifecycleRuleType
+
+	// Matched is the number of objects matched by the resources of the rule, regardless of the
+	// time condition. A rule with zero matched objects silently matches nothing.
+	Matched int
+
+	// Due is the objects which the rule would transit or delete at the evaluation time.
+	Due []ObjectSummaryType
+}
+
+// EvaluateLifecycle - evaluate the lifecycle rules against the listed objects locally
+//
+// The disabled rules and the AbortMultipartUpload rules which do not apply to objects are
+// reported with no due objects. A transition is not due if the object is already in the target
+// or a colder storage class.
+//
+// PARAMS:
+//     - bucket: the bucket name of the objects
+//     - rules: the lifecycle rules, eg. from GetBucketLifecycle or the builder
+//     - objects: the objects, eg. the contents of all the pages of ListObjects
+//     - now: the evaluation time, usually time.Now()
+// RETURNS:
+//     - []LifecycleRuleReport: the report of every rule in the given order
+//     - error: nil if ok otherwise the specific error
+func EvaluateLifecycle(bucket string, rules []LifecycleRuleType, objects []ObjectSummaryType,
+	now time.Time) ([]LifecycleRuleReport, error) {
+	result := make([]LifecycleRuleReport, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if err := ValidateLifecycleRule(&rule, bucket); err != nil {
+			return nil, err
+		}
+		days, date, _ := parseLifecycleTime(rule.Condition.Time.DateGreaterThan)
+		report := LifecycleRuleReport{Rule: rule}
+		for _, obj := range objects {
+			if !lifecycleResourceMatch(rule.Resource, bucket, obj.Key) {
+				continue
+			}
+			report.Matched++
+			if rule.Status != STATUS_ENABLED ||
+				rule.Action.Name == LIFECYCLE_ACTION_ABORT_MULTIPART_UPLOAD {
+				continue
+			}
+			if days >= 0 {
+				lastModified, err := parseLastModified(obj.LastModified)
+				if err != nil {
+					return nil, err
+				}
+				if !now.After(lastModified.AddDate(0, 0, days)) {
+					continue
+				}
+			} else if !now.After(date) {
+				continue
+			}
+			if rule.Action.Name == LIFECYCLE_ACTION_TRANSITION {
+				current := obj.StorageClass
+				if len(current) == 0 {
+					current = STORAGE_CLASS_STANDARD
+				}
+				if VALID_STORAGE_CLASS_TYPE[current] >=
+					VALID_STORAGE_CLASS_TYPE[rule.Action.StorageClass] {
+					continue
+				}
+			}
+			report.Due = append(report.Due, obj)
+		}
+		result = append(result, report)
+	}
+	return result, nil
+}
+
+// parseLifecycleTime returns the relative days, or -1 with the absolute date
+func parseLifecycleTime(cond string) (int, time.Time, error) {
+	if m := lifecycleDaysPattern.FindStringSubmatch(cond); m != nil {
+		days, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid days of dateGreaterThan %q", cond)
+		}
+		return days, time.Time{}, nil
+	}
+	date, err := util.ParseISO8601Date(cond)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("dateGreaterThan %q should be %s+P<n>D or "+
+			"yyyy-mm-ddT00:00:00Z", cond, LIFECYCLE_LAST_MODIFIED)
+	}
+	if date.Hour() != 0 || date.Minute() != 0 || date.Second() != 0 {
+		return 0, time.Time{}, fmt.Errorf("dateGreaterThan %q should be 00:00:00 of UTC", cond)
+	}
+	return -1, date, nil
+}
+
+func parseLastModified(val string) (time.Time, error) {
+	if t, err := util.ParseISO8601Date(val); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return t, bce.NewBceClientError("invalid lastModified of object: " + val)
+	}
+	return t, nil
+}
+
+func lifecycleResourceMatch(resources []string, bucket, key string) bool {
+	target := bucket + "/" + key
+	for _, res := range resources {
+		if strings.HasSuffix(res, "*") {
+			if strings.HasPrefix(target, res[:len(res)-1]) {
+				return true
+			}
+		} else if target == res {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleRuleBuilder(t *testing.T) {
+	rule, err := NewLifecycleRule("to-cold").WithPrefix("bucket", "logs/").
+		AfterDays(30).Transition(STORAGE_CLASS_COLD).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Resource[0] != "bucket/logs/*" ||
+		rule.Condition.Time.DateGreaterThan != "$(lastModified)+P30D" {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	invalid := []*LifecycleRuleBuilder{
+		NewLifecycleRule("no-resource").AfterDays(1).DeleteObject(),
+		NewLifecycleRule("standard").WithPrefix("b", "").AfterDays(1).Transition(STORAGE_CLASS_STANDARD),
+		NewLifecycleRule("not-midnight").WithPrefix("b", "").
+			AfterDate(time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC)).DeleteObject(),
+		NewLifecycleRule("no-action").WithPrefix("b", "").AfterDays(1),
+		NewLifecycleRule("negative").WithPrefix("b", "").AfterDays(-1).DeleteObject(),
+		NewLifecycleRule("wildcard").WithResource("b/*.log").AfterDays(1).DeleteObject(),
+	}
+	for _, b := range invalid {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("expect error of rule %s", b.rule.Id)
+		}
+	}
+
+	dup := &PutBucketLifecycleArgs{Rule: []LifecycleRuleType{*rule, *rule}}
+	if err := ValidateLifecycleRules(dup, "bucket"); err == nil {
+		t.Errorf("expect error of duplicate rule id")
+	}
+	if err := ValidateLifecycleRules(&PutBucketLifecycleArgs{Rule: dup.Rule[:1]}, "other"); err == nil {
+		t.Errorf("expect error of resource in other bucket")
+	}
+}
+
+func TestEvaluateLifecycle(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	toCold, _ := NewLifecycleRule("to-cold").WithPrefix("bucket", "logs/").
+		AfterDays(30).Transition(STORAGE_CLASS_COLD).Build()
+	expire, _ := NewLifecycleRule("expire").WithPrefix("bucket", "tmp/").
+		AfterDate(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)).DeleteObject().Build()
+	nothing, _ := NewLifecycleRule("nothing").WithPrefix("bucket", "missing/").
+		AfterDays(1).DeleteObject().Build()
+	objects := []ObjectSummaryType{
+		{Key: "logs/old", LastModified: "2020-01-01T00:00:00Z"},
+		{Key: "logs/new", LastModified: "2020-02-20T00:00:00Z"},
+		{Key: "logs/archived", LastModified: "2019-01-01T00:00:00Z", StorageClass: STORAGE_CLASS_ARCHIVE},
+		{Key: "tmp/a", LastModified: "2020-02-28T00:00:00Z"},
+	}
+
+	reports, err := EvaluateLifecycle("bucket",
+		[]LifecycleRuleType{*toCold, *expire, *nothing}, objects, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports[0].Matched != 3 || len(reports[0].Due) != 1 || reports[0].Due[0].Key != "logs/old" {
+		t.Errorf("unexpected transition report %+v", reports[0])
+	}
+	if reports[1].Matched != 1 || len(reports[1].Due) != 1 || reports[1].Due[0].Key != "tmp/a" {
+		t.Errorf("unexpected expiration report %+v", reports[1])
+	}
+	if reports[2].Matched != 0 || len(reports[2].Due) != 0 {
+		t.Errorf("unexpected report %+v", reports[2])
+	}
+}
@@ -510,6 +510,7 @@ func AppendObject(cli bce.Client, bucket, object string, content *bce.Body,
 	req := &bce.BceRequest{}
 	req.SetUri(getObjectUri(bucket, object))
 	req.SetMethod(http.POST)
+	req.SetIdempotent(false)
 	req.SetParam("append", "")
 	if content == nil {
 		return nil, bce.NewBceClientError("AppendObject body should not be emtpy")
@@ -383,6 +383,29 @@ func (c *Client) PutBucketLifecycleFromString(bucket, lifecycle string) error {
 	return api.PutBucketLifecycle(c, bucket, body)
 }
 
+// PutBucketLifecycleFromStruct - validate and set the lifecycle rules of the given bucket
+//
+// PARAMS:
+//     - bucket: the bucket name
+//     - args: the lifecycle rules, which can be built by `api.NewLifecycleRule`
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func (c *Client) PutBucketLifecycleFromStruct(bucket string,
+	args *api.PutBucketLifecycleArgs) error {
+	if err := api.ValidateLifecycleRules(args, bucket); err != nil {
+		return err
+	}
+	jsonBytes, jsonErr := json.Marshal(args)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	body, err := bce.NewBodyFromBytes(jsonBytes)
+	if err != nil {
+		return err
+	}
+	return api.PutBucketLifecycle(c, bucket, body)
+}
+
 // GetBucketLifecycle - get the lifecycle rule of the given bucket
 //
 // PARAMS:
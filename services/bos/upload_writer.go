@@ -0,0 +1,280 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// upload_writer.go - define the streaming multipart upload writer for data of unknown length
+
+package bos
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/bos/api"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+// UploadWriterArgs defines the optional arguments of the streaming upload writer.
+type UploadWriterArgs struct {
+	api.InitiateMultipartUploadArgs
+	ContentType string
+	UserMeta    map[string]string
+	PartSize    int64 // default is the `MultipartSize` of the client, aligned to 1MB
+	MaxParallel int64 // default is the `MaxParallel` of the client
+}
+
+// UploadWriter is an io.WriteCloser which uploads the written data to BOS by the multipart upload
+// interface. The data is buffered into part-sized chunks which are uploaded concurrently, so at
+// most MaxParallel+1 parts are held in memory. The multipart upload is completed on `Close` and
+// aborted if any error occurs or `Abort` is called. Since the total size is unknown, the object
+// size is limited to PartSize * MAX_PART_NUMBER. It is not safe for concurrent use.
+type UploadWriter struct {
+	client     *Client
+	bucket     string
+	object     string
+	args       UploadWriterArgs
+	partSize   int64
+	uploadId   string
+	buf        []byte
+	nextPart   int
+	closed     bool
+	workerPool chan struct{}
+	wg         sync.WaitGroup
+	lock       sync.Mutex
+	parts      []api.UploadInfoType
+	err        error
+	result     *api.CompleteMultipartUploadResult
+}
+
+// NewUploadWriter - create a writer to upload the object from a stream of unknown length
+//
+// PARAMS:
+//     - bucket: the destination bucket name
+//     - object: the destination object name
+//     - args: the optional arguments
+// RETURNS:
+//     - *UploadWriter: the writer to write the object content, it must be closed to finish
+//     - error: nil if ok otherwise the specific error
+func (c *Client) NewUploadWriter(bucket, object string,
+	args *UploadWriterArgs) (*UploadWriter, error) {
+	w := &UploadWriter{client: c, bucket: bucket, object: object, nextPart: 1}
+	if args != nil {
+		w.args = *args
+	}
+	w.partSize = w.args.PartSize
+	if w.partSize <= 0 {
+		w.partSize = c.MultipartSize
+	}
+	if w.partSize < MIN_MULTIPART_SIZE {
+		return nil, bce.NewBceClientError("multipart size should not be less than 100KB")
+	}
+	if w.partSize > MAX_SINGLE_PART_SIZE {
+		return nil, bce.NewBceClientError("multipart size should not be greater than 5GB")
+	}
+	w.partSize = (w.partSize + MULTIPART_ALIGN - 1) / MULTIPART_ALIGN * MULTIPART_ALIGN
+	parallel := w.args.MaxParallel
+	if parallel <= 0 {
+		parallel = c.MaxParallel
+	}
+	if parallel <= 0 {
+		parallel = DEFAULT_MAX_PARALLEL
+	}
+	w.workerPool = make(chan struct{}, parallel)
+	return w, nil
+}
+
+// Write - buffer the data and upload every full part in the background
+//
+// PARAMS:
+//     - p: the data to be written
+// RETURNS:
+//     - int: the number of bytes written from p
+//     - error: the first error of the upload if any occurs
+func (w *UploadWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, bce.NewBceClientError("write to a closed upload writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		if err := w.getError(); err != nil {
+			return written, err
+		}
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.partSize)
+		}
+		n := int(w.partSize) - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if int64(len(w.buf)) == w.partSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close - upload the remaining data and complete the multipart upload, the upload is aborted if
+// any error occurs. If the data is less than one part, it is uploaded by a single put request.
+//
+// RETURNS:
+//     - error: nil if ok otherwise the specific error
+func (w *UploadWriter) Close() error {
+	if w.closed {
+		return w.getError()
+	}
+	w.closed = true
+	if len(w.uploadId) == 0 && w.getError() == nil {
+		return w.putObject()
+	}
+	if len(w.buf) > 0 && w.getError() == nil {
+		w.flush()
+	}
+	w.wg.Wait()
+	if err := w.getError(); err != nil {
+		w.abort()
+		return err
+	}
+
+	sort.Slice(w.parts, func(i, j int) bool { return w.parts[i].PartNumber < w.parts[j].PartNumber })
+	completeArgs := &api.CompleteMultipartUploadArgs{Parts: w.parts, UserMeta: w.args.UserMeta}
+	result, err := w.client.CompleteMultipartUploadFromStruct(w.bucket, w.object, w.uploadId,
+		completeArgs)
+	if err != nil {
+		w.setError(err)
+		w.abort()
+		return err
+	}
+	w.result = result
+	return nil
+}
+
+// Abort - cancel the upload, the uploaded parts are discarded and the writer can not be used any
+// more
+//
+// RETURNS:
+//     - error: nil if ok otherwise the specific error
+func (w *UploadWriter) Abort() error {
+	if w.closed {
+		return bce.NewBceClientError("abort a closed upload writer")
+	}
+	w.closed = true
+	w.setError(bce.NewBceClientError("upload writer is aborted"))
+	w.wg.Wait()
+	return w.abort()
+}
+
+// Result - get the result of the finished upload, it is nil before `Close` succeeds
+func (w *UploadWriter) Result() *api.CompleteMultipartUploadResult {
+	return w.result
+}
+
+// UploadId - get the multipart upload id, it is empty before the first part is uploaded
+func (w *UploadWriter) UploadId() string {
+	return w.uploadId
+}
+
+func (w *UploadWriter) flush() error {
+	if len(w.uploadId) == 0 {
+		resp, err := w.client.InitiateMultipartUpload(w.bucket, w.object, w.args.ContentType,
+			&w.args.InitiateMultipartUploadArgs)
+		if err != nil {
+			w.setError(err)
+			return err
+		}
+		w.uploadId = resp.UploadId
+	}
+	if w.nextPart > MAX_PART_NUMBER {
+		err := bce.NewBceClientError("object exceeds the max part number, use a larger part size")
+		w.setError(err)
+		return err
+	}
+
+	// Wait until get a worker to upload, the buffer is owned by the worker since then
+	w.workerPool <- struct{}{}
+	partNumber, content := w.nextPart, w.buf
+	w.nextPart++
+	w.buf = nil
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.workerPool
+			w.wg.Done()
+		}()
+		if w.getError() != nil {
+			return
+		}
+		etag, err := w.client.UploadPartFromBytes(w.bucket, w.object, w.uploadId, partNumber,
+			content, nil)
+		if err != nil {
+			log.Errorf("upload part %d failed: %v", partNumber, err)
+			w.setError(err)
+			return
+		}
+		log.Debugf("upload part %d success, etag: %s", partNumber, etag)
+		w.lock.Lock()
+		w.parts = append(w.parts, api.UploadInfoType{PartNumber: partNumber, ETag: etag})
+		w.lock.Unlock()
+	}()
+	return nil
+}
+
+func (w *UploadWriter) putObject() error {
+	args := &api.PutObjectArgs{
+		CacheControl:       w.args.CacheControl,
+		ContentDisposition: w.args.ContentDisposition,
+		ContentType:        w.args.ContentType,
+		Expires:            w.args.Expires,
+		UserMeta:           w.args.UserMeta,
+		StorageClass:       w.args.StorageClass,
+	}
+	content := w.buf
+	if content == nil {
+		content = []byte{}
+	}
+	etag, err := w.client.PutObjectFromBytes(w.bucket, w.object, content, args)
+	if err != nil {
+		w.setError(err)
+		return err
+	}
+	w.buf = nil
+	w.result = &api.CompleteMultipartUploadResult{Bucket: w.bucket, Key: w.object, ETag: etag}
+	return nil
+}
+
+func (w *UploadWriter) abort() error {
+	w.buf = nil
+	if len(w.uploadId) == 0 {
+		return nil
+	}
+	return w.client.AbortMultipartUpload(w.bucket, w.object, w.uploadId)
+}
+
+func (w *UploadWriter) getError() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+func (w *UploadWriter) setError(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
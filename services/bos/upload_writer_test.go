@@ -0,0 +1,137 @@
+package bos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/bos/api"
+)
+
+type fakeMultipartServer struct {
+	lock     sync.Mutex
+	parts    map[int][]byte
+	object   []byte
+	aborted  bool
+	complete *api.CompleteMultipartUploadArgs
+}
+
+func (s *fakeMultipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := r.URL.Query()
+	_, initiate := query["uploads"]
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && initiate:
+		json.NewEncoder(w).Encode(&api.InitiateMultipartUploadResult{UploadId: "upload-id"})
+	case r.Method == http.MethodPut && query.Get("partNumber") != "":
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if partNumber == 3 && s.parts[-1] != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"code":"AccessDenied","message":"denied"}`)
+			return
+		}
+		s.parts[partNumber] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"etag-%d\"", partNumber))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		s.complete = &api.CompleteMultipartUploadArgs{}
+		json.Unmarshal(body, s.complete)
+		json.NewEncoder(w).Encode(&api.CompleteMultipartUploadResult{ETag: "final"})
+	case r.Method == http.MethodDelete:
+		s.aborted = true
+	case r.Method == http.MethodPut:
+		s.object = body
+		w.Header().Set("ETag", "\"single\"")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newFakeClient(t *testing.T, handler http.Handler) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	client, err := NewClient("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func TestUploadWriterMultipart(t *testing.T) {
+	server := &fakeMultipartServer{parts: map[int][]byte{}}
+	client, clean := newFakeClient(t, server)
+	defer clean()
+
+	w, err := client.NewUploadWriter("bucket", "object",
+		&UploadWriterArgs{PartSize: MULTIPART_ALIGN, MaxParallel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), MULTIPART_ALIGN/4)
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Result().ETag != "final" || len(server.complete.Parts) != 3 {
+		t.Fatalf("unexpected result %v, %v", w.Result(), server.complete)
+	}
+	uploaded := []byte{}
+	for i, part := range server.complete.Parts {
+		if part.PartNumber != i+1 || part.ETag != fmt.Sprintf("etag-%d", i+1) {
+			t.Errorf("unexpected part %v", part)
+		}
+		uploaded = append(uploaded, server.parts[part.PartNumber]...)
+	}
+	if !bytes.Equal(uploaded, data) {
+		t.Errorf("uploaded content mismatch")
+	}
+}
+
+func TestUploadWriterSmallObject(t *testing.T) {
+	server := &fakeMultipartServer{parts: map[int][]byte{}}
+	client, clean := newFakeClient(t, server)
+	defer clean()
+
+	w, err := client.NewUploadWriter("bucket", "object", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(server.object) != "hello" || w.Result().ETag != "single" || w.UploadId() != "" {
+		t.Errorf("unexpected result %v", w.Result())
+	}
+}
+
+func TestUploadWriterAbortOnError(t *testing.T) {
+	server := &fakeMultipartServer{parts: map[int][]byte{-1: {}}}
+	client, clean := newFakeClient(t, server)
+	defer clean()
+
+	w, err := client.NewUploadWriter("bucket", "object",
+		&UploadWriterArgs{PartSize: MULTIPART_ALIGN, MaxParallel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 4*MULTIPART_ALIGN)
+	io.Copy(w, bytes.NewReader(data))
+	if err := w.Close(); err == nil {
+		t.Fatal("expect error of the failed part")
+	}
+	if !server.aborted || server.complete != nil {
+		t.Errorf("expect the upload to be aborted")
+	}
+}
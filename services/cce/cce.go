@@ -38,6 +38,7 @@ func (c *Client) CreateCluster(args *CreateClusterArgs) (*CreateClusterResult, e
 	result := &CreateClusterResult{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getClusterUri()).
 		WithBody(args).
 		WithResult(result).
@@ -28,6 +28,7 @@ func (c *Client) CreateCluster(args *CreateClusterArgs) (*CreateClusterResponse,
 	result := &CreateClusterResponse{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getClusterURI()).
 		WithBody(args.CreateClusterRequest).
 		WithResult(result).
@@ -112,6 +113,7 @@ func (c *Client) CreateInstances(args *CreateInstancesArgs) (*CreateInstancesRes
 	result := &CreateInstancesResponse{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getClusterInstanceListURI(args.ClusterID)).
 		WithBody(args.Instances).
 		WithResult(result).
@@ -299,6 +301,7 @@ func (c *Client) CreateInstanceGroup(args *CreateInstanceGroupArgs) (*CreateInst
 	result := &CreateInstanceGroupResponse{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getInstanceGroupURI(args.ClusterID)).
 		WithBody(args.Request).
 		WithResult(result).
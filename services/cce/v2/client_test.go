@@ -49,8 +49,9 @@ func setup() {
 	fmt.Println(conf)
 	fp, err := os.Open(conf)
 	if err != nil {
-		log.Fatal("config json file of ak/sk not given:", conf)
-		os.Exit(1)
+		// the offline tests still run, the live tests are skipped without the client
+		fmt.Println("config json file of ak/sk not given, skip the live tests:", conf)
+		return
 	}
 	decoder := json.NewDecoder(fp)
 	confObj := &Conf{}
@@ -66,6 +67,13 @@ func setup() {
 	log.Info("Setup Complete")
 }
 
+// requireLiveClient skips the live test if the client is not set up by the config json file
+func requireLiveClient(t *testing.T) {
+	if CCE_CLIENT == nil {
+		t.Skip("config json file of ak/sk not given")
+	}
+}
+
 //Try to clean environment
 func teardown() {
 	if CCE_INSTANCE_ID != "" && CCE_CLIENT != nil {
@@ -139,6 +147,7 @@ func ExpectEqual(alert func(format string, args ...interface{}),
 }
 
 func TestClient_CheckClusterIPCIDR(t *testing.T) {
+	requireLiveClient(t)
 	args := &CheckClusterIPCIDRArgs{
 		VPCID:         VPC_TEST_ID,
 		VPCCIDR:       "192.168.0.0/16",
@@ -154,6 +163,7 @@ func TestClient_CheckClusterIPCIDR(t *testing.T) {
 }
 
 func TestClient_CheckContainerNetworkCIDR(t *testing.T) {
+	requireLiveClient(t)
 	args := &CheckContainerNetworkCIDRArgs{
 		VPCID:          VPC_TEST_ID,
 		VPCCIDR:        "192.168.0.0/16",
@@ -170,6 +180,7 @@ func TestClient_CheckContainerNetworkCIDR(t *testing.T) {
 }
 
 func TestClient_RecommendClusterIPCIDR(t *testing.T) {
+	requireLiveClient(t)
 	args := &RecommendClusterIPCIDRArgs{
 		ClusterMaxServiceNum: 8,
 		ContainerCIDR:        "172.28.0.0/16",
@@ -186,6 +197,7 @@ func TestClient_RecommendClusterIPCIDR(t *testing.T) {
 }
 
 func TestClient_RecommendContainerCIDR(t *testing.T) {
+	requireLiveClient(t)
 	args := &RecommendContainerCIDRArgs{
 		ClusterMaxNodeNum: 2,
 		IPVersion:         "ipv4",
@@ -207,6 +219,7 @@ func TestClient_RecommendContainerCIDR(t *testing.T) {
 }
 
 func TestClient_CreateCluster(t *testing.T) {
+	requireLiveClient(t)
 	args := &CreateClusterArgs{
 		CreateClusterRequest: &CreateClusterRequest{
 			ClusterSpec: &types.ClusterSpec{
@@ -253,6 +266,7 @@ func TestClient_CreateCluster(t *testing.T) {
 }
 
 func TestClient_GetCluster(t *testing.T) {
+	requireLiveClient(t)
 	resp, err := CCE_CLIENT.GetCluster(CCE_CLUSTER_ID)
 
 	ExpectEqual(t.Errorf, nil, err)
@@ -262,6 +276,7 @@ func TestClient_GetCluster(t *testing.T) {
 }
 
 func TestClient_ListClusters(t *testing.T) {
+	requireLiveClient(t)
 	args := &ListClustersArgs{
 		KeywordType: "clusterName",
 		Keyword:     "",
@@ -279,6 +294,7 @@ func TestClient_ListClusters(t *testing.T) {
 }
 
 func TestClient_CreateInstanceGroup(t *testing.T) {
+	requireLiveClient(t)
 	args := &CreateInstanceGroupArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		Request: &CreateInstanceGroupRequest{
@@ -336,6 +352,7 @@ func TestClient_CreateInstanceGroup(t *testing.T) {
 }
 
 func TestClient_ListInstanceGroups(t *testing.T) {
+	requireLiveClient(t)
 	args := &ListInstanceGroupsArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		ListOption: &InstanceGroupListOption{
@@ -352,6 +369,7 @@ func TestClient_ListInstanceGroups(t *testing.T) {
 }
 
 func TestClient_ListInstancesByInstanceGroupID(t *testing.T) {
+	requireLiveClient(t)
 	args := &ListInstanceByInstanceGroupIDArgs{
 		ClusterID:       CCE_CLUSTER_ID,
 		InstanceGroupID: CCE_INSTANCE_GROUP_ID,
@@ -368,6 +386,7 @@ func TestClient_ListInstancesByInstanceGroupID(t *testing.T) {
 }
 
 func TestClient_GetInstanceGroup(t *testing.T) {
+	requireLiveClient(t)
 	args := &GetInstanceGroupArgs{
 		ClusterID:       CCE_CLUSTER_ID,
 		InstanceGroupID: CCE_INSTANCE_GROUP_ID,
@@ -382,6 +401,7 @@ func TestClient_GetInstanceGroup(t *testing.T) {
 }
 
 func TestClient_UpdateInstanceGroupReplicas(t *testing.T) {
+	requireLiveClient(t)
 	args := &UpdateInstanceGroupReplicasArgs{
 		ClusterID:       CCE_CLUSTER_ID,
 		InstanceGroupID: CCE_INSTANCE_GROUP_ID,
@@ -407,6 +427,7 @@ func TestClient_UpdateInstanceGroupReplicas(t *testing.T) {
 }
 
 func TestClient_CreateAutoscaler(t *testing.T) {
+	requireLiveClient(t)
 	args := &CreateAutoscalerArgs{
 		ClusterID: CCE_CLUSTER_ID,
 	}
@@ -420,6 +441,7 @@ func TestClient_CreateAutoscaler(t *testing.T) {
 }
 
 func TestClient_GetAutoscaler(t *testing.T) {
+	requireLiveClient(t)
 	args := &GetAutoscalerArgs{
 		ClusterID: CCE_CLUSTER_ID,
 	}
@@ -433,6 +455,7 @@ func TestClient_GetAutoscaler(t *testing.T) {
 }
 
 func TestClient_UpdateAutoscaler(t *testing.T) {
+	requireLiveClient(t)
 	args := &UpdateAutoscalerArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		AutoscalerConfig: ClusterAutoscalerConfig{
@@ -451,6 +474,7 @@ func TestClient_UpdateAutoscaler(t *testing.T) {
 }
 
 func TestClient_UpdateInstanceGroupClusterAutoscalerSpec(t *testing.T) {
+	requireLiveClient(t)
 	args := &UpdateInstanceGroupClusterAutoscalerSpecArgs{
 		ClusterID:       CCE_CLUSTER_ID,
 		InstanceGroupID: CCE_INSTANCE_GROUP_ID,
@@ -471,6 +495,7 @@ func TestClient_UpdateInstanceGroupClusterAutoscalerSpec(t *testing.T) {
 }
 
 func TestClient_GetKubeConfig(t *testing.T) {
+	requireLiveClient(t)
 	args := &GetKubeConfigArgs{
 		ClusterID:      CCE_CLUSTER_ID,
 		KubeConfigType: KubeConfigTypeVPC,
@@ -485,6 +510,7 @@ func TestClient_GetKubeConfig(t *testing.T) {
 }
 
 func TestClient_DeleteInstanceGroup(t *testing.T) {
+	requireLiveClient(t)
 	args := &DeleteInstanceGroupArgs{
 		ClusterID:       CCE_CLUSTER_ID,
 		InstanceGroupID: CCE_INSTANCE_GROUP_ID,
@@ -502,6 +528,7 @@ func TestClient_DeleteInstanceGroup(t *testing.T) {
 }
 
 func TestClient_CreateInstances(t *testing.T) {
+	requireLiveClient(t)
 	args := &CreateInstancesArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		Instances: []*InstanceSet{
@@ -558,6 +585,7 @@ func TestClient_CreateInstances(t *testing.T) {
 }
 
 func TestClient_ListInstancesByPage(t *testing.T) {
+	requireLiveClient(t)
 	args := &ListInstancesByPageArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		Params: &ListInstancesByPageParams{
@@ -578,6 +606,7 @@ func TestClient_ListInstancesByPage(t *testing.T) {
 }
 
 func TestClient_GetInstance(t *testing.T) {
+	requireLiveClient(t)
 	args := &GetInstanceArgs{
 		ClusterID:  CCE_CLUSTER_ID,
 		InstanceID: CCE_INSTANCE_ID,
@@ -591,6 +620,7 @@ func TestClient_GetInstance(t *testing.T) {
 }
 
 func TestClient_UpdateInstance(t *testing.T) {
+	requireLiveClient(t)
 	args := &GetInstanceArgs{
 		ClusterID:  CCE_CLUSTER_ID,
 		InstanceID: CCE_INSTANCE_ID,
@@ -615,6 +645,7 @@ func TestClient_UpdateInstance(t *testing.T) {
 }
 
 func TestClient_GetClusterQuota(t *testing.T) {
+	requireLiveClient(t)
 	resp, err := CCE_CLIENT.GetClusterQuota()
 
 	ExpectEqual(t.Errorf, nil, err)
@@ -624,6 +655,7 @@ func TestClient_GetClusterQuota(t *testing.T) {
 }
 
 func TestClient_GetClusterNodeQuota(t *testing.T) {
+	requireLiveClient(t)
 	resp, err := CCE_CLIENT.GetClusterNodeQuota(CCE_CLUSTER_ID)
 
 	ExpectEqual(t.Errorf, nil, err)
@@ -633,6 +665,7 @@ func TestClient_GetClusterNodeQuota(t *testing.T) {
 }
 
 func TestClient_DeleteInstances(t *testing.T) {
+	requireLiveClient(t)
 	args := &DeleteInstancesArgs{
 		ClusterID: CCE_CLUSTER_ID,
 		DeleteInstancesRequest: &DeleteInstancesRequest{
@@ -653,6 +686,7 @@ func TestClient_DeleteInstances(t *testing.T) {
 }
 
 func TestClient_DeleteCluster(t *testing.T) {
+	requireLiveClient(t)
 	args := &DeleteClusterArgs{
 		ClusterID:         CCE_CLUSTER_ID,
 		DeleteResource:    true,
@@ -0,0 +1,597 @@
+package v2
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultKubeConfigRefreshBefore - 客户端证书在该时间内过期时重新获取 kubeconfig
+	DefaultKubeConfigRefreshBefore = 24 * time.Hour
+
+	// RecommendedKubeConfigEnv - kubectl 使用的 kubeconfig 路径环境变量
+	RecommendedKubeConfigEnv = "KUBECONFIG"
+
+	// 服务端未签发新证书时, 两次重新获取之间的最小间隔
+	minKubeConfigRefreshInterval = time.Minute
+)
+
+// KubeConfig - kubeconfig 中访问集群所需的字段, 字段名与 client-go clientcmd/api/v1 保持一致
+type KubeConfig struct {
+	APIVersion     string             `json:"apiVersion,omitempty"`
+	Kind           string             `json:"kind,omitempty"`
+	CurrentContext string             `json:"current-context,omitempty"`
+	Clusters       []NamedKubeCluster `json:"clusters,omitempty"`
+	Users          []NamedKubeUser    `json:"users,omitempty"`
+	Contexts       []NamedKubeContext `json:"contexts,omitempty"`
+}
+
+type NamedKubeCluster struct {
+	Name    string      `json:"name"`
+	Cluster KubeCluster `json:"cluster"`
+}
+
+type KubeCluster struct {
+	Server                   string `json:"server"`
+	TLSServerName            string `json:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthority     string `json:"certificate-authority,omitempty"`
+	CertificateAuthorityData string `json:"certificate-authority-data,omitempty"`
+	ProxyURL                 string `json:"proxy-url,omitempty"`
+}
+
+type NamedKubeUser struct {
+	Name string   `json:"name"`
+	User KubeUser `json:"user"`
+}
+
+type KubeUser struct {
+	ClientCertificate     string `json:"client-certificate,omitempty"`
+	ClientCertificateData string `json:"client-certificate-data,omitempty"`
+	ClientKey             string `json:"client-key,omitempty"`
+	ClientKeyData         string `json:"client-key-data,omitempty"`
+	Token                 string `json:"token,omitempty"`
+	TokenFile             string `json:"tokenFile,omitempty"`
+	Username              string `json:"username,omitempty"`
+	Password              string `json:"password,omitempty"`
+}
+
+type NamedKubeContext struct {
+	Name    string      `json:"name"`
+	Context KubeContext `json:"context"`
+}
+
+type KubeContext struct {
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RESTConfig - 访问 kube-apiserver 所需的配置, 字段名与 client-go rest.Config 保持一致, 例如:
+//
+//	&rest.Config{
+//	    Host:        c.Host,
+//	    BearerToken: c.BearerToken,
+//	    TLSClientConfig: rest.TLSClientConfig{
+//	        CAData: c.TLSClientConfig.CAData, CertData: c.TLSClientConfig.CertData,
+//	        KeyData: c.TLSClientConfig.KeyData,
+//	    },
+//	}
+type RESTConfig struct {
+	Host            string
+	BearerToken     string
+	BearerTokenFile string
+	Username        string
+	Password        string
+	Proxy           string
+	TLSClientConfig RESTTLSClientConfig
+}
+
+type RESTTLSClientConfig struct {
+	Insecure   bool
+	ServerName string
+
+	CertFile string
+	KeyFile  string
+	CAFile   string
+
+	CertData []byte
+	KeyData  []byte
+	CAData   []byte
+}
+
+// ParseKubeConfig - 解析 YAML 或 JSON 格式的 kubeconfig
+func ParseKubeConfig(data []byte) (*KubeConfig, error) {
+	tree, err := decodeKubeConfigTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig failed: %v", err)
+	}
+	return kubeConfigFromTree(tree)
+}
+
+func kubeConfigFromTree(tree map[string]interface{}) (*KubeConfig, error) {
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	config := &KubeConfig{}
+	if err := json.Unmarshal(raw, config); err != nil {
+		return nil, fmt.Errorf("parse kubeconfig failed: %v", err)
+	}
+	return config, nil
+}
+
+// Context - 返回指定 context 及其引用的 cluster 和 user, contextName 为空时使用 current-context
+func (k *KubeConfig) Context(contextName string) (*KubeContext, *KubeCluster, *KubeUser, error) {
+	if len(contextName) == 0 {
+		contextName = k.CurrentContext
+	}
+	if len(contextName) == 0 {
+		return nil, nil, nil, fmt.Errorf("current-context is empty")
+	}
+	var context *KubeContext
+	for i := range k.Contexts {
+		if k.Contexts[i].Name == contextName {
+			context = &k.Contexts[i].Context
+		}
+	}
+	if context == nil {
+		return nil, nil, nil, fmt.Errorf("context %s not found", contextName)
+	}
+	var cluster *KubeCluster
+	for i := range k.Clusters {
+		if k.Clusters[i].Name == context.Cluster {
+			cluster = &k.Clusters[i].Cluster
+		}
+	}
+	if cluster == nil {
+		return nil, nil, nil, fmt.Errorf("cluster %s of context %s not found", context.Cluster, contextName)
+	}
+	var user *KubeUser
+	for i := range k.Users {
+		if k.Users[i].Name == context.User {
+			user = &k.Users[i].User
+		}
+	}
+	if user == nil {
+		return nil, nil, nil, fmt.Errorf("user %s of context %s not found", context.User, contextName)
+	}
+	return context, cluster, user, nil
+}
+
+// RESTConfig - 将指定 context 转换为 RESTConfig, *-data 字段会被 base64 解码
+func (k *KubeConfig) RESTConfig(contextName string) (*RESTConfig, error) {
+	_, cluster, user, err := k.Context(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if len(cluster.Server) == 0 {
+		return nil, fmt.Errorf("server of context %s is empty", contextName)
+	}
+	config := &RESTConfig{
+		Host:            cluster.Server,
+		BearerToken:     user.Token,
+		BearerTokenFile: user.TokenFile,
+		Username:        user.Username,
+		Password:        user.Password,
+		Proxy:           cluster.ProxyURL,
+		TLSClientConfig: RESTTLSClientConfig{
+			Insecure:   cluster.InsecureSkipTLSVerify,
+			ServerName: cluster.TLSServerName,
+			CertFile:   user.ClientCertificate,
+			KeyFile:    user.ClientKey,
+			CAFile:     cluster.CertificateAuthority,
+		},
+	}
+	fields := []struct {
+		name  string
+		value string
+		dst   *[]byte
+	}{
+		{"certificate-authority-data", cluster.CertificateAuthorityData, &config.TLSClientConfig.CAData},
+		{"client-certificate-data", user.ClientCertificateData, &config.TLSClientConfig.CertData},
+		{"client-key-data", user.ClientKeyData, &config.TLSClientConfig.KeyData},
+	}
+	for _, f := range fields {
+		if len(f.value) == 0 {
+			continue
+		}
+		if *f.dst, err = base64.StdEncoding.DecodeString(f.value); err != nil {
+			return nil, fmt.Errorf("decode %s failed: %v", f.name, err)
+		}
+	}
+	return config, nil
+}
+
+// ClientCertificateExpiry - 返回指定 context 中客户端证书的过期时间, 未使用客户端证书时返回零值
+func (k *KubeConfig) ClientCertificateExpiry(contextName string) (time.Time, error) {
+	config, err := k.RESTConfig(contextName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return config.ClientCertificateExpiry()
+}
+
+// ClientCertificateExpiry - 返回 CertData 中第一个证书的过期时间, CertData 为空时返回零值
+func (c *RESTConfig) ClientCertificateExpiry() (time.Time, error) {
+	if len(c.TLSClientConfig.CertData) == 0 {
+		return time.Time{}, nil
+	}
+	block, _ := pem.Decode(c.TLSClientConfig.CertData)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("client certificate is not a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse client certificate failed: %v", err)
+	}
+	return cert.NotAfter, nil
+}
+
+// TLSConfig - 根据 TLSClientConfig 中的内嵌证书构造 tls.Config, 可用于标准库的 http.Client
+func (c *RESTConfig) TLSConfig() (*tls.Config, error) {
+	tc := c.TLSClientConfig
+	config := &tls.Config{
+		InsecureSkipVerify: tc.Insecure,
+		ServerName:         tc.ServerName,
+	}
+	caData, certData, keyData := tc.CAData, tc.CertData, tc.KeyData
+	files := []struct {
+		path string
+		dst  *[]byte
+	}{{tc.CAFile, &caData}, {tc.CertFile, &certData}, {tc.KeyFile, &keyData}}
+	for _, f := range files {
+		if len(*f.dst) != 0 || len(f.path) == 0 {
+			continue
+		}
+		data, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = data
+	}
+	if len(caData) != 0 {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no valid certificate found in certificate authority")
+		}
+	}
+	if len(certData) != 0 || len(keyData) != 0 {
+		cert, err := tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate failed: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// DefaultKubeConfigPath - 返回 $KUBECONFIG 中的第一个路径, 未设置时返回用户主目录下的 .kube/config
+func DefaultKubeConfigPath() string {
+	if env := os.Getenv(RecommendedKubeConfigEnv); len(env) != 0 {
+		for _, path := range filepath.SplitList(env) {
+			if len(path) != 0 {
+				return path
+			}
+		}
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "config")
+}
+
+// MergeKubeConfig - 将 src 的 current-context 及其 cluster 和 user 以 contextName 命名合并到 dst 中,
+// dst 中同名的条目会被替换; setCurrent 为 true 或 dst 没有 current-context 时将 contextName 设置为
+// current-context. dst 按行编辑, 只改写被替换或新增的条目, 其余的行(包括注释)保持不变;
+// dst 为 JSON 格式或者编辑后的内容与预期不一致时返回错误, 不会改写 dst
+func MergeKubeConfig(dst, src []byte, contextName string, setCurrent bool) ([]byte, error) {
+	if len(contextName) == 0 {
+		return nil, fmt.Errorf("contextName is empty")
+	}
+	if trimmed := bytes.TrimSpace(dst); len(trimmed) != 0 && trimmed[0] == '{' {
+		return nil, fmt.Errorf("target kubeconfig in JSON can not be merged without rewriting it")
+	}
+	dstTree, err := decodeKubeConfigTree(dst)
+	if err != nil {
+		return nil, fmt.Errorf("parse target kubeconfig failed: %v", err)
+	}
+	srcTree, err := decodeKubeConfigTree(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig failed: %v", err)
+	}
+
+	currentContext, _ := treeString(srcTree["current-context"])
+	context, ok := findNamedEntry(srcTree, "contexts", currentContext, "context")
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", currentContext)
+	}
+	clusterName, _ := treeString(context["cluster"])
+	cluster, ok := findNamedEntry(srcTree, "clusters", clusterName, "cluster")
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", clusterName)
+	}
+	userName, _ := treeString(context["user"])
+	user, ok := findNamedEntry(srcTree, "users", userName, "user")
+	if !ok {
+		return nil, fmt.Errorf("user %q not found in kubeconfig", userName)
+	}
+
+	newContext := map[string]interface{}{}
+	for k, v := range context {
+		newContext[k] = v
+	}
+	newContext["cluster"] = contextName
+	newContext["user"] = contextName
+	text := newKubeConfigText(dst)
+	entries := []struct {
+		listKey string
+		itemKey string
+		item    map[string]interface{}
+	}{
+		{"clusters", "cluster", cluster},
+		{"users", "user", user},
+		{"contexts", "context", newContext},
+	}
+	for _, e := range entries {
+		index, err := upsertNamedEntry(dstTree, e.listKey, contextName, e.itemKey, e.item)
+		if err != nil {
+			return nil, err
+		}
+		text.setEntry(e.listKey, index, map[string]interface{}{"name": contextName, e.itemKey: e.item})
+	}
+	if current, _ := treeString(dstTree["current-context"]); setCurrent || len(current) == 0 {
+		dstTree["current-context"] = contextName
+		text.setScalar("current-context", contextName)
+	}
+	if _, ok := dstTree["apiVersion"]; !ok {
+		dstTree["apiVersion"] = "v1"
+		text.setScalar("apiVersion", "v1")
+	}
+	if _, ok := dstTree["kind"]; !ok {
+		dstTree["kind"] = "Config"
+		text.setScalar("kind", "Config")
+	}
+	if _, ok := dstTree["preferences"]; !ok {
+		dstTree["preferences"] = map[string]interface{}{}
+		text.setScalar("preferences", yamlPlain("{}"))
+	}
+	if len(bytes.TrimSpace(dst)) == 0 {
+		return encodeKubeConfigTree(dstTree), nil
+	}
+
+	merged := text.bytes()
+	mergedTree, err := decodeKubeConfigTree(merged)
+	if err != nil ||
+		!reflect.DeepEqual(normalizeKubeConfigTree(mergedTree), normalizeKubeConfigTree(dstTree)) {
+		return nil, fmt.Errorf("target kubeconfig can not be merged without rewriting it, " +
+			"please merge it manually")
+	}
+	return merged, nil
+}
+
+// MergeKubeConfigFile - 将 kubeconfig 合并到 path 指向的文件中, 文件不存在时新建;
+// 通过临时文件替换原文件, 文件权限为 0600
+func MergeKubeConfigFile(path string, kubeConfig []byte, contextName string, setCurrent bool) error {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	merged, err := MergeKubeConfig(existing, kubeConfig, contextName, setCurrent)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(merged); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func treeString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case yamlPlain:
+		return string(s), true
+	}
+	return "", false
+}
+
+func findNamedEntry(tree map[string]interface{}, listKey, name, itemKey string) (map[string]interface{}, bool) {
+	list, _ := tree[listKey].([]interface{})
+	for _, e := range list {
+		entry, _ := e.(map[string]interface{})
+		if n, _ := treeString(entry["name"]); n == name && len(name) != 0 {
+			item, ok := entry[itemKey].(map[string]interface{})
+			return item, ok
+		}
+	}
+	return nil, false
+}
+
+// upsertNamedEntry - 替换或追加名为 name 的条目, 返回被替换的条目的下标, 追加时返回 -1
+func upsertNamedEntry(tree map[string]interface{}, listKey, name, itemKey string,
+	item map[string]interface{}) (int, error) {
+	var list []interface{}
+	switch v := tree[listKey].(type) {
+	case nil:
+	case []interface{}:
+		list = v
+	default:
+		return 0, fmt.Errorf("%s of target kubeconfig is not a list", listKey)
+	}
+	entry := map[string]interface{}{"name": name, itemKey: item}
+	for i, e := range list {
+		if m, ok := e.(map[string]interface{}); ok {
+			if n, _ := treeString(m["name"]); n == name {
+				list[i] = entry
+				return i, nil
+			}
+		}
+	}
+	tree[listKey] = append(list, entry)
+	return -1, nil
+}
+
+// GetParsedKubeConfig - 获取并解析集群的 kubeconfig
+func (c *Client) GetParsedKubeConfig(args *GetKubeConfigArgs) (*KubeConfig, error) {
+	resp, err := c.GetKubeConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKubeConfig([]byte(resp.KubeConfig))
+}
+
+// GetKubeRESTConfig - 获取集群的 kubeconfig 并转换为 current-context 对应的 RESTConfig
+func (c *Client) GetKubeRESTConfig(args *GetKubeConfigArgs) (*RESTConfig, error) {
+	config, err := c.GetParsedKubeConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	return config.RESTConfig("")
+}
+
+// MergeKubeConfigArgs - 合并 kubeconfig 到本地文件的参数
+type MergeKubeConfigArgs struct {
+	ClusterID      string
+	KubeConfigType KubeConfigType
+
+	// Path 为空时使用 DefaultKubeConfigPath()
+	Path string
+
+	// ContextName 为空时使用 ClusterID, 同时作为 cluster 和 user 的名称
+	ContextName string
+
+	SetCurrentContext bool
+}
+
+// MergeKubeConfig - 获取集群的 kubeconfig 并合并到本地 kubeconfig 文件中
+func (c *Client) MergeKubeConfig(args *MergeKubeConfigArgs) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	resp, err := c.GetKubeConfig(&GetKubeConfigArgs{
+		ClusterID:      args.ClusterID,
+		KubeConfigType: args.KubeConfigType,
+	})
+	if err != nil {
+		return err
+	}
+	path := args.Path
+	if len(path) == 0 {
+		path = DefaultKubeConfigPath()
+	}
+	contextName := args.ContextName
+	if len(contextName) == 0 {
+		contextName = args.ClusterID
+	}
+	return MergeKubeConfigFile(path, []byte(resp.KubeConfig), contextName, args.SetCurrentContext)
+}
+
+// KubeConfigRefresher - 缓存集群的 kubeconfig, 客户端证书即将过期时重新获取, 可并发使用
+type KubeConfigRefresher struct {
+	client        *Client
+	args          GetKubeConfigArgs
+	refreshBefore time.Duration
+
+	lock      sync.Mutex
+	config    *KubeConfig
+	expiry    time.Time
+	fetchedAt time.Time
+}
+
+// NewKubeConfigRefresher - 创建 KubeConfigRefresher, refreshBefore 不大于 0 时使用
+// DefaultKubeConfigRefreshBefore
+func (c *Client) NewKubeConfigRefresher(args *GetKubeConfigArgs, refreshBefore time.Duration) (*KubeConfigRefresher, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args is nil")
+	}
+	if err := CheckKubeConfigType(string(args.KubeConfigType)); err != nil {
+		return nil, err
+	}
+	if refreshBefore <= 0 {
+		refreshBefore = DefaultKubeConfigRefreshBefore
+	}
+	return &KubeConfigRefresher{client: c, args: *args, refreshBefore: refreshBefore}, nil
+}
+
+// KubeConfig - 返回缓存的 kubeconfig, 尚未获取或客户端证书将在 refreshBefore 内过期时重新获取
+func (r *KubeConfigRefresher) KubeConfig() (*KubeConfig, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.config != nil && !r.expiring(time.Now()) {
+		return r.config, nil
+	}
+	return r.refresh()
+}
+
+// RESTConfig - 返回缓存的 kubeconfig 中 current-context 对应的 RESTConfig
+func (r *KubeConfigRefresher) RESTConfig() (*RESTConfig, error) {
+	config, err := r.KubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.RESTConfig("")
+}
+
+// Refresh - 忽略缓存重新获取 kubeconfig
+func (r *KubeConfigRefresher) Refresh() (*KubeConfig, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.refresh()
+}
+
+// Expiry - 返回缓存的 kubeconfig 中客户端证书的过期时间, 未获取或未使用客户端证书时返回零值
+func (r *KubeConfigRefresher) Expiry() time.Time {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.expiry
+}
+
+func (r *KubeConfigRefresher) expiring(now time.Time) bool {
+	if r.expiry.IsZero() || now.Add(r.refreshBefore).Before(r.expiry) {
+		return false
+	}
+	return now.After(r.expiry) || now.Sub(r.fetchedAt) >= minKubeConfigRefreshInterval
+}
+
+func (r *KubeConfigRefresher) refresh() (*KubeConfig, error) {
+	args := r.args
+	config, err := r.client.GetParsedKubeConfig(&args)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := config.ClientCertificateExpiry("")
+	if err != nil {
+		return nil, err
+	}
+	r.config, r.expiry, r.fetchedAt = config, expiry, time.Now()
+	return config, nil
+}
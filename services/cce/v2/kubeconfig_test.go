@@ -0,0 +1,369 @@
+package v2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKubeConfig(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return fmt.Sprintf(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: eca97e148cb74e9683d7b7240829d1ff
+  name: eca97e148cb74e9683d7b7240829d1ff@kubernetes
+current-context: eca97e148cb74e9683d7b7240829d1ff@kubernetes
+kind: Config
+preferences: {}
+users:
+- name: eca97e148cb74e9683d7b7240829d1ff
+  user:
+    client-certificate-data: %s
+    client-key-data: %s
+`, base64.StdEncoding.EncodeToString(cert), base64.StdEncoding.EncodeToString(cert),
+		base64.StdEncoding.EncodeToString(keyPem))
+}
+
+func TestParseKubeConfig(t *testing.T) {
+	notAfter := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	config, err := ParseKubeConfig([]byte(newTestKubeConfig(t, notAfter)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := config.RESTConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Host != "https://10.0.0.1:6443" || len(rest.TLSClientConfig.CAData) == 0 ||
+		len(rest.TLSClientConfig.KeyData) == 0 {
+		t.Errorf("unexpected rest config %+v", rest)
+	}
+	if _, err := rest.TLSConfig(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	expiry, err := config.ClientCertificateExpiry("")
+	if err != nil || !expiry.Equal(notAfter) {
+		t.Errorf("expect expiry %v, got %v %v", notAfter, expiry, err)
+	}
+	if _, err := config.RESTConfig("not-exist"); err == nil {
+		t.Errorf("expect error for unknown context")
+	}
+
+	invalid := []string{
+		"clusters:\n\t- name: a\n",
+		"key: |\n  block\n",
+		"a: 1\n  b: 2\n",
+		"- a\n- b\n",
+	}
+	for i, data := range invalid {
+		if _, err := ParseKubeConfig([]byte(data)); err == nil {
+			t.Errorf("case %d: expect error", i)
+		}
+	}
+}
+
+func TestMergeKubeConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	existing := `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: "https://dev.example.com"  # dev cluster
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+users:
+- name: dev
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: get-token
+      args: ["--cluster", 'dev']
+`
+	if err := ioutil.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src := newTestKubeConfig(t, time.Now().Add(time.Hour))
+	if err := MergeKubeConfigFile(path, []byte(src), "cce-prod", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeKubeConfigFile(path, []byte(src), "cce-prod", true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseKubeConfig(data)
+	if err != nil {
+		t.Fatalf("parse merged kubeconfig failed: %v\n%s", err, data)
+	}
+	if config.CurrentContext != "cce-prod" || len(config.Contexts) != 2 ||
+		len(config.Clusters) != 2 || len(config.Users) != 2 {
+		t.Errorf("unexpected merged kubeconfig:\n%s", data)
+	}
+	if rest, err := config.RESTConfig("cce-prod"); err != nil || rest.Host != "https://10.0.0.1:6443" {
+		t.Errorf("unexpected rest config %+v %v", rest, err)
+	}
+	kept := "- name: dev\n  cluster:\n    server: \"https://dev.example.com\"  # dev cluster\n"
+	if !strings.Contains(string(data), kept) ||
+		!strings.Contains(string(data), "      args: [\"--cluster\", 'dev']\n") {
+		t.Errorf("the existing entries are rewritten:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected file mode %v %v", info, err)
+	}
+}
+
+const testMergeSrcKubeConfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Q0EK
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: admin
+  name: admin@kubernetes
+current-context: admin@kubernetes
+kind: Config
+preferences: {}
+users:
+- name: admin
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+`
+
+// a kubeconfig written by "aws eks update-kubeconfig" and edited by hand
+const testEKSKubeConfig = `# managed by the platform team, do not remove the staging cluster
+apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: RUtTLUNBCg==
+    server: https://ABCDEF0123456789.gr7.us-west-2.eks.amazonaws.com
+  name: arn:aws:eks:us-west-2:111122223333:cluster/staging
+
+# the production cluster is only reachable from the VPN
+- cluster:
+    server: https://10.1.0.1:6443  # through the VPN
+    insecure-skip-tls-verify: true
+  name: cce-prod
+contexts:
+- context:
+    cluster: arn:aws:eks:us-west-2:111122223333:cluster/staging
+    user: arn:aws:eks:us-west-2:111122223333:cluster/staging
+    namespace: 'default'
+  name: staging
+- context: {cluster: cce-prod, user: cce-prod}
+  name: cce-prod
+current-context: staging
+kind: Config
+preferences: {}
+users:
+- name: arn:aws:eks:us-west-2:111122223333:cluster/staging
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - --region
+      - us-west-2
+      - eks
+      - get-token
+      - --cluster-name
+      - staging
+      command: aws
+      env:
+      - name: AWS_PROFILE
+        value: "staging"   # the profile in ~/.aws/config
+      interactiveMode: IfAvailable
+      provideClusterInfo: false
+`
+
+func TestMergeKubeConfigRoundTrip(t *testing.T) {
+	newCluster := `- cluster:
+    certificate-authority-data: Q0EK
+    server: https://10.0.0.1:6443
+  name: cce-prod`
+	newUser := `- name: cce-prod
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==`
+	newContext := `- context:
+    cluster: cce-prod
+    user: cce-prod
+  name: cce-prod`
+
+	// the context in the flow mapping can not be parsed, drop it so that the context is appended
+	dst := strings.Replace(testEKSKubeConfig,
+		"- context: {cluster: cce-prod, user: cce-prod}\n  name: cce-prod\n", "", 1)
+	merged, err := MergeKubeConfig([]byte(dst), []byte(testMergeSrcKubeConfig), "cce-prod", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the replaced cluster is rewritten, the new user and context are appended to their lists
+	expected := strings.Replace(dst, `- cluster:
+    server: https://10.1.0.1:6443  # through the VPN
+    insecure-skip-tls-verify: true
+  name: cce-prod`, newCluster, 1)
+	expected = strings.Replace(expected, "  name: staging\n", "  name: staging\n"+newContext+"\n", 1)
+	expected += newUser + "\n"
+	if string(merged) != expected {
+		t.Errorf("expect merged kubeconfig:\n%s\ngot:\n%s", expected, merged)
+	}
+
+	// merging again changes nothing, switching the current context changes only its line
+	again, err := MergeKubeConfig(merged, []byte(testMergeSrcKubeConfig), "cce-prod", false)
+	if err != nil || string(again) != expected {
+		t.Errorf("expect merged kubeconfig unchanged, got %v:\n%s", err, again)
+	}
+	current, err := MergeKubeConfig(merged, []byte(testMergeSrcKubeConfig), "cce-prod", true)
+	expected = strings.Replace(expected, "current-context: staging\n", "current-context: cce-prod\n", 1)
+	if err != nil || string(current) != expected {
+		t.Errorf("expect only the current context switched, got %v:\n%s", err, current)
+	}
+
+	// the missing lists and fields are appended, the comments and CRLF are kept
+	dst = "# generated by hand\r\ncurrent-context: dev\r\nclusters: []\r\n"
+	merged, err = MergeKubeConfig([]byte(dst), []byte(testMergeSrcKubeConfig), "cce-prod", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Replace("# generated by hand\ncurrent-context: dev\nclusters:\n"+newCluster+
+		"\nusers:\n"+newUser+"\ncontexts:\n"+newContext+
+		"\napiVersion: v1\nkind: Config\npreferences: {}\n", "\n", "\r\n", -1)
+	if string(merged) != expected {
+		t.Errorf("expect merged kubeconfig:\n%q\ngot:\n%q", expected, merged)
+	}
+
+	merged, err = MergeKubeConfig(nil, []byte(testMergeSrcKubeConfig), "cce-prod", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseKubeConfig(merged)
+	if err != nil || config.CurrentContext != "cce-prod" || config.APIVersion != "v1" ||
+		len(config.Clusters) != 1 || len(config.Users) != 1 || len(config.Contexts) != 1 {
+		t.Errorf("unexpected new kubeconfig %v:\n%s", err, merged)
+	}
+}
+
+func TestMergeKubeConfigRefused(t *testing.T) {
+	// a kubeconfig written by "gcloud container clusters get-credentials", the install hint
+	// spans several lines
+	gke := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: R0tFLUNBCg==
+    server: https://34.68.0.1
+  name: gke_project_us-central1_prod
+contexts:
+- context:
+    cluster: gke_project_us-central1_prod
+    user: gke_project_us-central1_prod
+  name: gke_project_us-central1_prod
+current-context: gke_project_us-central1_prod
+kind: Config
+preferences: {}
+users:
+- name: gke_project_us-central1_prod
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: gke-gcloud-auth-plugin
+      installHint: Install gke-gcloud-auth-plugin for use with kubectl by following
+        https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl#install_plugin
+      provideClusterInfo: true
+`
+	refused := []string{
+		gke,
+		strings.Replace(gke, "installHint: Install", "installHint: |\n        Install", 1),
+		testEKSKubeConfig,
+		`{"apiVersion": "v1", "kind": "Config", "clusters": []}`,
+	}
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	for i, dst := range refused {
+		if err := ioutil.WriteFile(path, []byte(dst), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := MergeKubeConfigFile(path, []byte(testMergeSrcKubeConfig), "cce-prod", true)
+		if err == nil {
+			t.Errorf("case %d: expect error", i)
+		}
+		if data, err := ioutil.ReadFile(path); err != nil || string(data) != dst {
+			t.Errorf("case %d: expect the file untouched, got %v:\n%s", i, err, data)
+		}
+	}
+}
+
+func TestKubeConfigRefresherExpiring(t *testing.T) {
+	now := time.Now()
+	r := &KubeConfigRefresher{refreshBefore: time.Hour}
+	if r.expiring(now) {
+		t.Errorf("kubeconfig without client certificate should not expire")
+	}
+	r.expiry, r.fetchedAt = now.Add(2*time.Hour), now
+	if r.expiring(now) {
+		t.Errorf("expect not expiring")
+	}
+	r.expiry = now.Add(30 * time.Minute)
+	if r.expiring(now) {
+		t.Errorf("expect not refetching within the minimum interval")
+	}
+	if !r.expiring(now.Add(2 * time.Minute)) {
+		t.Errorf("expect expiring")
+	}
+}
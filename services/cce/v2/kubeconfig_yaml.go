@@ -0,0 +1,563 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kubeconfig 使用的 YAML 子集编解码: 块风格的 map 与 list、纯量与引号字符串、{} 与 [] 以及
+// 只包含纯量的单行 [a, b]; 不支持锚点、多文档以及 | 和 > 块纯量
+// JSON 格式的 kubeconfig 按 JSON 解析
+
+// yamlPlain - 未加引号的纯量, 重新编码时原样输出以保留数字等取值
+type yamlPlain string
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func decodeKubeConfigTree(data []byte) (map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if trimmed[0] == '{' {
+		tree := map[string]interface{}{}
+		if err := json.Unmarshal(trimmed, &tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	}
+
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \r")
+		text := strings.TrimLeft(raw, " ")
+		if len(text) == 0 || text[0] == '#' || text == "---" {
+			continue
+		}
+		if text[0] == '\t' {
+			return nil, fmt.Errorf("yaml line %d: tab is not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(raw) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	root, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	tree, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig is not a mapping")
+	}
+	return tree, nil
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if len(rest) == 0 {
+			p.pos++
+			var item interface{}
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				var err error
+				if item, err = p.parseBlock(p.lines[p.pos].indent); err != nil {
+					return nil, err
+				}
+			}
+			seq = append(seq, item)
+			continue
+		}
+		if _, _, ok := splitYAMLMapEntry(rest); ok || isYAMLSeqItem(rest) {
+			// "- key: value" 中的 map 以 key 所在的列为缩进继续解析
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, item)
+			continue
+		}
+		item, err := parseYAMLScalar(rest, line.num)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+		p.pos++
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLSeqItem(line.text) {
+			return nil, fmt.Errorf("yaml line %d: unexpected sequence item", line.num)
+		}
+		key, value, ok := splitYAMLMapEntry(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml line %d: invalid mapping entry %q", line.num, line.text)
+		}
+		p.pos++
+
+		var v interface{}
+		var err error
+		switch {
+		case len(value) != 0:
+			v, err = parseYAMLScalar(value, line.num)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			v, err = p.parseBlock(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent &&
+			isYAMLSeqItem(p.lines[p.pos].text):
+			// kubectl 生成的 list 与其 key 处于同一缩进
+			v, err = p.parseSeq(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return m, nil
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLMapEntry - 拆分 "key: value", value 中保留可能存在的注释, 由 parseYAMLScalar 处理
+func splitYAMLMapEntry(text string) (string, string, bool) {
+	if len(text) == 0 {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", false
+		}
+		key, err := unquoteYAML(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if len(rest) != 0 && rest[0] != ' ' {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest), true
+	}
+	if strings.ContainsAny(text[:1], "[{#&*!|>%@`") {
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			if i+1 < len(text) && strings.HasPrefix(strings.TrimSpace(text[i+1:]), "#") {
+				return strings.TrimSpace(text[:i]), "", true
+			}
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+	}
+	return "", "", false
+}
+
+func parseYAMLScalar(text string, num int) (interface{}, error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return nil, fmt.Errorf("yaml line %d: unterminated quoted string", num)
+		}
+		if rest := strings.TrimSpace(text[end+1:]); len(rest) != 0 && rest[0] != '#' {
+			return nil, fmt.Errorf("yaml line %d: unexpected %q after quoted string", num, rest)
+		}
+		s, err := unquoteYAML(text[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("yaml line %d: %v", num, err)
+		}
+		return s, nil
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+	switch text[0] {
+	case '|', '>':
+		return nil, fmt.Errorf("yaml line %d: block scalar is not supported", num)
+	case '&', '*', '!':
+		return nil, fmt.Errorf("yaml line %d: anchor, alias and tag are not supported", num)
+	case '{':
+		if text == "{}" {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("yaml line %d: flow mapping is not supported", num)
+	case '[':
+		return parseYAMLFlowSeq(text, num)
+	}
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	return yamlPlain(text), nil
+}
+
+func parseYAMLFlowSeq(text string, num int) (interface{}, error) {
+	if text[len(text)-1] != ']' {
+		return nil, fmt.Errorf("yaml line %d: unterminated flow sequence", num)
+	}
+	seq := []interface{}{}
+	body := strings.TrimSpace(text[1 : len(text)-1])
+	for len(body) != 0 {
+		item := body
+		if body[0] == '"' || body[0] == '\'' {
+			end := closingQuote(body)
+			if end < 0 {
+				return nil, fmt.Errorf("yaml line %d: unterminated quoted string", num)
+			}
+			item, body = body[:end+1], strings.TrimSpace(body[end+1:])
+			if len(body) != 0 && body[0] != ',' {
+				return nil, fmt.Errorf("yaml line %d: invalid flow sequence", num)
+			}
+		} else if i := strings.IndexByte(body, ','); i >= 0 {
+			item, body = strings.TrimSpace(body[:i]), body[i:]
+		} else {
+			body = ""
+		}
+		body = strings.TrimSpace(strings.TrimPrefix(body, ","))
+		if strings.ContainsAny(item[:1], "[{") {
+			return nil, fmt.Errorf("yaml line %d: nested flow collection is not supported", num)
+		}
+		v, err := parseYAMLScalar(item, num)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+	return seq, nil
+}
+
+// closingQuote - 返回与 text[0] 匹配的结束引号的下标, 未找到时返回 -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteYAML(text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	return strconv.Unquote(text)
+}
+
+// encodeKubeConfigTree - 以 kubectl 的风格输出 YAML: key 按字典序排列, list 与其 key 处于同一缩进
+func encodeKubeConfigTree(tree map[string]interface{}) []byte {
+	buf := &bytes.Buffer{}
+	encodeYAMLMap(buf, tree, 0)
+	return buf.Bytes()
+}
+
+func encodeYAMLMap(buf *bytes.Buffer, m map[string]interface{}, indent int) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteString(strings.Repeat(" ", indent))
+		buf.WriteString(formatYAMLScalar(k))
+		buf.WriteString(":")
+		switch v := m[k].(type) {
+		case map[string]interface{}:
+			if len(v) == 0 {
+				buf.WriteString(" {}\n")
+				continue
+			}
+			buf.WriteString("\n")
+			encodeYAMLMap(buf, v, indent+2)
+		case []interface{}:
+			if len(v) == 0 {
+				buf.WriteString(" []\n")
+				continue
+			}
+			buf.WriteString("\n")
+			encodeYAMLSeq(buf, v, indent)
+		default:
+			buf.WriteString(" ")
+			buf.WriteString(formatYAMLScalar(v))
+			buf.WriteString("\n")
+		}
+	}
+}
+
+func encodeYAMLSeq(buf *bytes.Buffer, seq []interface{}, indent int) {
+	prefix := strings.Repeat(" ", indent)
+	for _, item := range seq {
+		switch v := item.(type) {
+		case map[string]interface{}:
+			if len(v) == 0 {
+				buf.WriteString(prefix + "- {}\n")
+				continue
+			}
+			sub := &bytes.Buffer{}
+			encodeYAMLMap(sub, v, indent+2)
+			buf.WriteString(prefix + "- ")
+			buf.Write(sub.Bytes()[indent+2:])
+		case []interface{}:
+			if len(v) == 0 {
+				buf.WriteString(prefix + "- []\n")
+				continue
+			}
+			buf.WriteString(prefix + "-\n")
+			encodeYAMLSeq(buf, v, indent+2)
+		default:
+			buf.WriteString(prefix + "- " + formatYAMLScalar(v) + "\n")
+		}
+	}
+}
+
+func formatYAMLScalar(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(s)
+	case yamlPlain:
+		return string(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case string:
+		if needYAMLQuote(s) {
+			return strconv.Quote(s)
+		}
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func needYAMLQuote(s string) bool {
+	if len(s) == 0 || s[0] == ' ' || s[len(s)-1] == ' ' ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	switch s {
+	case "~", "null", "Null", "NULL", "true", "True", "TRUE", "false", "False", "FALSE":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// kubeConfigText - 按行编辑 YAML 格式的 kubeconfig, 只改写被替换或新增的条目所在的行,
+// 其余的行(包括注释、引号与缩进风格)原样保留
+type kubeConfigText struct {
+	lines   []string
+	newline string
+	final   bool // 原文件是否以换行结尾
+	indent  int  // 顶层 key 的缩进
+}
+
+func newKubeConfigText(data []byte) *kubeConfigText {
+	t := &kubeConfigText{newline: "\n", final: true}
+	if bytes.Contains(data, []byte("\r\n")) {
+		t.newline = "\r\n"
+	}
+	text := string(data)
+	if len(text) != 0 {
+		t.final = strings.HasSuffix(text, "\n")
+		t.lines = strings.Split(strings.TrimSuffix(text, t.newline), t.newline)
+	}
+	for _, line := range t.lines {
+		if isYAMLContent(line) {
+			t.indent = yamlIndent(line)
+			break
+		}
+	}
+	return t
+}
+
+func (t *kubeConfigText) bytes() []byte {
+	if len(t.lines) == 0 {
+		return nil
+	}
+	text := strings.Join(t.lines, t.newline)
+	if t.final {
+		text += t.newline
+	}
+	return []byte(text)
+}
+
+// findKey - 返回顶层 key 所在的行, 不存在时返回 -1
+func (t *kubeConfigText) findKey(key string) int {
+	for i, line := range t.lines {
+		if !isYAMLContent(line) || yamlIndent(line) != t.indent {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if k, _, ok := splitYAMLMapEntry(text); ok && !isYAMLSeqItem(text) && k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockEnd - 返回顶层 key 的取值所占的最后一行之后的位置, 其后的空行与注释不计入
+func (t *kubeConfigText) blockEnd(start int) int {
+	end := start + 1
+	for i := start + 1; i < len(t.lines); i++ {
+		if !isYAMLContent(t.lines[i]) {
+			continue
+		}
+		indent := yamlIndent(t.lines[i])
+		if indent < t.indent || indent == t.indent && !isYAMLSeqItem(strings.TrimSpace(t.lines[i])) {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+func (t *kubeConfigText) replace(start, end int, lines []string) {
+	tail := append(append([]string{}, lines...), t.lines[end:]...)
+	t.lines = append(t.lines[:start], tail...)
+}
+
+// setEntry - 以 entry 替换列表 listKey 中的第 index 项, index 为 -1 时追加到列表末尾
+func (t *kubeConfigText) setEntry(listKey string, index int, entry map[string]interface{}) {
+	entryLines := func(indent int) []string {
+		buf := &bytes.Buffer{}
+		encodeYAMLSeq(buf, []interface{}{entry}, indent)
+		return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	}
+	keyLine := strings.Repeat(" ", t.indent) + formatYAMLScalar(listKey) + ":"
+	k := t.findKey(listKey)
+	if k < 0 {
+		t.replace(len(t.lines), len(t.lines), append([]string{keyLine}, entryLines(t.indent)...))
+		return
+	}
+	end := t.blockEnd(k)
+	if _, value, _ := splitYAMLMapEntry(strings.TrimSpace(t.lines[k])); len(value) != 0 {
+		// null 或 [] 等单行的取值
+		t.replace(k, end, append([]string{keyLine}, entryLines(t.indent)...))
+		return
+	}
+
+	seqIndent, starts := -1, []int{}
+	for i := k + 1; i < end; i++ {
+		if !isYAMLContent(t.lines[i]) {
+			continue
+		}
+		if seqIndent < 0 {
+			seqIndent = yamlIndent(t.lines[i])
+		}
+		if yamlIndent(t.lines[i]) == seqIndent && isYAMLSeqItem(strings.TrimSpace(t.lines[i])) {
+			starts = append(starts, i)
+		}
+	}
+	switch {
+	case seqIndent < 0:
+		t.replace(k+1, k+1, entryLines(t.indent))
+	case index < 0 || index >= len(starts):
+		t.replace(end, end, entryLines(seqIndent))
+	default:
+		// 条目之间的空行与注释保留在原处
+		itemEnd := end
+		if index+1 < len(starts) {
+			for itemEnd = starts[index+1]; !isYAMLContent(t.lines[itemEnd-1]); itemEnd-- {
+			}
+		}
+		t.replace(starts[index], itemEnd, entryLines(seqIndent))
+	}
+}
+
+// setScalar - 设置顶层 key 的纯量取值, key 不存在时追加到文件末尾
+func (t *kubeConfigText) setScalar(key string, value interface{}) {
+	line := strings.Repeat(" ", t.indent) + formatYAMLScalar(key) + ": " + formatYAMLScalar(value)
+	if k := t.findKey(key); k >= 0 {
+		t.replace(k, t.blockEnd(k), []string{line})
+		return
+	}
+	t.replace(len(t.lines), len(t.lines), []string{line})
+}
+
+func isYAMLContent(line string) bool {
+	text := strings.TrimSpace(line)
+	return len(text) != 0 && text[0] != '#' && text != "---"
+}
+
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// normalizeKubeConfigTree - 将未加引号的纯量转为字符串, 用于比较编辑前后的内容
+func normalizeKubeConfigTree(v interface{}) interface{} {
+	switch value := v.(type) {
+	case yamlPlain:
+		return string(value)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			m[k] = normalizeKubeConfigTree(item)
+		}
+		return m
+	case []interface{}:
+		seq := make([]interface{}, len(value))
+		for i, item := range value {
+			seq[i] = normalizeKubeConfigTree(item)
+		}
+		return seq
+	}
+	return v
+}
@@ -0,0 +1,130 @@
+package v2
+
+import (
+	"fmt"
+
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+var privateIPv4Nets = []PrivateNetString{PrivateIPv4Net10, PrivateIPv4Net172, PrivateIPv4Net192}
+
+// ValidateCheckContainerNetworkCIDRArgs - 在本地预检查容器网络网段参数中的 IPv4 网段
+// 容器网段须位于私有网段内，且与 VPC 网段、ClusterIP 网段互不重叠
+func ValidateCheckContainerNetworkCIDRArgs(args *CheckContainerNetworkCIDRArgs) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if args.IPVersion == types.ContainerNetworkIPTypeIPv6 {
+		return nil
+	}
+	blocks, err := parseIPv4Blocks(map[string]string{
+		"vpcCIDR":       args.VPCCIDR,
+		"containerCIDR": args.ContainerCIDR,
+		"clusterIPCIDR": args.ClusterIPCIDR,
+	})
+	if err != nil {
+		return err
+	}
+	container, ok := blocks["containerCIDR"]
+	if !ok {
+		return fmt.Errorf("containerCIDR is empty")
+	}
+	if !inPrivateIPv4Net(container) {
+		return fmt.Errorf("containerCIDR %s is not in %v", container, privateIPv4Nets)
+	}
+	if args.MaxPodsPerNode > 0 && container.Size() < uint64(args.MaxPodsPerNode) {
+		return fmt.Errorf("containerCIDR %s is smaller than maxPodsPerNode %d", container,
+			args.MaxPodsPerNode)
+	}
+	return checkIPv4Overlaps(blocks)
+}
+
+// ValidateRecommendClusterIPCIDRArgs - 在本地预检查推荐 ClusterIP 网段的参数
+// 候选私有网段中须存在与 VPC 网段、容器网段均不重叠且能容纳 ClusterMaxServiceNum 的空闲网段
+func ValidateRecommendClusterIPCIDRArgs(args *RecommendClusterIPCIDRArgs) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if args.ClusterMaxServiceNum <= 0 || args.ClusterMaxServiceNum > MaxClusterIPServiceNum {
+		return fmt.Errorf("clusterMaxServiceNum should be in (0, %d]", MaxClusterIPServiceNum)
+	}
+	if args.IPVersion == types.ContainerNetworkIPTypeIPv6 {
+		return nil
+	}
+	blocks, err := parseIPv4Blocks(map[string]string{
+		"vpcCIDR":       args.VPCCIDR,
+		"containerCIDR": args.ContainerCIDR,
+	})
+	if err != nil {
+		return err
+	}
+	if err := checkIPv4Overlaps(blocks); err != nil {
+		return err
+	}
+	used := make([]util.IPv4Block, 0, len(blocks))
+	for _, block := range blocks {
+		used = append(used, block)
+	}
+
+	prefixLength := 32
+	for uint64(1)<<uint(32-prefixLength) < uint64(args.ClusterMaxServiceNum) {
+		prefixLength--
+	}
+	for _, candidate := range args.PrivateNetCIDRs {
+		parent, err := util.ParseIPv4Block(string(candidate))
+		if err != nil || !inPrivateIPv4Net(parent) {
+			return fmt.Errorf("privateNetCIDR %s is not in %v", candidate, privateIPv4Nets)
+		}
+		if prefixLength < parent.PrefixLength {
+			continue
+		}
+		free, err := util.FreeIPv4Blocks(parent, used, prefixLength, 1)
+		if err != nil {
+			return err
+		}
+		if len(free) != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("no free /%d block in privateNetCIDRs %v", prefixLength, args.PrivateNetCIDRs)
+}
+
+func parseIPv4Blocks(cidrs map[string]string) (map[string]util.IPv4Block, error) {
+	result := make(map[string]util.IPv4Block, len(cidrs))
+	for name, cidr := range cidrs {
+		if len(cidr) == 0 {
+			continue
+		}
+		block, err := util.ParseIPv4Block(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		result[name] = block
+	}
+	return result, nil
+}
+
+func checkIPv4Overlaps(blocks map[string]util.IPv4Block) error {
+	names := []string{"vpcCIDR", "containerCIDR", "clusterIPCIDR"}
+	for i, name := range names {
+		for _, other := range names[i+1:] {
+			a, okA := blocks[name]
+			b, okB := blocks[other]
+			if okA && okB && a.Overlaps(b) {
+				return fmt.Errorf("%s %s overlaps %s %s", name, a, other, b)
+			}
+		}
+	}
+	return nil
+}
+
+func inPrivateIPv4Net(block util.IPv4Block) bool {
+	for _, cidr := range privateIPv4Nets {
+		private, _ := util.ParseIPv4Block(string(cidr))
+		if private.Contains(block) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package v2
+
+import "testing"
+
+func TestValidateContainerNetworkCIDR(t *testing.T) {
+	valid := &CheckContainerNetworkCIDRArgs{
+		VPCCIDR:        "192.168.0.0/16",
+		ContainerCIDR:  "172.16.0.0/16",
+		ClusterIPCIDR:  "172.31.0.0/16",
+		MaxPodsPerNode: 128,
+	}
+	if err := ValidateCheckContainerNetworkCIDRArgs(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	invalid := []CheckContainerNetworkCIDRArgs{
+		{VPCCIDR: "192.168.0.0/16", ContainerCIDR: "192.168.128.0/17"},
+		{ContainerCIDR: "172.16.0.0/16", ClusterIPCIDR: "172.16.255.0/24"},
+		{ContainerCIDR: "8.8.0.0/16"},
+		{ContainerCIDR: "172.16.0.0/28", MaxPodsPerNode: 64},
+		{ContainerCIDR: "172.16.0.1/16"},
+	}
+	for i := range invalid {
+		if err := ValidateCheckContainerNetworkCIDRArgs(&invalid[i]); err == nil {
+			t.Errorf("case %d: expect error", i)
+		}
+	}
+}
+
+func TestValidateRecommendClusterIPCIDR(t *testing.T) {
+	args := &RecommendClusterIPCIDRArgs{
+		VPCCIDR:              "192.168.0.0/16",
+		ContainerCIDR:        "172.16.0.0/12",
+		ClusterMaxServiceNum: 1024,
+		PrivateNetCIDRs:      []PrivateNetString{PrivateIPv4Net172, PrivateIPv4Net192},
+	}
+	if err := ValidateRecommendClusterIPCIDRArgs(args); err == nil {
+		t.Errorf("expect error of no free block")
+	}
+	args.PrivateNetCIDRs = append(args.PrivateNetCIDRs, PrivateIPv4Net10)
+	if err := ValidateRecommendClusterIPCIDRArgs(args); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	args.ClusterMaxServiceNum = MaxClusterIPServiceNum + 1
+	if err := ValidateRecommendClusterIPCIDRArgs(args); err == nil {
+		t.Errorf("expect error of too many services")
+	}
+}
@@ -0,0 +1,138 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+)
+
+// 服务端不会返回的字段, 不参与比较
+var ignoredSpecDiffFields = map[string]bool{
+	"adminPassword": true,
+}
+
+// SpecChange - 期望的 spec 与线上 spec 之间一个字段的差异, Path 为字段的 JSON 路径,
+// Live 为 nil 表示线上没有该字段
+type SpecChange struct {
+	Path    string
+	Live    interface{}
+	Desired interface{}
+}
+
+func (c SpecChange) String() string {
+	live, _ := json.Marshal(c.Live)
+	desired, _ := json.Marshal(c.Desired)
+	return fmt.Sprintf("%s: %s -> %s", c.Path, live, desired)
+}
+
+// DiffClusterSpec - 比较期望的 ClusterSpec 与 GetCluster 返回的 ClusterSpec,
+// 只比较 desired 中设置了的字段, 结果按 Path 排序
+func DiffClusterSpec(desired *types.ClusterSpec, live *ClusterSpec) ([]SpecChange, error) {
+	if desired == nil || live == nil {
+		return nil, fmt.Errorf("spec is nil")
+	}
+	return diffSpec(desired, live)
+}
+
+// DiffInstanceGroupSpec - 比较期望的 InstanceGroupSpec 与 GetInstanceGroup 返回的 InstanceGroupSpec,
+// 只比较 desired 中设置了的字段, 结果按 Path 排序
+func DiffInstanceGroupSpec(desired *types.InstanceGroupSpec, live *InstanceGroupSpec) ([]SpecChange, error) {
+	if desired == nil || live == nil {
+		return nil, fmt.Errorf("spec is nil")
+	}
+	return diffSpec(desired, live)
+}
+
+// DiffCluster - 查询集群并与期望的 ClusterSpec 比较
+func (c *Client) DiffCluster(clusterID string, desired *types.ClusterSpec) ([]SpecChange, error) {
+	resp, err := c.GetCluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Cluster == nil || resp.Cluster.Spec == nil {
+		return nil, fmt.Errorf("spec of cluster %s is empty", clusterID)
+	}
+	return DiffClusterSpec(desired, resp.Cluster.Spec)
+}
+
+// DiffInstanceGroup - 查询节点组并与期望的 InstanceGroupSpec 比较
+func (c *Client) DiffInstanceGroup(args *GetInstanceGroupArgs, desired *types.InstanceGroupSpec) ([]SpecChange, error) {
+	resp, err := c.GetInstanceGroup(args)
+	if err != nil {
+		return nil, err
+	}
+	if resp.InstanceGroup == nil || resp.InstanceGroup.Spec == nil {
+		return nil, fmt.Errorf("spec of instance group %s is empty", args.InstanceGroupID)
+	}
+	return DiffInstanceGroupSpec(desired, resp.InstanceGroup.Spec)
+}
+
+func diffSpec(desired, live interface{}) ([]SpecChange, error) {
+	d, err := specTree(desired)
+	if err != nil {
+		return nil, err
+	}
+	l, err := specTree(live)
+	if err != nil {
+		return nil, err
+	}
+	changes := []SpecChange{}
+	diffSpecTree("", d, l, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func specTree(spec interface{}) (interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func diffSpecTree(path string, desired, live interface{}, changes *[]SpecChange) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for key, value := range d {
+			if ignoredSpecDiffFields[key] {
+				continue
+			}
+			diffSpecTree(joinSpecPath(path, key), value, l[key], changes)
+		}
+		return
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			break
+		}
+		for i := range d {
+			diffSpecTree(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(desired, live) {
+		*changes = append(*changes, SpecChange{Path: path, Live: live, Desired: desired})
+	}
+}
+
+func joinSpecPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	return path + "." + key
+}
@@ -0,0 +1,48 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+)
+
+func TestDiffInstanceGroupSpec(t *testing.T) {
+	live := &InstanceGroupSpec{
+		InstanceGroupName: "ig",
+		Replicas:          3,
+		InstanceTemplate: InstanceTemplate{InstanceSpec: types.InstanceSpec{
+			InstanceName: "node",
+			InstanceType: "N3",
+			ImageID:      "m-old",
+			Labels:       types.InstanceLabels{"env": "test"},
+		}},
+	}
+	desired := &types.InstanceGroupSpec{
+		InstanceGroupName: "ig",
+		Replicas:          5,
+		InstanceTemplate: types.InstanceTemplate{InstanceSpec: types.InstanceSpec{
+			InstanceName:  "node",
+			InstanceType:  "N3",
+			ImageID:       "m-new",
+			AdminPassword: "password",
+			Labels:        types.InstanceLabels{"env": "test", "app.kubernetes.io/name": "web"},
+		}},
+	}
+	changes, err := DiffInstanceGroupSpec(desired, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`instanceTemplate.imageID: "m-old" -> "m-new"`,
+		`instanceTemplate.labels["app.kubernetes.io/name"]: null -> "web"`,
+		`replicas: 3 -> 5`,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	for i := range expected {
+		if changes[i].String() != expected[i] {
+			t.Errorf("expect %s, got %s", expected[i], changes[i])
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package v2
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	bccapi "github.com/kougazhang/bce-sdk-go/services/bcc/api"
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+var runtimeVersionPattern = regexp.MustCompile(`^\d+(\.\d+)*([-+.][0-9A-Za-z.-]+)?$`)
+
+var supportedAvailableZones = map[types.AvailableZone]string{
+	types.AvailableZoneA: "",
+	types.AvailableZoneB: "",
+	types.AvailableZoneC: "",
+	types.AvailableZoneD: "",
+	types.AvailableZoneE: "",
+	types.AvailableZoneF: "",
+}
+
+// SpecError - 本地校验发现的一个问题, Field 为字段的 JSON 路径, 如 cluster.containerNetworkConfig.clusterPodCIDR
+type SpecError struct {
+	Field   string
+	Message string
+}
+
+func (e SpecError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// SpecErrors - 本地校验发现的全部问题
+type SpecErrors []SpecError
+
+func (e SpecErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type specValidator struct {
+	errs SpecErrors
+}
+
+func (v *specValidator) addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, SpecError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *specValidator) required(field string, empty bool) {
+	if empty {
+		v.addf(field, "is required")
+	}
+}
+
+func (v *specValidator) supported(field string, value interface{}, ok bool) {
+	if !ok {
+		v.addf(field, "%v is not supported", value)
+	}
+}
+
+func (v *specValidator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// ValidateCreateClusterRequest - 在本地校验创建集群的请求, 返回的 error 为 SpecErrors
+func ValidateCreateClusterRequest(req *CreateClusterRequest) error {
+	if req == nil || req.ClusterSpec == nil {
+		return fmt.Errorf("args is nil")
+	}
+	v := &specValidator{}
+	v.validateClusterSpec("cluster", req.ClusterSpec)
+	sets := []struct {
+		field string
+		specs []*InstanceSet
+	}{{"masters", req.MasterSpecs}, {"nodes", req.NodeSpecs}}
+	for _, set := range sets {
+		for i, s := range set.specs {
+			field := fmt.Sprintf("%s[%d]", set.field, i)
+			if s == nil {
+				v.addf(field, "is nil")
+				continue
+			}
+			if s.Count <= 0 {
+				v.addf(field+".count", "should be greater than 0")
+			}
+			v.validateInstanceSpec(field+".instanceSpec", &s.InstanceSpec, req.ClusterSpec)
+		}
+	}
+	if req.ClusterSpec.MasterConfig.MasterType == types.MasterTypeCustom {
+		masters := 0
+		for _, s := range req.MasterSpecs {
+			if s != nil {
+				masters += s.Count
+			}
+		}
+		if ha := req.ClusterSpec.MasterConfig.ClusterHA; ha != 0 && masters != int(ha) {
+			v.addf("masters", "%d masters do not match clusterHA %d", masters, ha)
+		}
+	}
+	return v.result()
+}
+
+// ValidateClusterSpec - 在本地校验 ClusterSpec 的必填字段、可选值以及网段, 返回的 error 为 SpecErrors
+func ValidateClusterSpec(spec *types.ClusterSpec) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	v := &specValidator{}
+	v.validateClusterSpec("cluster", spec)
+	return v.result()
+}
+
+// ValidateInstanceSpec - 在本地校验 InstanceSpec, cluster 不为空时同时校验 VPC 与集群网段是否一致,
+// 返回的 error 为 SpecErrors
+func ValidateInstanceSpec(spec *types.InstanceSpec, cluster *types.ClusterSpec) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	v := &specValidator{}
+	v.validateInstanceSpec("instanceSpec", spec, cluster)
+	return v.result()
+}
+
+// ValidateInstanceGroupSpec - 在本地校验 InstanceGroupSpec 及其 InstanceTemplate, cluster 可以为空,
+// 返回的 error 为 SpecErrors
+func ValidateInstanceGroupSpec(spec *types.InstanceGroupSpec, cluster *types.ClusterSpec) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	v := &specValidator{}
+	v.required("instanceGroupName", len(spec.InstanceGroupName) == 0)
+	if spec.Replicas < 0 {
+		v.addf("replicas", "should not be negative")
+	}
+	if len(spec.ClusterRole) != 0 {
+		v.supported("clusterRole", spec.ClusterRole,
+			spec.ClusterRole == types.ClusterRoleMaster || spec.ClusterRole == types.ClusterRoleNode)
+	}
+	if len(spec.ShrinkPolicy) != 0 {
+		v.supported("shrinkPolicy", spec.ShrinkPolicy,
+			spec.ShrinkPolicy == types.PriorityShrinkPolicy || spec.ShrinkPolicy == types.RandomShrinkPolicy)
+	}
+	if len(spec.UpdatePolicy) != 0 {
+		v.supported("updatePolicy", spec.UpdatePolicy,
+			spec.UpdatePolicy == types.RollingUpdatePolicy || spec.UpdatePolicy == types.ConcurrencyUpdatePolicy)
+	}
+	if len(spec.CleanPolicy) != 0 {
+		v.supported("cleanPolicy", spec.CleanPolicy,
+			spec.CleanPolicy == types.RemainCleanPolicy || spec.CleanPolicy == types.DeleteCleanPolicy)
+	}
+	if as := spec.ClusterAutoscalerSpec; as != nil && as.Enabled {
+		if as.MinReplicas < 0 || as.MaxReplicas < as.MinReplicas {
+			v.addf("clusterAutoscalerSpec", "replicas range [%d, %d] is invalid", as.MinReplicas, as.MaxReplicas)
+		} else if spec.Replicas < as.MinReplicas || spec.Replicas > as.MaxReplicas {
+			v.addf("replicas", "%d is out of autoscaler range [%d, %d]",
+				spec.Replicas, as.MinReplicas, as.MaxReplicas)
+		}
+	}
+	v.validateInstanceSpec("instanceTemplate", &spec.InstanceTemplate.InstanceSpec, cluster)
+	return v.result()
+}
+
+func (v *specValidator) validateClusterSpec(field string, spec *types.ClusterSpec) {
+	v.required(field+".clusterName", len(spec.ClusterName) == 0)
+	v.required(field+".vpcID", len(spec.VPCID) == 0)
+	if len(spec.ClusterType) == 0 {
+		v.required(field+".clusterType", true)
+	} else {
+		v.supported(field+".clusterType", spec.ClusterType, spec.ClusterType == types.ClusterTypeNormal)
+	}
+	if len(spec.K8SVersion) != 0 {
+		_, ok := types.SupportedK8SVersions[spec.K8SVersion]
+		v.supported(field+".k8sVersion", spec.K8SVersion, ok)
+	}
+	v.validateRuntime(field, spec.RuntimeType, spec.RuntimeVersion)
+
+	master := spec.MasterConfig
+	if len(master.MasterType) == 0 {
+		v.required(field+".masterConfig.masterType", true)
+	} else {
+		_, ok := types.SupportedMasterType[master.MasterType]
+		v.supported(field+".masterConfig.masterType", master.MasterType, ok)
+	}
+	if master.ClusterHA != 0 && master.MasterType != types.MasterTypeServerless {
+		_, ok := types.SupportedClusterHA[master.ClusterHA]
+		v.supported(field+".masterConfig.clusterHA", master.ClusterHA, ok)
+	}
+	if zone := master.MasterVPCSubnetZone; len(zone) != 0 {
+		_, ok := supportedAvailableZones[zone]
+		v.supported(field+".masterConfig.managedClusterMasterOption.masterVPCSubnetZone", zone, ok)
+	}
+
+	network := spec.ContainerNetworkConfig
+	nf := field + ".containerNetworkConfig"
+	if len(network.Mode) != 0 {
+		_, ok := types.SupportedContainerNetworkMode[network.Mode]
+		v.supported(nf+".mode", network.Mode, ok)
+	}
+	if network.Mode == types.ContainerNetworkModeVPCCNI {
+		v.required(nf+".eniVPCSubnetIDs", len(network.ENIVPCSubnetIDs) == 0)
+		v.required(nf+".eniSecurityGroupID", len(network.ENISecurityGroupID) == 0)
+	}
+	for zone := range network.ENIVPCSubnetIDs {
+		_, ok := supportedAvailableZones[zone]
+		v.supported(nf+".eniVPCSubnetIDs", zone, ok)
+	}
+	v.required(nf+".lbServiceVPCSubnetID", len(network.LBServiceVPCSubnetID) == 0)
+	if len(network.KubeProxyMode) != 0 {
+		_, ok := types.SupportedKubeProxyMode[network.KubeProxyMode]
+		v.supported(nf+".kubeProxyMode", network.KubeProxyMode, ok)
+	}
+	ipVersion := network.IPVersion
+	if len(ipVersion) != 0 {
+		v.supported(nf+".ipVersion", ipVersion, ipVersion == types.ContainerNetworkIPTypeIPv4 ||
+			ipVersion == types.ContainerNetworkIPTypeIPv6 || ipVersion == types.ContainerNetworkIPTypeDualStack)
+	}
+	if network.NodePortRangeMin != 0 || network.NodePortRangeMax != 0 {
+		if network.NodePortRangeMin <= 0 || network.NodePortRangeMax > 65535 ||
+			network.NodePortRangeMin >= network.NodePortRangeMax {
+			v.addf(nf+".nodePortRangeMin", "node port range [%d, %d] is invalid",
+				network.NodePortRangeMin, network.NodePortRangeMax)
+		}
+	}
+	if network.MaxPodsPerNode < 0 {
+		v.addf(nf+".maxPodsPerNode", "should not be negative")
+	}
+
+	if ipVersion != types.ContainerNetworkIPTypeIPv6 {
+		blocks := v.parseCIDRs([][2]string{
+			{field + ".vpcCIDR", spec.VPCCIDR},
+			{nf + ".clusterPodCIDR", network.ClusterPodCIDR},
+			{nf + ".clusterIPServiceCIDR", network.ClusterIPServiceCIDR},
+		})
+		for _, name := range []string{nf + ".clusterPodCIDR", nf + ".clusterIPServiceCIDR"} {
+			if block, ok := blocks[name]; ok && !inPrivateIPv4Net(block) {
+				v.addf(name, "%s is not in %v", block, privateIPv4Nets)
+			}
+		}
+		v.checkOverlaps(blocks, field+".vpcCIDR", nf+".clusterPodCIDR", nf+".clusterIPServiceCIDR")
+		if pod, ok := blocks[nf+".clusterPodCIDR"]; ok && network.MaxPodsPerNode > 0 &&
+			pod.Size() < uint64(network.MaxPodsPerNode) {
+			v.addf(nf+".clusterPodCIDR", "%s is smaller than maxPodsPerNode %d", pod, network.MaxPodsPerNode)
+		}
+	}
+	if ipVersion == types.ContainerNetworkIPTypeIPv6 || ipVersion == types.ContainerNetworkIPTypeDualStack {
+		v.checkIPv6CIDRs([][2]string{
+			{field + ".vpcCIDRIPv6", spec.VPCCIDRIPv6},
+			{nf + ".clusterPodCIDRIPv6", network.ClusterPodCIDRIPv6},
+			{nf + ".clusterIPServiceCIDRIPv6", network.ClusterIPServiceCIDRIPv6},
+		})
+	}
+}
+
+func (v *specValidator) validateInstanceSpec(field string, spec *types.InstanceSpec, cluster *types.ClusterSpec) {
+	if len(spec.ClusterRole) != 0 {
+		v.supported(field+".clusterRole", spec.ClusterRole,
+			spec.ClusterRole == types.ClusterRoleMaster || spec.ClusterRole == types.ClusterRoleNode)
+	}
+	if len(spec.MachineType) != 0 {
+		v.supported(field+".machineType", spec.MachineType, spec.MachineType == types.MachineTypeBCC ||
+			spec.MachineType == types.MachineTypeBBC || spec.MachineType == types.MachineTypeMetal)
+	}
+	v.validateRuntime(field, spec.RuntimeType, spec.RuntimeVersion)
+
+	if spec.Existed {
+		v.required(field+".existedOption.existedInstanceID", len(spec.ExistedOption.ExistedInstanceID) == 0)
+		v.required(field+".adminPassword", len(spec.AdminPassword) == 0)
+	} else {
+		if len(spec.InstanceType) == 0 {
+			v.required(field+".instanceType", true)
+		} else {
+			_, ok := types.SupportedInstanceType[spec.InstanceType]
+			v.supported(field+".instanceType", spec.InstanceType, ok)
+		}
+		v.required(field+".imageID", len(spec.ImageID) == 0 && len(spec.InstanceOS.ImageType) == 0 &&
+			len(spec.InstanceOS.ImageName) == 0)
+		v.required(field+".vpcConfig.vpcSubnetID", len(spec.VPCConfig.VPCSubnetID) == 0)
+	}
+	if len(spec.InstanceOS.ImageType) != 0 {
+		_, ok := types.SupportedImageType[spec.InstanceOS.ImageType]
+		v.supported(field+".instanceOS.imageType", spec.InstanceOS.ImageType, ok)
+	}
+
+	resource := spec.InstanceResource
+	rf := field + ".instanceResource"
+	if resource.CPU < 0 || resource.MEM < 0 || resource.RootDiskSize < 0 || resource.LocalDiskSize < 0 {
+		v.addf(rf, "cpu, mem and disk sizes should not be negative")
+	}
+	if len(resource.RootDiskType) != 0 {
+		_, ok := types.SupportedRootDiskStorageType[resource.RootDiskType]
+		v.supported(rf+".rootDiskType", resource.RootDiskType, ok)
+	}
+	for i, cds := range resource.CDSList {
+		cf := fmt.Sprintf("%s.cdsList[%d]", rf, i)
+		if len(cds.StorageType) != 0 {
+			_, ok := types.SupportedStorageType[cds.StorageType]
+			v.supported(cf+".storageType", cds.StorageType, ok)
+		}
+		if cds.CDSSize <= 0 && len(cds.SnapshotID) == 0 {
+			v.addf(cf+".cdsSize", "should be greater than 0")
+		}
+	}
+	if len(resource.GPUType) != 0 {
+		_, ok := types.SupportedGPUType[resource.GPUType]
+		v.supported(rf+".gpuType", resource.GPUType, ok)
+	}
+	if resource.GPUCount < 0 || (resource.GPUCount > 0 && len(resource.GPUType) == 0) {
+		v.addf(rf+".gpuCount", "%d is invalid for gpuType %q", resource.GPUCount, resource.GPUType)
+	}
+
+	if spec.Bid {
+		bid := spec.BidOption
+		v.supported(field+".bidOption.bidMode", bid.BidMode,
+			bid.BidMode == types.BidModeMarketPrice || bid.BidMode == types.BidModeCustomPrice)
+		if bid.BidMode == types.BidModeCustomPrice {
+			if price, err := strconv.ParseFloat(bid.BidPrice, 64); err != nil || price <= 0 {
+				v.addf(field+".bidOption.bidPrice", "%q is not a positive price", bid.BidPrice)
+			}
+		}
+	}
+	if spec.InstanceChargingType == bccapi.PaymentTimingPrePaid && spec.InstancePreChargingOption.PurchaseTime <= 0 {
+		v.addf(field+".instancePreChargingOption.purchaseTime", "should be greater than 0 for prepaid instance")
+	}
+	if spec.NeedEIP {
+		if spec.EIPOption == nil {
+			v.required(field+".eipOption", true)
+		} else {
+			if spec.EIPOption.EIPBandwidth <= 0 {
+				v.addf(field+".eipOption.eipBandwidth", "should be greater than 0")
+			}
+			charging := spec.EIPOption.EIPChargingType
+			if len(charging) != 0 {
+				v.supported(field+".eipOption.eipChargeType", charging,
+					charging == types.BillingMethodByTraffic || charging == types.BillingMethodByBandwidth)
+			}
+		}
+	}
+
+	vf := field + ".vpcConfig"
+	if zone := spec.VPCConfig.AvailableZone; len(zone) != 0 {
+		_, ok := supportedAvailableZones[zone]
+		v.supported(vf+".availableZone", zone, ok)
+	}
+	if cluster == nil {
+		return
+	}
+	if len(spec.VPCConfig.VPCID) != 0 && len(cluster.VPCID) != 0 && spec.VPCConfig.VPCID != cluster.VPCID {
+		v.addf(vf+".vpcID", "%s is not the vpc %s of the cluster", spec.VPCConfig.VPCID, cluster.VPCID)
+	}
+	if len(spec.VPCConfig.VPCSubnetCIDR) == 0 {
+		return
+	}
+	network := cluster.ContainerNetworkConfig
+	subnets := v.parseCIDRs([][2]string{{vf + ".vpcSubnetCIDR", spec.VPCConfig.VPCSubnetCIDR}})
+	subnet, ok := subnets[vf+".vpcSubnetCIDR"]
+	if !ok {
+		return
+	}
+	if vpc, err := util.ParseIPv4Block(cluster.VPCCIDR); err == nil && !vpc.Contains(subnet) {
+		v.addf(vf+".vpcSubnetCIDR", "%s is not in the vpc %s of the cluster", subnet, vpc)
+	}
+	others := map[string]string{
+		"clusterPodCIDR":       network.ClusterPodCIDR,
+		"clusterIPServiceCIDR": network.ClusterIPServiceCIDR,
+	}
+	for name, cidr := range others {
+		if block, err := util.ParseIPv4Block(cidr); err == nil && block.Overlaps(subnet) {
+			v.addf(vf+".vpcSubnetCIDR", "%s overlaps %s %s of the cluster", subnet, name, block)
+		}
+	}
+}
+
+func (v *specValidator) validateRuntime(field string, runtime types.RuntimeType, version string) {
+	if len(runtime) != 0 {
+		_, ok := types.SupportedRuntimeType[runtime]
+		v.supported(field+".runtimeType", runtime, ok)
+	}
+	if len(version) != 0 && !runtimeVersionPattern.MatchString(version) {
+		v.addf(field+".runtimeVersion", "%q is not a valid version", version)
+	}
+}
+
+// parseCIDRs - 解析非空的 IPv4 网段, 每个元素为 {字段, 网段}
+func (v *specValidator) parseCIDRs(cidrs [][2]string) map[string]util.IPv4Block {
+	blocks := make(map[string]util.IPv4Block, len(cidrs))
+	for _, c := range cidrs {
+		if len(c[1]) == 0 {
+			continue
+		}
+		block, err := util.ParseIPv4Block(c[1])
+		if err != nil {
+			v.addf(c[0], "%v", err)
+			continue
+		}
+		blocks[c[0]] = block
+	}
+	return blocks
+}
+
+func (v *specValidator) checkOverlaps(blocks map[string]util.IPv4Block, names ...string) {
+	for i, name := range names {
+		for _, other := range names[i+1:] {
+			a, okA := blocks[name]
+			b, okB := blocks[other]
+			if okA && okB && a.Overlaps(b) {
+				v.addf(other, "%s overlaps %s %s", b, name, a)
+			}
+		}
+	}
+}
+
+func (v *specValidator) checkIPv6CIDRs(cidrs [][2]string) {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		if len(c[1]) == 0 {
+			continue
+		}
+		ip, ipNet, err := net.ParseCIDR(c[1])
+		if err != nil || ip.To4() != nil {
+			v.addf(c[0], "%q is not an IPv6 CIDR", c[1])
+			continue
+		}
+		nets[i] = ipNet
+	}
+	for i := 1; i < len(nets); i++ {
+		for j := i + 1; j < len(nets); j++ {
+			if nets[i] != nil && nets[j] != nil && (nets[i].Contains(nets[j].IP) || nets[j].Contains(nets[i].IP)) {
+				v.addf(cidrs[j][0], "%s overlaps %s %s", nets[j], cidrs[i][0], nets[i])
+			}
+		}
+	}
+}
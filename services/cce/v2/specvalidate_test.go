@@ -0,0 +1,114 @@
+package v2
+
+import (
+	"strings"
+	"testing"
+
+	bccapi "github.com/kougazhang/bce-sdk-go/services/bcc/api"
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+)
+
+func newTestCreateClusterRequest() *CreateClusterRequest {
+	return &CreateClusterRequest{
+		ClusterSpec: &types.ClusterSpec{
+			ClusterName: "test",
+			ClusterType: types.ClusterTypeNormal,
+			K8SVersion:  types.K8S_1_16_8,
+			RuntimeType: types.RuntimeTypeDocker,
+			VPCID:       "vpc-test",
+			VPCCIDR:     "192.168.0.0/16",
+			MasterConfig: types.MasterConfig{
+				MasterType: types.MasterTypeManaged,
+				ClusterHA:  types.ClusterHALow,
+			},
+			ContainerNetworkConfig: types.ContainerNetworkConfig{
+				Mode:                 types.ContainerNetworkModeKubenet,
+				LBServiceVPCSubnetID: "sbn-test",
+				ClusterPodCIDR:       "172.28.0.0/16",
+				ClusterIPServiceCIDR: "172.31.0.0/16",
+				MaxPodsPerNode:       64,
+			},
+		},
+		NodeSpecs: []*InstanceSet{{
+			Count: 1,
+			InstanceSpec: types.InstanceSpec{
+				ClusterRole:  types.ClusterRoleNode,
+				InstanceType: bccapi.InstanceTypeN3,
+				ImageID:      "m-test",
+				VPCConfig: types.VPCConfig{
+					VPCSubnetID:   "sbn-test",
+					VPCSubnetCIDR: "192.168.1.0/24",
+					AvailableZone: types.AvailableZoneA,
+				},
+				InstanceResource: types.InstanceResource{CPU: 4, MEM: 8},
+			},
+		}},
+	}
+}
+
+func TestValidateCreateClusterRequest(t *testing.T) {
+	if err := ValidateCreateClusterRequest(newTestCreateClusterRequest()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cases := []struct {
+		field  string
+		modify func(req *CreateClusterRequest)
+	}{
+		{"cluster.vpcID", func(req *CreateClusterRequest) { req.ClusterSpec.VPCID = "" }},
+		{"cluster.k8sVersion", func(req *CreateClusterRequest) { req.ClusterSpec.K8SVersion = "1.8.6" }},
+		{"cluster.containerNetworkConfig.clusterIPServiceCIDR", func(req *CreateClusterRequest) {
+			req.ClusterSpec.ContainerNetworkConfig.ClusterIPServiceCIDR = "172.28.128.0/17"
+		}},
+		{"cluster.containerNetworkConfig.clusterPodCIDR", func(req *CreateClusterRequest) {
+			req.ClusterSpec.ContainerNetworkConfig.ClusterPodCIDR = "8.8.0.0/16"
+		}},
+		{"cluster.containerNetworkConfig.eniVPCSubnetIDs", func(req *CreateClusterRequest) {
+			req.ClusterSpec.ContainerNetworkConfig.Mode = types.ContainerNetworkModeVPCCNI
+		}},
+		{"nodes[0].instanceSpec.instanceType", func(req *CreateClusterRequest) {
+			req.NodeSpecs[0].InstanceSpec.InstanceType = "unknown"
+		}},
+		{"nodes[0].instanceSpec.vpcConfig.vpcSubnetCIDR", func(req *CreateClusterRequest) {
+			req.NodeSpecs[0].InstanceSpec.VPCConfig.VPCSubnetCIDR = "10.0.0.0/24"
+		}},
+		{"nodes[0].instanceSpec.bidOption.bidPrice", func(req *CreateClusterRequest) {
+			req.NodeSpecs[0].InstanceSpec.Bid = true
+			req.NodeSpecs[0].InstanceSpec.BidOption.BidMode = types.BidModeCustomPrice
+		}},
+		{"nodes[0].count", func(req *CreateClusterRequest) { req.NodeSpecs[0].Count = 0 }},
+	}
+	for _, c := range cases {
+		req := newTestCreateClusterRequest()
+		c.modify(req)
+		err := ValidateCreateClusterRequest(req)
+		errs, ok := err.(SpecErrors)
+		if !ok || len(errs) == 0 {
+			t.Errorf("%s: expect SpecErrors, got %v", c.field, err)
+			continue
+		}
+		if !strings.HasPrefix(errs[0].Field, c.field) {
+			t.Errorf("%s: unexpected errors %v", c.field, errs)
+		}
+	}
+}
+
+func TestValidateInstanceGroupSpec(t *testing.T) {
+	cluster := newTestCreateClusterRequest().ClusterSpec
+	spec := &types.InstanceGroupSpec{
+		InstanceGroupName: "ig",
+		Replicas:          5,
+		InstanceTemplate: types.InstanceTemplate{
+			InstanceSpec: newTestCreateClusterRequest().NodeSpecs[0].InstanceSpec,
+		},
+		ClusterAutoscalerSpec: &types.ClusterAutoscalerSpec{Enabled: true, MinReplicas: 1, MaxReplicas: 3},
+	}
+	err := ValidateInstanceGroupSpec(spec, cluster)
+	if errs, ok := err.(SpecErrors); !ok || len(errs) != 1 || errs[0].Field != "replicas" {
+		t.Errorf("unexpected error %v", err)
+	}
+	spec.Replicas = 2
+	if err := ValidateInstanceGroupSpec(spec, cluster); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
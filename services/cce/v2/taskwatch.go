@@ -0,0 +1,411 @@
+package v2
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+const (
+	DefaultTaskPollInitialInterval  = 5 * time.Second
+	DefaultTaskPollMaxInterval      = time.Minute
+	DefaultTaskPollMultiplier       = 2.0
+	DefaultTaskMaxConsecutiveErrors = 5
+	DefaultTaskEventBufferSize      = 100
+)
+
+// TaskEventType - 任务事件类型
+type TaskEventType string
+
+const (
+	// TaskEventPhaseChanged 任务的 Phase 发生变化
+	TaskEventPhaseChanged TaskEventType = "PhaseChanged"
+
+	// TaskEventProcessChanged 任务的某个步骤(包括子步骤)的 Phase 发生变化
+	TaskEventProcessChanged TaskEventType = "ProcessChanged"
+
+	// TaskEventPollFailed 查询任务失败, 连续失败次数未超过上限时会继续查询
+	TaskEventPollFailed TaskEventType = "PollFailed"
+
+	// TaskEventSucceeded 任务执行成功, 为最后一个事件
+	TaskEventSucceeded TaskEventType = "Succeeded"
+
+	// TaskEventFailed 任务执行失败、超时或无法查询, 为最后一个事件
+	TaskEventFailed TaskEventType = "Failed"
+)
+
+// TaskEvent - 任务事件
+type TaskEvent struct {
+	Type   TaskEventType
+	TaskID string
+	Time   time.Time
+
+	// Process 为步骤的路径, 子步骤以 "/" 连接, 如 "CreateMachines/WaitMachinesReady"
+	Process string
+
+	// OldPhase 和 NewPhase 为任务或步骤变化前后的 Phase, 首次观察到时 OldPhase 为空
+	OldPhase string
+	NewPhase string
+
+	Message string
+	Err     error
+
+	// Task 为本次查询到的任务
+	Task *types.Task
+}
+
+// TaskResult - 任务的最终结果
+type TaskResult struct {
+	TaskID    string
+	Task      *types.Task
+	Succeeded bool
+
+	// FailedProcess 和 Message 为失败的步骤及其错误信息
+	FailedProcess string
+	Message       string
+
+	// Err 不为空表示任务没有成功: 任务失败、超时、查询失败或 TaskWatcher 已停止
+	Err error
+
+	// DroppedEvents 为因事件 channel 已满而丢弃的事件数
+	DroppedEvents int
+}
+
+// TaskWatcherOptions - TaskWatcher 的轮询参数, 零值字段使用对应的默认值
+type TaskWatcherOptions struct {
+	// 任务有变化时使用 InitialInterval, 否则每次乘以 Multiplier 直至 MaxInterval
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+
+	// Timeout 为单个任务的最长等待时间, 为 0 表示不限制
+	Timeout time.Duration
+
+	MaxConsecutiveErrors int
+	EventBufferSize      int
+}
+
+type taskGetter interface {
+	GetTask(args *GetTaskArgs) (*GetTaskResp, error)
+}
+
+// TaskWatcher - 在同一个轮询循环中跟踪多个任务的进度, 可并发使用
+type TaskWatcher struct {
+	getter  taskGetter
+	options TaskWatcherOptions
+
+	lock    sync.Mutex
+	tasks   map[string]*TaskWatch
+	running bool
+	stopped bool
+	wake    chan struct{}
+	stop    chan struct{}
+}
+
+// TaskWatch - 一个被跟踪的任务
+type TaskWatch struct {
+	args   GetTaskArgs
+	events chan TaskEvent
+	done   chan struct{}
+	result *TaskResult
+
+	deadline  time.Time
+	nextPoll  time.Time
+	interval  time.Duration
+	errors    int
+	phase     types.TaskPhase
+	processes map[string]types.TaskProcessPhase
+	dropped   int
+}
+
+// NewTaskWatcher - 创建 TaskWatcher, options 为 nil 时使用默认参数
+func (c *Client) NewTaskWatcher(options *TaskWatcherOptions) *TaskWatcher {
+	return newTaskWatcher(c, options)
+}
+
+func newTaskWatcher(getter taskGetter, options *TaskWatcherOptions) *TaskWatcher {
+	w := &TaskWatcher{
+		getter: getter,
+		tasks:  make(map[string]*TaskWatch),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	if options != nil {
+		w.options = *options
+	}
+	if w.options.InitialInterval <= 0 {
+		w.options.InitialInterval = DefaultTaskPollInitialInterval
+	}
+	if w.options.MaxInterval < w.options.InitialInterval {
+		w.options.MaxInterval = DefaultTaskPollMaxInterval
+		if w.options.MaxInterval < w.options.InitialInterval {
+			w.options.MaxInterval = w.options.InitialInterval
+		}
+	}
+	if w.options.Multiplier < 1 {
+		w.options.Multiplier = DefaultTaskPollMultiplier
+	}
+	if w.options.MaxConsecutiveErrors <= 0 {
+		w.options.MaxConsecutiveErrors = DefaultTaskMaxConsecutiveErrors
+	}
+	if w.options.EventBufferSize <= 0 {
+		w.options.EventBufferSize = DefaultTaskEventBufferSize
+	}
+	return w
+}
+
+// Watch - 开始跟踪任务, 同一个任务重复调用时返回同一个 TaskWatch
+func (w *TaskWatcher) Watch(args *GetTaskArgs) (*TaskWatch, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args is nil")
+	}
+	if args.TaskType == "" {
+		return nil, fmt.Errorf("taskType is not set")
+	}
+	if args.TaskID == "" {
+		return nil, fmt.Errorf("taskID is empty")
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return nil, fmt.Errorf("task watcher is stopped")
+	}
+	key := string(args.TaskType) + "/" + args.TaskID
+	if t, ok := w.tasks[key]; ok {
+		return t, nil
+	}
+	now := time.Now()
+	t := &TaskWatch{
+		args:      *args,
+		events:    make(chan TaskEvent, w.options.EventBufferSize),
+		done:      make(chan struct{}),
+		nextPoll:  now,
+		interval:  w.options.InitialInterval,
+		processes: make(map[string]types.TaskProcessPhase),
+	}
+	if w.options.Timeout > 0 {
+		t.deadline = now.Add(w.options.Timeout)
+	}
+	w.tasks[key] = t
+	if !w.running {
+		w.running = true
+		go w.loop()
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return t, nil
+}
+
+// Stop - 停止轮询, 尚未结束的任务以错误结束
+func (w *TaskWatcher) Stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stop)
+	for key, t := range w.tasks {
+		t.finish(&TaskResult{TaskID: t.args.TaskID, Err: fmt.Errorf("task watcher is stopped")}, time.Now())
+		delete(w.tasks, key)
+	}
+}
+
+// Events - 任务事件, 任务结束后关闭; channel 已满时新事件会被丢弃
+func (t *TaskWatch) Events() <-chan TaskEvent {
+	return t.events
+}
+
+// Done - 任务结束后关闭
+func (t *TaskWatch) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait - 等待任务结束并返回最终结果, 任务没有成功时 error 与 TaskResult.Err 相同
+func (t *TaskWatch) Wait() (*TaskResult, error) {
+	<-t.done
+	return t.result, t.result.Err
+}
+
+// WaitTask - 轮询任务直至结束, 返回最终结果
+func (c *Client) WaitTask(args *GetTaskArgs, options *TaskWatcherOptions) (*TaskResult, error) {
+	w := c.NewTaskWatcher(options)
+	defer w.Stop()
+	t, err := w.Watch(args)
+	if err != nil {
+		return nil, err
+	}
+	return t.Wait()
+}
+
+func (w *TaskWatcher) loop() {
+	for {
+		w.lock.Lock()
+		if len(w.tasks) == 0 || w.stopped {
+			w.running = false
+			w.lock.Unlock()
+			return
+		}
+		now := time.Now()
+		next := now.Add(w.options.MaxInterval)
+		due := make([]*TaskWatch, 0, len(w.tasks))
+		for _, t := range w.tasks {
+			if !t.nextPoll.After(now) {
+				due = append(due, t)
+			} else if t.nextPoll.Before(next) {
+				next = t.nextPoll
+			}
+		}
+		w.lock.Unlock()
+
+		if len(due) == 0 {
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-timer.C:
+			case <-w.wake:
+			case <-w.stop:
+			}
+			timer.Stop()
+			continue
+		}
+		for _, t := range due {
+			resp, err := w.getter.GetTask(&t.args)
+			w.lock.Lock()
+			if !w.stopped {
+				if w.update(t, resp, err, time.Now()) {
+					delete(w.tasks, string(t.args.TaskType)+"/"+t.args.TaskID)
+				}
+			}
+			w.lock.Unlock()
+		}
+	}
+}
+
+// update - 处理一次查询结果, 返回任务是否已结束
+func (w *TaskWatcher) update(t *TaskWatch, resp *GetTaskResp, err error, now time.Time) bool {
+	if err == nil && (resp == nil || resp.Task == nil) {
+		err = fmt.Errorf("task %s not found in response", t.args.TaskID)
+	}
+	if err != nil {
+		t.errors++
+		log.Warnf("get task %s failed %d times: %v", t.args.TaskID, t.errors, err)
+		if t.errors >= w.options.MaxConsecutiveErrors {
+			t.finish(&TaskResult{TaskID: t.args.TaskID, Err: err}, now)
+			return true
+		}
+		t.emit(TaskEvent{Type: TaskEventPollFailed, TaskID: t.args.TaskID, Time: now, Err: err})
+		return w.schedule(t, false, now)
+	}
+	t.errors = 0
+
+	task := resp.Task
+	changed := false
+	if task.Phase != t.phase {
+		t.emit(TaskEvent{Type: TaskEventPhaseChanged, TaskID: t.args.TaskID, Time: now,
+			OldPhase: string(t.phase), NewPhase: string(task.Phase), Message: task.ErrMessage, Task: task})
+		t.phase, changed = task.Phase, true
+	}
+	walkTaskProcesses("", task.TaskProcesses, func(path string, p *types.TaskProcess) {
+		if old, ok := t.processes[path]; !ok || old != p.Phase {
+			t.emit(TaskEvent{Type: TaskEventProcessChanged, TaskID: t.args.TaskID, Time: now, Process: path,
+				OldPhase: string(old), NewPhase: string(p.Phase), Message: p.ErrMessage, Task: task})
+			t.processes[path], changed = p.Phase, true
+		}
+	})
+
+	switch task.Phase {
+	case types.TaskPhaseDone:
+		t.finish(&TaskResult{TaskID: t.args.TaskID, Task: task, Succeeded: true}, now)
+		return true
+	case types.TaskPhaseAborted:
+		process, message := failedTaskProcess(task)
+		err := fmt.Errorf("task %s aborted", t.args.TaskID)
+		if len(process) != 0 {
+			err = fmt.Errorf("task %s aborted at %s: %s", t.args.TaskID, process, message)
+		} else if len(message) != 0 {
+			err = fmt.Errorf("task %s aborted: %s", t.args.TaskID, message)
+		}
+		t.finish(&TaskResult{TaskID: t.args.TaskID, Task: task, FailedProcess: process,
+			Message: message, Err: err}, now)
+		return true
+	}
+	return w.schedule(t, changed, now)
+}
+
+// schedule - 计算下一次查询的时间, 已超时时结束任务并返回 true
+func (w *TaskWatcher) schedule(t *TaskWatch, changed bool, now time.Time) bool {
+	if !t.deadline.IsZero() && !now.Before(t.deadline) {
+		t.finish(&TaskResult{TaskID: t.args.TaskID, Err: fmt.Errorf("wait task %s timeout after %v",
+			t.args.TaskID, w.options.Timeout)}, now)
+		return true
+	}
+	if changed {
+		t.interval = w.options.InitialInterval
+	} else {
+		t.interval = time.Duration(float64(t.interval) * w.options.Multiplier)
+		if t.interval > w.options.MaxInterval {
+			t.interval = w.options.MaxInterval
+		}
+	}
+	t.nextPoll = now.Add(t.interval)
+	if !t.deadline.IsZero() && t.nextPoll.After(t.deadline) {
+		t.nextPoll = t.deadline
+	}
+	return false
+}
+
+func (t *TaskWatch) emit(event TaskEvent) {
+	select {
+	case t.events <- event:
+	default:
+		t.dropped++
+	}
+}
+
+func (t *TaskWatch) finish(result *TaskResult, now time.Time) {
+	event := TaskEvent{Type: TaskEventSucceeded, TaskID: t.args.TaskID, Time: now, Process: result.FailedProcess,
+		NewPhase: string(t.phase), Message: result.Message, Err: result.Err, Task: result.Task}
+	if !result.Succeeded {
+		event.Type = TaskEventFailed
+	}
+	t.emit(event)
+	result.DroppedEvents = t.dropped
+	t.result = result
+	close(t.events)
+	close(t.done)
+}
+
+func walkTaskProcesses(prefix string, processes []types.TaskProcess, fn func(string, *types.TaskProcess)) {
+	for i := range processes {
+		path := processes[i].Name
+		if len(prefix) != 0 {
+			path = prefix + "/" + path
+		}
+		fn(path, &processes[i])
+		walkTaskProcesses(path, processes[i].SubProcesses, fn)
+	}
+}
+
+// failedTaskProcess - 返回最深一层失败的步骤及其错误信息, 没有失败的步骤时返回任务的错误信息
+func failedTaskProcess(task *types.Task) (string, string) {
+	process, message, depth := "", task.ErrMessage, -1
+	walkTaskProcesses("", task.TaskProcesses, func(path string, p *types.TaskProcess) {
+		if p.Phase != types.TaskProcessPhaseAborted && len(p.ErrMessage) == 0 {
+			return
+		}
+		if d := strings.Count(path, "/"); d > depth {
+			process, depth = path, d
+			if len(p.ErrMessage) != 0 {
+				message = p.ErrMessage
+			}
+		}
+	})
+	return process, message
+}
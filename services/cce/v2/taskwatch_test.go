@@ -0,0 +1,105 @@
+package v2
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/services/cce/v2/types"
+)
+
+type fakeTaskGetter struct {
+	lock  sync.Mutex
+	tasks map[string][]*types.Task
+	calls map[string]int
+}
+
+func (f *fakeTaskGetter) GetTask(args *GetTaskArgs) (*GetTaskResp, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	seq := f.tasks[args.TaskID]
+	i := f.calls[args.TaskID]
+	f.calls[args.TaskID]++
+	if i >= len(seq) {
+		i = len(seq) - 1
+	}
+	if seq[i] == nil {
+		return nil, fmt.Errorf("internal error")
+	}
+	return &GetTaskResp{Task: seq[i]}, nil
+}
+
+func newTestTask(id string, phase types.TaskPhase, processes ...types.TaskProcess) *types.Task {
+	return &types.Task{ID: id, Phase: phase, TaskProcesses: processes}
+}
+
+func TestTaskWatcher(t *testing.T) {
+	getter := &fakeTaskGetter{calls: map[string]int{}, tasks: map[string][]*types.Task{
+		"task-ok": {
+			newTestTask("task-ok", types.TaskPhasePending),
+			nil,
+			newTestTask("task-ok", types.TaskPhaseProcessing,
+				types.TaskProcess{Name: "CreateMachines", Phase: types.TaskProcessPhaseProcessing}),
+			newTestTask("task-ok", types.TaskPhaseDone,
+				types.TaskProcess{Name: "CreateMachines", Phase: types.TaskProcessPhaseDone}),
+		},
+		"task-failed": {
+			newTestTask("task-failed", types.TaskPhaseProcessing),
+			newTestTask("task-failed", types.TaskPhaseAborted, types.TaskProcess{
+				Name: "CreateMachines", Phase: types.TaskProcessPhaseAborted, ErrMessage: "create failed",
+				SubProcesses: []types.TaskProcess{{Name: "CreateBCC", Phase: types.TaskProcessPhaseAborted,
+					ErrMessage: "quota exceeded"}},
+			}),
+		},
+	}}
+	w := newTaskWatcher(getter, &TaskWatcherOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+	})
+	defer w.Stop()
+
+	ok, err := w.Watch(&GetTaskArgs{TaskType: types.TaskTypeInstanceGroupReplicas, TaskID: "task-ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := w.Watch(&GetTaskArgs{TaskType: types.TaskTypeInstanceGroupReplicas, TaskID: "task-failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ok.Wait()
+	if err != nil || !result.Succeeded {
+		t.Errorf("unexpected result %+v %v", result, err)
+	}
+	var eventTypes []TaskEventType
+	for event := range ok.Events() {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	expected := []TaskEventType{TaskEventPhaseChanged, TaskEventPollFailed, TaskEventPhaseChanged,
+		TaskEventProcessChanged, TaskEventPhaseChanged, TaskEventProcessChanged, TaskEventSucceeded}
+	if fmt.Sprint(eventTypes) != fmt.Sprint(expected) {
+		t.Errorf("expect events %v, got %v", expected, eventTypes)
+	}
+
+	result, err = failed.Wait()
+	if err == nil || result.Succeeded || result.FailedProcess != "CreateMachines/CreateBCC" ||
+		result.Message != "quota exceeded" {
+		t.Errorf("unexpected result %+v %v", result, err)
+	}
+}
+
+func TestTaskWatcherTimeout(t *testing.T) {
+	getter := &fakeTaskGetter{calls: map[string]int{}, tasks: map[string][]*types.Task{
+		"task": {newTestTask("task", types.TaskPhaseProcessing)},
+	}}
+	w := newTaskWatcher(getter, &TaskWatcherOptions{InitialInterval: time.Millisecond, Timeout: 20 * time.Millisecond})
+	defer w.Stop()
+	watch, err := w.Watch(&GetTaskArgs{TaskType: types.TaskTypeInstanceGroupReplicas, TaskID: "task"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := watch.Wait(); err == nil || result.Succeeded {
+		t.Errorf("expect timeout, got %+v", result)
+	}
+}
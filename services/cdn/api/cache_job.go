@@ -0,0 +1,312 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+const (
+	CacheJobPurge    = "purge"
+	CacheJobPrefetch = "prefetch"
+
+	PurgeTypeFile      = "file"
+	PurgeTypeDirectory = "directory"
+
+	CacheTaskStatusWaiting    = "waiting"
+	CacheTaskStatusInProgress = "in-progress"
+	CacheTaskStatusCompleted  = "completed"
+	CacheTaskStatusFailed     = "failed"
+
+	DefaultCacheJobBatchSize    = 100
+	DefaultCacheJobPollInterval = 10 * time.Second
+	DefaultCacheJobTimeout      = 30 * time.Minute
+)
+
+// ErrCacheQuotaExhausted is the error of the tasks not submitted for the exhausted quota
+var ErrCacheQuotaExhausted = errors.New("the quota of purge or prefetch is exhausted")
+
+// CacheJobArgs defined a struct for a job of purging and prefetching many URLs or directories
+type CacheJobArgs struct {
+	Purge    []PurgeTask
+	Prefetch []PrefetchTask
+
+	// BatchSize is the count of the tasks submitted in one request
+	BatchSize int
+
+	// WaitForQuota keeps the tasks queued until the quota is available again before Timeout,
+	// otherwise the tasks beyond the remaining quota fail with ErrCacheQuotaExhausted
+	WaitForQuota bool
+
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// CacheTaskResult defined a struct for the outcome of one URL or directory
+type CacheTaskResult struct {
+	Job      string // purge or prefetch
+	Url      string
+	Type     string // the purge type, "file" or "directory"
+	Id       string // the ID returned by Purge or Prefetch, empty if not submitted
+	Status   string
+	Progress int64
+	Error    error
+}
+
+// Done returns true if the task is completed or failed
+func (r *CacheTaskResult) Done() bool {
+	return r.Status == CacheTaskStatusCompleted || r.Status == CacheTaskStatusFailed || r.Error != nil
+}
+
+// CacheJobReport defined a struct for the per-URL outcomes of a cache job
+type CacheJobReport struct {
+	Results []CacheTaskResult
+}
+
+// Failed returns the tasks failed or not finished
+func (r *CacheJobReport) Failed() []CacheTaskResult {
+	result := []CacheTaskResult{}
+	for _, res := range r.Results {
+		if res.Status != CacheTaskStatusCompleted {
+			result = append(result, res)
+		}
+	}
+	return result
+}
+
+// cacheJobQueue is the queue of the tasks sharing one quota
+type cacheJobQueue struct {
+	job     string
+	pending []int // the indexes of the results
+}
+
+// RunCacheJob - purge and prefetch the tasks batch by batch within the quota, and wait until every
+// task is completed or failed
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - args: the tasks and the options of the job
+// RETURNS:
+//     - *CacheJobReport: the outcome of each task, the duplicated tasks are merged
+//     - error: nil if the job ran otherwise the specific error
+func RunCacheJob(cli bce.Client, args *CacheJobArgs) (*CacheJobReport, error) {
+	if args == nil {
+		return nil, errors.New("the args of the cache job is nil")
+	}
+	opts := *args
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultCacheJobBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultCacheJobPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultCacheJobTimeout
+	}
+
+	report := &CacheJobReport{}
+	files, dirs := &cacheJobQueue{job: CacheJobPurge}, &cacheJobQueue{job: CacheJobPurge}
+	prefetches := &cacheJobQueue{job: CacheJobPrefetch}
+	seen := map[string]bool{}
+	add := func(queue *cacheJobQueue, url, purgeType string) int {
+		key := queue.job + "|" + purgeType + "|" + url
+		if len(url) == 0 || seen[key] {
+			return -1
+		}
+		seen[key] = true
+		queue.pending = append(queue.pending, len(report.Results))
+		report.Results = append(report.Results, CacheTaskResult{Job: queue.job, Url: url,
+			Type: purgeType, Status: CacheTaskStatusWaiting})
+		return len(report.Results) - 1
+	}
+	for _, task := range opts.Purge {
+		if task.Type == PurgeTypeDirectory {
+			add(dirs, task.Url, PurgeTypeDirectory)
+		} else {
+			add(files, task.Url, PurgeTypeFile)
+		}
+	}
+	prefetchTasks := map[int]PrefetchTask{}
+	for _, task := range opts.Prefetch {
+		if index := add(prefetches, task.Url, ""); index >= 0 {
+			prefetchTasks[index] = task
+		}
+	}
+
+	deadline := time.Now().Add(opts.Timeout)
+	queues := []*cacheJobQueue{files, dirs, prefetches}
+	ids := map[string][]int{} // job|id -> indexes of the results
+	var quotaErr error
+	for {
+		pending := 0
+		for _, queue := range queues {
+			pending += len(queue.pending)
+		}
+		if pending != 0 {
+			// like a failed query of the status, a failed query of the quota is retried in the
+			// next round and the tasks are kept queued
+			quotaErr = submitCacheJob(cli, report, queues, ids, prefetchTasks, opts.BatchSize)
+		}
+
+		pending = 0
+		for _, queue := range queues {
+			pending += len(queue.pending)
+		}
+		if pending != 0 && quotaErr == nil && !opts.WaitForQuota {
+			for _, queue := range queues {
+				for _, index := range queue.pending {
+					report.Results[index].Error = ErrCacheQuotaExhausted
+				}
+				queue.pending = nil
+			}
+		}
+
+		pollCacheJob(cli, report, ids)
+		running := 0
+		for _, res := range report.Results {
+			if !res.Done() {
+				running++
+			}
+		}
+		if running == 0 {
+			return report, nil
+		}
+		if time.Now().Add(opts.PollInterval).After(deadline) {
+			for i := range report.Results {
+				res := &report.Results[i]
+				if res.Done() {
+					continue
+				}
+				if len(res.Id) == 0 && quotaErr != nil {
+					res.Error = quotaErr
+				} else if len(res.Id) == 0 {
+					res.Error = ErrCacheQuotaExhausted
+				} else {
+					res.Error = fmt.Errorf("wait for %s of %s timeout, current status %s", res.Job,
+						res.Url, res.Status)
+				}
+			}
+			return report, nil
+		}
+		time.Sleep(opts.PollInterval)
+	}
+}
+
+func submitCacheJob(cli bce.Client, report *CacheJobReport, queues []*cacheJobQueue,
+	ids map[string][]int, prefetchTasks map[int]PrefetchTask, batchSize int) error {
+	quota, err := GetQuota(cli)
+	if err != nil {
+		return err
+	}
+	// the purged files and the prefetched URLs share the URL quota
+	remains := map[*cacheJobQueue]*int64{}
+	urlRemain, dirRemain := quota.UrlRemain, quota.DirRemain
+	remains[queues[0]], remains[queues[1]], remains[queues[2]] = &urlRemain, &dirRemain, &urlRemain
+
+	for _, queue := range queues {
+		remain := remains[queue]
+		for len(queue.pending) != 0 && *remain > 0 {
+			n := batchSize
+			if int64(n) > *remain {
+				n = int(*remain)
+			}
+			if n > len(queue.pending) {
+				n = len(queue.pending)
+			}
+			batch := queue.pending[:n]
+			queue.pending = queue.pending[n:]
+			*remain -= int64(n)
+
+			var id string
+			var err error
+			if queue.job == CacheJobPurge {
+				tasks := make([]PurgeTask, 0, n)
+				for _, index := range batch {
+					res := &report.Results[index]
+					task := PurgeTask{Url: res.Url}
+					if res.Type == PurgeTypeDirectory {
+						task.Type = PurgeTypeDirectory
+					}
+					tasks = append(tasks, task)
+				}
+				var purgedId PurgedId
+				purgedId, err = Purge(cli, tasks)
+				id = string(purgedId)
+			} else {
+				tasks := make([]PrefetchTask, 0, n)
+				for _, index := range batch {
+					tasks = append(tasks, prefetchTasks[index])
+				}
+				var prefetchId PrefetchId
+				prefetchId, err = Prefetch(cli, tasks)
+				id = string(prefetchId)
+			}
+			for _, index := range batch {
+				res := &report.Results[index]
+				if err != nil {
+					res.Error = err
+					continue
+				}
+				res.Id = id
+				res.Status = CacheTaskStatusInProgress
+			}
+			if err == nil {
+				key := queue.job + "|" + id
+				ids[key] = append(ids[key], batch...)
+			}
+		}
+	}
+	return nil
+}
+
+func pollCacheJob(cli bce.Client, report *CacheJobReport, ids map[string][]int) {
+	for key, indexes := range ids {
+		byUrl := map[string]*CacheTaskResult{}
+		for _, index := range indexes {
+			if res := &report.Results[index]; !res.Done() {
+				byUrl[res.Url] = res
+			}
+		}
+		if len(byUrl) == 0 {
+			delete(ids, key)
+			continue
+		}
+
+		update := func(url string, detail *CachedDetail) {
+			res, ok := byUrl[url]
+			if !ok || detail == nil {
+				return
+			}
+			res.Status, res.Progress = detail.Status, detail.Progress
+		}
+		res := report.Results[indexes[0]]
+		query := &CStatusQueryData{Id: res.Id}
+		for {
+			var err error
+			var truncated bool
+			if res.Job == CacheJobPurge {
+				var status *PurgedStatus
+				if status, err = GetPurgedStatus(cli, query); err == nil {
+					for _, detail := range status.Details {
+						update(detail.Task.Url, detail.CachedDetail)
+					}
+					truncated, query.Marker = status.IsTruncated, status.NextMarker
+				}
+			} else {
+				var status *PrefetchStatus
+				if status, err = GetPrefetchStatus(cli, query); err == nil {
+					for _, detail := range status.Details {
+						update(detail.Task.Url, detail.CachedDetail)
+					}
+					truncated, query.Marker = status.IsTruncated, status.NextMarker
+				}
+			}
+			// a failed query is retried in the next round
+			if err != nil || !truncated || len(query.Marker) == 0 {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+// fakeCacheJob serves the purge, prefetch and quota APIs, a task is in progress at the first
+// query of its ID and finished afterwards
+type fakeCacheJob struct {
+	lock       sync.Mutex
+	quotas     []QuotaDetail
+	quotaFails int // the count of the failed quota queries before the quotas are served
+	batches    map[string][]PurgeTask
+	prefetch   map[string][]PrefetchTask
+	polls      map[string]int
+	failUrl    string
+	pageSize   int
+}
+
+func newFakeCacheJob(quotas ...QuotaDetail) *fakeCacheJob {
+	return &fakeCacheJob{quotas: quotas, batches: map[string][]PurgeTask{},
+		prefetch: map[string][]PrefetchTask{}, polls: map[string]int{}, pageSize: 30}
+}
+
+func (f *fakeCacheJob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	query := r.URL.Query()
+	switch {
+	case r.URL.Path == "/v2/cache/quota" && f.quotaFails > 0:
+		f.quotaFails--
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"code":"InternalError","message":"quota unavailable"}`)
+	case r.URL.Path == "/v2/cache/quota":
+		q := f.quotas[0]
+		if len(f.quotas) > 1 {
+			f.quotas = f.quotas[1:]
+		}
+		json.NewEncoder(w).Encode(&q)
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/cache/purge":
+		args := &struct {
+			Tasks []PurgeTask `json:"tasks"`
+		}{}
+		json.Unmarshal(body, args)
+		id := fmt.Sprintf("purge-%d", len(f.batches))
+		f.batches[id] = args.Tasks
+		fmt.Fprintf(w, `{"id":%q}`, id)
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/cache/prefetch":
+		args := &struct {
+			Tasks []PrefetchTask `json:"tasks"`
+		}{}
+		json.Unmarshal(body, args)
+		id := fmt.Sprintf("prefetch-%d", len(f.prefetch))
+		f.prefetch[id] = args.Tasks
+		fmt.Fprintf(w, `{"id":%q}`, id)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/cache/purge":
+		status := &PurgedStatus{}
+		urls := []string{}
+		for _, task := range f.batches[query.Get("id")] {
+			urls = append(urls, task.Url)
+		}
+		details, marker := f.page(query.Get("id"), query.Get("marker"), urls)
+		for _, d := range details {
+			status.Details = append(status.Details, PurgedDetail{CachedDetail: d.detail,
+				Task: PurgeTask{Url: d.url}})
+		}
+		status.IsTruncated, status.NextMarker = len(marker) != 0, marker
+		json.NewEncoder(w).Encode(status)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/cache/prefetch":
+		status := &PrefetchStatus{}
+		urls := []string{}
+		for _, task := range f.prefetch[query.Get("id")] {
+			urls = append(urls, task.Url)
+		}
+		details, marker := f.page(query.Get("id"), query.Get("marker"), urls)
+		for _, d := range details {
+			status.Details = append(status.Details, PrefetchDetail{CachedDetail: d.detail,
+				Task: PrefetchTask{Url: d.url}})
+		}
+		status.IsTruncated, status.NextMarker = len(marker) != 0, marker
+		json.NewEncoder(w).Encode(status)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newCacheJobTestClient(t *testing.T, f *fakeCacheJob) (*bce.BceClient, func()) {
+	server := httptest.NewServer(f)
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+type fakeCacheDetail struct {
+	url    string
+	detail *CachedDetail
+}
+
+func (f *fakeCacheJob) page(id, marker string, urls []string) ([]fakeCacheDetail, string) {
+	start := 0
+	if len(marker) != 0 {
+		fmt.Sscanf(marker, "%d", &start)
+	} else {
+		f.polls[id]++
+	}
+	end, next := start+f.pageSize, ""
+	if end < len(urls) {
+		next = fmt.Sprintf("%d", end)
+	} else {
+		end = len(urls)
+	}
+	result := []fakeCacheDetail{}
+	for _, url := range urls[start:end] {
+		status := CacheTaskStatusInProgress
+		if f.polls[id] > 1 {
+			status = CacheTaskStatusCompleted
+			if url == f.failUrl {
+				status = CacheTaskStatusFailed
+			}
+		}
+		result = append(result, fakeCacheDetail{url, &CachedDetail{Status: status}})
+	}
+	return result, next
+}
+
+func TestRunCacheJob(t *testing.T) {
+	f := newFakeCacheJob(QuotaDetail{UrlRemain: 220, DirRemain: 1})
+	f.failUrl = "http://a.com/7"
+	client, clean := newCacheJobTestClient(t, f)
+	defer clean()
+	args := &CacheJobArgs{BatchSize: 100, PollInterval: 10 * time.Millisecond}
+	for i := 0; i < 200; i++ {
+		args.Purge = append(args.Purge, PurgeTask{Url: fmt.Sprintf("http://a.com/%d", i)})
+	}
+	args.Purge = append(args.Purge, PurgeTask{Url: "http://a.com/1"}, // duplicated
+		PurgeTask{Url: "http://a.com/dir1/", Type: PurgeTypeDirectory},
+		PurgeTask{Url: "http://a.com/dir2/", Type: PurgeTypeDirectory})
+	for i := 0; i < 30; i++ {
+		args.Prefetch = append(args.Prefetch, PrefetchTask{Url: fmt.Sprintf("http://b.com/%d", i)})
+	}
+
+	report, err := RunCacheJob(client, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 232 {
+		t.Fatalf("expect 232 results, got %d", len(report.Results))
+	}
+	if len(f.batches) != 3 || len(f.batches["purge-0"]) != 100 || len(f.batches["purge-2"]) != 1 {
+		t.Errorf("unexpected purge batches %d", len(f.batches))
+	}
+	if len(f.prefetch) != 1 || len(f.prefetch["prefetch-0"]) != 20 {
+		t.Errorf("expect 20 prefetch tasks within the URL quota, got %d", len(f.prefetch["prefetch-0"]))
+	}
+
+	completed, failed, exhausted := 0, 0, 0
+	for _, res := range report.Results {
+		switch {
+		case res.Error == ErrCacheQuotaExhausted:
+			exhausted++
+		case res.Status == CacheTaskStatusCompleted:
+			completed++
+		case res.Status == CacheTaskStatusFailed && res.Url == f.failUrl:
+			failed++
+		default:
+			t.Errorf("unexpected result %+v", res)
+		}
+	}
+	if completed != 220 || failed != 1 || exhausted != 11 {
+		t.Errorf("expect 220 completed, 1 failed, 11 exhausted, got %d %d %d", completed, failed,
+			exhausted)
+	}
+	if len(report.Failed()) != 12 {
+		t.Errorf("expect 12 failed, got %d", len(report.Failed()))
+	}
+}
+
+func TestRunCacheJobWaitForQuota(t *testing.T) {
+	f := newFakeCacheJob(QuotaDetail{UrlRemain: 5}, QuotaDetail{}, QuotaDetail{UrlRemain: 100})
+	client, clean := newCacheJobTestClient(t, f)
+	defer clean()
+	args := &CacheJobArgs{WaitForQuota: true, PollInterval: 10 * time.Millisecond,
+		Timeout: 5 * time.Second}
+	for i := 0; i < 10; i++ {
+		args.Purge = append(args.Purge, PurgeTask{Url: fmt.Sprintf("http://a.com/%d", i)})
+	}
+	report, err := RunCacheJob(client, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed()) != 0 || len(f.batches) != 2 {
+		t.Errorf("expect all completed in 2 batches, got %v in %d", report.Failed(), len(f.batches))
+	}
+
+	f = newFakeCacheJob(QuotaDetail{})
+	client, clean = newCacheJobTestClient(t, f)
+	defer clean()
+	args.Timeout = 100 * time.Millisecond
+	report, _ = RunCacheJob(client, args)
+	if failed := report.Failed(); len(failed) != 10 || failed[0].Error != ErrCacheQuotaExhausted {
+		t.Errorf("expect all exhausted at timeout, got %v", failed)
+	}
+}
+
+func TestRunCacheJobQuotaFailure(t *testing.T) {
+	// the failed quota queries are retried even without waiting for the quota
+	f := newFakeCacheJob(QuotaDetail{UrlRemain: 100})
+	f.quotaFails = 2
+	client, clean := newCacheJobTestClient(t, f)
+	defer clean()
+	args := &CacheJobArgs{PollInterval: 10 * time.Millisecond, Timeout: 5 * time.Second,
+		Purge: []PurgeTask{{Url: "http://a.com/1"}, {Url: "http://a.com/2"}}}
+	report, err := RunCacheJob(client, args)
+	if err != nil || len(report.Failed()) != 0 || len(f.batches) != 1 {
+		t.Errorf("expect all completed after the failures, got %v %v in %d", report.Failed(), err,
+			len(f.batches))
+	}
+
+	// the tasks never submitted report the error of the quota at timeout
+	f = newFakeCacheJob(QuotaDetail{UrlRemain: 100})
+	f.quotaFails = 1000
+	client, clean = newCacheJobTestClient(t, f)
+	defer clean()
+	args.Timeout = 100 * time.Millisecond
+	report, err = RunCacheJob(client, args)
+	failed := report.Failed()
+	if err != nil || len(failed) != 2 || len(f.batches) != 0 {
+		t.Fatalf("expect all failed without submitting, got %v %v", failed, err)
+	}
+	if realErr, ok := failed[0].Error.(*bce.BceServiceError); !ok || realErr.Code != "InternalError" {
+		t.Errorf("expect the error of the quota, got %v", failed[0].Error)
+	}
+}
@@ -0,0 +1,551 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+// DomainDocument defined a struct for the whole configuration of a domain, it can be exported from a
+// domain, kept in files and applied to the same or the other domains. A nil field is not managed by
+// the document, an empty list means clearing the setting. The TLS versions are left out as they can
+// not be read back, comparing them would report a change and set them again on every apply, so use
+// SetTlsVersions for them.
+type DomainDocument struct {
+	Domain string `json:"domain,omitempty"`
+
+	Origin         []OriginPeer `json:"origin"`
+	DefaultHost    string       `json:"defaultHost,omitempty"`
+	OriginProtocol *string      `json:"originProtocol,omitempty"`
+	FollowProtocol *bool        `json:"followProtocol,omitempty"`
+	RetryOrigin    *RetryOrigin `json:"retryOrigin,omitempty"`
+	Seo            *SeoSwitch   `json:"seoSwitch,omitempty"`
+
+	CacheTTL     []CacheTTL    `json:"cacheTTL"`
+	CacheUrlArgs *CacheUrlArgs `json:"cacheUrlArgs,omitempty"`
+	CacheShared  *CacheShared  `json:"cacheShared,omitempty"`
+	ErrorPage    []ErrorPage   `json:"errorPage"`
+	HttpHeader   []HttpHeader  `json:"httpHeader"`
+	Cors         *CorsCfg      `json:"cors,omitempty"`
+
+	Https       *HTTPSConfig `json:"https,omitempty"`
+	OCSP        *bool        `json:"ocsp,omitempty"`
+	RequestAuth *RequestAuth `json:"requestAuth,omitempty"`
+	RefererACL  *RefererACL  `json:"refererACL,omitempty"`
+	IpACL       *IpACL       `json:"ipACL,omitempty"`
+	UaACL       *UaACL       `json:"uaACL,omitempty"`
+	AccessLimit *AccessLimit `json:"accessLimit,omitempty"`
+	ClientIp    *ClientIp    `json:"clientIp,omitempty"`
+
+	LimitRate       *int           `json:"limitRate,omitempty"`
+	TrafficLimit    *TrafficLimit  `json:"trafficLimit,omitempty"`
+	MediaDrag       *MediaDragConf `json:"mediaDragConf,omitempty"`
+	FileTrim        *bool          `json:"fileTrim,omitempty"`
+	RangeSwitch     *bool          `json:"rangeSwitch,omitempty"`
+	ContentEncoding *string        `json:"contentEncoding,omitempty"` // empty means disabled
+	MobileAccess    *bool          `json:"mobileAccess,omitempty"`
+	IPv6            *bool          `json:"ipv6,omitempty"`
+	QUIC            *bool          `json:"quic,omitempty"`
+	OfflineMode     *bool          `json:"offlineMode,omitempty"`
+}
+
+// DomainConfigChange defined a struct for a setting to change when applying a DomainDocument,
+// Live is nil if the setting is not in the live document
+type DomainConfigChange struct {
+	Item    string      `json:"item"`
+	Live    interface{} `json:"live"`
+	Desired interface{} `json:"desired"`
+}
+
+func (c DomainConfigChange) String() string {
+	live, _ := json.Marshal(c.Live)
+	desired, _ := json.Marshal(c.Desired)
+	return fmt.Sprintf("%s: %s -> %s", c.Item, live, desired)
+}
+
+// domainDocumentItem is one setting of the document, the items are applied in the order of
+// domainDocumentItems so that the origin and HTTPS are ready before the settings depending on them
+type domainDocumentItem struct {
+	name  string
+	value func(doc *DomainDocument) interface{}
+	get   func(cli bce.Client, domain string, config *DomainConfig, doc *DomainDocument) error
+	set   func(cli bce.Client, domain string, doc *DomainDocument) error
+}
+
+type documentOrigin struct {
+	Origin      []OriginPeer `json:"origin"`
+	DefaultHost string       `json:"defaultHost,omitempty"`
+}
+
+var domainDocumentItems = []domainDocumentItem{
+	{
+		name: "origin",
+		value: func(doc *DomainDocument) interface{} {
+			if doc.Origin == nil {
+				return nil
+			}
+			return &documentOrigin{doc.Origin, doc.DefaultHost}
+		},
+		get: func(_ bce.Client, _ string, config *DomainConfig, doc *DomainDocument) error {
+			doc.Origin, doc.DefaultHost = nonNilList(config.Origin).([]OriginPeer), config.DefaultHost
+			return nil
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetDomainOrigin(cli, domain, doc.Origin, doc.DefaultHost)
+		},
+	},
+	{
+		name:  "originProtocol",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.OriginProtocol) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetOriginProtocol(cli, domain)
+			doc.OriginProtocol = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetOriginProtocol(cli, domain, *doc.OriginProtocol)
+		},
+	},
+	{
+		name:  "followProtocol",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.FollowProtocol) },
+		get: func(_ bce.Client, _ string, config *DomainConfig, doc *DomainDocument) error {
+			doc.FollowProtocol = &config.FollowProtocol
+			return nil
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetFollowProtocol(cli, domain, *doc.FollowProtocol)
+		},
+	},
+	{
+		name:  "retryOrigin",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.RetryOrigin) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.RetryOrigin, err = GetRetryOrigin(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetRetryOrigin(cli, domain, doc.RetryOrigin)
+		},
+	},
+	{
+		name:  "seoSwitch",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.Seo) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.Seo, err = GetDomainSeo(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetDomainSeo(cli, domain, doc.Seo)
+		},
+	},
+	{
+		name:  "https",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.Https) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.Https, err = GetDomainHttps(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetDomainHttps(cli, domain, doc.Https)
+		},
+	},
+	{
+		name:  "ocsp",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.OCSP) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetOCSP(cli, domain)
+			doc.OCSP = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetOCSP(cli, domain, *doc.OCSP)
+		},
+	},
+	{
+		name:  "cacheTTL",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.CacheTTL) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetCacheTTL(cli, domain)
+			doc.CacheTTL = nonNilList(value).([]CacheTTL)
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetCacheTTL(cli, domain, doc.CacheTTL)
+		},
+	},
+	{
+		name:  "cacheUrlArgs",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.CacheUrlArgs) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.CacheUrlArgs, err = GetCacheUrlArgs(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetCacheUrlArgs(cli, domain, doc.CacheUrlArgs)
+		},
+	},
+	{
+		name:  "cacheShared",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.CacheShared) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.CacheShared, err = GetCacheShared(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetCacheShared(cli, domain, doc.CacheShared)
+		},
+	},
+	{
+		name:  "errorPage",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.ErrorPage) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetErrorPage(cli, domain)
+			doc.ErrorPage = nonNilList(value).([]ErrorPage)
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetErrorPage(cli, domain, doc.ErrorPage)
+		},
+	},
+	{
+		name:  "httpHeader",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.HttpHeader) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetHttpHeader(cli, domain)
+			doc.HttpHeader = nonNilList(value).([]HttpHeader)
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetHttpHeader(cli, domain, doc.HttpHeader)
+		},
+	},
+	{
+		name:  "cors",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.Cors) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.Cors, err = GetCors(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetCors(cli, domain, doc.Cors.IsAllow, doc.Cors.Origins)
+		},
+	},
+	{
+		name:  "requestAuth",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.RequestAuth) },
+		get: func(_ bce.Client, _ string, config *DomainConfig, doc *DomainDocument) error {
+			doc.RequestAuth = config.RequestAuth
+			return nil
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetDomainRequestAuth(cli, domain, doc.RequestAuth)
+		},
+	},
+	{
+		name:  "refererACL",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.RefererACL) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.RefererACL, err = GetRefererACL(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			blackList, whiteList := aclLists(doc.RefererACL.BlackList, doc.RefererACL.WhiteList)
+			return SetRefererACL(cli, domain, blackList, whiteList, doc.RefererACL.AllowEmpty)
+		},
+	},
+	{
+		name:  "ipACL",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.IpACL) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.IpACL, err = GetIpACL(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			blackList, whiteList := aclLists(doc.IpACL.BlackList, doc.IpACL.WhiteList)
+			return SetIpACL(cli, domain, blackList, whiteList)
+		},
+	},
+	{
+		name:  "uaACL",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.UaACL) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.UaACL, err = GetUaACL(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			blackList, whiteList := aclLists(doc.UaACL.BlackList, doc.UaACL.WhiteList)
+			return SetUaACL(cli, domain, blackList, whiteList)
+		},
+	},
+	{
+		name:  "accessLimit",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.AccessLimit) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.AccessLimit, err = GetAccessLimit(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetAccessLimit(cli, domain, doc.AccessLimit)
+		},
+	},
+	{
+		name:  "clientIp",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.ClientIp) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.ClientIp, err = GetClientIp(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetClientIp(cli, domain, doc.ClientIp)
+		},
+	},
+	{
+		name:  "limitRate",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.LimitRate) },
+		get: func(_ bce.Client, _ string, config *DomainConfig, doc *DomainDocument) error {
+			doc.LimitRate = &config.LimitRate
+			return nil
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetLimitRate(cli, domain, *doc.LimitRate)
+		},
+	},
+	{
+		name:  "trafficLimit",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.TrafficLimit) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.TrafficLimit, err = GetTrafficLimit(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetTrafficLimit(cli, domain, doc.TrafficLimit)
+		},
+	},
+	{
+		name:  "mediaDragConf",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.MediaDrag) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) (err error) {
+			doc.MediaDrag, err = GetMediaDrag(cli, domain)
+			return
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetMediaDrag(cli, domain, doc.MediaDrag)
+		},
+	},
+	{
+		name:  "fileTrim",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.FileTrim) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetFileTrim(cli, domain)
+			doc.FileTrim = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetFileTrim(cli, domain, *doc.FileTrim)
+		},
+	},
+	{
+		name:  "rangeSwitch",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.RangeSwitch) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetRangeSwitch(cli, domain)
+			doc.RangeSwitch = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetRangeSwitch(cli, domain, *doc.RangeSwitch)
+		},
+	},
+	{
+		name:  "contentEncoding",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.ContentEncoding) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetContentEncoding(cli, domain)
+			doc.ContentEncoding = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			encoding := *doc.ContentEncoding
+			return SetContentEncoding(cli, domain, len(encoding) != 0, encoding)
+		},
+	},
+	{
+		name:  "mobileAccess",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.MobileAccess) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetMobileAccess(cli, domain)
+			doc.MobileAccess = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetMobileAccess(cli, domain, *doc.MobileAccess)
+		},
+	},
+	{
+		name:  "ipv6",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.IPv6) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetIPv6(cli, domain)
+			doc.IPv6 = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetIPv6(cli, domain, *doc.IPv6)
+		},
+	},
+	{
+		name:  "quic",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.QUIC) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetQUIC(cli, domain)
+			doc.QUIC = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetQUIC(cli, domain, *doc.QUIC)
+		},
+	},
+	{
+		name:  "offlineMode",
+		value: func(doc *DomainDocument) interface{} { return documentValue(doc.OfflineMode) },
+		get: func(cli bce.Client, domain string, _ *DomainConfig, doc *DomainDocument) error {
+			value, err := GetOfflineMode(cli, domain)
+			doc.OfflineMode = &value
+			return err
+		},
+		set: func(cli bce.Client, domain string, doc *DomainDocument) error {
+			return SetOfflineMode(cli, domain, *doc.OfflineMode)
+		},
+	},
+}
+
+// documentValue returns nil for the nil pointers and slices, so that an unset field is not managed
+func documentValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Slice) && rv.IsNil() {
+		return nil
+	}
+	return v
+}
+
+// nonNilList makes an exported empty list be kept in the document as "[]" rather than "null"
+func nonNilList(list interface{}) interface{} {
+	rv := reflect.ValueOf(list)
+	if rv.IsNil() {
+		return reflect.MakeSlice(rv.Type(), 0, 0).Interface()
+	}
+	return list
+}
+
+// aclLists clears the ACL by an empty white list when both lists are empty
+func aclLists(blackList, whiteList []string) ([]string, []string) {
+	if blackList == nil && whiteList == nil {
+		return nil, []string{}
+	}
+	return blackList, whiteList
+}
+
+func documentValueEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	aJson, errA := json.Marshal(a)
+	bJson, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var aTree, bTree interface{}
+	if json.Unmarshal(aJson, &aTree) != nil || json.Unmarshal(bJson, &bTree) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aTree, bTree)
+}
+
+// ExportDomainDocument - get all the settings of the domain as a document
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - domain: the specified domain
+// RETURNS:
+//     - *DomainDocument: the whole configuration of the domain
+//     - error: nil if success otherwise the specific error
+func ExportDomainDocument(cli bce.Client, domain string) (*DomainDocument, error) {
+	config, err := GetDomainConfig(cli, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &DomainDocument{Domain: domain}
+	for _, item := range domainDocumentItems {
+		if err := item.get(cli, domain, config, doc); err != nil {
+			return nil, fmt.Errorf("get %s of %s: %v", item.name, domain, err)
+		}
+	}
+	return doc, nil
+}
+
+// PlanDomainDocument - compare the desired document with the live one and list the settings to change,
+// only the settings set in the desired document are compared
+//
+// PARAMS:
+//     - live: the document exported from the domain
+//     - desired: the desired document
+// RETURNS:
+//     - []DomainConfigChange: the settings to change in the order of applying
+func PlanDomainDocument(live, desired *DomainDocument) []DomainConfigChange {
+	if live == nil {
+		live = &DomainDocument{}
+	}
+	changes := []DomainConfigChange{}
+	for _, item := range domainDocumentItems {
+		desiredValue := item.value(desired)
+		if desiredValue == nil {
+			continue
+		}
+		liveValue := item.value(live)
+		if documentValueEqual(liveValue, desiredValue) {
+			continue
+		}
+		changes = append(changes, DomainConfigChange{Item: item.name, Live: liveValue, Desired: desiredValue})
+	}
+	return changes
+}
+
+// ApplyDomainDocument - apply the desired document to the domain by calling only the setters of the
+// changed settings
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - domain: the specified domain, it may differ from the Domain of the document for cloning
+//     - desired: the desired document
+//     - dryRun: true means only planning the changes without applying
+// RETURNS:
+//     - []DomainConfigChange: the planned changes, or the applied ones if failed
+//     - error: nil if success otherwise the specific error
+func ApplyDomainDocument(cli bce.Client, domain string, desired *DomainDocument, dryRun bool) ([]DomainConfigChange, error) {
+	if desired == nil {
+		return nil, fmt.Errorf("the desired document is nil")
+	}
+	live, err := ExportDomainDocument(cli, domain)
+	if err != nil {
+		return nil, err
+	}
+	changes := PlanDomainDocument(live, desired)
+	if dryRun {
+		return changes, nil
+	}
+
+	items := map[string]*domainDocumentItem{}
+	for i := range domainDocumentItems {
+		items[domainDocumentItems[i].name] = &domainDocumentItems[i]
+	}
+	for i, change := range changes {
+		if err := items[change.Item].set(cli, domain, desired); err != nil {
+			return changes[:i], fmt.Errorf("set %s of %s: %v", change.Item, domain, err)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+func TestPlanDomainDocument(t *testing.T) {
+	on, off := true, false
+	gzip := "gzip"
+	live := &DomainDocument{
+		Origin:          []OriginPeer{{Peer: "1.2.3.4"}},
+		CacheTTL:        []CacheTTL{},
+		Https:           &HTTPSConfig{Enabled: true, CertId: "cert-1"},
+		QUIC:            &off,
+		ContentEncoding: &gzip,
+		RefererACL:      &RefererACL{WhiteList: []string{"a.com"}},
+	}
+
+	// the document is kept in files, so it must survive a round trip
+	data, err := json.Marshal(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := &DomainDocument{}
+	if err := json.Unmarshal(data, exported); err != nil {
+		t.Fatal(err)
+	}
+	if changes := PlanDomainDocument(live, exported); len(changes) != 0 {
+		t.Errorf("expect no change of the exported document, got %v", changes)
+	}
+	if exported.CacheTTL == nil || exported.ErrorPage != nil {
+		t.Errorf("expect empty list kept and nil list unmanaged")
+	}
+
+	desired := &DomainDocument{
+		Origin:     []OriginPeer{{Peer: "1.2.3.4"}},
+		CacheTTL:   []CacheTTL{{Type: "suffix", Value: ".jpg", TTL: 3600}},
+		Https:      &HTTPSConfig{Enabled: true, CertId: "cert-2"},
+		QUIC:       &on,
+		RefererACL: &RefererACL{WhiteList: []string{"a.com"}},
+	}
+	changes := PlanDomainDocument(live, desired)
+	expected := []string{"https", "cacheTTL", "quic"}
+	if len(changes) != len(expected) {
+		t.Fatalf("expect %v, got %v", expected, changes)
+	}
+	for i, item := range expected {
+		if changes[i].Item != item {
+			t.Errorf("change %d: expect %s, got %s", i, item, changes[i].Item)
+		}
+	}
+	if s := changes[2].String(); s != "quic: false -> true" {
+		t.Errorf("unexpected change string %q", s)
+	}
+}
+
+// fakeDomainConfig serves the domain config APIs, a setting reads back the body last set
+type fakeDomainConfig struct {
+	lock     sync.Mutex
+	config   string
+	settings map[string]string
+	fail     string // the setting failing to set
+	calls    []string
+}
+
+func (s *fakeDomainConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := ""
+	for k := range r.URL.Query() {
+		key = k
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		fmt.Fprint(w, s.config)
+	case r.Method == http.MethodGet:
+		if body, ok := s.settings[key]; ok {
+			fmt.Fprint(w, body)
+			return
+		}
+		fmt.Fprint(w, "{}")
+	case r.Method == http.MethodPut && key == s.fail:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":"InvalidArgument","message":"bad setting"}`)
+	case r.Method == http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		s.settings[key] = string(body)
+		s.calls = append(s.calls, key)
+		fmt.Fprint(w, "{}")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newDomainDocumentTestClient(t *testing.T, s *fakeDomainConfig) (*bce.BceClient, func()) {
+	server := httptest.NewServer(s)
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func newFakeDomainConfig() *fakeDomainConfig {
+	return &fakeDomainConfig{
+		config: `{"domain":"a.com","origin":[{"peer":"1.2.3.4"}],"defaultHost":"a.com",` +
+			`"followProtocol":true,"limitRate":100}`,
+		settings: map[string]string{
+			"https":        `{"https":{"enabled":true,"certId":"cert-1"}}`,
+			"quic":         `{"quic":false}`,
+			"mobileAccess": `{"mobileAccess":{"distinguishClient":false}}`,
+		},
+	}
+}
+
+func TestExportDomainDocument(t *testing.T) {
+	s := newFakeDomainConfig()
+	client, clean := newDomainDocumentTestClient(t, s)
+	defer clean()
+
+	doc, err := ExportDomainDocument(client, "a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Domain != "a.com" || len(doc.Origin) != 1 || doc.Origin[0].Peer != "1.2.3.4" ||
+		doc.DefaultHost != "a.com" || !*doc.FollowProtocol || *doc.LimitRate != 100 {
+		t.Errorf("expect the settings of the domain config, got %+v", doc)
+	}
+	if doc.Https == nil || doc.Https.CertId != "cert-1" || *doc.QUIC {
+		t.Errorf("expect the settings read one by one, got %+v %v", doc.Https, *doc.QUIC)
+	}
+	if doc.CacheTTL == nil || len(doc.CacheTTL) != 0 {
+		t.Errorf("expect an empty list of cacheTTL, got %v", doc.CacheTTL)
+	}
+}
+
+func TestApplyDomainDocument(t *testing.T) {
+	s := newFakeDomainConfig()
+	client, clean := newDomainDocumentTestClient(t, s)
+	defer clean()
+
+	on := true
+	desired := &DomainDocument{
+		Origin:      []OriginPeer{{Peer: "1.2.3.4"}},
+		DefaultHost: "a.com",
+		CacheTTL:    []CacheTTL{{Type: "suffix", Value: ".jpg", TTL: 3600}},
+		Https:       &HTTPSConfig{Enabled: true, CertId: "cert-2"},
+		QUIC:        &on,
+	}
+	changes, err := ApplyDomainDocument(client, "a.com", desired, true)
+	if err != nil || len(changes) != 3 || len(s.calls) != 0 {
+		t.Fatalf("expect 3 changes planned by dry run, got %v %v, calls %v", changes, err, s.calls)
+	}
+
+	changes, err = ApplyDomainDocument(client, "a.com", desired, false)
+	if err != nil || len(changes) != 3 {
+		t.Fatalf("expect 3 changes applied, got %v %v", changes, err)
+	}
+	if strings.Join(s.calls, ",") != "https,cacheTTL,quic" {
+		t.Errorf("expect the changed settings set in order, got %v", s.calls)
+	}
+
+	// the applied settings are read back, so applying again changes nothing
+	s.calls = nil
+	changes, err = ApplyDomainDocument(client, "a.com", desired, false)
+	if err != nil || len(changes) != 0 || len(s.calls) != 0 {
+		t.Errorf("expect no change of the second apply, got %v %v, calls %v", changes, err, s.calls)
+	}
+}
+
+func TestApplyDomainDocumentPartialFailure(t *testing.T) {
+	s := newFakeDomainConfig()
+	s.fail = "quic"
+	client, clean := newDomainDocumentTestClient(t, s)
+	defer clean()
+
+	on := true
+	desired := &DomainDocument{
+		Https: &HTTPSConfig{Enabled: true, CertId: "cert-2"},
+		QUIC:  &on,
+	}
+	changes, err := ApplyDomainDocument(client, "a.com", desired, false)
+	if err == nil || !strings.Contains(err.Error(), "set quic of a.com") {
+		t.Errorf("expect error of setting quic, got %v", err)
+	}
+	if len(changes) != 1 || changes[0].Item != "https" || strings.Join(s.calls, ",") != "https" {
+		t.Errorf("expect only https applied, got %v, calls %v", changes, s.calls)
+	}
+}
@@ -0,0 +1,527 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+const (
+	DefaultLogWindow        = 24 * time.Hour
+	DefaultLogParallelism   = 4
+	DefaultLogRetries       = 3
+	DefaultLogRetryInterval = 2 * time.Second
+	DefaultLogReadTimeout   = time.Minute
+
+	accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// AccessLogRecord defined a struct for one line of the CDN access log
+type AccessLogRecord struct {
+	Domain      string    `json:"domain"`
+	Time        time.Time `json:"time"`
+	ClientIp    string    `json:"clientIp"`
+	Method      string    `json:"method"`
+	Url         string    `json:"url"`
+	Protocol    string    `json:"protocol"`
+	Status      int       `json:"status"`
+	Bytes       int64     `json:"bytes"`
+	Referer     string    `json:"referer"`
+	UserAgent   string    `json:"userAgent"`
+	CacheStatus string    `json:"cacheStatus,omitempty"` // such as "HIT" or "MISS", empty if not logged
+	CacheHit    bool      `json:"cacheHit"`
+	Extra       []string  `json:"extra,omitempty"` // the fields after the user agent
+}
+
+// LogPipelineArgs defined a struct for downloading and parsing the access logs of the domains
+type LogPipelineArgs struct {
+	Domains []string
+	Start   time.Time
+	End     time.Time
+
+	// Window is the time range of one log listing request, the period is split by it
+	Window time.Duration
+
+	Parallelism   int
+	Retries       int
+	RetryInterval time.Duration
+
+	// HTTPClient downloads the log files, a client with the timeouts of connecting and waiting for
+	// the response header if nil, its Timeout should be long enough to read the whole file
+	HTTPClient *http.Client
+
+	// ReadTimeout fails the download if no data is read in it, DefaultLogReadTimeout if 0
+	ReadTimeout time.Duration
+
+	// Parser parses one line of the log, ParseAccessLogLine if nil
+	Parser func(line string) (*AccessLogRecord, error)
+}
+
+// LogPipeline lists, downloads and parses the access log files of the domains
+type LogPipeline struct {
+	args     LogPipelineArgs
+	listLogs func(domain string, timeInterval TimeInterval) ([]LogEntry, error)
+}
+
+// NewLogPipeline - create a pipeline of the access logs
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - args: the domains, the period and the options of downloading
+// RETURNS:
+//     - *LogPipeline: the pipeline
+func NewLogPipeline(cli bce.Client, args *LogPipelineArgs) *LogPipeline {
+	return newLogPipeline(func(domain string, timeInterval TimeInterval) ([]LogEntry, error) {
+		return GetDomainLog(cli, domain, timeInterval)
+	}, args)
+}
+
+// defaultLogHTTPClient has no total timeout as the log files may be large, the stalled reading of
+// the body is failed by the ReadTimeout of the pipeline
+var defaultLogHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConnsPerHost:   DefaultLogParallelism,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+func newLogPipeline(listLogs func(string, TimeInterval) ([]LogEntry, error), args *LogPipelineArgs) *LogPipeline {
+	p := &LogPipeline{listLogs: listLogs}
+	if args != nil {
+		p.args = *args
+	}
+	if p.args.Window <= 0 {
+		p.args.Window = DefaultLogWindow
+	}
+	if p.args.Parallelism <= 0 {
+		p.args.Parallelism = DefaultLogParallelism
+	}
+	if p.args.Retries <= 0 {
+		p.args.Retries = DefaultLogRetries
+	}
+	if p.args.RetryInterval <= 0 {
+		p.args.RetryInterval = DefaultLogRetryInterval
+	}
+	if p.args.HTTPClient == nil {
+		p.args.HTTPClient = defaultLogHTTPClient
+	}
+	if p.args.ReadTimeout <= 0 {
+		p.args.ReadTimeout = DefaultLogReadTimeout
+	}
+	if p.args.Parser == nil {
+		p.args.Parser = ParseAccessLogLine
+	}
+	return p
+}
+
+// ListLogFiles - list the log files of all the domains in the period, the period is split into
+// windows and the files returned by the adjacent windows are merged
+//
+// RETURNS:
+//     - []LogEntry: the log files ordered by domain and time
+//     - error: nil if success otherwise the specific error
+func (p *LogPipeline) ListLogFiles() ([]LogEntry, error) {
+	if !p.args.Start.Before(p.args.End) {
+		return nil, errors.New("error time range, the start time should be less than the end time")
+	}
+	result := []LogEntry{}
+	for _, domain := range p.args.Domains {
+		seen := map[string]bool{}
+		for start := p.args.Start; start.Before(p.args.End); start = start.Add(p.args.Window) {
+			end := start.Add(p.args.Window)
+			if end.After(p.args.End) {
+				end = p.args.End
+			}
+			logs, err := p.listLogs(domain, TimeInterval{
+				StartTime: util.FormatISO8601Date(start.Unix()),
+				EndTime:   util.FormatISO8601Date(end.Unix()),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("list logs of %s: %v", domain, err)
+			}
+			for _, entry := range logs {
+				if entry.LogBase == nil || seen[entry.Name] {
+					continue
+				}
+				seen[entry.Name] = true
+				entry.Domain = domain
+				result = append(result, entry)
+			}
+		}
+	}
+	return result, nil
+}
+
+// AccessLogIterator iterates the records of the log files, the files are downloaded concurrently so
+// the records of different files are interleaved, while the records of one file keep their order
+type AccessLogIterator struct {
+	records chan *AccessLogRecord
+	done    chan struct{}
+	once    sync.Once
+	current *AccessLogRecord
+
+	mu      sync.Mutex
+	err     error
+	skipped int64
+}
+
+// Next moves to the next record, it returns false at the end or on error
+func (it *AccessLogIterator) Next() bool {
+	record, ok := <-it.records
+	it.current = record
+	return ok
+}
+
+// Record returns the current record
+func (it *AccessLogIterator) Record() *AccessLogRecord {
+	return it.current
+}
+
+// Err returns the first error of downloading the files
+func (it *AccessLogIterator) Err() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.err
+}
+
+// Skipped returns the count of the lines failed to parse
+func (it *AccessLogIterator) Skipped() int64 {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.skipped
+}
+
+// Close stops downloading, it must be called if the iteration stops before the end
+func (it *AccessLogIterator) Close() {
+	it.once.Do(func() { close(it.done) })
+	for range it.records {
+	}
+}
+
+func (it *AccessLogIterator) fail(err error) {
+	it.mu.Lock()
+	if it.err == nil {
+		it.err = err
+	}
+	it.mu.Unlock()
+	it.once.Do(func() { close(it.done) })
+}
+
+// Records - list the log files and iterate the parsed records of them
+//
+// RETURNS:
+//     - *AccessLogIterator: the iterator of the records
+//     - error: nil if the log files are listed otherwise the specific error
+func (p *LogPipeline) Records() (*AccessLogIterator, error) {
+	files, err := p.ListLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	return p.RecordsOf(files), nil
+}
+
+// RecordsOf - iterate the parsed records of the log files
+//
+// PARAMS:
+//     - files: the log files returned by ListLogFiles
+// RETURNS:
+//     - *AccessLogIterator: the iterator of the records
+func (p *LogPipeline) RecordsOf(files []LogEntry) *AccessLogIterator {
+	it := &AccessLogIterator{
+		records: make(chan *AccessLogRecord, 1024),
+		done:    make(chan struct{}),
+	}
+	jobs := make(chan LogEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < p.args.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				if err := p.readLogFile(it, file); err != nil {
+					it.fail(fmt.Errorf("read log %s of %s: %v", file.Name, file.Domain, err))
+				}
+			}
+		}()
+	}
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(it.records)
+		}()
+		for _, file := range files {
+			select {
+			case jobs <- file:
+			case <-it.done:
+				return
+			}
+		}
+	}()
+	return it
+}
+
+func (p *LogPipeline) readLogFile(it *AccessLogIterator, file LogEntry) error {
+	body, err := p.open(it, file.Url)
+	if err != nil || body == nil {
+		return err
+	}
+	defer body.Close()
+	reader, err := decompressLog(body.Reader)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		record, err := p.args.Parser(line)
+		if err != nil || record == nil {
+			it.mu.Lock()
+			it.skipped++
+			it.mu.Unlock()
+			continue
+		}
+		if len(record.Domain) == 0 {
+			record.Domain = file.Domain
+		}
+		select {
+		case it.records <- record:
+		case <-it.done:
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// logBody is the streamed body of a log file whose first bytes are already read
+type logBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// open gets the file with retries until its first bytes are read, the records may be sent once the
+// body is read so it is never retried after that, the body is nil if the iterator is closed
+func (p *LogPipeline) open(it *AccessLogIterator, url string) (*logBody, error) {
+	var lastErr error
+	for attempt := 0; attempt < p.args.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(p.args.RetryInterval):
+			case <-it.done:
+				return nil, nil
+			}
+		}
+		resp, err := p.args.HTTPClient.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("http status %d", resp.StatusCode)
+			if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				break
+			}
+			continue
+		}
+		body := newIdleTimeoutReader(resp.Body, p.args.ReadTimeout)
+		reader := bufio.NewReaderSize(body, 64*1024)
+		if _, err := reader.Peek(2); err != nil && err != io.EOF {
+			body.Close()
+			lastErr = err
+			continue
+		}
+		return &logBody{Reader: reader, Closer: body}, nil
+	}
+	return nil, lastErr
+}
+
+// idleTimeoutReader closes the body if no data is read in the timeout
+type idleTimeoutReader struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut int32
+}
+
+func newIdleTimeoutReader(body io.ReadCloser, timeout time.Duration) *idleTimeoutReader {
+	r := &idleTimeoutReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&r.timedOut, 1)
+		body.Close()
+	})
+	return r
+}
+
+func (r *idleTimeoutReader) Read(b []byte) (int, error) {
+	n, err := r.body.Read(b)
+	if atomic.LoadInt32(&r.timedOut) == 1 {
+		return n, fmt.Errorf("no data read in %v", r.timeout)
+	}
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *idleTimeoutReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
+
+// decompressLog detects the gzip data by its magic number, the plain text is returned as it is
+func decompressLog(reader *bufio.Reader) (io.Reader, error) {
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(reader)
+	}
+	return reader, nil
+}
+
+// WriteNDJSON - write the records of all the log files as newline-delimited JSON
+//
+// PARAMS:
+//     - w: the writer of the output
+// RETURNS:
+//     - int64: the count of the records written
+//     - error: nil if success otherwise the specific error
+func (p *LogPipeline) WriteNDJSON(w io.Writer) (int64, error) {
+	it, err := p.Records()
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+	encoder := json.NewEncoder(w)
+	count := int64(0)
+	for it.Next() {
+		if err := encoder.Encode(it.Record()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, it.Err()
+}
+
+// ParseAccessLogLine - parse a line of the CDN access log in the combined format, the fields after
+// the user agent are kept in Extra and the cache status such as "HIT" or "TCP_MISS" is detected in them
+//
+//     1.2.3.4 - - [12/Mar/2020:15:18:56 +0800] "GET http://a.com/b.jpg HTTP/1.1" 200 2342 "-" "curl/7.0" HIT
+//
+// PARAMS:
+//     - line: a line of the log
+// RETURNS:
+//     - *AccessLogRecord: the parsed record
+//     - error: nil if success otherwise the specific error
+func ParseAccessLogLine(line string) (*AccessLogRecord, error) {
+	fields, err := splitAccessLogLine(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 9 {
+		return nil, fmt.Errorf("invalid access log line with %d fields", len(fields))
+	}
+
+	record := &AccessLogRecord{ClientIp: fields[0]}
+	if record.Time, err = time.Parse(accessLogTimeLayout, fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid time of access log: %v", err)
+	}
+	request := strings.Fields(fields[4])
+	switch len(request) {
+	case 3:
+		record.Method, record.Url, record.Protocol = request[0], request[1], request[2]
+	case 2:
+		record.Method, record.Url = request[0], request[1]
+	default:
+		return nil, fmt.Errorf("invalid request %q of access log", fields[4])
+	}
+	if record.Status, err = strconv.Atoi(fields[5]); err != nil {
+		return nil, fmt.Errorf("invalid status %q of access log", fields[5])
+	}
+	if fields[6] != "-" {
+		if record.Bytes, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid bytes %q of access log", fields[6])
+		}
+	}
+	record.Referer, record.UserAgent = dashToEmpty(fields[7]), dashToEmpty(fields[8])
+	if len(fields) > 9 {
+		record.Extra = fields[9:]
+	}
+	for _, field := range record.Extra {
+		status := strings.ToUpper(field)
+		if strings.Contains(status, "HIT") || strings.Contains(status, "MISS") {
+			record.CacheStatus = field
+			record.CacheHit = strings.Contains(status, "HIT")
+			break
+		}
+	}
+	return record, nil
+}
+
+func dashToEmpty(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// splitAccessLogLine splits the line by spaces, the fields quoted by "" or [] are kept as one
+func splitAccessLogLine(line string) ([]string, error) {
+	fields := []string{}
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ', '\t':
+			i++
+		case '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quote in access log")
+			}
+			fields = append(fields, strings.Replace(line[i+1:end], `\"`, `"`, -1))
+			i = end + 1
+		case '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				return nil, errors.New("unterminated bracket in access log")
+			}
+			fields = append(fields, line[i+1:i+end])
+			i += end + 1
+		default:
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testAccessLog = `1.2.3.4 - - [12/Mar/2020:15:18:56 +0800] "GET http://a.com/b.jpg?x=1 HTTP/1.1" 200 2342 "-" "curl/7.0 (x86_64)" 0.012 HIT
+5.6.7.8 - - [12/Mar/2020:15:18:57 +0800] "GET http://a.com/c.jpg HTTP/1.1" 404 - "http://ref.com/" "Mozilla/5.0 \"quoted\"" TCP_MISS
+broken line
+`
+
+func TestParseAccessLogLine(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(testAccessLog), "\n")
+	record, err := ParseAccessLogLine(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2020, 3, 12, 7, 18, 56, 0, time.UTC)
+	if !record.Time.Equal(expected) || record.ClientIp != "1.2.3.4" || record.Method != "GET" ||
+		record.Url != "http://a.com/b.jpg?x=1" || record.Status != 200 || record.Bytes != 2342 ||
+		record.Referer != "" || record.UserAgent != "curl/7.0 (x86_64)" || !record.CacheHit ||
+		record.CacheStatus != "HIT" || len(record.Extra) != 2 {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	record, err = ParseAccessLogLine(lines[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != 404 || record.Bytes != 0 || record.Referer != "http://ref.com/" ||
+		record.UserAgent != `Mozilla/5.0 "quoted"` || record.CacheHit || record.CacheStatus != "TCP_MISS" {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	if _, err := ParseAccessLogLine(lines[2]); err == nil {
+		t.Errorf("expect error of broken line")
+	}
+}
+
+func TestLogPipeline(t *testing.T) {
+	gz := &bytes.Buffer{}
+	w := gzip.NewWriter(gz)
+	w.Write([]byte(testAccessLog))
+	w.Close()
+
+	var mu sync.Mutex
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain.log":
+			rw.Write([]byte(testAccessLog))
+		case "/flaky.log.gz":
+			mu.Lock()
+			defer mu.Unlock()
+			if failures > 0 {
+				failures--
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rw.Write(gz.Bytes())
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	windows := 0
+	list := func(domain string, interval TimeInterval) ([]LogEntry, error) {
+		windows++
+		// every window returns both files, they must be merged
+		return []LogEntry{
+			{LogBase: &LogBase{Name: "plain.log", Url: server.URL + "/plain.log"}},
+			{LogBase: &LogBase{Name: "flaky.log.gz", Url: server.URL + "/flaky.log.gz"}},
+		}, nil
+	}
+	start := time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC)
+	p := newLogPipeline(list, &LogPipelineArgs{
+		Domains:       []string{"a.com"},
+		Start:         start,
+		End:           start.Add(60 * time.Hour),
+		RetryInterval: time.Millisecond,
+	})
+
+	out := &bytes.Buffer{}
+	count, err := p.WriteNDJSON(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if windows != 3 || count != 4 {
+		t.Errorf("expect 3 windows and 4 records, got %d and %d", windows, count)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	record := &AccessLogRecord{}
+	if err := json.Unmarshal([]byte(lines[0]), record); err != nil || record.Domain != "a.com" {
+		t.Errorf("unexpected record %s: %v", lines[0], err)
+	}
+
+	it := p.RecordsOf([]LogEntry{{LogBase: &LogBase{Name: "missing", Url: server.URL + "/missing"}}})
+	for it.Next() {
+	}
+	if it.Err() == nil || !strings.Contains(it.Err().Error(), "404") {
+		t.Errorf("expect error of missing file, got %v", it.Err())
+	}
+}
+
+func TestLogPipelineStreaming(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/truncated.log":
+			// the connection is closed before the declared length is sent
+			rw.Header().Set("Content-Length", "100000")
+			rw.Write([]byte(testAccessLog))
+		case "/stalled.log":
+			rw.Write([]byte(testAccessLog))
+			rw.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	p := newLogPipeline(nil, &LogPipelineArgs{RetryInterval: time.Millisecond, ReadTimeout: 50 * time.Millisecond})
+	for _, name := range []string{"truncated.log", "stalled.log"} {
+		it := p.RecordsOf([]LogEntry{{LogBase: &LogBase{Name: name, Url: server.URL + "/" + name}}})
+		count := 0
+		for it.Next() {
+			count++
+		}
+		if it.Err() == nil || count != 2 || requests["/"+name] != 1 {
+			t.Errorf("%s: expect error without retry after %d records, got %v of %d request(s)",
+				name, count, it.Err(), requests["/"+name])
+		}
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(testAccessLog))
+	}))
+	defer server.Close()
+	p = newLogPipeline(nil, &LogPipelineArgs{Parser: func(line string) (*AccessLogRecord, error) {
+		if strings.HasPrefix(line, "1.2.3.4") {
+			return nil, nil
+		}
+		return ParseAccessLogLine(line)
+	}})
+	it := p.RecordsOf([]LogEntry{{LogBase: &LogBase{Name: "a.log", Url: server.URL}}})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 1 || it.Skipped() != 2 {
+		t.Errorf("expect the nil record skipped, got %d record(s) and %d skipped: %v", count, it.Skipped(),
+			it.Err())
+	}
+}
@@ -0,0 +1,427 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+// StatKeyType defined the type of the keys of QueryCondition
+type StatKeyType int
+
+const (
+	StatKeyDomain StatKeyType = 0
+	StatKeyUserId StatKeyType = 1
+	StatKeyTag    StatKeyType = 2
+)
+
+// StatPeriod defined the granularity of the statistics in seconds
+type StatPeriod int
+
+const (
+	StatPeriodMinute     StatPeriod = 60
+	StatPeriodFiveMinute StatPeriod = 300
+	StatPeriodHour       StatPeriod = 3600
+	StatPeriodDay        StatPeriod = 86400
+
+	// MaxStatQueryRange is the longest time range allowed by the statistics API
+	MaxStatQueryRange = 90 * 24 * time.Hour
+)
+
+// StatMetric defined the time-series metrics of QueryStatSeries, each one is queried by the typed
+// API of the metric and every numeric value of it is a series
+type StatMetric string
+
+const (
+	StatMetricAvgSpeed    StatMetric = "avg_speed"    // "avgspeed" by GetAvgSpeed
+	StatMetricPv          StatMetric = "pv"           // "pv" and "qps" by GetPv
+	StatMetricSrcPv       StatMetric = "pv_src"       // "pv" and "qps" by GetSrcPv
+	StatMetricUv          StatMetric = "uv"           // "uv" by GetUv
+	StatMetricFlow        StatMetric = "flow"         // "flow" and "bps" by GetFlow
+	StatMetricSrcFlow     StatMetric = "src_flow"     // "flow" and "bps" by GetSrcFlow
+	StatMetricRealHit     StatMetric = "real_hit"     // "hitrate" by GetRealHit
+	StatMetricPvHit       StatMetric = "pv_hit"       // "hitrate" by GetPvHit
+	StatMetricHttpCode    StatMetric = "httpcode"     // "counters.<code>" by GetHttpCode
+	StatMetricSrcHttpCode StatMetric = "src_httpcode" // "counters.<code>" by GetSrcHttpCode
+)
+
+// statPeriodRanges are the longest time range of one query of each period, the longer queries
+// are split so that each one returns a moderate number of points
+var statPeriodRanges = map[StatPeriod]time.Duration{
+	StatPeriodMinute:     time.Hour,
+	StatPeriodFiveMinute: 24 * time.Hour,
+	StatPeriodHour:       31 * 24 * time.Hour,
+	StatPeriodDay:        MaxStatQueryRange,
+}
+
+// StatQuery defined a struct for building the statistics queries with typed conditions
+type StatQuery struct {
+	keyType  StatKeyType
+	keys     []string
+	start    time.Time
+	end      time.Time
+	period   StatPeriod
+	groupBy  string
+	level    string
+	maxRange time.Duration
+}
+
+// NewStatQuery - create a statistics query of the keys, the default range is the last 24 hours and
+// the default period is 5 minutes
+func NewStatQuery(keyType StatKeyType, keys ...string) *StatQuery {
+	end := time.Now().UTC().Truncate(time.Minute)
+	return &StatQuery{
+		keyType: keyType,
+		keys:    keys,
+		start:   end.Add(-24 * time.Hour),
+		end:     end,
+		period:  StatPeriodFiveMinute,
+	}
+}
+
+// Range sets the time range [start, end) of the query
+func (q *StatQuery) Range(start, end time.Time) *StatQuery {
+	q.start, q.end = start.UTC(), end.UTC()
+	return q
+}
+
+// Period sets the granularity of the query
+func (q *StatQuery) Period(period StatPeriod) *StatQuery {
+	q.period = period
+	return q
+}
+
+// GroupByKey makes the results grouped by each key, otherwise the results are of all the keys
+func (q *StatQuery) GroupByKey() *StatQuery {
+	q.groupBy = "key"
+	return q
+}
+
+// Level sets the node level of the metrics pv and flow, "edge", "internal" or "all", the other
+// metrics ignore it
+func (q *StatQuery) Level(level string) *StatQuery {
+	q.level = level
+	return q
+}
+
+// MaxRange overrides the longest time range of one query before splitting
+func (q *StatQuery) MaxRange(maxRange time.Duration) *StatQuery {
+	q.maxRange = maxRange
+	return q
+}
+
+// Validate checks the period and the time range of the query
+func (q *StatQuery) Validate() error {
+	if _, ok := statPeriodRanges[q.period]; !ok {
+		return fmt.Errorf("invalid period %d, it must be 60, 300, 3600 or 86400", q.period)
+	}
+	if q.keyType < StatKeyDomain || q.keyType > StatKeyTag {
+		return fmt.Errorf("invalid key type %d", q.keyType)
+	}
+	if !q.start.Before(q.end) {
+		return errors.New("error time range, the start time should be less than the end time")
+	}
+	if q.start.Unix()%int64(q.period) != 0 || q.end.Unix()%int64(q.period) != 0 {
+		return fmt.Errorf("the start time and the end time must be aligned to the period %ds", q.period)
+	}
+	return nil
+}
+
+// Conditions - split the query into the conditions within the allowed range of each request
+//
+// RETURNS:
+//     - []QueryCondition: the conditions covering the whole time range in order
+//     - error: nil if success otherwise the specific error
+func (q *StatQuery) Conditions() ([]QueryCondition, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	window := q.maxRange
+	if window <= 0 {
+		window = statPeriodRanges[q.period]
+	}
+	period := time.Duration(q.period) * time.Second
+	if window = window / period * period; window <= 0 {
+		window = period
+	}
+	if window > MaxStatQueryRange {
+		window = MaxStatQueryRange
+	}
+
+	conditions := []QueryCondition{}
+	for start := q.start; start.Before(q.end); start = start.Add(window) {
+		end := start.Add(window)
+		if end.After(q.end) {
+			end = q.end
+		}
+		conditions = append(conditions, QueryCondition{
+			StartTime: util.FormatISO8601Date(start.Unix()),
+			EndTime:   util.FormatISO8601Date(end.Unix()),
+			Period:    int(q.period),
+			KeyType:   int(q.keyType),
+			Key:       q.keys,
+			GroupBy:   q.groupBy,
+		})
+	}
+	return conditions, nil
+}
+
+// StatPoint defined a struct for one point of a time series
+type StatPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// StatSeries defined a struct for a time series of one value of the statistics, such as "flow" or
+// "bps" of the metric "flow", the count of a http code is named like "counters.404"
+type StatSeries struct {
+	Metric StatMetric
+	Name   string
+	Key    string // the key if grouped by key, otherwise empty
+	Points []StatPoint
+}
+
+// Total returns the sum of the values
+func (s *StatSeries) Total() float64 {
+	total := 0.0
+	for _, p := range s.Points {
+		total += p.Value
+	}
+	return total
+}
+
+// Max returns the point of the max value
+func (s *StatSeries) Max() StatPoint {
+	max := StatPoint{Value: math.Inf(-1)}
+	for _, p := range s.Points {
+		if p.Value > max.Value {
+			max = p
+		}
+	}
+	return max
+}
+
+// Percentile returns the value at the percentile p (0-100) by the nearest-rank method
+func (s *StatSeries) Percentile(p float64) float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	values := make([]float64, len(s.Points))
+	for i, point := range s.Points {
+		values[i] = point.Value
+	}
+	sort.Float64s(values)
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(values) {
+		rank = len(values)
+	}
+	return values[rank-1]
+}
+
+// Peak95 returns the billing point of the 95th percentile bandwidth: the points are sorted in
+// descending order, the top 5% are dropped and the next one is the peak 95 point
+func (s *StatSeries) Peak95() StatPoint {
+	if len(s.Points) == 0 {
+		return StatPoint{}
+	}
+	points := make([]StatPoint, len(s.Points))
+	copy(points, s.Points)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Value > points[j].Value })
+	return points[len(points)*5/100]
+}
+
+// statDetail is a point of the typed details with the values named by their JSON fields
+type statDetail struct {
+	*DetailBase
+	values map[string]float64
+}
+
+type statSeriesGetter func(cli bce.Client, c *QueryCondition, level string) ([]statDetail, error)
+
+// statSeriesGetters query one window of each metric by its typed API
+var statSeriesGetters = map[StatMetric]statSeriesGetter{
+	StatMetricAvgSpeed: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		details, err := GetAvgSpeed(cli, c)
+		result := make([]statDetail, 0, len(details))
+		for _, d := range details {
+			result = append(result, statDetail{d.DetailBase,
+				map[string]float64{"avgspeed": float64(d.AvgSpeed)}})
+		}
+		return result, err
+	},
+	StatMetricPv: func(cli bce.Client, c *QueryCondition, level string) ([]statDetail, error) {
+		return pvStatDetails(GetPv(cli, c, level))
+	},
+	StatMetricSrcPv: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return pvStatDetails(GetSrcPv(cli, c))
+	},
+	StatMetricUv: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		details, err := GetUv(cli, c)
+		result := make([]statDetail, 0, len(details))
+		for _, d := range details {
+			result = append(result, statDetail{d.DetailBase, map[string]float64{"uv": float64(d.Uv)}})
+		}
+		return result, err
+	},
+	StatMetricFlow: func(cli bce.Client, c *QueryCondition, level string) ([]statDetail, error) {
+		return flowStatDetails(GetFlow(cli, c, level))
+	},
+	StatMetricSrcFlow: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return flowStatDetails(GetSrcFlow(cli, c))
+	},
+	StatMetricRealHit: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return hitStatDetails(GetRealHit(cli, c))
+	},
+	StatMetricPvHit: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return hitStatDetails(GetPvHit(cli, c))
+	},
+	StatMetricHttpCode: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return httpCodeStatDetails(GetHttpCode(cli, c))
+	},
+	StatMetricSrcHttpCode: func(cli bce.Client, c *QueryCondition, _ string) ([]statDetail, error) {
+		return httpCodeStatDetails(GetSrcHttpCode(cli, c))
+	},
+}
+
+func pvStatDetails(details []PvDetail, err error) ([]statDetail, error) {
+	result := make([]statDetail, 0, len(details))
+	for _, d := range details {
+		result = append(result, statDetail{d.DetailBase,
+			map[string]float64{"pv": float64(d.Pv), "qps": float64(d.Qps)}})
+	}
+	return result, err
+}
+
+func flowStatDetails(details []FlowDetail, err error) ([]statDetail, error) {
+	result := make([]statDetail, 0, len(details))
+	for _, d := range details {
+		result = append(result, statDetail{d.DetailBase,
+			map[string]float64{"flow": d.Flow, "bps": float64(d.Bps)}})
+	}
+	return result, err
+}
+
+func hitStatDetails(details []HitDetail, err error) ([]statDetail, error) {
+	result := make([]statDetail, 0, len(details))
+	for _, d := range details {
+		result = append(result, statDetail{d.DetailBase, map[string]float64{"hitrate": d.HitRate}})
+	}
+	return result, err
+}
+
+// httpCodeStatDetails names the count of each http code like "counters.404"
+func httpCodeStatDetails(details []HttpCodeDetail, err error) ([]statDetail, error) {
+	result := make([]statDetail, 0, len(details))
+	for _, d := range details {
+		values := map[string]float64{}
+		for _, counter := range d.Counters {
+			values["counters."+strconv.FormatInt(counter.Name, 10)] = float64(counter.Count)
+		}
+		result = append(result, statDetail{d.DetailBase, values})
+	}
+	return result, err
+}
+
+// QueryStatSeries - query the time-series statistics of the metric, the query is split by the allowed
+// range and the results are merged. The metrics with the distributions such as "pv_region" and
+// "top_urls" are not time series and not supported.
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - query: the statistics query
+//     - metric: the time-series metric, such as StatMetricPv, StatMetricFlow, StatMetricHttpCode
+// RETURNS:
+//     - []StatSeries: the series sorted by key and name
+//     - error: nil if success otherwise the specific error
+func QueryStatSeries(cli bce.Client, query *StatQuery, metric StatMetric) ([]StatSeries, error) {
+	get, ok := statSeriesGetters[metric]
+	if !ok {
+		return nil, fmt.Errorf("unsupported metric %q of the time series", metric)
+	}
+	conditions, err := query.Conditions()
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]*StatSeries{}
+	seen := map[string]bool{}
+	for i := range conditions {
+		details, err := get(cli, &conditions[i], query.level)
+		if err != nil {
+			return nil, err
+		}
+		for _, detail := range details {
+			if detail.DetailBase == nil {
+				return nil, fmt.Errorf("missing timestamp of metric %s", metric)
+			}
+			ts, key := detail.Timestamp, detail.Key
+			t, err := time.Parse(time.RFC3339, ts)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q of metric %s", ts, metric)
+			}
+			for name, value := range detail.values {
+				id := key + "|" + name
+				// the adjacent windows may both return the point at the boundary
+				if seen[id+"|"+ts] {
+					continue
+				}
+				seen[id+"|"+ts] = true
+				s, ok := index[id]
+				if !ok {
+					s = &StatSeries{Metric: metric, Name: name, Key: key}
+					index[id] = s
+				}
+				s.Points = append(s.Points, StatPoint{Time: t, Value: value})
+			}
+		}
+	}
+
+	result := make([]StatSeries, 0, len(index))
+	for _, s := range index {
+		sort.SliceStable(s.Points, func(i, j int) bool { return s.Points[i].Time.Before(s.Points[j].Time) })
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Key != result[j].Key {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// WriteStatCSV - write the series as CSV with the header "time,key,metric,name,value"
+//
+// PARAMS:
+//     - w: the writer of the CSV
+//     - series: the series to write
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func WriteStatCSV(w io.Writer, series []StatSeries) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "key", "metric", "name", "value"}); err != nil {
+		return err
+	}
+	for _, s := range series {
+		for _, p := range s.Points {
+			record := []string{p.Time.UTC().Format(util.ISO8601Format), s.Key, string(s.Metric), s.Name,
+				strconv.FormatFloat(p.Value, 'f', -1, 64)}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
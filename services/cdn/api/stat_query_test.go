@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+func TestStatQueryConditions(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewStatQuery(StatKeyDomain, "a.com").Range(start, start.Add(3*time.Hour+30*time.Minute)).
+		Period(StatPeriodMinute)
+	conditions, err := q.Conditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conditions) != 4 || conditions[3].StartTime != "2024-01-01T03:00:00Z" ||
+		conditions[3].EndTime != "2024-01-01T03:30:00Z" || conditions[0].Period != 60 {
+		t.Errorf("unexpected conditions %+v", conditions)
+	}
+
+	if _, err := NewStatQuery(StatKeyTag).Period(120).Conditions(); err == nil {
+		t.Errorf("expect error of invalid period")
+	}
+	if _, err := NewStatQuery(StatKeyTag).Range(start, start.Add(90*time.Second)).Conditions(); err == nil {
+		t.Errorf("expect error of unaligned range")
+	}
+	if _, err := NewStatQuery(StatKeyTag).Range(start, start).Conditions(); err == nil {
+		t.Errorf("expect error of empty range")
+	}
+}
+
+// fakeStatQuery serves the statistics API with a point of each key every 5 minutes of the range,
+// the point at the end is included so that the adjacent windows both return it
+type fakeStatQuery struct {
+	lock   sync.Mutex
+	start  time.Time
+	bodies []map[string]interface{}
+}
+
+func (f *fakeStatQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	body := map[string]interface{}{}
+	if r.Method != http.MethodPost || r.URL.Path != "/v2/stat/query" ||
+		json.NewDecoder(r.Body).Decode(&body) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.bodies = append(f.bodies, body)
+	s, _ := util.ParseISO8601Date(body["startTime"].(string))
+	e, _ := util.ParseISO8601Date(body["endTime"].(string))
+	details := []map[string]interface{}{}
+	for ts := s; !ts.After(e); ts = ts.Add(5 * time.Minute) {
+		i := float64(ts.Sub(f.start) / (5 * time.Minute))
+		for _, key := range body["key"].([]interface{}) {
+			details = append(details, map[string]interface{}{
+				"timestamp": ts.Format(time.RFC3339), "key": key, "flow": i, "bps": i * 10,
+				"counters": []interface{}{map[string]interface{}{"name": 200, "count": 1}},
+			})
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "details": details})
+}
+
+func TestQueryStatSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := &fakeStatQuery{start: start}
+	server := httptest.NewServer(f)
+	defer server.Close()
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+
+	q := NewStatQuery(StatKeyDomain, "a.com", "b.com").Range(start, start.Add(2*time.Hour)).
+		Period(StatPeriodFiveMinute).GroupByKey().Level("edge").MaxRange(time.Hour)
+	series, err := QueryStatSeries(client, q, StatMetricFlow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.bodies) != 2 || len(series) != 4 {
+		t.Fatalf("expect 2 requests and 4 series, got %d and %d", len(f.bodies), len(series))
+	}
+	for _, b := range f.bodies {
+		if b["metric"] != "flow" || b["level"] != "edge" || b["groupBy"] != "key" {
+			t.Errorf("unexpected body %v", b)
+		}
+	}
+	bps := series[0]
+	if bps.Key != "a.com" || bps.Name != "bps" || bps.Metric != StatMetricFlow ||
+		len(bps.Points) != 25 {
+		t.Fatalf("unexpected series %s %s with %d points", bps.Key, bps.Name, len(bps.Points))
+	}
+	if series[1].Name != "flow" || series[1].Total() != 300 {
+		t.Errorf("unexpected flow series %+v", series[1])
+	}
+	if bps.Total() != 3000 || bps.Max().Value != 240 || !bps.Max().Time.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("unexpected total %v or max %+v", bps.Total(), bps.Max())
+	}
+	if p := bps.Percentile(50); p != 120 {
+		t.Errorf("expect median 120, got %v", p)
+	}
+	// 25 points, drop the top 1 and take the next
+	if p := bps.Peak95(); p.Value != 230 {
+		t.Errorf("expect peak95 230, got %+v", p)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteStatCSV(buf, series[:1]); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 26 || lines[0] != "time,key,metric,name,value" ||
+		lines[2] != "2024-01-01T00:05:00Z,a.com,flow,bps,10" {
+		t.Errorf("unexpected csv %q", lines[:3])
+	}
+}
+
+func TestQueryStatSeriesHttpCode(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := &fakeStatQuery{start: start}
+	server := httptest.NewServer(f)
+	defer server.Close()
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+
+	q := NewStatQuery(StatKeyDomain, "a.com").Range(start, start.Add(time.Hour)).Level("edge")
+	series, err := QueryStatSeries(client, q, StatMetricHttpCode)
+	if err != nil || len(series) != 1 || series[0].Name != "counters.200" || series[0].Total() != 13 {
+		t.Fatalf("expect one series of the http code 200, got %+v %v", series, err)
+	}
+	// the level only applies to pv and flow
+	if b := f.bodies[0]; b["metric"] != "httpcode" || b["level"] != nil {
+		t.Errorf("unexpected body %v", b)
+	}
+
+	if _, err := QueryStatSeries(client, q, StatMetric("pv_region")); err == nil {
+		t.Errorf("expect error of the metric not in time series")
+	}
+	if len(f.bodies) != 1 {
+		t.Errorf("expect nothing sent for the unsupported metric, got %d requests", len(f.bodies))
+	}
+}
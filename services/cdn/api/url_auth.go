@@ -0,0 +1,271 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RequestAuthTypeA = "a"
+	RequestAuthTypeB = "b"
+	RequestAuthTypeC = "c"
+
+	DefaultRequestAuthSignArg = "sign"
+	DefaultRequestAuthTimeout = 1800
+	DefaultRequestAuthRand    = "0"
+	DefaultRequestAuthUid     = "0"
+
+	requestAuthTimeLayoutB = "200601021504"
+)
+
+var (
+	ErrURLUnsigned          = errors.New("the url is not signed")
+	ErrURLSignatureMismatch = errors.New("the signature of the url mismatches")
+	ErrURLExpired           = errors.New("the signed url is expired")
+)
+
+// the time of the type B is in Beijing time
+var requestAuthZoneB = time.FixedZone("CST", 8*60*60)
+
+// SignURLOptions defined a struct for the options of signing a URL
+type SignURLOptions struct {
+	// Time is the signing time, the URL expires at Time + RequestAuth.Timeout, now if zero
+	Time time.Time
+
+	// UseKey2 signs with Key2, used when rotating the keys: set the new key as Key2, sign with it,
+	// and then move it to Key1 after all the URLs signed with the old Key1 are expired
+	UseKey2 bool
+
+	// Rand and Uid are the fields of the type A, "0" if empty
+	Rand string
+	Uid  string
+}
+
+// SignedURLInfo defined a struct for the result of verifying a signed URL
+type SignedURLInfo struct {
+	Path       string // the path of the resource without the signature
+	SignTime   time.Time
+	ExpireTime time.Time
+	KeyIndex   int  // 1 or 2, the key matching the signature
+	WhiteList  bool // true if the path is in the white list and not verified
+}
+
+// SignURL - sign a URL for the request authorization of the domain
+// For details, please refer https://cloud.baidu.com/doc/CDN/s/ujwvyeo0t
+//
+// PARAMS:
+//     - auth: the request authorization setting of the domain
+//     - rawURL: the URL to sign, such as "http://www.example.com/a/b.jpg?x=1"
+//     - options: the signing options, the default ones are used if nil
+// RETURNS:
+//     - string: the signed URL
+//     - error: nil if success otherwise the specific error
+func SignURL(auth *RequestAuth, rawURL string, options *SignURLOptions) (string, error) {
+	opts := SignURLOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+	if len(opts.Rand) == 0 {
+		opts.Rand = DefaultRequestAuthRand
+	}
+	if len(opts.Uid) == 0 {
+		opts.Uid = DefaultRequestAuthUid
+	}
+	if err := checkRequestAuth(auth); err != nil {
+		return "", err
+	}
+	key := auth.Key1
+	if opts.UseKey2 {
+		if len(auth.Key2) == 0 {
+			return "", errors.New("key2 of the request auth is empty")
+		}
+		key = auth.Key2
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	path := u.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+
+	switch strings.ToLower(auth.Type) {
+	case RequestAuthTypeA:
+		ts := opts.Time.Unix()
+		hash := md5Hex(fmt.Sprintf("%s-%d-%s-%s-%s", path, ts, opts.Rand, opts.Uid, key))
+		addQuery(u, signArg(auth), fmt.Sprintf("%d-%s-%s-%s", ts, opts.Rand, opts.Uid, hash))
+	case RequestAuthTypeB:
+		ts := opts.Time.In(requestAuthZoneB).Format(requestAuthTimeLayoutB)
+		err = setEscapedPath(u, "/"+ts+"/"+md5Hex(key+ts+path)+path)
+	case RequestAuthTypeC:
+		ts := strconv.FormatInt(opts.Time.Unix(), 16)
+		hash := md5Hex(key + path + ts)
+		if isQueryTypeC(auth) {
+			addQuery(u, auth.SignArg, hash)
+			addQuery(u, auth.TimeArg, ts)
+		} else {
+			err = setEscapedPath(u, "/"+hash+"/"+ts+path)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// VerifySignedURL - verify the signature and the expiry of a signed URL locally
+//
+// PARAMS:
+//     - auth: the request authorization setting of the domain
+//     - signedURL: the signed URL
+//     - now: the time to check the expiry
+// RETURNS:
+//     - *SignedURLInfo: the information of the signed URL, it is returned with ErrURLExpired too
+//     - error: nil if the URL is valid otherwise the specific error
+func VerifySignedURL(auth *RequestAuth, signedURL string, now time.Time) (*SignedURLInfo, error) {
+	if err := checkRequestAuth(auth); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return nil, err
+	}
+	path := u.EscapedPath()
+	for _, white := range auth.WhiteList {
+		if len(white) != 0 && strings.HasSuffix(path, white) {
+			return &SignedURLInfo{Path: path, WhiteList: true}, nil
+		}
+	}
+
+	info := &SignedURLInfo{}
+	var hash string
+	var message func(key string) string
+	switch strings.ToLower(auth.Type) {
+	case RequestAuthTypeA:
+		fields := strings.Split(u.Query().Get(signArg(auth)), "-")
+		if len(fields) != 4 {
+			return nil, ErrURLUnsigned
+		}
+		ts, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, ErrURLUnsigned
+		}
+		info.Path, info.SignTime, hash = path, time.Unix(ts, 0), fields[3]
+		message = func(key string) string {
+			return fmt.Sprintf("%s-%s-%s-%s-%s", path, fields[0], fields[1], fields[2], key)
+		}
+	case RequestAuthTypeB:
+		parts := strings.SplitN(path, "/", 4)
+		if len(parts) != 4 {
+			return nil, ErrURLUnsigned
+		}
+		t, err := time.ParseInLocation(requestAuthTimeLayoutB, parts[1], requestAuthZoneB)
+		if err != nil {
+			return nil, ErrURLUnsigned
+		}
+		info.Path, info.SignTime, hash = "/"+parts[3], t, parts[2]
+		message = func(key string) string { return key + parts[1] + info.Path }
+	case RequestAuthTypeC:
+		var ts string
+		if isQueryTypeC(auth) {
+			query := u.Query()
+			info.Path, hash, ts = path, query.Get(auth.SignArg), query.Get(auth.TimeArg)
+		} else {
+			parts := strings.SplitN(path, "/", 4)
+			if len(parts) != 4 {
+				return nil, ErrURLUnsigned
+			}
+			info.Path, hash, ts = "/"+parts[3], parts[1], parts[2]
+		}
+		seconds, err := strconv.ParseInt(ts, 16, 64)
+		if err != nil {
+			return nil, ErrURLUnsigned
+		}
+		info.SignTime = time.Unix(seconds, 0)
+		message = func(key string) string { return key + info.Path + ts }
+	}
+	if len(hash) == 0 {
+		return nil, ErrURLUnsigned
+	}
+
+	for i, key := range []string{auth.Key1, auth.Key2} {
+		if len(key) != 0 && subtle.ConstantTimeCompare([]byte(md5Hex(message(key))), []byte(hash)) == 1 {
+			info.KeyIndex = i + 1
+			break
+		}
+	}
+	if info.KeyIndex == 0 {
+		return nil, ErrURLSignatureMismatch
+	}
+	timeout := auth.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRequestAuthTimeout
+	}
+	info.ExpireTime = info.SignTime.Add(time.Duration(timeout) * time.Second)
+	if now.After(info.ExpireTime) {
+		return info, ErrURLExpired
+	}
+	return info, nil
+}
+
+func checkRequestAuth(auth *RequestAuth) error {
+	if auth == nil {
+		return errors.New("the request auth is nil")
+	}
+	switch strings.ToLower(auth.Type) {
+	case RequestAuthTypeA, RequestAuthTypeB, RequestAuthTypeC:
+	default:
+		return fmt.Errorf("invalid request auth type \"%s\", it must be \"a\", \"b\" or \"c\"", auth.Type)
+	}
+	if len(auth.Key1) == 0 {
+		return errors.New("key1 of the request auth is empty")
+	}
+	return nil
+}
+
+func signArg(auth *RequestAuth) string {
+	if len(auth.SignArg) != 0 {
+		return auth.SignArg
+	}
+	return DefaultRequestAuthSignArg
+}
+
+// isQueryTypeC returns true if the type C signature is carried by the query args rather than the path
+func isQueryTypeC(auth *RequestAuth) bool {
+	return len(auth.SignArg) != 0 && len(auth.TimeArg) != 0
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// addQuery appends the arg without reordering the existing ones
+func addQuery(u *url.URL, name, value string) {
+	arg := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	if len(u.RawQuery) == 0 {
+		u.RawQuery = arg
+	} else {
+		u.RawQuery += "&" + arg
+	}
+}
+
+func setEscapedPath(u *url.URL, escaped string) error {
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = path, escaped
+	return nil
+}
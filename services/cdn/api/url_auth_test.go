@@ -0,0 +1,92 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	signTime := time.Unix(1700000000, 0)
+	cases := []struct {
+		auth     RequestAuth
+		expected string
+	}{
+		{RequestAuth{Type: "a", Key1: "secretekey1"},
+			"http://www.example.com/a/b.jpg?x=1&sign=1700000000-0-0-5c3548d2d7f79bc2bb82dbc8b242b332"},
+		{RequestAuth{Type: "b", Key1: "secretekey1"},
+			"http://www.example.com/202311150613/63739fc4dbfd2dd03a4df8aaee1d999d/a/b.jpg?x=1"},
+		{RequestAuth{Type: "c", Key1: "secretekey1"},
+			"http://www.example.com/cb4ef6ee99c2b877d86eaf7d725e4baf/6553f100/a/b.jpg?x=1"},
+		{RequestAuth{Type: "c", Key1: "secretekey1", SignArg: "sign", TimeArg: "t"},
+			"http://www.example.com/a/b.jpg?x=1&sign=cb4ef6ee99c2b877d86eaf7d725e4baf&t=6553f100"},
+	}
+	for _, c := range cases {
+		signed, err := SignURL(&c.auth, "http://www.example.com/a/b.jpg?x=1", &SignURLOptions{Time: signTime})
+		if err != nil {
+			t.Errorf("type %s: %v", c.auth.Type, err)
+			continue
+		}
+		if signed != c.expected {
+			t.Errorf("type %s: expect %s, got %s", c.auth.Type, c.expected, signed)
+		}
+
+		info, err := VerifySignedURL(&c.auth, signed, signTime.Add(time.Minute))
+		if err != nil {
+			t.Errorf("type %s: verify %s: %v", c.auth.Type, signed, err)
+			continue
+		}
+		expectedTime := signTime
+		if c.auth.Type == "b" { // the time of type B is in minutes
+			expectedTime = signTime.Truncate(time.Minute)
+		}
+		if info.Path != "/a/b.jpg" || info.KeyIndex != 1 || !info.SignTime.Equal(expectedTime) ||
+			!info.ExpireTime.Equal(expectedTime.Add(DefaultRequestAuthTimeout*time.Second)) {
+			t.Errorf("type %s: unexpected info %+v", c.auth.Type, info)
+		}
+		if _, err := VerifySignedURL(&c.auth, signed, signTime.Add(time.Hour)); err != ErrURLExpired {
+			t.Errorf("type %s: expect expired, got %v", c.auth.Type, err)
+		}
+		tampered := strings.Replace(signed, "b.jpg", "c.jpg", 1)
+		if _, err := VerifySignedURL(&c.auth, tampered, signTime); err != ErrURLSignatureMismatch {
+			t.Errorf("type %s: expect mismatch of %s, got %v", c.auth.Type, tampered, err)
+		}
+	}
+}
+
+func TestSignURLKeyRotation(t *testing.T) {
+	now := time.Now()
+	old := &RequestAuth{Type: "a", Key1: "old", Timeout: 60, SignArg: "auth_key",
+		WhiteList: []string{"/crossdomain.xml"}}
+	rotating := &RequestAuth{Type: "a", Key1: "old", Key2: "new", Timeout: 60, SignArg: "auth_key"}
+	rotated := &RequestAuth{Type: "a", Key1: "new", Timeout: 60, SignArg: "auth_key"}
+
+	signedOld, _ := SignURL(old, "https://www.example.com/v.mp4", nil)
+	signedNew, err := SignURL(rotating, "https://www.example.com/v.mp4", &SignURLOptions{UseKey2: true})
+	if err != nil || !strings.Contains(signedNew, "auth_key=") {
+		t.Fatalf("unexpected signed url %s: %v", signedNew, err)
+	}
+	if info, err := VerifySignedURL(rotating, signedOld, now); err != nil || info.KeyIndex != 1 {
+		t.Errorf("expect old url valid by key1 while rotating: %+v %v", info, err)
+	}
+	if info, err := VerifySignedURL(rotating, signedNew, now); err != nil || info.KeyIndex != 2 {
+		t.Errorf("expect new url valid by key2 while rotating: %+v %v", info, err)
+	}
+	if _, err := VerifySignedURL(rotated, signedOld, now); err != ErrURLSignatureMismatch {
+		t.Errorf("expect old url invalid after rotation, got %v", err)
+	}
+	if _, err := SignURL(old, "https://www.example.com/v.mp4", &SignURLOptions{UseKey2: true}); err == nil {
+		t.Errorf("expect error of signing with empty key2")
+	}
+
+	if _, err := VerifySignedURL(old, "https://www.example.com/v.mp4", now); err != ErrURLUnsigned {
+		t.Errorf("expect unsigned, got %v", err)
+	}
+	if info, err := VerifySignedURL(old, "https://www.example.com/crossdomain.xml", now); err != nil ||
+		!info.WhiteList {
+		t.Errorf("expect white list passed: %+v %v", info, err)
+	}
+	if _, err := SignURL(&RequestAuth{Type: "d", Key1: "k"}, "http://a.com/", nil); err == nil {
+		t.Errorf("expect error of invalid type")
+	}
+}
@@ -904,6 +904,31 @@ func (cli *Client) GetContentEncoding(domain string) (string, error) {
 	return api.GetContentEncoding(cli, domain)
 }
 
+// ExportDomainDocument - get all the settings of the domain as a document
+//
+// PARAMS:
+//     - domain: the specified domain
+// RETURNS:
+//     - *api.DomainDocument: the whole configuration of the domain
+//     - error: nil if success otherwise the specific error
+func (cli *Client) ExportDomainDocument(domain string) (*api.DomainDocument, error) {
+	return api.ExportDomainDocument(cli, domain)
+}
+
+// ApplyDomainDocument - apply the desired document to the domain by calling only the setters of the
+// changed settings
+//
+// PARAMS:
+//     - domain: the specified domain
+//     - desired: the desired document, the nil fields are not managed
+//     - dryRun: true means only planning the changes without applying
+// RETURNS:
+//     - []api.DomainConfigChange: the planned changes, or the applied ones if failed
+//     - error: nil if success otherwise the specific error
+func (cli *Client) ApplyDomainDocument(domain string, desired *api.DomainDocument, dryRun bool) ([]api.DomainConfigChange, error) {
+	return api.ApplyDomainDocument(cli, domain, desired, dryRun)
+}
+
 // Purge - tells the CDN system to purge the specified files
 // For more details, please refer https://cloud.baidu.com/doc/CDN/s/ijwvyeyyj
 //
@@ -960,6 +985,18 @@ func (cli *Client) GetQuota() (*api.QuotaDetail, error) {
 	return api.GetQuota(cli)
 }
 
+// RunCacheJob - purge and prefetch many URLs or directories batch by batch within the quota,
+// and wait until every task is completed or failed
+//
+// PARAMS:
+//     - args: the tasks and the options of the job
+// RETURNS:
+//     - *api.CacheJobReport: the outcome of each task
+//     - error: nil if the job ran otherwise the specific error
+func (cli *Client) RunCacheJob(args *api.CacheJobArgs) (*api.CacheJobReport, error) {
+	return api.RunCacheJob(cli, args)
+}
+
 // GetCacheOpRecords get the history operating records
 // For details, please refer https://cloud.baidu.com/doc/CDN/s/5jypnzjqt
 //
@@ -1035,6 +1072,16 @@ func (cli *Client) GetMultiDomainLog(queryData *api.LogQueryData) ([]api.LogEntr
 	return api.GetMultiDomainLog(cli, queryData)
 }
 
+// NewLogPipeline - create a pipeline which lists, downloads and parses the access logs of the domains
+//
+// PARAMS:
+//     - args: the domains, the period and the options of downloading
+// RETURNS:
+//     - *api.LogPipeline: the pipeline, use Records or WriteNDJSON to get the parsed records
+func (cli *Client) NewLogPipeline(args *api.LogPipelineArgs) *api.LogPipeline {
+	return api.NewLogPipeline(cli, args)
+}
+
 // GetAvgSpeed - get the average speed
 // For details, please refer https://cloud.baidu.com/doc/CDN/s/5jwvyf8zn#%E6%9F%A5%E8%AF%A2%E5%B9%B3%E5%9D%87%E9%80%9F%E7%8E%87
 //
@@ -1291,3 +1338,16 @@ func (cli *Client) GetError(queryCondition *api.QueryCondition) ([]api.ErrorDeta
 func (cli *Client) GetPeak95Bandwidth(startTime, endTime string, domains, tags []string) (string, int64, error) {
 	return api.GetPeak95Bandwidth(cli, startTime, endTime, domains, tags)
 }
+
+// QueryStatSeries - query the time-series statistics of the metric, the long query is split by the
+// allowed range and the results are merged
+//
+// PARAMS:
+//     - query: the statistics query built by api.NewStatQuery
+//     - metric: the time-series metric, such as api.StatMetricPv, api.StatMetricFlow
+// RETURNS:
+//     - []api.StatSeries: the series sorted by key and name
+//     - error: nil if success otherwise the specific error
+func (cli *Client) QueryStatSeries(query *api.StatQuery, metric api.StatMetric) ([]api.StatSeries, error) {
+	return api.QueryStatSeries(cli, query, metric)
+}
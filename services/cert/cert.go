@@ -49,6 +49,7 @@ func (c *Client) CreateCert(args *CreateCertArgs) (*CreateCertResult, error) {
 	result := &CreateCertResult{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getCertUri()).
 		WithBody(args).
 		WithResult(result).
@@ -0,0 +1,660 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// rotation.go - the rotation of the certificates in use by the CDN domains and the BLB listeners
+
+// Package rotation rotates the certificates in use by the CDN domains and the BLB listeners, and
+// lists the expiring certificates.
+package rotation
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/services/appblb"
+	"github.com/kougazhang/bce-sdk-go/services/blb"
+	"github.com/kougazhang/bce-sdk-go/services/cdn"
+	"github.com/kougazhang/bce-sdk-go/services/cert"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+const (
+	CERT_BINDING_CDN    = "cdn"
+	CERT_BINDING_BLB    = "blb"
+	CERT_BINDING_APPBLB = "appblb"
+
+	CERT_BINDING_REASON_CERT_ID = "certId"
+	CERT_BINDING_REASON_SAN     = "san"
+
+	certListenerMaxKeys = 1000
+)
+
+// LocalCertificate is a certificate with its private key parsed from the local PEM data.
+type LocalCertificate struct {
+	CommonName string
+	DNSNames   []string
+	NotBefore  time.Time
+	NotAfter   time.Time
+
+	// ServerData is the leaf certificate, LinkData is the rest of the chain
+	ServerData  string
+	LinkData    string
+	PrivateData string
+}
+
+// ParseCertificatePEM - parse the PEM encoded certificate chain and private key, the first
+// certificate of the chain is the server certificate
+//
+// PARAMS:
+//     - certPEM: the PEM data of the certificate chain
+//     - keyPEM: the PEM data of the private key, the key is not checked if empty
+// RETURNS:
+//     - *LocalCertificate: the parsed certificate
+//     - error: nil if success otherwise the specific error
+func ParseCertificatePEM(certPEM, keyPEM []byte) (*LocalCertificate, error) {
+	var blocks [][]byte
+	rest := certPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			blocks = append(blocks, pem.EncodeToMemory(block))
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no certificate found in the PEM data")
+	}
+	leafBlock, _ := pem.Decode(blocks[0])
+	leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %v", err)
+	}
+	if len(keyPEM) != 0 {
+		if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+			return nil, fmt.Errorf("invalid private key: %v", err)
+		}
+	}
+
+	return &LocalCertificate{
+		CommonName:  leaf.Subject.CommonName,
+		DNSNames:    leaf.DNSNames,
+		NotBefore:   leaf.NotBefore,
+		NotAfter:    leaf.NotAfter,
+		ServerData:  string(blocks[0]),
+		LinkData:    string(bytes.Join(blocks[1:], nil)),
+		PrivateData: string(keyPEM),
+	}, nil
+}
+
+// LoadCertificateFiles - load the certificate chain and the private key from the local PEM files
+//
+// PARAMS:
+//     - certFile: the path of the certificate chain
+//     - keyFile: the path of the private key
+// RETURNS:
+//     - *LocalCertificate: the parsed certificate
+//     - error: nil if success otherwise the specific error
+func LoadCertificateFiles(certFile, keyFile string) (*LocalCertificate, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificatePEM(certPEM, keyPEM)
+}
+
+// Hosts returns the SANs of the certificate, or the common name if there is no SAN.
+func (l *LocalCertificate) Hosts() []string {
+	if len(l.DNSNames) != 0 {
+		return l.DNSNames
+	}
+	if len(l.CommonName) != 0 {
+		return []string{l.CommonName}
+	}
+	return nil
+}
+
+// Covers returns whether the host is covered by one of the hosts of the certificate.
+func (l *LocalCertificate) Covers(host string) bool {
+	for _, pattern := range l.Hosts() {
+		if MatchCertHostname(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCertArgs returns the arguments to upload the certificate with the name.
+func (l *LocalCertificate) CreateCertArgs(name string) *cert.CreateCertArgs {
+	return &cert.CreateCertArgs{
+		CertName:        name,
+		CertServerData:  l.ServerData,
+		CertPrivateData: l.PrivateData,
+		CertLinkData:    l.LinkData,
+	}
+}
+
+// MatchCertHostname returns whether the host matches the name of a certificate, the wildcard
+// "*.example.com" matches exactly one label such as "www.example.com". A wildcard host such as a
+// wildcard CDN domain only matches the same wildcard.
+func MatchCertHostname(pattern, host string) bool {
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(pattern) == 0 || len(host) == 0 {
+		return false
+	}
+	if pattern == host {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*.") || strings.HasPrefix(host, "*.") {
+		return false
+	}
+	dot := strings.Index(host, ".")
+	return dot > 0 && host[dot:] == pattern[1:]
+}
+
+// Args defines a rotation to a new certificate. The CDN domains and the BLB and APPBLB
+// HTTPS/SSL listeners of the given clients are switched to the new certificate if they are using
+// OldCertId, or if MatchSAN is set and their hosts are covered by the new certificate.
+type Args struct {
+	// Name is the name of the new cert, the default is "<common name>-<expiry date>"
+	Name string
+
+	// Certificate is the new certificate, loaded from CertFile and KeyFile if nil
+	Certificate *LocalCertificate
+	CertFile    string
+	KeyFile     string
+
+	OldCertId string
+	MatchSAN  bool
+
+	CdnClient    *cdn.Client
+	BlbClient    *blb.Client
+	AppBlbClient *appblb.Client
+
+	// DeleteOld deletes OldCertId after all the bindings are switched, DryRun only reports the
+	// bindings to switch without uploading the new cert
+	DeleteOld bool
+	DryRun    bool
+}
+
+// CertBinding is a CDN domain or a BLB listener using some certificates.
+type CertBinding struct {
+	Service      string // one of CERT_BINDING_CDN, CERT_BINDING_BLB and CERT_BINDING_APPBLB
+	Resource     string // the CDN domain or the BLB ID
+	ListenerType string // "HTTPS" or "SSL" of the listeners, empty for the CDN domains
+	ListenerPort uint16
+	CertIds      []string
+}
+
+func (b *CertBinding) String() string {
+	if b.Service == CERT_BINDING_CDN {
+		return fmt.Sprintf("%s %s", b.Service, b.Resource)
+	}
+	return fmt.Sprintf("%s %s %s:%d", b.Service, b.Resource, b.ListenerType, b.ListenerPort)
+}
+
+// CertSwitchResult is the switch of one binding, NewCertIds are the certs after the switch.
+type CertSwitchResult struct {
+	CertBinding
+	NewCertIds []string
+	Reason     string // CERT_BINDING_REASON_CERT_ID or CERT_BINDING_REASON_SAN
+	Error      error
+}
+
+// Report is the result of a rotation.
+type Report struct {
+	Certificate    *LocalCertificate
+	NewCertId      string
+	Switches       []CertSwitchResult
+	OldCertDeleted bool
+	DeleteError    error
+}
+
+// Failed returns whether any switch or the deletion of the old cert failed.
+func (r *Report) Failed() bool {
+	for _, s := range r.Switches {
+		if s.Error != nil {
+			return true
+		}
+	}
+	return r.DeleteError != nil
+}
+
+// certCandidate is a binding found by a source, apply switches it to the certs.
+type certCandidate struct {
+	binding CertBinding
+	hosts   []string // the hosts served by the binding if known, such as the CDN domain
+	apply   func(certIds []string) error
+}
+
+// RotateCert - upload a new certificate and switch the CDN domains and the BLB listeners to it
+//
+// PARAMS:
+//     - cli: the client of the CERT service
+//     - args: the arguments of the rotation
+// RETURNS:
+//     - *Report: the new cert and the result of each switch
+//     - error: nil if success otherwise the specific error, the failures of the switches are
+//       reported in the report
+func RotateCert(cli *cert.Client, args *Args) (*Report, error) {
+	if args == nil {
+		return nil, fmt.Errorf("unset args")
+	}
+	local := args.Certificate
+	if local == nil {
+		if len(args.CertFile) == 0 || len(args.KeyFile) == 0 {
+			return nil, fmt.Errorf("unset Certificate or CertFile and KeyFile")
+		}
+		var err error
+		if local, err = LoadCertificateFiles(args.CertFile, args.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(local.PrivateData) == 0 {
+		return nil, fmt.Errorf("unset the private key of the certificate")
+	}
+	if !local.NotAfter.After(time.Now()) {
+		return nil, fmt.Errorf("the certificate has expired at %s", local.NotAfter.Format(time.RFC3339))
+	}
+	if len(args.OldCertId) == 0 && !args.MatchSAN {
+		return nil, fmt.Errorf("unset OldCertId or MatchSAN")
+	}
+
+	// the common names of the certs referenced by the listeners are matched with the SANs
+	commonNames := map[string]string{}
+	if args.MatchSAN {
+		certs, err := cli.ListCerts()
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range certs.Certs {
+			commonNames[meta.CertId] = meta.CertCommonName
+		}
+	}
+
+	var sources []func() ([]certCandidate, error)
+	if args.CdnClient != nil {
+		sources = append(sources, cdnCertCandidates(args.CdnClient))
+	}
+	if args.BlbClient != nil {
+		sources = append(sources, blbCertCandidates(args.BlbClient))
+	}
+	if args.AppBlbClient != nil {
+		sources = append(sources, appBlbCertCandidates(args.AppBlbClient))
+	}
+
+	const pendingCertId = "<new>"
+	report := &Report{Certificate: local}
+	var candidates []certCandidate
+	for _, source := range sources {
+		found, err := source()
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range found {
+			certIds, reason := planCertSwitch(&candidate, local, args.OldCertId, args.MatchSAN,
+				commonNames, pendingCertId)
+			if len(reason) == 0 {
+				continue
+			}
+			candidates = append(candidates, candidate)
+			report.Switches = append(report.Switches, CertSwitchResult{
+				CertBinding: candidate.binding,
+				NewCertIds:  certIds,
+				Reason:      reason,
+			})
+		}
+	}
+	if args.DryRun {
+		return report, nil
+	}
+
+	name := args.Name
+	if len(name) == 0 {
+		name = defaultCertName(local)
+	}
+	created, err := cli.CreateCert(local.CreateCertArgs(name))
+	if err != nil {
+		return report, err
+	}
+	newCertId := created.CertId
+	report.NewCertId = newCertId
+
+	for i := range report.Switches {
+		s := &report.Switches[i]
+		for j, id := range s.NewCertIds {
+			if id == pendingCertId {
+				s.NewCertIds[j] = newCertId
+			}
+		}
+		s.Error = candidates[i].apply(s.NewCertIds)
+	}
+
+	if args.DeleteOld && len(args.OldCertId) != 0 && !report.Failed() {
+		if report.DeleteError = cli.DeleteCert(args.OldCertId); report.DeleteError == nil {
+			report.OldCertDeleted = true
+		}
+	}
+	return report, nil
+}
+
+// planCertSwitch returns the certs of the binding after the switch and the reason, the reason is
+// empty if the binding is not affected. The replaced certs are substituted by the newCertId.
+func planCertSwitch(candidate *certCandidate, local *LocalCertificate, oldCertId string,
+	matchSAN bool, commonNames map[string]string, newCertId string) ([]string, string) {
+	hostsCovered := false
+	if matchSAN {
+		for _, host := range candidate.hosts {
+			if local.Covers(host) {
+				hostsCovered = true
+				break
+			}
+		}
+	}
+
+	reason := ""
+	certIds := make([]string, 0, len(candidate.binding.CertIds))
+	replaced := false
+	for _, id := range candidate.binding.CertIds {
+		switch {
+		case len(oldCertId) != 0 && id == oldCertId:
+			reason = CERT_BINDING_REASON_CERT_ID
+		case hostsCovered:
+		case matchSAN && len(commonNames[id]) != 0 && local.Covers(commonNames[id]):
+		default:
+			certIds = append(certIds, id)
+			continue
+		}
+		if len(reason) == 0 {
+			reason = CERT_BINDING_REASON_SAN
+		}
+		if !replaced {
+			certIds = append(certIds, newCertId)
+			replaced = true
+		}
+	}
+	if !replaced {
+		return nil, ""
+	}
+	return certIds, reason
+}
+
+func defaultCertName(local *LocalCertificate) string {
+	name := local.CommonName
+	if len(name) == 0 && len(local.DNSNames) != 0 {
+		name = local.DNSNames[0]
+	}
+	name = strings.Replace(name, "*", "wildcard", -1)
+	return fmt.Sprintf("%s-%s", name, local.NotAfter.UTC().Format("20060102"))
+}
+
+func cdnCertCandidates(cli *cdn.Client) func() ([]certCandidate, error) {
+	return func() ([]certCandidate, error) {
+		var candidates []certCandidate
+		marker := ""
+		for {
+			domains, nextMarker, err := cli.ListDomains(marker)
+			if err != nil {
+				return nil, err
+			}
+			for _, domain := range domains {
+				config, err := cli.GetDomainHttps(domain)
+				if err != nil {
+					return nil, err
+				}
+				if !config.Enabled || len(config.CertId) == 0 {
+					continue
+				}
+				domain := domain
+				candidates = append(candidates, certCandidate{
+					binding: CertBinding{
+						Service:  CERT_BINDING_CDN,
+						Resource: domain,
+						CertIds:  []string{config.CertId},
+					},
+					hosts: []string{domain},
+					apply: func(certIds []string) error {
+						config.CertId = certIds[0]
+						return cli.SetDomainHttps(domain, config)
+					},
+				})
+			}
+			if len(nextMarker) == 0 {
+				return candidates, nil
+			}
+			marker = nextMarker
+		}
+	}
+}
+
+func blbCertCandidates(cli *blb.Client) func() ([]certCandidate, error) {
+	return func() ([]certCandidate, error) {
+		var candidates []certCandidate
+		args := &blb.DescribeLoadBalancersArgs{MaxKeys: certListenerMaxKeys}
+		for {
+			listResult, err := cli.DescribeLoadBalancers(args)
+			if err != nil {
+				return nil, err
+			}
+			for _, lb := range listResult.BlbList {
+				blbId := lb.BlbId
+				listenerArgs := &blb.DescribeListenerArgs{MaxKeys: certListenerMaxKeys}
+				for {
+					httpsResult, err := cli.DescribeHTTPSListeners(blbId, listenerArgs)
+					if err != nil {
+						return nil, err
+					}
+					for _, listener := range httpsResult.ListenerList {
+						port := listener.ListenerPort
+						candidates = append(candidates, certCandidate{
+							binding: CertBinding{CERT_BINDING_BLB, blbId, "HTTPS", port, listener.CertIds},
+							apply: func(certIds []string) error {
+								return cli.UpdateHTTPSListener(blbId, &blb.UpdateHTTPSListenerArgs{
+									ListenerPort: port,
+									CertIds:      certIds,
+								})
+							},
+						})
+					}
+					if !httpsResult.IsTruncated || len(httpsResult.NextMarker) == 0 {
+						break
+					}
+					listenerArgs.Marker = httpsResult.NextMarker
+				}
+
+				listenerArgs = &blb.DescribeListenerArgs{MaxKeys: certListenerMaxKeys}
+				for {
+					sslResult, err := cli.DescribeSSLListeners(blbId, listenerArgs)
+					if err != nil {
+						return nil, err
+					}
+					for _, listener := range sslResult.ListenerList {
+						port := listener.ListenerPort
+						candidates = append(candidates, certCandidate{
+							binding: CertBinding{CERT_BINDING_BLB, blbId, "SSL", port, listener.CertIds},
+							apply: func(certIds []string) error {
+								return cli.UpdateSSLListener(blbId, &blb.UpdateSSLListenerArgs{
+									ListenerPort: port,
+									CertIds:      certIds,
+								})
+							},
+						})
+					}
+					if !sslResult.IsTruncated || len(sslResult.NextMarker) == 0 {
+						break
+					}
+					listenerArgs.Marker = sslResult.NextMarker
+				}
+			}
+			if !listResult.IsTruncated || len(listResult.NextMarker) == 0 {
+				return candidates, nil
+			}
+			args.Marker = listResult.NextMarker
+		}
+	}
+}
+
+func appBlbCertCandidates(cli *appblb.Client) func() ([]certCandidate, error) {
+	return func() ([]certCandidate, error) {
+		var candidates []certCandidate
+		args := &appblb.DescribeLoadBalancersArgs{MaxKeys: certListenerMaxKeys}
+		for {
+			listResult, err := cli.DescribeLoadBalancers(args)
+			if err != nil {
+				return nil, err
+			}
+			for _, lb := range listResult.BlbList {
+				blbId := lb.BlbId
+				listenerArgs := &appblb.DescribeAppListenerArgs{MaxKeys: certListenerMaxKeys}
+				for {
+					httpsResult, err := cli.DescribeAppHTTPSListeners(blbId, listenerArgs)
+					if err != nil {
+						return nil, err
+					}
+					for i := range httpsResult.ListenerList {
+						// the update of the APPBLB listeners overwrites all the settings
+						listener := httpsResult.ListenerList[i]
+						candidates = append(candidates, certCandidate{
+							binding: CertBinding{CERT_BINDING_APPBLB, blbId, "HTTPS", listener.ListenerPort,
+								listener.CertIds},
+							apply: func(certIds []string) error {
+								return cli.UpdateAppHTTPSListener(blbId, &appblb.UpdateAppHTTPSListenerArgs{
+									ListenerPort:          listener.ListenerPort,
+									Scheduler:             listener.Scheduler,
+									KeepSession:           listener.KeepSession,
+									KeepSessionType:       listener.KeepSessionType,
+									KeepSessionTimeout:    listener.KeepSessionTimeout,
+									KeepSessionCookieName: listener.KeepSessionCookieName,
+									XForwardedFor:         listener.XForwardedFor,
+									ServerTimeout:         listener.ServerTimeout,
+									CertIds:               certIds,
+									EncryptionType:        listener.EncryptionType,
+									EncryptionProtocols:   listener.EncryptionProtocols,
+									AppliedCiphers:        listener.AppliedCiphers,
+									DualAuth:              listener.DualAuth,
+									ClientCertIds:         listener.ClientCertIds,
+								})
+							},
+						})
+					}
+					if !httpsResult.IsTruncated || len(httpsResult.NextMarker) == 0 {
+						break
+					}
+					listenerArgs.Marker = httpsResult.NextMarker
+				}
+
+				listenerArgs = &appblb.DescribeAppListenerArgs{MaxKeys: certListenerMaxKeys}
+				for {
+					sslResult, err := cli.DescribeAppSSLListeners(blbId, listenerArgs)
+					if err != nil {
+						return nil, err
+					}
+					for i := range sslResult.ListenerList {
+						listener := sslResult.ListenerList[i]
+						candidates = append(candidates, certCandidate{
+							binding: CertBinding{CERT_BINDING_APPBLB, blbId, "SSL", listener.ListenerPort,
+								listener.CertIds},
+							apply: func(certIds []string) error {
+								return cli.UpdateAppSSLListener(blbId, &appblb.UpdateAppSSLListenerArgs{
+									ListenerPort:        listener.ListenerPort,
+									Scheduler:           listener.Scheduler,
+									CertIds:             certIds,
+									EncryptionType:      listener.EncryptionType,
+									EncryptionProtocols: listener.EncryptionProtocols,
+									AppliedCiphers:      listener.AppliedCiphers,
+									DualAuth:            listener.DualAuth,
+									ClientCertIds:       listener.ClientCertIds,
+								})
+							},
+						})
+					}
+					if !sslResult.IsTruncated || len(sslResult.NextMarker) == 0 {
+						break
+					}
+					listenerArgs.Marker = sslResult.NextMarker
+				}
+			}
+			if !listResult.IsTruncated || len(listResult.NextMarker) == 0 {
+				return candidates, nil
+			}
+			args.Marker = listResult.NextMarker
+		}
+	}
+}
+
+// CertExpiry is a cert with its expiry time, DaysLeft is negative if the cert has expired.
+type CertExpiry struct {
+	cert.CertificateMeta
+	StopTime time.Time
+	DaysLeft int
+}
+
+// ListExpiringCerts - list the certs expiring within the days, including the expired ones
+//
+// PARAMS:
+//     - cli: the client of the CERT service
+//     - days: the number of days from now
+// RETURNS:
+//     - []CertExpiry: the expiring certs sorted by the expiry time
+//     - error: nil if success otherwise the specific error
+func ListExpiringCerts(cli *cert.Client, days int) ([]CertExpiry, error) {
+	result, err := cli.ListCerts()
+	if err != nil {
+		return nil, err
+	}
+	return expiringCerts(result.Certs, days, time.Now())
+}
+
+func expiringCerts(certs []cert.CertificateMeta, days int, now time.Time) ([]CertExpiry, error) {
+	deadline := now.Add(time.Duration(days) * 24 * time.Hour)
+	result := []CertExpiry{}
+	for _, meta := range certs {
+		stopTime, err := parseCertTime(meta.CertStopTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stop time %q of cert %s", meta.CertStopTime, meta.CertId)
+		}
+		if stopTime.After(deadline) {
+			continue
+		}
+		daysLeft := int(math.Floor(stopTime.Sub(now).Hours() / 24))
+		result = append(result, CertExpiry{CertificateMeta: meta, StopTime: stopTime, DaysLeft: daysLeft})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].StopTime.Before(result[j].StopTime) })
+	return result, nil
+}
+
+func parseCertTime(s string) (time.Time, error) {
+	if t, err := util.ParseISO8601Date(s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+}
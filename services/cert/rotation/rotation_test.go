@@ -0,0 +1,302 @@
+package rotation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/services/appblb"
+	"github.com/kougazhang/bce-sdk-go/services/blb"
+	"github.com/kougazhang/bce-sdk-go/services/cdn"
+	"github.com/kougazhang/bce-sdk-go/services/cert"
+)
+
+func generateTestCert(t *testing.T, cn string, dnsNames []string, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestParseCertificatePEM(t *testing.T) {
+	notAfter := time.Now().Add(60 * 24 * time.Hour).Truncate(time.Second)
+	certPEM, keyPEM := generateTestCert(t, "example.com", []string{"example.com", "*.example.com"}, notAfter)
+	chainPEM, _ := generateTestCert(t, "ca", nil, notAfter)
+
+	local, err := ParseCertificatePEM(append(certPEM, chainPEM...), keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local.CommonName != "example.com" || len(local.DNSNames) != 2 || !local.NotAfter.Equal(notAfter) ||
+		local.ServerData != string(certPEM) || local.LinkData != string(chainPEM) {
+		t.Errorf("unexpected certificate %+v", local)
+	}
+	for host, expected := range map[string]bool{
+		"example.com": true, "WWW.example.com.": true, "a.b.example.com": false,
+		"*.example.com": true, "*.b.example.com": false, "example.org": false,
+	} {
+		if local.Covers(host) != expected {
+			t.Errorf("expect covers %s to be %v", host, expected)
+		}
+	}
+
+	_, otherKey := generateTestCert(t, "example.com", nil, notAfter)
+	if _, err := ParseCertificatePEM(certPEM, otherKey); err == nil {
+		t.Errorf("expect error of mismatched key")
+	}
+	if _, err := ParseCertificatePEM(keyPEM, nil); err == nil {
+		t.Errorf("expect error of no certificate")
+	}
+}
+
+// fakeRotation serves the APIs of CERT, CDN, BLB and APPBLB used by the rotation, the listeners
+// are keyed by their URL such as "/v1/blb/lb-1/HTTPSlistener"
+type fakeRotation struct {
+	lock      sync.Mutex
+	certs     []cert.CertificateMeta
+	created   []cert.CreateCertArgs
+	deleted   []string
+	domains   map[string]string // domain -> cert ID
+	blbs      map[string][]string
+	listeners map[string][]map[string]interface{}
+	applied   map[string][]string // the domain or "<BLB ID>:<port>" -> cert IDs
+	broken    string              // the BLB failing to update
+}
+
+func newFakeRotation() *fakeRotation {
+	return &fakeRotation{
+		certs: []cert.CertificateMeta{
+			{CertId: "cert-old", CertCommonName: "example.com"},
+			{CertId: "cert-www", CertCommonName: "www.example.com"},
+			{CertId: "cert-other", CertCommonName: "example.org"},
+		},
+		domains: map[string]string{"img.example.com": "cert-any", "example.org": "cert-other"},
+		blbs:    map[string][]string{"blb": {"lb-1"}, "appblb": {"lb-2"}},
+		listeners: map[string][]map[string]interface{}{
+			"/v1/blb/lb-1/HTTPSlistener": {{"listenerPort": 443,
+				"certIds": []string{"cert-old", "cert-other"}}},
+			"/v1/appblb/lb-2/SSLlistener": {{"listenerPort": 8443, "scheduler": "RoundRobin",
+				"certIds": []string{"cert-other", "cert-www"}}},
+		},
+		applied: map[string][]string{},
+	}
+}
+
+func (f *fakeRotation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/v1/certificate" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(&cert.ListCertResult{Certs: f.certs})
+	case r.URL.Path == "/v1/certificate" && r.Method == http.MethodPost:
+		args := cert.CreateCertArgs{}
+		json.Unmarshal(body, &args)
+		f.created = append(f.created, args)
+		json.NewEncoder(w).Encode(&cert.CreateCertResult{CertName: args.CertName, CertId: "cert-new"})
+	case len(path) == 3 && path[1] == "certificate" && r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, path[2])
+	case r.URL.Path == "/v2/domain":
+		// one domain per page
+		names := []string{}
+		for name := range f.domains {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			if name <= r.URL.Query().Get("marker") {
+				continue
+			}
+			next := ""
+			if i+1 < len(names) {
+				next = name
+			}
+			fmt.Fprintf(w, `{"isTruncated":%v,"domains":[{"name":%q}],"nextMarker":%q}`,
+				len(next) != 0, name, next)
+			return
+		}
+		fmt.Fprint(w, `{"domains":[]}`)
+	case len(path) == 4 && path[1] == "domain" && r.Method == http.MethodGet:
+		fmt.Fprintf(w, `{"https":{"enabled":true,"certId":%q,"http2Enabled":true}}`, f.domains[path[2]])
+	case len(path) == 4 && path[1] == "domain" && r.Method == http.MethodPut:
+		config := &struct {
+			Https map[string]interface{} `json:"https"`
+		}{}
+		json.Unmarshal(body, config)
+		// the switch keeps the other settings of HTTPS
+		if config.Https["http2Enabled"] != true {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"BadRequest","message":"http2 is disabled"}`)
+			return
+		}
+		f.applied[path[2]] = []string{config.Https["certId"].(string)}
+	case len(path) == 2 && r.Method == http.MethodGet:
+		blbs := []map[string]string{}
+		for _, id := range f.blbs[path[1]] {
+			blbs = append(blbs, map[string]string{"blbId": id})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"blbList": blbs})
+	case len(path) == 4 && r.Method == http.MethodGet:
+		listeners := f.listeners[r.URL.Path]
+		if listeners == nil {
+			listeners = []map[string]interface{}{}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"listenerList": listeners})
+	case len(path) == 4 && r.Method == http.MethodPut:
+		if path[2] == f.broken {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code":"InternalError","message":"update failed"}`)
+			return
+		}
+		args := &struct {
+			CertIds []string `json:"certIds"`
+		}{}
+		json.Unmarshal(body, args)
+		f.applied[path[2]+":"+r.URL.Query().Get("listenerPort")] = args.CertIds
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestRotateCert(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t, "*.example.com", []string{"*.example.com"},
+		time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC))
+	local, err := ParseCertificatePEM(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeRotation()
+	server := httptest.NewServer(f)
+	defer server.Close()
+	certClient, _ := cert.NewClient("ak", "sk", server.URL)
+	cdnClient, _ := cdn.NewClient("ak", "sk", server.URL)
+	blbClient, _ := blb.NewClient("ak", "sk", server.URL)
+	appBlbClient, _ := appblb.NewClient("ak", "sk", server.URL)
+	for _, c := range []*bce.BceClient{certClient.BceClient, cdnClient.BceClient, blbClient.BceClient,
+		appBlbClient.BceClient} {
+		c.Config.Retry = bce.NewNoRetryPolicy()
+	}
+
+	args := &Args{Certificate: local, OldCertId: "cert-old", DeleteOld: true, DryRun: true,
+		CdnClient: cdnClient, BlbClient: blbClient, AppBlbClient: appBlbClient}
+	report, err := RotateCert(certClient, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Switches) != 1 || report.Switches[0].Reason != CERT_BINDING_REASON_CERT_ID ||
+		len(f.applied) != 0 || len(f.created) != 0 || report.NewCertId != "" {
+		t.Fatalf("unexpected dry run report %+v", report)
+	}
+
+	args.DryRun, args.MatchSAN = false, true
+	if report, err = RotateCert(certClient, args); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.created) != 1 || f.created[0].CertName != "wildcard.example.com-20990102" ||
+		f.created[0].CertPrivateData != string(keyPEM) || report.NewCertId != "cert-new" {
+		t.Errorf("unexpected created cert %+v", f.created)
+	}
+	expected := map[string][]string{
+		"img.example.com": {"cert-new"},
+		"lb-1:443":        {"cert-new", "cert-other"},
+		"lb-2:8443":       {"cert-other", "cert-new"},
+	}
+	if !reflect.DeepEqual(f.applied, expected) ||
+		report.Switches[0].Reason != CERT_BINDING_REASON_SAN {
+		t.Errorf("unexpected switches %v of report %+v", f.applied, report.Switches)
+	}
+	if !report.OldCertDeleted || !reflect.DeepEqual(f.deleted, []string{"cert-old"}) ||
+		report.Failed() {
+		t.Errorf("expect old cert deleted, got %+v", report)
+	}
+}
+
+func TestRotateCertPartialFailure(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t, "*.example.com", []string{"*.example.com"},
+		time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC))
+	local, err := ParseCertificatePEM(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeRotation()
+	f.blbs["blb"] = append(f.blbs["blb"], "broken")
+	f.listeners["/v1/blb/broken/SSLlistener"] = []map[string]interface{}{
+		{"listenerPort": 443, "certIds": []string{"cert-old"}}}
+	f.broken = "broken"
+	server := httptest.NewServer(f)
+	defer server.Close()
+	certClient, _ := cert.NewClient("ak", "sk", server.URL)
+	blbClient, _ := blb.NewClient("ak", "sk", server.URL)
+	certClient.Config.Retry = bce.NewNoRetryPolicy()
+	blbClient.Config.Retry = bce.NewNoRetryPolicy()
+
+	// the old cert is kept if any switch failed
+	args := &Args{Certificate: local, OldCertId: "cert-old", DeleteOld: true, BlbClient: blbClient}
+	report, err := RotateCert(certClient, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Switches) != 2 || report.Switches[0].Error != nil ||
+		report.Switches[1].Error == nil {
+		t.Errorf("expect the switch of the broken BLB failed, got %+v", report.Switches)
+	}
+	if !report.Failed() || report.OldCertDeleted || len(f.deleted) != 0 {
+		t.Errorf("expect failed report without deletion, got %+v", report)
+	}
+}
+
+func TestExpiringCerts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	certs := []cert.CertificateMeta{
+		{CertId: "a", CertStopTime: "2024-03-01T00:00:00Z"},
+		{CertId: "b", CertStopTime: "2024-01-10T12:00:00Z"},
+		{CertId: "c", CertStopTime: "2023-12-30T00:00:00Z"},
+	}
+	result, err := expiringCerts(certs, 30, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || result[0].CertId != "c" || result[0].DaysLeft != -2 ||
+		result[1].CertId != "b" || result[1].DaysLeft != 9 {
+		t.Errorf("unexpected expiring certs %+v", result)
+	}
+	if _, err := expiringCerts([]cert.CertificateMeta{{CertId: "d", CertStopTime: "soon"}}, 30, now); err == nil {
+		t.Errorf("expect error of invalid stop time")
+	}
+}
@@ -39,7 +39,7 @@ func (c *Client) PurchaseReserved(hostID string, args *PurchaseReservedArgs) (er
 		WithMethod(http.PUT).
 		WithURL(bce.URI_PREFIX+"v1/dedicatedHost/"+hostID).
 		WithQueryParamFilter("purchaseReserved", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(&args).
 		Do()
 	return
@@ -50,7 +50,7 @@ func (c *Client) Create(args *CreateArgs) (ret *CreateResult, err error) {
 	err = bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(bce.URI_PREFIX+"v1/dedicatedHost").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(&args).
 		WithResult(&ret).
 		Do()
@@ -96,7 +96,7 @@ func (c *Client) CreateInstance(args *CreateInstanceArgs) (ret *CreateInstanceRe
 	err = bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(bce.URI_PREFIX+"v1/dedicatedHost/instance").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(&args).
 		WithResult(&ret).
 		Do()
@@ -61,7 +61,7 @@ func (c *Client) CreateInstance(args *CreateInstanceArgs) (*CreateResult, error)
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getDdcInstanceUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		WithResult(result).
@@ -890,6 +890,7 @@ func (c *Client) CreateBackup(instanceId string) error {
 	result := &bce.BceResponse{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getDdcUriWithInstanceId(instanceId)+"/snapshot").
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithResult(result).
@@ -1023,7 +1024,7 @@ func (c *Client) CreateDatabase(instanceId string, args *CreateDatabaseArgs) err
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getDatabaseUriWithInstanceId(instanceId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		Do()
@@ -1148,7 +1149,7 @@ func (c *Client) CreateAccount(instanceId string, args *CreateAccountArgs) error
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAccountUriWithInstanceId(instanceId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		Do()
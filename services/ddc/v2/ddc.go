@@ -74,7 +74,7 @@ func (c *DDCClient) CreateInstance(args *CreateInstanceArgs) (*CreateResult, err
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getDdcInstanceUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		WithResult(result).
@@ -977,6 +977,7 @@ func (c *DDCClient) CreateBackup(instanceId string) error {
 	result := &bce.BceResponse{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getDdcUriWithInstanceId(instanceId)+"/snapshot").
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithResult(result).
@@ -1110,7 +1111,7 @@ func (c *DDCClient) CreateDatabase(instanceId string, args *CreateDatabaseArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getDatabaseUriWithInstanceId(instanceId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		Do()
@@ -1335,7 +1336,7 @@ func (c *DDCClient) CreateAccount(instanceId string, args *CreateAccountArgs) er
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getAccountUriWithInstanceId(instanceId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		Do()
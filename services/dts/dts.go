@@ -56,6 +56,7 @@ func (c *Client) CreateDts(args *CreateDtsArgs) (*CreateDtsResult, error) {
 	result := &CreateDtsResult{}
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithURL(getDtsUri()).
 		WithQueryParamFilter("orderType","NEW").
 		WithBody(args).
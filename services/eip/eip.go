@@ -43,7 +43,7 @@ func (c *Client) CreateEip(args *CreateEipArgs) (*CreateEipResult, error) {
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getEipUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -66,7 +66,7 @@ func (c *Client) ResizeEip(eip string, args *ResizeEipArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("resize", "").
 		WithBody(args).
 		Do()
@@ -87,7 +87,7 @@ func (c *Client) BindEip(eip string, args *BindEipArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("bind", "").
 		WithBody(args).
 		Do()
@@ -104,7 +104,7 @@ func (c *Client) UnBindEip(eip, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithQueryParam("unbind", "").
 		Do()
 }
@@ -120,7 +120,7 @@ func (c *Client) DeleteEip(eip, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.DELETE).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -171,7 +171,7 @@ func (c *Client) PurchaseReservedEip(eip string, args *PurchaseReservedEipArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -192,7 +192,7 @@ func (c *Client) StartAutoRenew(eip string, args *StartAutoRenewArgs) error {
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
 		WithQueryParam("startAutoRenew", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -209,7 +209,7 @@ func (c *Client) StopAutoRenew(eip string, clientToken string) error {
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
 		WithQueryParam("stopAutoRenew", "").
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -275,7 +275,7 @@ func (c *Client) DirectEip(eip, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithQueryParam("direct", "").
 		Do()
 }
@@ -290,7 +290,7 @@ func (c *Client) UnDirectEip(eip, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(getEipUriWithEip(eip)).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithQueryParam("unDirect", "").
 		Do()
 }
@@ -313,7 +313,7 @@ func (c *Client) CreateEipTp(args *CreateEipTpArgs) (*CreateEipTpResult, error)
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getEipTpUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
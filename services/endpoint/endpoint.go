@@ -55,7 +55,7 @@ func (c *Client) CreateEndpoint(args *CreateEndpointArgs) (*CreateEndpointResult
 		WithURL(getURLForEndpoint()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -73,7 +73,7 @@ func (c *Client) DeleteEndpoint(endpointId string, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEndpointId(endpointId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -93,7 +93,7 @@ func (c *Client) UpdateEndpoint(endpointId string, args *UpdateEndpointArgs) err
 		WithURL(getURLForEndpointId(endpointId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
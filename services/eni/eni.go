@@ -41,7 +41,7 @@ func (c *Client) CreateEni(args *CreateEniArgs) (*CreateEniResult, error) {
 		WithURL(getURLForEni()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -63,7 +63,7 @@ func (c *Client) UpdateEni(args *UpdateEniArgs) error {
 		WithURL(getURLForEniId(args.EniId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("modifyAttribute", "").
 		Do()
 }
@@ -78,7 +78,7 @@ func (c *Client) DeleteEni(args *DeleteEniArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEniId(args.EniId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -156,7 +156,7 @@ func (c *Client) AddPrivateIp(args *EniPrivateIpArgs) (*AddPrivateIpResult, erro
 		WithURL(getURLForEniId(args.EniId)+"/privateIp").
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -177,7 +177,7 @@ func (c *Client) DeletePrivateIp(args *EniPrivateIpArgs) error {
 	err := bce.NewRequestBuilder(c).
 		WithURL(getURLForEniId(args.EniId)+"/privateIp/"+args.PrivateIpAddress).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
@@ -199,7 +199,7 @@ func (c *Client) AttachEniInstance(args *EniInstance) error {
 		WithMethod(http.PUT).
 		WithQueryParam("attach", "").
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
@@ -221,7 +221,7 @@ func (c *Client) DetachEniInstance(args *EniInstance) error {
 		WithMethod(http.PUT).
 		WithQueryParam("detach", "").
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
@@ -243,7 +243,7 @@ func (c *Client) BindEniPublicIp(args *BindEniPublicIpArgs) error {
 		WithMethod(http.PUT).
 		WithQueryParam("bind", "").
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
@@ -265,7 +265,7 @@ func (c *Client) UnBindEniPublicIp(args *UnBindEniPublicIpArgs) error {
 		WithMethod(http.PUT).
 		WithQueryParam("unBind", "").
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
@@ -287,7 +287,7 @@ func (c *Client) UpdateEniSecurityGroup(args *UpdateEniSecurityGroupArgs) error
 		WithMethod(http.PUT).
 		WithQueryParam("bindSg", "").
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 
 	return err
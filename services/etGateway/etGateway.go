@@ -25,7 +25,7 @@ func (c *Client) CreateEtGateway(args *CreateEtGatewayArgs) (*CreateEtGatewayRes
 		WithURL(getURLForEtGateway()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -85,7 +85,7 @@ func (c *Client) GetEtGatewayDetail(etGatewayId string) (*EtGatewayDetail, error
 func (c *Client) UpdateEtGateway(updateEtGatewayArgs *UpdateEtGatewayArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEtGatewayId(updateEtGatewayArgs.EtGatewayId)).
-		WithQueryParamFilter("clientToken", updateEtGatewayArgs.ClientToken).
+		WithClientToken(updateEtGatewayArgs.ClientToken).
 		WithMethod(http.PUT).
 		WithBody(updateEtGatewayArgs).
 		Do()
@@ -100,7 +100,7 @@ func (c *Client) UpdateEtGateway(updateEtGatewayArgs *UpdateEtGatewayArgs) error
 func (c *Client) DeleteEtGateway(etGatewayId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEtGatewayId(etGatewayId)).
-		WithQueryParam("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithMethod(http.DELETE).
 		Do()
 }
@@ -113,7 +113,7 @@ func (c *Client) DeleteEtGateway(etGatewayId, clientToken string) error {
 func (c *Client) BindEt(args *BindEtArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEtGatewayId(args.EtGatewayId)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("bind", "").
 		WithBody(args).
 		WithMethod(http.PUT).
@@ -128,7 +128,7 @@ func (c *Client) BindEt(args *BindEtArgs) error {
 func (c *Client) UnBindEt(EtGatewayId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEtGatewayId(EtGatewayId)).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithQueryParam("unbind", "").
 		WithMethod(http.PUT).
 		Do()
@@ -142,7 +142,7 @@ func (c *Client) UnBindEt(EtGatewayId, clientToken string) error {
 func (c *Client) CreateHealthCheck(args *CreateHealthCheckArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForEtGatewayId(args.EtGatewayId)+"/healthCheck").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithMethod(http.POST).
 		Do()
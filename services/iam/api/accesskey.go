@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+package api
+
+import (
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/http"
+)
+
+// CreateAccessKey - create an access key for the sub-user, a user has at most 2 access keys
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the sub-user
+// RETURNS:
+//     - *CreateAccessKeyResult: the new access key, its secret is only returned here
+//     - error: nil if success otherwise the specific error
+func CreateAccessKey(cli bce.Client, userName string) (*CreateAccessKeyResult, error) {
+	req := &bce.BceRequest{}
+	req.SetUri(getAccessKeyUri(userName))
+	req.SetMethod(http.POST)
+	req.SetIdempotent(false)
+
+	resp := &bce.BceResponse{}
+	if err := cli.SendRequest(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.IsFail() {
+		return nil, resp.ServiceError()
+	}
+	jsonBody := &CreateAccessKeyResult{}
+	if err := resp.ParseJsonBody(jsonBody); err != nil {
+		return nil, err
+	}
+	return jsonBody, nil
+}
+
+// DisableAccessKey - disable the access key of the sub-user, it can be enabled again
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to disable
+// RETURNS:
+//     - *UpdateAccessKeyResult: the access key after the update
+//     - error: nil if success otherwise the specific error
+func DisableAccessKey(cli bce.Client, userName, accessKeyId string) (*UpdateAccessKeyResult, error) {
+	return updateAccessKey(cli, userName, accessKeyId, "disable")
+}
+
+// EnableAccessKey - enable the disabled access key of the sub-user
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to enable
+// RETURNS:
+//     - *UpdateAccessKeyResult: the access key after the update
+//     - error: nil if success otherwise the specific error
+func EnableAccessKey(cli bce.Client, userName, accessKeyId string) (*UpdateAccessKeyResult, error) {
+	return updateAccessKey(cli, userName, accessKeyId, "enable")
+}
+
+// updateAccessKey sends the action, "enable" or "disable", to the access key of the sub-user
+func updateAccessKey(cli bce.Client, userName, accessKeyId, action string) (*UpdateAccessKeyResult, error) {
+	req := &bce.BceRequest{}
+	req.SetUri(getAccessKeyUri(userName) + "/" + accessKeyId)
+	req.SetParam(action, "")
+	req.SetMethod(http.PUT)
+
+	resp := &bce.BceResponse{}
+	if err := cli.SendRequest(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.IsFail() {
+		return nil, resp.ServiceError()
+	}
+	jsonBody := &UpdateAccessKeyResult{}
+	if err := resp.ParseJsonBody(jsonBody); err != nil {
+		return nil, err
+	}
+	return jsonBody, nil
+}
+
+// DeleteAccessKey - delete the access key of the sub-user
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to delete
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func DeleteAccessKey(cli bce.Client, userName, accessKeyId string) error {
+	req := &bce.BceRequest{}
+	req.SetUri(getAccessKeyUri(userName) + "/" + accessKeyId)
+	req.SetMethod(http.DELETE)
+
+	resp := &bce.BceResponse{}
+	if err := cli.SendRequest(req, resp); err != nil {
+		return err
+	}
+	if resp.IsFail() {
+		return resp.ServiceError()
+	}
+	return nil
+}
+
+// ListAccessKey - list the access keys of the sub-user, the secrets are not returned
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the sub-user
+// RETURNS:
+//     - *ListAccessKeyResult: the access keys of the sub-user
+//     - error: nil if success otherwise the specific error
+func ListAccessKey(cli bce.Client, userName string) (*ListAccessKeyResult, error) {
+	req := &bce.BceRequest{}
+	req.SetUri(getAccessKeyUri(userName))
+	req.SetMethod(http.GET)
+
+	resp := &bce.BceResponse{}
+	if err := cli.SendRequest(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.IsFail() {
+		return nil, resp.ServiceError()
+	}
+	jsonBody := &ListAccessKeyResult{}
+	if err := resp.ParseJsonBody(jsonBody); err != nil {
+		return nil, err
+	}
+	return jsonBody, nil
+}
+
+func getAccessKeyUri(userName string) string {
+	return getUserUri(userName) + URI_ACCESSKEY
+}
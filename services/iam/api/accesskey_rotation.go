@@ -0,0 +1,225 @@
+/*
+ * Copyright 2021 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// accesskey_rotation.go - the rotation workflow of the access keys of a sub-user
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/auth"
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+const (
+	MAX_ACCESS_KEYS_PER_USER = 2
+
+	DEFAULT_ACCESS_KEY_VERIFY_TIMEOUT  = 2 * time.Minute
+	DEFAULT_ACCESS_KEY_VERIFY_INTERVAL = 5 * time.Second
+)
+
+// AccessKeyRotationArgs defines the rotation of an access key of a sub-user. The new key is
+// created and verified before it is distributed, and the old key is disabled after the
+// distribution. The old key is kept disabled so that it can be enabled again if something still
+// depends on it, it is deleted by FinishAccessKeyRotation later.
+type AccessKeyRotationArgs struct {
+	UserName string
+
+	// OldAccessKeyId is the key to retire, the default is the only enabled key of the user
+	OldAccessKeyId string
+
+	// Distribute hands the verified new key to its consumers, it is required and it should return
+	// after the consumers have switched to the new key. If it fails, both keys are kept enabled as
+	// some consumers may have switched, and the new key is returned with the error.
+	Distribute func(newKey *AccessKeyModel) error
+
+	// Verify checks that the new key works, the default sends a signed request to IAM with it.
+	// The new key may take a while to take effect, so Verify is retried until VerifyTimeout. The
+	// new key is deleted if it is not verified, as nothing has used it yet.
+	Verify         func(accessKeyId, secretAccessKey string) error
+	VerifyTimeout  time.Duration
+	VerifyInterval time.Duration
+}
+
+// AccessKeyRotationResult is the result of a rotation.
+type AccessKeyRotationResult struct {
+	UserName       string
+	NewAccessKey   *AccessKeyModel
+	OldAccessKeyId string
+	OldDisabled    bool
+	OldDeleted     bool
+}
+
+// RotateAccessKey - rotate an access key of the sub-user
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - args: the arguments of the rotation
+// RETURNS:
+//     - *AccessKeyRotationResult: the result of the rotation, it is returned with the error if
+//       the new key has been verified but it is not distributed or the old key is not disabled
+//     - error: nil if success otherwise the specific error
+func RotateAccessKey(cli bce.Client, args *AccessKeyRotationArgs) (*AccessKeyRotationResult, error) {
+	if args == nil || len(args.UserName) == 0 {
+		return nil, fmt.Errorf("unset UserName")
+	}
+	if args.Distribute == nil {
+		return nil, fmt.Errorf("unset Distribute")
+	}
+	verify := args.Verify
+	if verify == nil {
+		verify = func(accessKeyId, secretAccessKey string) error {
+			return VerifyAccessKey(cli, args.UserName, accessKeyId, secretAccessKey)
+		}
+	}
+	verifyTimeout := args.VerifyTimeout
+	if verifyTimeout <= 0 {
+		verifyTimeout = DEFAULT_ACCESS_KEY_VERIFY_TIMEOUT
+	}
+	verifyInterval := args.VerifyInterval
+	if verifyInterval <= 0 {
+		verifyInterval = DEFAULT_ACCESS_KEY_VERIFY_INTERVAL
+	}
+
+	listResult, err := ListAccessKey(cli, args.UserName)
+	if err != nil {
+		return nil, err
+	}
+	keys := listResult.AccessKeys
+	oldId := args.OldAccessKeyId
+	if len(oldId) == 0 {
+		for _, key := range keys {
+			if !key.Enabled {
+				continue
+			}
+			if len(oldId) != 0 {
+				return nil, fmt.Errorf("user %s has more than one enabled access key, set OldAccessKeyId",
+					args.UserName)
+			}
+			oldId = key.Id
+		}
+		if len(oldId) == 0 {
+			return nil, fmt.Errorf("user %s has no enabled access key to rotate", args.UserName)
+		}
+	} else {
+		found := false
+		for _, key := range keys {
+			found = found || key.Id == oldId
+		}
+		if !found {
+			return nil, fmt.Errorf("access key %s of user %s not found", oldId, args.UserName)
+		}
+	}
+	if len(keys) >= MAX_ACCESS_KEYS_PER_USER {
+		return nil, fmt.Errorf("user %s already has %d access keys, delete the unused one first",
+			args.UserName, len(keys))
+	}
+
+	created, err := CreateAccessKey(cli, args.UserName)
+	if err != nil {
+		return nil, err
+	}
+	newKey := (*AccessKeyModel)(created)
+	for deadline := time.Now().Add(verifyTimeout); ; {
+		err := verify(newKey.Id, newKey.Secret)
+		if err == nil {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			// the new key is not distributed yet, so it is safe to remove
+			cause := fmt.Errorf("verify the new access key: %v", err)
+			if err := DeleteAccessKey(cli, args.UserName, newKey.Id); err != nil {
+				return nil, fmt.Errorf("%v, and delete the new access key %s failed: %v", cause,
+					newKey.Id, err)
+			}
+			return nil, cause
+		}
+		time.Sleep(verifyInterval)
+	}
+
+	result := &AccessKeyRotationResult{
+		UserName:       args.UserName,
+		NewAccessKey:   newKey,
+		OldAccessKeyId: oldId,
+	}
+	if err := args.Distribute(newKey); err != nil {
+		return result, fmt.Errorf("distribute the new access key %s: %v", newKey.Id, err)
+	}
+	if _, err := DisableAccessKey(cli, args.UserName, oldId); err != nil {
+		return result, fmt.Errorf("disable the old access key %s: %v", oldId, err)
+	}
+	result.OldDisabled = true
+	return result, nil
+}
+
+// VerifyAccessKey - verify the access key by sending a signed request with it to the endpoint of
+// the client, the key is valid if the request is authenticated even if it is not authorized
+//
+// PARAMS:
+//     - cli: the client agent whose configuration is used except the credentials
+//     - userName: the user to query by the signed request
+//     - accessKeyId: the access key ID to verify
+//     - secretAccessKey: the secret access key to verify
+// RETURNS:
+//     - error: nil if the key is valid otherwise the specific error
+func VerifyAccessKey(cli bce.Client, userName, accessKeyId, secretAccessKey string) error {
+	credentials, err := auth.NewBceCredentials(accessKeyId, secretAccessKey)
+	if err != nil {
+		return err
+	}
+	conf := *cli.GetBceClientConfig()
+	conf.Credentials = credentials
+	conf.Retry = bce.NewNoRetryPolicy()
+	_, err = GetUser(bce.NewBceClient(&conf, &auth.BceV1Signer{}), userName)
+	if realErr, ok := err.(*bce.BceServiceError); ok && realErr.Code == bce.EACCESS_DENIED {
+		return nil
+	}
+	return err
+}
+
+// FinishAccessKeyRotation - delete the old access key disabled by the rotation, it is refused if
+// the old key has been enabled again as something may still depend on it
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - result: the result of RotateAccessKey, its OldDeleted is set if the old key is deleted
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func FinishAccessKeyRotation(cli bce.Client, result *AccessKeyRotationResult) error {
+	if result == nil || len(result.UserName) == 0 || len(result.OldAccessKeyId) == 0 {
+		return fmt.Errorf("unset UserName or OldAccessKeyId of the rotation")
+	}
+	if result.OldDeleted {
+		return nil
+	}
+	if !result.OldDisabled {
+		return fmt.Errorf("the old access key %s is not disabled by the rotation", result.OldAccessKeyId)
+	}
+	listResult, err := ListAccessKey(cli, result.UserName)
+	if err != nil {
+		return err
+	}
+	for _, key := range listResult.AccessKeys {
+		if key.Id == result.OldAccessKeyId && key.Enabled {
+			return fmt.Errorf("the old access key %s has been enabled again", result.OldAccessKeyId)
+		}
+	}
+	if err := DeleteAccessKey(cli, result.UserName, result.OldAccessKeyId); err != nil {
+		return fmt.Errorf("delete the old access key %s: %v", result.OldAccessKeyId, err)
+	}
+	result.OldDeleted = true
+	return nil
+}
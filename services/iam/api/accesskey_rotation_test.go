@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+// fakeAccessKeys serves the access key APIs of the user "user" with the admin key "ak". A request
+// signed by an access key of the user is authenticated but not authorized, a new key is rejected
+// for the first `pending` requests as it takes a while to take effect.
+type fakeAccessKeys struct {
+	lock    sync.Mutex
+	keys    []AccessKeyModel
+	calls   []string
+	created int
+	pending int
+}
+
+func (f *fakeAccessKeys) record(call string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeAccessKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	// the authorization is "bce-auth-v1/<access key ID>/..."
+	signer := strings.Split(r.Header.Get("Authorization"), "/")[1]
+	path := strings.TrimPrefix(r.URL.Path, "/v1/user/user")
+	switch {
+	case signer != "ak":
+		f.calls = append(f.calls, "verify "+signer)
+		if f.pending > 0 {
+			f.pending--
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":"InvalidAccessKeyId","message":"the access key is invalid"}`)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"code":"AccessDenied","message":"access denied"}`)
+	case path == "/accesskey" && r.Method == http.MethodGet:
+		fmt.Fprint(w, `{"accessKeys":[`)
+		for i, key := range f.keys {
+			if i != 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%q,"enabled":%v}`, key.Id, key.Enabled)
+		}
+		fmt.Fprint(w, `]}`)
+	case path == "/accesskey" && r.Method == http.MethodPost:
+		f.created++
+		key := AccessKeyModel{Id: fmt.Sprintf("ak-%d", f.created+1), Secret: "sk", Enabled: true}
+		f.keys = append(f.keys, key)
+		f.calls = append(f.calls, "create "+key.Id)
+		fmt.Fprintf(w, `{"id":%q,"secret":"sk","enabled":true}`, key.Id)
+	case strings.HasPrefix(path, "/accesskey/") && r.Method == http.MethodPut:
+		id := strings.TrimPrefix(path, "/accesskey/")
+		_, enable := r.URL.Query()["enable"]
+		for i := range f.keys {
+			if f.keys[i].Id == id {
+				f.keys[i].Enabled = enable
+			}
+		}
+		if enable {
+			f.calls = append(f.calls, "enable "+id)
+		} else {
+			f.calls = append(f.calls, "disable "+id)
+		}
+		fmt.Fprintf(w, `{"id":%q,"enabled":%v}`, id, enable)
+	case strings.HasPrefix(path, "/accesskey/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, "/accesskey/")
+		for i := range f.keys {
+			if f.keys[i].Id == id {
+				f.keys = append(f.keys[:i], f.keys[i+1:]...)
+				break
+			}
+		}
+		f.calls = append(f.calls, "delete "+id)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newAccessKeyTestClient(t *testing.T, f *fakeAccessKeys) (*bce.BceClient, func()) {
+	server := httptest.NewServer(f)
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.Retry = bce.NewNoRetryPolicy()
+	return client, server.Close
+}
+
+func TestRotateAccessKey(t *testing.T) {
+	f := &fakeAccessKeys{keys: []AccessKeyModel{{Id: "ak-1", Enabled: true}}, pending: 2}
+	client, clean := newAccessKeyTestClient(t, f)
+	defer clean()
+
+	args := &AccessKeyRotationArgs{
+		UserName: "user",
+		Distribute: func(newKey *AccessKeyModel) error {
+			f.record("distribute " + newKey.Id)
+			return nil
+		},
+		VerifyInterval: 10 * time.Millisecond,
+	}
+	result, err := RotateAccessKey(client, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new key is verified by the default signed request before it is distributed
+	expected := []string{"create ak-2", "verify ak-2", "verify ak-2", "verify ak-2",
+		"distribute ak-2", "disable ak-1"}
+	if !reflect.DeepEqual(f.calls, expected) {
+		t.Errorf("expect calls %v, got %v", expected, f.calls)
+	}
+	if result.UserName != "user" || result.NewAccessKey.Id != "ak-2" ||
+		result.OldAccessKeyId != "ak-1" || !result.OldDisabled || result.OldDeleted || len(f.keys) != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// the disabled key is kept and the next rotation is refused as the user has two keys
+	if _, err := RotateAccessKey(client, args); err == nil ||
+		!strings.Contains(err.Error(), "already has") {
+		t.Errorf("expect error of too many keys, got %v", err)
+	}
+
+	f.calls = nil
+	if err := FinishAccessKeyRotation(client, result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.calls, []string{"delete ak-1"}) || !result.OldDeleted || len(f.keys) != 1 {
+		t.Errorf("expect the old key deleted, got calls %v of result %+v", f.calls, result)
+	}
+}
+
+func TestFinishAccessKeyRotation(t *testing.T) {
+	f := &fakeAccessKeys{keys: []AccessKeyModel{{Id: "ak-1", Enabled: true},
+		{Id: "ak-2", Enabled: true}}}
+	client, clean := newAccessKeyTestClient(t, f)
+	defer clean()
+
+	result := &AccessKeyRotationResult{UserName: "user", OldAccessKeyId: "ak-1", OldDisabled: true}
+	if err := FinishAccessKeyRotation(client, result); err == nil ||
+		!strings.Contains(err.Error(), "enabled again") || result.OldDeleted {
+		t.Errorf("expect error of the enabled old key, got %v", err)
+	}
+	result.OldDisabled = false
+	if err := FinishAccessKeyRotation(client, result); err == nil || len(f.calls) != 0 {
+		t.Errorf("expect error of the old key not disabled, got %v", err)
+	}
+	if err := FinishAccessKeyRotation(client, &AccessKeyRotationResult{}); err == nil {
+		t.Errorf("expect error of unset user")
+	}
+}
+
+func TestRotateAccessKeyVerifyFailure(t *testing.T) {
+	f := &fakeAccessKeys{keys: []AccessKeyModel{{Id: "ak-1", Enabled: true}}}
+	client, clean := newAccessKeyTestClient(t, f)
+	defer clean()
+
+	args := &AccessKeyRotationArgs{
+		UserName: "user",
+		Distribute: func(newKey *AccessKeyModel) error {
+			f.record("distribute " + newKey.Id)
+			return nil
+		},
+		Verify:         func(ak, sk string) error { return fmt.Errorf("SignatureDoesNotMatch") },
+		VerifyTimeout:  50 * time.Millisecond,
+		VerifyInterval: 10 * time.Millisecond,
+	}
+	result, err := RotateAccessKey(client, args)
+	if err == nil || !strings.Contains(err.Error(), "verify") || result != nil {
+		t.Errorf("expect error of verification, got %+v %v", result, err)
+	}
+	// nothing has used the new key, so it is deleted without distributing
+	if !reflect.DeepEqual(f.calls, []string{"create ak-2", "delete ak-2"}) {
+		t.Errorf("expect the new key deleted before distributing, got calls %v", f.calls)
+	}
+	if len(f.keys) != 1 || f.keys[0].Id != "ak-1" || !f.keys[0].Enabled {
+		t.Errorf("expect the old key untouched, got %+v", f.keys)
+	}
+}
+
+func TestRotateAccessKeyDistributeFailure(t *testing.T) {
+	f := &fakeAccessKeys{keys: []AccessKeyModel{{Id: "ak-1", Enabled: true}}}
+	client, clean := newAccessKeyTestClient(t, f)
+	defer clean()
+
+	args := &AccessKeyRotationArgs{
+		UserName:   "user",
+		Distribute: func(newKey *AccessKeyModel) error { return fmt.Errorf("vault unavailable") },
+	}
+	result, err := RotateAccessKey(client, args)
+	if err == nil || !strings.Contains(err.Error(), "distribute") {
+		t.Fatalf("expect error of distribution, got %v", err)
+	}
+	// some consumers may have switched, so both keys are kept enabled and the new key is returned
+	if result == nil || result.NewAccessKey.Id != "ak-2" || result.NewAccessKey.Secret != "sk" ||
+		result.OldDisabled {
+		t.Errorf("expect the new key returned, got %+v", result)
+	}
+	if len(f.keys) != 2 || !f.keys[0].Enabled || !f.keys[1].Enabled {
+		t.Errorf("expect both keys enabled, got %+v", f.keys)
+	}
+
+	if _, err := RotateAccessKey(client, args); err == nil ||
+		!strings.Contains(err.Error(), "more than one") {
+		t.Errorf("expect error of ambiguous old key, got %v", err)
+	}
+}
@@ -20,6 +20,8 @@ const (
 	URI_GROUP  = "/group"
 	URI_POLICY = "/policy"
 
+	URI_ACCESSKEY = "/accesskey"
+
 	POLICY_TYPE_SYSTEM = "System"
 	POLICY_TYPE_CUSTOM = "Custom"
 )
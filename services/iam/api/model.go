@@ -150,3 +150,20 @@ type DetachPolicyFromGroupArgs struct {
 	PolicyName string `json:"policyName"`
 	PolicyType string `json:"policyType,omitempty"`
 }
+
+type AccessKeyModel struct {
+	Id           string    `json:"id"`
+	Secret       string    `json:"secret,omitempty"`
+	CreateTime   time.Time `json:"createTime"`
+	LastUsedTime time.Time `json:"lastUsedTime"`
+	Enabled      bool      `json:"enabled"`
+	Description  string    `json:"description,omitempty"`
+}
+
+type CreateAccessKeyResult AccessKeyModel
+
+type UpdateAccessKeyResult AccessKeyModel
+
+type ListAccessKeyResult struct {
+	AccessKeys []AccessKeyModel `json:"accessKeys"`
+}
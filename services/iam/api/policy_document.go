@@ -0,0 +1,325 @@
+/*
+ * Copyright 2021 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// policy_document.go - the typed policy documents and the local evaluation of the permissions
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+const (
+	ACL_EFFECT_ALLOW = "Allow"
+	ACL_EFFECT_DENY  = "Deny"
+
+	ACL_WILDCARD                = "*"
+	ACL_PERMISSION_FULL_CONTROL = "FULL_CONTROL"
+
+	ACCESS_DECISION_ALLOW         = "Allow"
+	ACCESS_DECISION_DENY          = "Deny"
+	ACCESS_DECISION_IMPLICIT_DENY = "ImplicitDeny"
+
+	POLICY_SOURCE_USER  = "user"
+	POLICY_SOURCE_GROUP = "group"
+)
+
+// NewAcl returns an empty policy document.
+func NewAcl() *Acl {
+	return &Acl{AccessControlList: []AclEntry{}}
+}
+
+// Allow appends an entry allowing the permissions on the resources of the service in all regions.
+func (a *Acl) Allow(service string, permissions []string, resources ...string) *Acl {
+	return a.AddEntry(AclEntry{Service: service, Region: ACL_WILDCARD, Permission: permissions,
+		Resource: resources, Effect: ACL_EFFECT_ALLOW})
+}
+
+// Deny appends an entry denying the permissions on the resources of the service in all regions.
+func (a *Acl) Deny(service string, permissions []string, resources ...string) *Acl {
+	return a.AddEntry(AclEntry{Service: service, Region: ACL_WILDCARD, Permission: permissions,
+		Resource: resources, Effect: ACL_EFFECT_DENY})
+}
+
+// AddEntry appends the entry, the region of the entry defaults to all regions.
+func (a *Acl) AddEntry(entry AclEntry) *Acl {
+	if len(entry.Region) == 0 {
+		entry.Region = ACL_WILDCARD
+	}
+	a.AccessControlList = append(a.AccessControlList, entry)
+	return a
+}
+
+// Validate checks the required fields and the effect of every entry, the effect is case-insensitive
+// as it is in EvaluateAccess.
+func (a *Acl) Validate() error {
+	if len(a.AccessControlList) == 0 {
+		return fmt.Errorf("empty accessControlList")
+	}
+	for i, entry := range a.AccessControlList {
+		switch {
+		case len(entry.Service) == 0:
+			return fmt.Errorf("unset service of entry %d", i)
+		case len(entry.Permission) == 0:
+			return fmt.Errorf("unset permission of entry %d", i)
+		case len(entry.Resource) == 0:
+			return fmt.Errorf("unset resource of entry %d", i)
+		case !strings.EqualFold(entry.Effect, ACL_EFFECT_ALLOW) &&
+			!strings.EqualFold(entry.Effect, ACL_EFFECT_DENY):
+			return fmt.Errorf("invalid effect %q of entry %d", entry.Effect, i)
+		}
+	}
+	return nil
+}
+
+// Document - serialize the validated policy to the document of the policy APIs
+//
+// RETURNS:
+//     - string: the policy document
+//     - error: nil if success otherwise the specific error
+func (a *Acl) Document() (string, error) {
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseAcl - parse the policy document
+//
+// PARAMS:
+//     - document: the document of the policy
+// RETURNS:
+//     - *Acl: the typed policy
+//     - error: nil if success otherwise the specific error
+func ParseAcl(document string) (*Acl, error) {
+	acl := &Acl{}
+	if err := json.Unmarshal([]byte(document), acl); err != nil {
+		return nil, fmt.Errorf("invalid policy document: %v", err)
+	}
+	return acl, nil
+}
+
+// NewCreatePolicyArgs returns the arguments to create a policy of the typed document.
+func NewCreatePolicyArgs(name, description string, acl *Acl) (*CreatePolicyArgs, error) {
+	document, err := acl.Document()
+	if err != nil {
+		return nil, err
+	}
+	return &CreatePolicyArgs{Name: name, Description: description, Document: document}, nil
+}
+
+// AccessRequest is the access to evaluate, such as the permission "READ" of the service "bce:bos"
+// on the resource "bucket/path/to/object". An empty region matches all regions.
+type AccessRequest struct {
+	Service    string
+	Region     string
+	Permission string
+	Resource   string
+}
+
+// AttachedPolicy is a policy attached to the user directly or through a group.
+type AttachedPolicy struct {
+	Source    string // POLICY_SOURCE_USER or POLICY_SOURCE_GROUP
+	GroupName string
+	Policy    PolicyModel
+	Acl       *Acl
+}
+
+// AccessDecision is the result of an evaluation, the matched entries are the explicit deny which
+// decides the result or all the allows.
+type AccessDecision struct {
+	Decision string // one of ACCESS_DECISION_ALLOW, ACCESS_DECISION_DENY, ACCESS_DECISION_IMPLICIT_DENY
+	Matched  []MatchedAclEntry
+}
+
+// Allowed returns whether the access is allowed.
+func (d *AccessDecision) Allowed() bool {
+	return d.Decision == ACCESS_DECISION_ALLOW
+}
+
+// MatchedAclEntry is an entry matching the access and the policy it belongs to.
+type MatchedAclEntry struct {
+	Policy    string
+	Source    string
+	GroupName string
+	Entry     AclEntry
+}
+
+// EvaluateAccess - evaluate the access with the policies by the deny-overrides semantics: the
+// access is denied if any entry denies it, otherwise it is allowed if any entry allows it, and it
+// is implicitly denied if no entry matches. The services, the permissions and the effects are
+// case-insensitive, the regions and the resources are case-sensitive.
+//
+// PARAMS:
+//     - policies: the policies of the user
+//     - req: the access to evaluate
+// RETURNS:
+//     - *AccessDecision: the decision and the matched entries
+func EvaluateAccess(policies []AttachedPolicy, req *AccessRequest) *AccessDecision {
+	var allows, denies []MatchedAclEntry
+	for _, p := range policies {
+		if p.Acl == nil {
+			continue
+		}
+		for _, entry := range p.Acl.AccessControlList {
+			if !aclEntryMatches(&entry, req) {
+				continue
+			}
+			matched := MatchedAclEntry{Policy: p.Policy.Name, Source: p.Source, GroupName: p.GroupName,
+				Entry: entry}
+			if strings.EqualFold(entry.Effect, ACL_EFFECT_DENY) {
+				denies = append(denies, matched)
+			} else if strings.EqualFold(entry.Effect, ACL_EFFECT_ALLOW) {
+				allows = append(allows, matched)
+			}
+		}
+	}
+	switch {
+	case len(denies) != 0:
+		return &AccessDecision{Decision: ACCESS_DECISION_DENY, Matched: denies}
+	case len(allows) != 0:
+		return &AccessDecision{Decision: ACCESS_DECISION_ALLOW, Matched: allows}
+	}
+	return &AccessDecision{Decision: ACCESS_DECISION_IMPLICIT_DENY}
+}
+
+func aclEntryMatches(entry *AclEntry, req *AccessRequest) bool {
+	if !matchAclPattern(strings.ToLower(entry.Service), strings.ToLower(req.Service)) {
+		return false
+	}
+	if len(entry.Region) != 0 && len(req.Region) != 0 && !matchAclPattern(entry.Region, req.Region) {
+		return false
+	}
+	permitted := false
+	for _, p := range entry.Permission {
+		// FULL_CONTROL grants all the permissions of the service
+		if p == ACL_WILDCARD || strings.EqualFold(p, ACL_PERMISSION_FULL_CONTROL) ||
+			strings.EqualFold(p, req.Permission) {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return false
+	}
+	for _, r := range entry.Resource {
+		if matchAclPattern(r, req.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAclPattern matches the string with the pattern, "*" in the pattern matches any characters
+// including "/".
+func matchAclPattern(pattern, s string) bool {
+	// the greedy matching with backtracking to the last "*"
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case star >= 0:
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// ListUserPolicies - list the policies attached to the user and to the groups of the user, the
+// documents are parsed and fetched by GetPolicy if not listed
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the user
+// RETURNS:
+//     - []AttachedPolicy: the policies of the user
+//     - error: nil if success otherwise the specific error
+func ListUserPolicies(cli bce.Client, userName string) ([]AttachedPolicy, error) {
+	result := []AttachedPolicy{}
+	userPolicies, err := ListUserAttachedPolicies(cli, userName)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range userPolicies.Policies {
+		result = append(result, AttachedPolicy{Source: POLICY_SOURCE_USER, Policy: policy})
+	}
+
+	groups, err := ListGroupsForUser(cli, userName)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups.Groups {
+		groupPolicies, err := ListGroupAttachedPolicies(cli, group.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range groupPolicies.Policies {
+			result = append(result, AttachedPolicy{Source: POLICY_SOURCE_GROUP, GroupName: group.Name,
+				Policy: policy})
+		}
+	}
+
+	for i := range result {
+		p := &result[i]
+		if len(p.Policy.Document) == 0 {
+			detail, err := GetPolicy(cli, p.Policy.Name, p.Policy.Type)
+			if err != nil {
+				return nil, err
+			}
+			p.Policy = PolicyModel(*detail)
+		}
+		if p.Acl, err = ParseAcl(p.Policy.Document); err != nil {
+			return nil, fmt.Errorf("policy %s: %v", p.Policy.Name, err)
+		}
+	}
+	return result, nil
+}
+
+// EvaluateUserAccess - evaluate the access of the user with all the policies of the user
+//
+// PARAMS:
+//     - cli: the client agent which can perform sending request
+//     - userName: the name of the user
+//     - req: the access to evaluate
+// RETURNS:
+//     - *AccessDecision: the decision and the matched entries
+//     - error: nil if success otherwise the specific error
+func EvaluateUserAccess(cli bce.Client, userName string, req *AccessRequest) (*AccessDecision, error) {
+	policies, err := ListUserPolicies(cli, userName)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateAccess(policies, req), nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAclDocument(t *testing.T) {
+	acl := NewAcl().Allow("bce:bos", []string{"READ", "LIST"}, "bucket/*").
+		Deny("bce:bos", []string{"READ"}, "bucket/secret/*")
+	document, err := acl.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"accessControlList":[{"service":"bce:bos","region":"*","permission":["READ","LIST"],` +
+		`"resource":["bucket/*"],"effect":"Allow"},{"service":"bce:bos","region":"*","permission":["READ"],` +
+		`"resource":["bucket/secret/*"],"effect":"Deny"}]}`
+	if document != expected {
+		t.Errorf("unexpected document %s", document)
+	}
+	parsed, err := ParseAcl(document)
+	if err != nil || len(parsed.AccessControlList) != 2 || parsed.AccessControlList[1].Effect != ACL_EFFECT_DENY {
+		t.Errorf("unexpected parsed acl %+v: %v", parsed, err)
+	}
+
+	if _, err := NewAcl().Document(); err == nil {
+		t.Errorf("expect error of empty acl")
+	}
+	invalid := NewAcl().AddEntry(AclEntry{Service: "bce:bcc", Permission: []string{"READ"},
+		Resource: []string{"*"}, Effect: "Permit"})
+	if _, err := NewCreatePolicyArgs("p", "", invalid); err == nil || !strings.Contains(err.Error(), "effect") {
+		t.Errorf("expect error of invalid effect, got %v", err)
+	}
+	// the effect is case-insensitive as it is in the evaluation
+	lower := NewAcl().AddEntry(AclEntry{Service: "bce:bcc", Permission: []string{"READ"},
+		Resource: []string{"*"}, Effect: "allow"})
+	if err := lower.Validate(); err != nil {
+		t.Errorf("expect the lower case effect valid, got %v", err)
+	}
+}
+
+func TestEvaluateAccess(t *testing.T) {
+	userAcl := NewAcl().Allow("bce:bos", []string{"READ"}, "logs/*")
+	groupAcl := NewAcl().Allow("bce:bos", []string{ACL_PERMISSION_FULL_CONTROL}, "data/*").
+		Deny("bce:bos", []string{"WRITE"}, "data/archive/*").
+		AddEntry(AclEntry{Service: "bce:*", Region: "bj", Permission: []string{"READ"}, Resource: []string{"*"},
+			Effect: ACL_EFFECT_ALLOW}).
+		AddEntry(AclEntry{Service: "bce:bos", Permission: []string{"delete"}, Resource: []string{"data/*"},
+			Effect: "deny"})
+	policies := []AttachedPolicy{
+		{Source: POLICY_SOURCE_USER, Policy: PolicyModel{Name: "user-logs"}, Acl: userAcl},
+		{Source: POLICY_SOURCE_GROUP, GroupName: "dev", Policy: PolicyModel{Name: "dev-data"}, Acl: groupAcl},
+	}
+
+	cases := []struct {
+		req      AccessRequest
+		decision string
+		policy   string
+	}{
+		{AccessRequest{"bce:bos", "", "READ", "logs/2024/01/a.log"}, ACCESS_DECISION_ALLOW, "user-logs"},
+		{AccessRequest{"bce:bos", "gz", "WRITE", "logs/a.log"}, ACCESS_DECISION_IMPLICIT_DENY, ""},
+		{AccessRequest{"bce:bos", "gz", "WRITE", "data/a"}, ACCESS_DECISION_ALLOW, "dev-data"},
+		{AccessRequest{"bce:bos", "gz", "WRITE", "data/archive/a"}, ACCESS_DECISION_DENY, "dev-data"},
+		{AccessRequest{"BCE:BCC", "bj", "READ", "i-xxx"}, ACCESS_DECISION_ALLOW, "dev-data"},
+		{AccessRequest{"bce:bcc", "gz", "READ", "i-xxx"}, ACCESS_DECISION_IMPLICIT_DENY, ""},
+		{AccessRequest{"bce:bos", "gz", "DELETE", "data/a"}, ACCESS_DECISION_DENY, "dev-data"},
+		{AccessRequest{"bce:bos", "gz", "write", "data/a"}, ACCESS_DECISION_ALLOW, "dev-data"},
+	}
+	for _, c := range cases {
+		d := EvaluateAccess(policies, &c.req)
+		if d.Decision != c.decision || d.Allowed() != (c.decision == ACCESS_DECISION_ALLOW) {
+			t.Errorf("%+v: expect %s, got %s", c.req, c.decision, d.Decision)
+			continue
+		}
+		if len(c.policy) != 0 && (len(d.Matched) == 0 || d.Matched[0].Policy != c.policy) {
+			t.Errorf("%+v: expect matched policy %s, got %+v", c.req, c.policy, d.Matched)
+		}
+	}
+}
+
+func TestMatchAclPattern(t *testing.T) {
+	for _, c := range []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"*", "", true},
+		{"bucket/*", "bucket/a/b", true},
+		{"bucket/*", "bucket", false},
+		{"bucket/*.jpg", "bucket/a/b.jpg", true},
+		{"bucket/*.jpg", "bucket/a/b.png", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYc!", false},
+		{"exact", "exact", true},
+	} {
+		if matchAclPattern(c.pattern, c.s) != c.expected {
+			t.Errorf("match %q with %q: expect %v", c.pattern, c.s, c.expected)
+		}
+	}
+}
@@ -106,6 +106,84 @@ func (c *Client) DeleteUserLoginProfile(name string) error {
 	return api.DeleteUserLoginProfile(c, name)
 }
 
+// CreateAccessKey - create an access key for the sub-user
+//
+// PARAMS:
+//     - userName: the name of the sub-user
+// RETURNS:
+//     - *api.CreateAccessKeyResult: the new access key, its secret is only returned here
+//     - error: nil if success otherwise the specific error
+func (c *Client) CreateAccessKey(userName string) (*api.CreateAccessKeyResult, error) {
+	return api.CreateAccessKey(c, userName)
+}
+
+// DisableAccessKey - disable the access key of the sub-user
+//
+// PARAMS:
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to disable
+// RETURNS:
+//     - *api.UpdateAccessKeyResult: the access key after the update
+//     - error: nil if success otherwise the specific error
+func (c *Client) DisableAccessKey(userName, accessKeyId string) (*api.UpdateAccessKeyResult, error) {
+	return api.DisableAccessKey(c, userName, accessKeyId)
+}
+
+// EnableAccessKey - enable the disabled access key of the sub-user
+//
+// PARAMS:
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to enable
+// RETURNS:
+//     - *api.UpdateAccessKeyResult: the access key after the update
+//     - error: nil if success otherwise the specific error
+func (c *Client) EnableAccessKey(userName, accessKeyId string) (*api.UpdateAccessKeyResult, error) {
+	return api.EnableAccessKey(c, userName, accessKeyId)
+}
+
+// DeleteAccessKey - delete the access key of the sub-user
+//
+// PARAMS:
+//     - userName: the name of the sub-user
+//     - accessKeyId: the access key ID to delete
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func (c *Client) DeleteAccessKey(userName, accessKeyId string) error {
+	return api.DeleteAccessKey(c, userName, accessKeyId)
+}
+
+// ListAccessKey - list the access keys of the sub-user
+//
+// PARAMS:
+//     - userName: the name of the sub-user
+// RETURNS:
+//     - *api.ListAccessKeyResult: the access keys of the sub-user
+//     - error: nil if success otherwise the specific error
+func (c *Client) ListAccessKey(userName string) (*api.ListAccessKeyResult, error) {
+	return api.ListAccessKey(c, userName)
+}
+
+// RotateAccessKey - rotate an access key of the sub-user, it returns after the old key is disabled
+//
+// PARAMS:
+//     - args: the arguments of the rotation
+// RETURNS:
+//     - *api.AccessKeyRotationResult: the result of the rotation
+//     - error: nil if success otherwise the specific error
+func (c *Client) RotateAccessKey(args *api.AccessKeyRotationArgs) (*api.AccessKeyRotationResult, error) {
+	return api.RotateAccessKey(c, args)
+}
+
+// FinishAccessKeyRotation - delete the old access key disabled by the rotation
+//
+// PARAMS:
+//     - result: the result of RotateAccessKey
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func (c *Client) FinishAccessKeyRotation(result *api.AccessKeyRotationResult) error {
+	return api.FinishAccessKeyRotation(c, result)
+}
+
 func (c *Client) CreateGroup(args *api.CreateGroupArgs) (*api.CreateGroupResult, error) {
 	body, err := NewBodyFromStruct(args)
 	if err != nil {
@@ -194,6 +272,14 @@ func (c *Client) ListGroupAttachedPolicies(name string) (*api.ListPolicyResult,
 	return api.ListGroupAttachedPolicies(c, name)
 }
 
+func (c *Client) ListUserPolicies(userName string) ([]api.AttachedPolicy, error) {
+	return api.ListUserPolicies(c, userName)
+}
+
+func (c *Client) EvaluateUserAccess(userName string, req *api.AccessRequest) (*api.AccessDecision, error) {
+	return api.EvaluateUserAccess(c, userName, req)
+}
+
 func NewBodyFromStruct(args interface{}) (*bce.Body, error) {
 	jsonBytes, err := json.Marshal(args)
 	if err != nil {
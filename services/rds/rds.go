@@ -51,7 +51,7 @@ func (c *Client) CreateRds(args *CreateRdsArgs) (*CreateResult, error) {
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getRdsUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		WithResult(result).
@@ -84,7 +84,7 @@ func (c *Client) CreateReadReplica(args *CreateReadReplicaArgs) (*CreateResult,
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getRdsUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("readReplica","").
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
@@ -118,7 +118,7 @@ func (c *Client) CreateRdsProxy(args *CreateRdsProxyArgs) (*CreateResult, error)
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getRdsUri()).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("rdsproxy","").
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
@@ -238,7 +238,7 @@ func (c *Client) CreateAccount(instanceId string, args *CreateAccountArgs)  erro
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(getRdsUriWithInstanceId(instanceId)+"/account").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE).
 		WithBody(args).
 		Do()
@@ -164,7 +164,7 @@ func (c *Client) CreateInstance(args *CreateInstanceArgs) (*CreateInstanceResult
 	err := bce.NewRequestBuilder(c).
 		WithMethod(http.POST).
 		WithURL(INSTANCE_URL_V2).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -233,7 +233,7 @@ func (c *Client) ResizeInstance(instanceId string, args *ResizeInstanceArgs) err
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/change").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -267,7 +267,7 @@ func (c *Client) DeleteInstance(instanceId string, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.DELETE).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -284,7 +284,7 @@ func (c *Client) UpdateInstanceName(instanceId string, args *UpdateInstanceNameA
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/rename").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -349,7 +349,7 @@ func (c *Client) UpdateInstanceDomainName(instanceId string, args *UpdateInstanc
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/renameDomain").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -391,7 +391,7 @@ func (c *Client) FlushInstance(instanceId string, args *FlushInstanceArgs) error
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/flush").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -463,7 +463,7 @@ func (c *Client) AddSecurityIp(instanceId string, args *SecurityIpArgs) error {
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/securityIp").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -481,7 +481,7 @@ func (c *Client) DeleteSecurityIp(instanceId string, args *SecurityIpArgs) error
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.DELETE).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/securityIp").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -505,7 +505,7 @@ func (c *Client) ModifyPassword(instanceId string, args *ModifyPasswordArgs) err
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/modifyPassword").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -543,7 +543,7 @@ func (c *Client) ModifyParameters(instanceId string, args *ModifyParametersArgs)
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/parameter").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -581,7 +581,7 @@ func (c *Client) ModifyBackupPolicy(instanceId string, args *ModifyBackupPolicyA
 	return bce.NewRequestBuilder(c).
 		WithMethod(http.PUT).
 		WithURL(INSTANCE_URL_V1+"/"+instanceId+"/modifyBackupPolicy").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -21,7 +21,6 @@ import (
 
 	"github.com/kougazhang/bce-sdk-go/bce"
 	"github.com/kougazhang/bce-sdk-go/http"
-	"github.com/kougazhang/bce-sdk-go/util"
 )
 
 // SendSms - send an sms message
@@ -46,11 +45,7 @@ func SendSms(cli bce.Client, args *SendSmsArgs) (*SendSmsResult, error) {
 	req.SetUri(REQUEST_URI_SEND_SMS)
 	req.SetMethod(http.POST)
 	req.SetHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE)
-	if len(args.ClientToken) > 0 {
-		req.SetParam(CLIENT_TOKEN, args.ClientToken)
-	} else {
-		req.SetParam(CLIENT_TOKEN, util.NewUUID())
-	}
+	req.SetClientToken(args.ClientToken)
 	jsonBytes, jsonErr := json.Marshal(args)
 	if jsonErr != nil {
 		return nil, jsonErr
@@ -21,7 +21,6 @@ import (
 
 	"github.com/kougazhang/bce-sdk-go/bce"
 	"github.com/kougazhang/bce-sdk-go/http"
-	"github.com/kougazhang/bce-sdk-go/util"
 )
 
 // CreateSignature - create an sms signature
@@ -49,7 +48,7 @@ func CreateSignature(cli bce.Client, args *CreateSignatureArgs) (*CreateSignatur
 	req.SetUri(REQUEST_URI_SIGNATURE)
 	req.SetMethod(http.POST)
 	req.SetHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE)
-	req.SetParam(CLIENT_TOKEN, util.NewUUID())
+	req.SetClientToken("")
 	jsonBytes, jsonErr := json.Marshal(args)
 	if jsonErr != nil {
 		return nil, jsonErr
@@ -21,7 +21,6 @@ import (
 
 	"github.com/kougazhang/bce-sdk-go/bce"
 	"github.com/kougazhang/bce-sdk-go/http"
-	"github.com/kougazhang/bce-sdk-go/util"
 )
 
 // CreateTemplate - create an sms template
@@ -52,7 +51,7 @@ func CreateTemplate(cli bce.Client, args *CreateTemplateArgs) (*CreateTemplateRe
 	req.SetUri(REQUEST_URI_TEMPLATE)
 	req.SetMethod(http.POST)
 	req.SetHeader(http.CONTENT_TYPE, bce.DEFAULT_CONTENT_TYPE)
-	req.SetParam(CLIENT_TOKEN, util.NewUUID())
+	req.SetClientToken("")
 	jsonBytes, jsonErr := json.Marshal(args)
 	if jsonErr != nil {
 		return nil, jsonErr
@@ -0,0 +1,403 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// credential_vendor.go - the embeddable HTTP handler vending the scoped temporary credentials
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+const (
+	DEFAULT_VENDING_DURATION_SECONDS = 3600
+	DEFAULT_VENDING_REFRESH_BEFORE   = 5 * time.Minute
+
+	VENDING_ERROR_UNAUTHORIZED = "Unauthorized"
+	VENDING_ERROR_FORBIDDEN    = "AccessDenied"
+	VENDING_ERROR_RATE_LIMITED = "TooManyRequests"
+	VENDING_ERROR_METHOD       = "MethodNotAllowed"
+	VENDING_ERROR_STS          = "StsError"
+)
+
+var (
+	// ErrVendingUnauthorized is returned by the authorizer if the caller is not authenticated
+	ErrVendingUnauthorized = errors.New("the caller is not authenticated")
+
+	// ErrVendingForbidden is returned by the authorizer if the caller is not allowed any credentials
+	ErrVendingForbidden = errors.New("the caller is not allowed to get credentials")
+)
+
+// VendingAuthorizer authenticates the caller of a request and decides the ACL of its credentials.
+// It returns ErrVendingUnauthorized or ErrVendingForbidden to reject the request, the other errors
+// are also treated as unauthorized.
+type VendingAuthorizer interface {
+	Authorize(r *http.Request) (identity string, acl *SessionAcl, err error)
+}
+
+// VendingAuthorizerFunc adapts a function to VendingAuthorizer.
+type VendingAuthorizerFunc func(r *http.Request) (string, *SessionAcl, error)
+
+func (f VendingAuthorizerFunc) Authorize(r *http.Request) (string, *SessionAcl, error) {
+	return f(r)
+}
+
+// SessionAclTemplate is an ACL with the variables like "${identity}" in the resources, the IP
+// addresses and the referers, the variables are replaced by Render.
+type SessionAclTemplate struct {
+	acl SessionAcl
+}
+
+// NewSessionAclTemplate - create a template of the ACL, the ACL is copied
+func NewSessionAclTemplate(acl *SessionAcl) *SessionAclTemplate {
+	return &SessionAclTemplate{acl: *copySessionAcl(acl, nil)}
+}
+
+// Render - replace the variables of the template, such as {"identity": "user-42"} for
+// "${identity}", and validate the result
+//
+// PARAMS:
+//     - vars: the values of the variables
+// RETURNS:
+//     - *SessionAcl: the rendered ACL
+//     - error: nil if success otherwise the specific error
+func (t *SessionAclTemplate) Render(vars map[string]string) (*SessionAcl, error) {
+	pairs := make([]string, 0, 2*len(vars))
+	for name, value := range vars {
+		// the values must not escape from the resources, eg. "a/*" for "bucket/${identity}/*"
+		if strings.ContainsAny(value, "*/$") {
+			return nil, fmt.Errorf("invalid value %q of the variable %s", value, name)
+		}
+		pairs = append(pairs, "${"+name+"}", value)
+	}
+	acl := copySessionAcl(&t.acl, strings.NewReplacer(pairs...))
+	for _, entry := range acl.AccessControlList {
+		for _, resource := range entry.Resource {
+			if strings.Contains(resource, "${") {
+				return nil, fmt.Errorf("unresolved variable in the resource %s", resource)
+			}
+		}
+	}
+	if err := acl.Validate(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// AuthorizeBy returns an authorizer identifying the caller by the function and rendering the
+// template with the variable "identity".
+func (t *SessionAclTemplate) AuthorizeBy(identify func(r *http.Request) (string, error)) VendingAuthorizer {
+	return VendingAuthorizerFunc(func(r *http.Request) (string, *SessionAcl, error) {
+		identity, err := identify(r)
+		if err != nil {
+			return "", nil, err
+		}
+		acl, err := t.Render(map[string]string{"identity": identity})
+		if err != nil {
+			return "", nil, ErrVendingForbidden
+		}
+		return identity, acl, nil
+	})
+}
+
+func copySessionAcl(acl *SessionAcl, replacer *strings.Replacer) *SessionAcl {
+	replace := func(values []string) []string {
+		if values == nil {
+			return nil
+		}
+		result := make([]string, len(values))
+		for i, v := range values {
+			if replacer != nil {
+				v = replacer.Replace(v)
+			}
+			result[i] = v
+		}
+		return result
+	}
+	result := &SessionAcl{Id: acl.Id, AccessControlList: make([]SessionAclEntry, len(acl.AccessControlList))}
+	for i, entry := range acl.AccessControlList {
+		entry.Permission = replace(entry.Permission)
+		entry.Resource = replace(entry.Resource)
+		if entry.Condition != nil {
+			condition := &SessionAclCondition{IpAddress: replace(entry.Condition.IpAddress)}
+			if entry.Condition.Referer != nil {
+				condition.Referer = &SessionAclReferer{
+					StringLike:   replace(entry.Condition.Referer.StringLike),
+					StringEquals: replace(entry.Condition.Referer.StringEquals),
+				}
+			}
+			entry.Condition = condition
+		}
+		result.AccessControlList[i] = entry
+	}
+	return result
+}
+
+// CredentialVendorConfig defines the behavior of the credential vendor.
+type CredentialVendorConfig struct {
+	Authorizer VendingAuthorizer
+
+	// DurationSeconds is the duration of the minted credentials
+	DurationSeconds int
+
+	// The credentials are cached per identity and ACL, and minted again when they expire within
+	// RefreshBefore
+	RefreshBefore time.Duration
+
+	// RateLimit is the requests per second allowed of each identity with the burst of RateBurst,
+	// no limit if not positive. The cached credentials are also counted.
+	RateLimit float64
+	RateBurst int
+}
+
+// VendedCredentials is the JSON response of the vendor, it has the same fields as the result of
+// GetSessionToken so that the consumers of the session tokens can decode it directly.
+type VendedCredentials struct {
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+	CreateTime      string `json:"createTime"`
+	Expiration      string `json:"expiration"`
+	UserId          string `json:"userId,omitempty"`
+}
+
+type vendingCacheEntry struct {
+	mutex       sync.Mutex
+	credentials *VendedCredentials
+	expiration  time.Time
+
+	// expiresAt is the copy of expiration guarded by the mutex of the vendor for the eviction,
+	// it is zero before the first minting
+	expiresAt time.Time
+}
+
+type vendingBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// CredentialVendor is an http.Handler minting the temporary credentials for the callers.
+type CredentialVendor struct {
+	config CredentialVendorConfig
+	mint   func(durationSec int, acl string) (*GetSessionTokenResult, error)
+	now    func() time.Time
+
+	mutex     sync.Mutex
+	cache     map[string]*vendingCacheEntry
+	buckets   map[string]*vendingBucket
+	nextSweep time.Time
+}
+
+// NewCredentialVendor - create the credential vendor minting the credentials by GetSessionToken
+//
+// PARAMS:
+//     - cli: the client of STS with the credentials of the user to vend for
+//     - config: the config of the vendor
+// RETURNS:
+//     - *CredentialVendor: the vendor which is an http.Handler
+//     - error: nil if success otherwise the specific error
+func NewCredentialVendor(cli bce.Client, config *CredentialVendorConfig) (*CredentialVendor, error) {
+	return newCredentialVendor(func(durationSec int, acl string) (*GetSessionTokenResult, error) {
+		return GetSessionToken(cli, durationSec, acl)
+	}, config)
+}
+
+func newCredentialVendor(mint func(int, string) (*GetSessionTokenResult, error),
+	config *CredentialVendorConfig) (*CredentialVendor, error) {
+	if config == nil || config.Authorizer == nil {
+		return nil, fmt.Errorf("unset Authorizer")
+	}
+	v := &CredentialVendor{
+		config:  *config,
+		mint:    mint,
+		now:     time.Now,
+		cache:   map[string]*vendingCacheEntry{},
+		buckets: map[string]*vendingBucket{},
+	}
+	if v.config.DurationSeconds == 0 {
+		v.config.DurationSeconds = DEFAULT_VENDING_DURATION_SECONDS
+	}
+	if err := ValidateDuration(v.config.DurationSeconds); err != nil {
+		return nil, err
+	}
+	if v.config.RefreshBefore <= 0 {
+		v.config.RefreshBefore = DEFAULT_VENDING_REFRESH_BEFORE
+	}
+	if v.config.RefreshBefore >= time.Duration(v.config.DurationSeconds)*time.Second {
+		return nil, fmt.Errorf("RefreshBefore %s must be less than the duration %ds",
+			v.config.RefreshBefore, v.config.DurationSeconds)
+	}
+	if v.config.RateLimit > 0 && v.config.RateBurst <= 0 {
+		v.config.RateBurst = int(math.Ceil(v.config.RateLimit))
+	}
+	return v, nil
+}
+
+func (v *CredentialVendor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeVendingError(w, http.StatusMethodNotAllowed, VENDING_ERROR_METHOD, "method not allowed")
+		return
+	}
+	identity, acl, err := v.config.Authorizer.Authorize(r)
+	if err == ErrVendingForbidden {
+		writeVendingError(w, http.StatusForbidden, VENDING_ERROR_FORBIDDEN, err.Error())
+		return
+	}
+	if err != nil || len(identity) == 0 {
+		writeVendingError(w, http.StatusUnauthorized, VENDING_ERROR_UNAUTHORIZED,
+			ErrVendingUnauthorized.Error())
+		return
+	}
+	if wait := v.reserve(identity); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeVendingError(w, http.StatusTooManyRequests, VENDING_ERROR_RATE_LIMITED, "rate limit exceeded")
+		return
+	}
+
+	credentials, err := v.Credentials(identity, acl)
+	if err != nil {
+		writeVendingError(w, http.StatusBadGateway, VENDING_ERROR_STS, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(credentials)
+}
+
+// Credentials - get the cached credentials of the identity and the ACL, or mint new ones if they
+// expire within RefreshBefore
+//
+// PARAMS:
+//     - identity: the identity of the caller
+//     - acl: the ACL of the credentials, nil for the full permissions of the user
+// RETURNS:
+//     - *VendedCredentials: the credentials
+//     - error: nil if success otherwise the specific error
+func (v *CredentialVendor) Credentials(identity string, acl *SessionAcl) (*VendedCredentials, error) {
+	document := ""
+	if acl != nil {
+		var err error
+		if document, err = acl.Document(); err != nil {
+			return nil, err
+		}
+	}
+
+	key := identity + "\n" + document
+	v.mutex.Lock()
+	entry, ok := v.cache[key]
+	if !ok {
+		if now := v.now(); !now.Before(v.nextSweep) {
+			v.evictExpired(now)
+			v.nextSweep = now.Add(v.config.RefreshBefore)
+		}
+		entry = &vendingCacheEntry{}
+		v.cache[key] = entry
+	}
+	v.mutex.Unlock()
+
+	// the concurrent requests of the same key wait for one minting
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.credentials != nil && v.now().Add(v.config.RefreshBefore).Before(entry.expiration) {
+		return entry.credentials, nil
+	}
+	// the entry never minted is dropped on failure, the others are kept until they expire
+	fail := func(err error) (*VendedCredentials, error) {
+		if entry.credentials == nil {
+			v.mutex.Lock()
+			if v.cache[key] == entry {
+				delete(v.cache, key)
+			}
+			v.mutex.Unlock()
+		}
+		return nil, err
+	}
+	token, err := v.mint(v.config.DurationSeconds, document)
+	if err != nil {
+		return fail(err)
+	}
+	expiration, err := util.ParseISO8601Date(token.Expiration)
+	if err != nil {
+		return fail(fmt.Errorf("invalid expiration %q of the session token", token.Expiration))
+	}
+	entry.credentials = &VendedCredentials{
+		AccessKeyId:     token.AccessKeyId,
+		SecretAccessKey: token.SecretAccessKey,
+		SessionToken:    token.SessionToken,
+		CreateTime:      token.CreateTime,
+		Expiration:      token.Expiration,
+		UserId:          token.UserId,
+	}
+	entry.expiration = expiration
+	v.mutex.Lock()
+	entry.expiresAt = expiration
+	v.mutex.Unlock()
+	return entry.credentials, nil
+}
+
+// evictExpired removes the expired credentials and the idle full buckets, the mutex is held. It
+// runs at most once every RefreshBefore when a new key is cached.
+func (v *CredentialVendor) evictExpired(now time.Time) {
+	for key, entry := range v.cache {
+		// the entries being minted for the first time are kept
+		if !entry.expiresAt.IsZero() && !entry.expiresAt.After(now) {
+			delete(v.cache, key)
+		}
+	}
+	for identity, b := range v.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*v.config.RateLimit >= float64(v.config.RateBurst) {
+			delete(v.buckets, identity)
+		}
+	}
+}
+
+// reserve takes a token of the identity, it returns the wait for the next token if none is left.
+func (v *CredentialVendor) reserve(identity string) time.Duration {
+	if v.config.RateLimit <= 0 {
+		return 0
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	now := v.now()
+	b, ok := v.buckets[identity]
+	if !ok {
+		b = &vendingBucket{tokens: float64(v.config.RateBurst), last: now}
+		v.buckets[identity] = b
+	}
+	b.tokens = math.Min(float64(v.config.RateBurst), b.tokens+now.Sub(b.last).Seconds()*v.config.RateLimit)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / v.config.RateLimit * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+func writeVendingError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+type fakeMinter struct {
+	now    time.Time
+	minted int
+	acls   []string
+	err    error
+}
+
+func (f *fakeMinter) mint(durationSec int, acl string) (*GetSessionTokenResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.minted++
+	f.acls = append(f.acls, acl)
+	return &GetSessionTokenResult{
+		AccessKeyId:     "ak-" + string(rune('0'+f.minted)),
+		SecretAccessKey: "sk",
+		SessionToken:    "token",
+		CreateTime:      util.FormatISO8601Date(f.now.Unix()),
+		Expiration:      util.FormatISO8601Date(f.now.Add(time.Duration(durationSec) * time.Second).Unix()),
+		UserId:          "user",
+	}, nil
+}
+
+func newTestVendor(t *testing.T, minter *fakeMinter, config *CredentialVendorConfig) *CredentialVendor {
+	template := NewSessionAclTemplate(&SessionAcl{AccessControlList: []SessionAclEntry{{
+		Service:    SESSION_ACL_SERVICE_BOS,
+		Region:     "bj",
+		Effect:     SESSION_ACL_EFFECT_ALLOW,
+		Permission: []string{SESSION_ACL_PERMISSION_READ},
+		Resource:   []string{"bucket/users/${identity}/*"},
+	}}})
+	config.Authorizer = template.AuthorizeBy(func(r *http.Request) (string, error) {
+		user := r.Header.Get("X-User")
+		if len(user) == 0 {
+			return "", ErrVendingUnauthorized
+		}
+		return user, nil
+	})
+	vendor, err := newCredentialVendor(minter.mint, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendor.now = func() time.Time { return minter.now }
+	return vendor
+}
+
+func vend(vendor *CredentialVendor, method, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/credentials", nil)
+	if len(user) != 0 {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	vendor.ServeHTTP(w, req)
+	return w
+}
+
+func TestCredentialVendorCache(t *testing.T) {
+	minter := &fakeMinter{now: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	vendor := newTestVendor(t, minter, &CredentialVendorConfig{DurationSeconds: 900,
+		RefreshBefore: time.Minute})
+
+	w := vend(vendor, http.MethodGet, "42")
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	result := &GetSessionTokenResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), result); err != nil || result.AccessKeyId != "ak-1" ||
+		result.SessionToken != "token" || result.Expiration != "2021-06-01T00:15:00Z" {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+	expected := `{"accessControlList":[{"service":"bce:bos","region":"bj","effect":"Allow",` +
+		`"permission":["READ"],"resource":["bucket/users/42/*"]}]}`
+	if minter.acls[0] != expected {
+		t.Errorf("unexpected acl %s", minter.acls[0])
+	}
+
+	// cached until 1 minute before the expiration
+	minter.now = minter.now.Add(13 * time.Minute)
+	vend(vendor, http.MethodPost, "42")
+	if minter.minted != 1 {
+		t.Errorf("expect the cached credentials, minted %d", minter.minted)
+	}
+	vend(vendor, http.MethodGet, "43")
+	if minter.minted != 2 {
+		t.Errorf("expect new credentials of another identity, minted %d", minter.minted)
+	}
+	minter.now = minter.now.Add(time.Minute)
+	w = vend(vendor, http.MethodGet, "42")
+	if minter.minted != 3 || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("expect refreshed credentials, minted %d", minter.minted)
+	}
+
+	// the expired credentials of 43 are evicted when a new key is cached
+	minter.now = minter.now.Add(time.Hour)
+	vend(vendor, http.MethodGet, "44")
+	if _, ok := vendor.cache["43\n"+expected]; ok || len(vendor.cache) != 1 {
+		t.Errorf("expect the expired credentials evicted, cached %d", len(vendor.cache))
+	}
+}
+
+func TestCredentialVendorRateLimit(t *testing.T) {
+	minter := &fakeMinter{now: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	vendor := newTestVendor(t, minter, &CredentialVendorConfig{RateLimit: 0.5, RateBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		if w := vend(vendor, http.MethodGet, "42"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: unexpected status %d", i, w.Code)
+		}
+	}
+	w := vend(vendor, http.MethodGet, "42")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w := vend(vendor, http.MethodGet, "43"); w.Code != http.StatusOK {
+		t.Errorf("expect another identity not limited, status %d", w.Code)
+	}
+	minter.now = minter.now.Add(2 * time.Second)
+	if w := vend(vendor, http.MethodGet, "42"); w.Code != http.StatusOK {
+		t.Errorf("expect a token refilled, status %d", w.Code)
+	}
+}
+
+func TestCredentialVendorErrors(t *testing.T) {
+	minter := &fakeMinter{now: time.Now()}
+	vendor := newTestVendor(t, minter, &CredentialVendorConfig{})
+
+	cases := []struct {
+		method string
+		user   string
+		status int
+		code   string
+	}{
+		{http.MethodDelete, "42", http.StatusMethodNotAllowed, VENDING_ERROR_METHOD},
+		{http.MethodGet, "", http.StatusUnauthorized, VENDING_ERROR_UNAUTHORIZED},
+		{http.MethodGet, "../*", http.StatusForbidden, VENDING_ERROR_FORBIDDEN},
+	}
+	for _, c := range cases {
+		w := vend(vendor, c.method, c.user)
+		body := map[string]string{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != c.status || body["code"] != c.code {
+			t.Errorf("%s %q: unexpected response %d %s", c.method, c.user, w.Code, w.Body.String())
+		}
+	}
+
+	minter.err = errors.New("sts unavailable")
+	if w := vend(vendor, http.MethodGet, "42"); w.Code != http.StatusBadGateway {
+		t.Errorf("unexpected status %d of the sts error", w.Code)
+	}
+	if len(vendor.cache) != 0 {
+		t.Errorf("expect the failed key not cached, cached %d", len(vendor.cache))
+	}
+
+	if _, err := newCredentialVendor(minter.mint, &CredentialVendorConfig{}); err == nil {
+		t.Errorf("expect error of unset authorizer")
+	}
+	if _, err := newCredentialVendor(minter.mint, &CredentialVendorConfig{Authorizer: vendor.config.Authorizer,
+		DurationSeconds: 60, RefreshBefore: time.Minute}); err == nil {
+		t.Errorf("expect error of too long RefreshBefore")
+	}
+}
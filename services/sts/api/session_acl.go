@@ -0,0 +1,285 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// session_acl.go - the typed ACL documents of the temporary credentials
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/bce"
+)
+
+const (
+	MAX_DURATION_SECONDS            = 129600 // the session token lasts 36 hours at most
+	MAX_ASSUMEROLE_DURATION_SECONDS = 43200  // the credential of a role lasts 12 hours at most
+
+	SESSION_ACL_EFFECT_ALLOW = "Allow"
+	SESSION_ACL_EFFECT_DENY  = "Deny"
+
+	SESSION_ACL_SERVICE_BOS = "bce:bos"
+	SESSION_ACL_REGION_ALL  = "*"
+
+	SESSION_ACL_PERMISSION_READ         = "READ"
+	SESSION_ACL_PERMISSION_WRITE        = "WRITE"
+	SESSION_ACL_PERMISSION_LIST         = "LIST"
+	SESSION_ACL_PERMISSION_FULL_CONTROL = "FULL_CONTROL"
+)
+
+// SessionAclReferer defines the referer condition of an ACL entry
+type SessionAclReferer struct {
+	StringLike   []string `json:"stringLike,omitempty"`
+	StringEquals []string `json:"stringEquals,omitempty"`
+}
+
+// SessionAclCondition defines the conditions of an ACL entry
+type SessionAclCondition struct {
+	IpAddress []string           `json:"ipAddress,omitempty"`
+	Referer   *SessionAclReferer `json:"referer,omitempty"`
+}
+
+// SessionAclEntry defines an entry of the ACL of the temporary credentials
+type SessionAclEntry struct {
+	Eid        string               `json:"eid,omitempty"`
+	Service    string               `json:"service"`
+	Region     string               `json:"region"`
+	Effect     string               `json:"effect"`
+	Permission []string             `json:"permission"`
+	Resource   []string             `json:"resource"`
+	Condition  *SessionAclCondition `json:"condition,omitempty"`
+}
+
+// SessionAcl defines the ACL of the temporary credentials, the permissions of the credentials are
+// the intersection of the ACL and the permissions of the user or the role.
+type SessionAcl struct {
+	Id                string            `json:"id,omitempty"`
+	AccessControlList []SessionAclEntry `json:"accessControlList"`
+}
+
+// Validate checks the required fields, the effect and the IP conditions of every entry.
+func (a *SessionAcl) Validate() error {
+	if len(a.AccessControlList) == 0 {
+		return fmt.Errorf("empty accessControlList")
+	}
+	for i, entry := range a.AccessControlList {
+		switch {
+		case len(entry.Service) == 0:
+			return fmt.Errorf("unset service of entry %d", i)
+		case len(entry.Region) == 0:
+			return fmt.Errorf("unset region of entry %d", i)
+		case len(entry.Permission) == 0:
+			return fmt.Errorf("unset permission of entry %d", i)
+		case len(entry.Resource) == 0:
+			return fmt.Errorf("unset resource of entry %d", i)
+		case entry.Effect != SESSION_ACL_EFFECT_ALLOW && entry.Effect != SESSION_ACL_EFFECT_DENY:
+			return fmt.Errorf("invalid effect %q of entry %d", entry.Effect, i)
+		}
+		if entry.Condition != nil {
+			for _, ip := range entry.Condition.IpAddress {
+				if !validSessionAclIp(ip) {
+					return fmt.Errorf("invalid ip address %q of entry %d", ip, i)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Document - serialize the validated ACL to the document of GetSessionToken and AssumeRole
+//
+// RETURNS:
+//     - string: the ACL document
+//     - error: nil if success otherwise the specific error
+func (a *SessionAcl) Document() (string, error) {
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SessionAclEntryBuilder builds an entry of the ACL, the first error occurs in the chain is
+// returned by the `Build` method.
+type SessionAclEntryBuilder struct {
+	entry SessionAclEntry
+	err   error
+}
+
+// NewSessionAclEntry - create an entry builder allowing the access to the service in the region,
+// the region is "*" for all regions
+func NewSessionAclEntry(service, region string) *SessionAclEntryBuilder {
+	return &SessionAclEntryBuilder{entry: SessionAclEntry{
+		Service: service,
+		Region:  region,
+		Effect:  SESSION_ACL_EFFECT_ALLOW,
+	}}
+}
+
+// NewBosAclEntry - create an entry builder allowing the access to BOS in the region
+func NewBosAclEntry(region string) *SessionAclEntryBuilder {
+	return NewSessionAclEntry(SESSION_ACL_SERVICE_BOS, region)
+}
+
+// Allow - add the permissions of the entry
+func (b *SessionAclEntryBuilder) Allow(permissions ...string) *SessionAclEntryBuilder {
+	b.entry.Permission = append(b.entry.Permission, permissions...)
+	return b
+}
+
+// Deny - make the entry deny the permissions instead of allowing them
+func (b *SessionAclEntryBuilder) Deny(permissions ...string) *SessionAclEntryBuilder {
+	b.entry.Effect = SESSION_ACL_EFFECT_DENY
+	return b.Allow(permissions...)
+}
+
+// OnBucket - apply the entry to the bucket itself and all of its objects
+func (b *SessionAclEntryBuilder) OnBucket(bucket string) *SessionAclEntryBuilder {
+	b.entry.Resource = append(b.entry.Resource, bucket, bucket+"/*")
+	return b
+}
+
+// OnPrefix - apply the entry to the objects with the given prefix, eg. `bucket/prefix/*`
+func (b *SessionAclEntryBuilder) OnPrefix(bucket, prefix string) *SessionAclEntryBuilder {
+	if len(bucket) == 0 && b.err == nil {
+		b.err = bce.NewBceClientError("unset bucket of the prefix " + prefix)
+	}
+	b.entry.Resource = append(b.entry.Resource, bucket+"/"+strings.TrimPrefix(prefix, "/")+"*")
+	return b
+}
+
+// OnResources - apply the entry to the raw resources
+func (b *SessionAclEntryBuilder) OnResources(resources ...string) *SessionAclEntryBuilder {
+	b.entry.Resource = append(b.entry.Resource, resources...)
+	return b
+}
+
+// FromIps - only allow the requests from the given IPs, CIDRs or wildcards like `192.168.1.*`
+func (b *SessionAclEntryBuilder) FromIps(ips ...string) *SessionAclEntryBuilder {
+	for _, ip := range ips {
+		if !validSessionAclIp(ip) && b.err == nil {
+			b.err = bce.NewBceClientError("invalid acl ip address: " + ip)
+		}
+	}
+	b.condition().IpAddress = append(b.condition().IpAddress, ips...)
+	return b
+}
+
+// WithRefererLike - only allow the requests with the referer matching the wildcard patterns
+func (b *SessionAclEntryBuilder) WithRefererLike(patterns ...string) *SessionAclEntryBuilder {
+	if b.condition().Referer == nil {
+		b.entry.Condition.Referer = &SessionAclReferer{}
+	}
+	b.entry.Condition.Referer.StringLike = append(b.entry.Condition.Referer.StringLike, patterns...)
+	return b
+}
+
+func (b *SessionAclEntryBuilder) condition() *SessionAclCondition {
+	if b.entry.Condition == nil {
+		b.entry.Condition = &SessionAclCondition{}
+	}
+	return b.entry.Condition
+}
+
+// Build - validate and return the built entry
+//
+// RETURNS:
+//     - *SessionAclEntry: the built entry
+//     - error: nil if the entry is valid otherwise the specific error
+func (b *SessionAclEntryBuilder) Build() (*SessionAclEntry, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	entry := b.entry
+	if err := (&SessionAcl{AccessControlList: []SessionAclEntry{entry}}).Validate(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// NewSessionAcl - build the ACL of the entries
+//
+// PARAMS:
+//     - entries: the builders of the entries
+// RETURNS:
+//     - *SessionAcl: the built ACL
+//     - error: nil if all the entries are valid otherwise the first error
+func NewSessionAcl(entries ...*SessionAclEntryBuilder) (*SessionAcl, error) {
+	acl := &SessionAcl{}
+	for _, b := range entries {
+		entry, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		acl.AccessControlList = append(acl.AccessControlList, *entry)
+	}
+	if err := acl.Validate(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// NewBosReadOnlyAcl - build the ACL of the read-only access to the objects with the prefix of the
+// bucket, optionally only from the given IPs or CIDRs
+//
+// PARAMS:
+//     - region: the region of the bucket, "*" for all regions
+//     - bucket: the bucket name
+//     - prefix: the prefix of the objects, empty for all the objects
+//     - ips: the IPs or CIDRs allowed, empty for all
+// RETURNS:
+//     - *SessionAcl: the built ACL
+//     - error: nil if success otherwise the specific error
+func NewBosReadOnlyAcl(region, bucket, prefix string, ips ...string) (*SessionAcl, error) {
+	entry := NewBosAclEntry(region).Allow(SESSION_ACL_PERMISSION_READ).OnPrefix(bucket, prefix)
+	if len(ips) != 0 {
+		entry.FromIps(ips...)
+	}
+	return NewSessionAcl(entry)
+}
+
+// ValidateDuration - check the duration of the session token, 0 means the default duration
+func ValidateDuration(durationSec int) error {
+	if durationSec < 0 || durationSec > MAX_DURATION_SECONDS {
+		return fmt.Errorf("invalid duration %ds, it must be in [0, %d], 0 for the default", durationSec,
+			MAX_DURATION_SECONDS)
+	}
+	return nil
+}
+
+// ValidateAssumeRoleDuration - check the duration of the credential of a role, 0 means the default
+func ValidateAssumeRoleDuration(durationSec int) error {
+	if durationSec < 0 || durationSec > MAX_ASSUMEROLE_DURATION_SECONDS {
+		return fmt.Errorf("invalid duration %ds, it must be in [0, %d], 0 for the default", durationSec,
+			MAX_ASSUMEROLE_DURATION_SECONDS)
+	}
+	return nil
+}
+
+func validSessionAclIp(ip string) bool {
+	if strings.Contains(ip, "/") {
+		_, _, err := net.ParseCIDR(ip)
+		return err == nil
+	}
+	if strings.Contains(ip, "*") {
+		return net.ParseIP(strings.Replace(ip, "*", "0", -1)) != nil
+	}
+	return net.ParseIP(ip) != nil
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewBosReadOnlyAcl(t *testing.T) {
+	acl, err := NewBosReadOnlyAcl("bj", "bucket", "/users/42/", "10.0.0.0/8", "192.168.1.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := acl.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"accessControlList":[{"service":"bce:bos","region":"bj","effect":"Allow",` +
+		`"permission":["READ"],"resource":["bucket/users/42/*"],` +
+		`"condition":{"ipAddress":["10.0.0.0/8","192.168.1.*"]}}]}`
+	if document != expected {
+		t.Errorf("unexpected document %s", document)
+	}
+
+	if _, err := NewBosReadOnlyAcl("bj", "bucket", "", "10.0.0.0/33"); err == nil {
+		t.Errorf("expect error of invalid cidr")
+	}
+	if _, err := NewBosReadOnlyAcl("bj", "", "prefix/"); err == nil {
+		t.Errorf("expect error of empty bucket")
+	}
+}
+
+func TestNewSessionAcl(t *testing.T) {
+	acl, err := NewSessionAcl(
+		NewBosAclEntry(SESSION_ACL_REGION_ALL).Allow(SESSION_ACL_PERMISSION_READ, SESSION_ACL_PERMISSION_WRITE).
+			OnBucket("bucket").WithRefererLike("https://*.example.com/*"),
+		NewBosAclEntry(SESSION_ACL_REGION_ALL).Deny(SESSION_ACL_PERMISSION_WRITE).OnPrefix("bucket", "readonly/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := acl.AccessControlList
+	if len(entries) != 2 || strings.Join(entries[0].Resource, ",") != "bucket,bucket/*" ||
+		entries[0].Condition.Referer.StringLike[0] != "https://*.example.com/*" ||
+		entries[1].Effect != SESSION_ACL_EFFECT_DENY || entries[1].Condition != nil {
+		t.Errorf("unexpected acl %+v", acl)
+	}
+
+	if _, err := NewSessionAcl(); err == nil {
+		t.Errorf("expect error of empty acl")
+	}
+	if _, err := NewSessionAcl(NewBosAclEntry("bj").OnBucket("bucket")); err == nil {
+		t.Errorf("expect error of no permission")
+	}
+	if _, err := NewSessionAcl(NewBosAclEntry("").Allow("READ").OnBucket("bucket")); err == nil {
+		t.Errorf("expect error of no region")
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for d, ok := range map[int]bool{0: true, 60: true, MAX_DURATION_SECONDS: true,
+		MAX_DURATION_SECONDS + 1: false, -1: false} {
+		if (ValidateDuration(d) == nil) != ok {
+			t.Errorf("duration %d: expect valid %v", d, ok)
+		}
+	}
+	if ValidateAssumeRoleDuration(MAX_DURATION_SECONDS) == nil {
+		t.Errorf("expect error of too long duration of assume role")
+	}
+}
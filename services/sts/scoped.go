@@ -0,0 +1,128 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// scoped.go - the temporary credentials scoped by the typed ACL and the clients bound to them
+
+package sts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kougazhang/bce-sdk-go/auth"
+	"github.com/kougazhang/bce-sdk-go/services/bos"
+	"github.com/kougazhang/bce-sdk-go/services/sts/api"
+	"github.com/kougazhang/bce-sdk-go/util"
+)
+
+// ScopedCredentials is the temporary credentials limited by the ACL.
+type ScopedCredentials struct {
+	Credentials *auth.BceCredentials
+	CreateTime  time.Time
+	Expiration  time.Time
+	UserId      string
+	Acl         *api.SessionAcl
+}
+
+// GetSessionTokenWithAcl - get the session token limited by the typed ACL
+//
+// PARAMS:
+//     - durationSec: the duration seconds of the token, 0 for the default duration
+//     - acl: the ACL of the token, nil for the full permissions of the user
+// RETURNS:
+//     - *api.GetSessionTokenResult: result of this api
+//     - error: nil if ok otherwise the specific error
+func (c *Client) GetSessionTokenWithAcl(durationSec int, acl *api.SessionAcl) (*api.GetSessionTokenResult, error) {
+	if err := api.ValidateDuration(durationSec); err != nil {
+		return nil, err
+	}
+	document := ""
+	if acl != nil {
+		var err error
+		if document, err = acl.Document(); err != nil {
+			return nil, err
+		}
+	}
+	return api.GetSessionToken(c, durationSec, document)
+}
+
+// GetScopedCredentials - get the temporary credentials limited by the typed ACL
+//
+// PARAMS:
+//     - durationSec: the duration seconds of the credentials, 0 for the default duration
+//     - acl: the ACL of the credentials
+// RETURNS:
+//     - *ScopedCredentials: the credentials with the session token and the expiration
+//     - error: nil if ok otherwise the specific error
+func (c *Client) GetScopedCredentials(durationSec int, acl *api.SessionAcl) (*ScopedCredentials, error) {
+	token, err := c.GetSessionTokenWithAcl(durationSec, acl)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := auth.NewSessionBceCredentials(token.AccessKeyId, token.SecretAccessKey,
+		token.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+	expiration, err := util.ParseISO8601Date(token.Expiration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration %q of the session token", token.Expiration)
+	}
+	createTime, _ := util.ParseISO8601Date(token.CreateTime)
+	return &ScopedCredentials{
+		Credentials: credentials,
+		CreateTime:  createTime,
+		Expiration:  expiration,
+		UserId:      token.UserId,
+		Acl:         acl,
+	}, nil
+}
+
+// GetScopedBosClient - get the temporary credentials limited by the typed ACL and the BOS client
+// bound to them, the client stops working after the credentials expire
+//
+// PARAMS:
+//     - durationSec: the duration seconds of the credentials, 0 for the default duration
+//     - acl: the ACL of the credentials, such as the result of `api.NewBosReadOnlyAcl`
+//     - bosEndpoint: the endpoint of BOS, empty for the default endpoint
+// RETURNS:
+//     - *bos.Client: the BOS client using the temporary credentials
+//     - *ScopedCredentials: the credentials to hand out
+//     - error: nil if ok otherwise the specific error
+func (c *Client) GetScopedBosClient(durationSec int, acl *api.SessionAcl, bosEndpoint string) (
+	*bos.Client, *ScopedCredentials, error) {
+	scoped, err := c.GetScopedCredentials(durationSec, acl)
+	if err != nil {
+		return nil, nil, err
+	}
+	bosClient, err := bos.NewClient(scoped.Credentials.AccessKeyId, scoped.Credentials.SecretAccessKey,
+		bosEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	bosClient.Config.Credentials = scoped.Credentials
+	return bosClient, scoped, nil
+}
+
+// NewCredentialVendor - create the http.Handler vending the temporary credentials limited by the
+// ACL of each caller, the credentials are minted by this client and cached until near expiry
+//
+// PARAMS:
+//     - config: the config of the vendor, the Authorizer is required
+// RETURNS:
+//     - *api.CredentialVendor: the vendor to mount on a net/http server
+//     - error: nil if ok otherwise the specific error
+func (c *Client) NewCredentialVendor(config *api.CredentialVendorConfig) (*api.CredentialVendor, error) {
+	return api.NewCredentialVendor(c, config)
+}
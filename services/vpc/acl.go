@@ -54,7 +54,7 @@ func (c *Client) CreateAclRule(args *CreateAclRuleArgs) error {
 		WithURL(getURLForAclRule()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -102,7 +102,7 @@ func (c *Client) UpdateAclRule(aclRuleId string, args *UpdateAclRuleArgs) error
 		WithURL(getURLForAclRuleId(aclRuleId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -117,6 +117,6 @@ func (c *Client) DeleteAclRule(aclRuleId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForAclRuleId(aclRuleId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// flow.go - evaluate the flows against the ACL and route rules
+
+package analysis
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/services/vpc"
+)
+
+// Flow defines the traffic to be evaluated. The port 0 stands for an unspecified port which only
+// matches the rules of all ports, and the ports are ignored for the protocols except tcp and udp.
+type Flow struct {
+	Protocol        string
+	SourceIp        string
+	SourcePort      int
+	DestinationIp   string
+	DestinationPort int
+}
+
+func (f Flow) String() string {
+	return fmt.Sprintf("%s %s:%d -> %s:%d", f.Protocol, f.SourceIp, f.SourcePort,
+		f.DestinationIp, f.DestinationPort)
+}
+
+type parsedFlow struct {
+	Flow
+	protocol string
+	src, dst net.IP
+}
+
+func parseFlow(flow Flow) (*parsedFlow, error) {
+	result := &parsedFlow{Flow: flow, protocol: strings.ToLower(flow.Protocol)}
+	switch result.protocol {
+	case string(vpc.ACL_RULE_PROTOCOL_TCP), string(vpc.ACL_RULE_PROTOCOL_UDP),
+		string(vpc.ACL_RULE_PROTOCOL_ICMP):
+	default:
+		return nil, fmt.Errorf("invalid protocol of flow: %q", flow.Protocol)
+	}
+	if result.src = net.ParseIP(flow.SourceIp); result.src == nil {
+		return nil, fmt.Errorf("invalid source ip of flow: %q", flow.SourceIp)
+	}
+	if result.dst = net.ParseIP(flow.DestinationIp); result.dst == nil {
+		return nil, fmt.Errorf("invalid destination ip of flow: %q", flow.DestinationIp)
+	}
+	return result, nil
+}
+
+// AclDecision is the result of the ACL rules of a subnet in one direction, the Rule is nil if no
+// rule matches and the flow is denied by default.
+type AclDecision struct {
+	SubnetId  string
+	Direction vpc.AclRuleDirectionType
+	Allowed   bool
+	Rule      *vpc.AclRule
+}
+
+func (d *AclDecision) String() string {
+	verdict := "deny"
+	if d.Allowed {
+		verdict = "allow"
+	}
+	if d.Rule == nil {
+		return fmt.Sprintf("%s acl of subnet %s: %s by default", d.Direction, d.SubnetId, verdict)
+	}
+	return fmt.Sprintf("%s acl of subnet %s: %s by rule %s at position %d", d.Direction,
+		d.SubnetId, verdict, d.Rule.Id, d.Rule.Position)
+}
+
+// RouteDecision is the route selected for the flow. The flow is delivered inside the VPC if
+// Local is true, otherwise it is forwarded to the next hop of the Rule, or dropped if no route
+// rule matches.
+type RouteDecision struct {
+	Local bool
+	Rule  *vpc.RouteRule
+}
+
+func (d *RouteDecision) String() string {
+	switch {
+	case d.Local:
+		return "route: local"
+	case d.Rule == nil:
+		return "route: no matching rule"
+	}
+	return fmt.Sprintf("route: %s via rule %s to nexthop %s(%s)", d.Rule.DestinationAddress,
+		d.Rule.RouteRuleId, d.Rule.NexthopId, d.Rule.NexthopType)
+}
+
+// FlowResult is the result of a flow evaluated by the Network. The Egress decision is made by the
+// subnet of the source and the Ingress one by the subnet of the destination, both of them are nil
+// if the endpoint is outside the VPC or the flow stays in the same subnet.
+type FlowResult struct {
+	Flow              Flow
+	SourceSubnet      string
+	DestinationSubnet string
+	Egress            *AclDecision
+	Route             *RouteDecision
+	Ingress           *AclDecision
+	Allowed           bool
+}
+
+func (r *FlowResult) String() string {
+	lines := []string{r.Flow.String()}
+	if r.Egress != nil {
+		lines = append(lines, "  "+r.Egress.String())
+	}
+	if r.Route != nil {
+		lines = append(lines, "  "+r.Route.String())
+	}
+	if r.Ingress != nil {
+		lines = append(lines, "  "+r.Ingress.String())
+	}
+	if r.Allowed {
+		lines = append(lines, "  result: allowed")
+	} else {
+		lines = append(lines, "  result: denied")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Evaluate - evaluate the flow against the ACL rules by position with first-match-wins semantics
+// and select the route by the longest prefix of the destination
+//
+// PARAMS:
+//     - flow: the flow to be evaluated
+// RETURNS:
+//     - *FlowResult: the decisions of the ACL and the route
+//     - error: nil if success otherwise the specific error
+func (n *Network) Evaluate(flow Flow) (*FlowResult, error) {
+	parsed, err := parseFlow(flow)
+	if err != nil {
+		return nil, err
+	}
+	result := &FlowResult{Flow: flow, Allowed: true}
+	srcSubnet, dstSubnet := n.findSubnet(parsed.src), n.findSubnet(parsed.dst)
+	if srcSubnet != nil {
+		result.SourceSubnet = srcSubnet.id
+	}
+	if dstSubnet != nil {
+		result.DestinationSubnet = dstSubnet.id
+	}
+	if srcSubnet != nil && srcSubnet == dstSubnet {
+		result.Route = &RouteDecision{Local: true}
+		return result, nil
+	}
+
+	if srcSubnet != nil {
+		result.Egress = evaluateAcl(srcSubnet, vpc.ACL_RULE_DIRECTION_EGRESS, srcSubnet.egress, parsed)
+		result.Route = n.route(parsed)
+		result.Allowed = result.Egress.Allowed && (result.Route.Local || result.Route.Rule != nil)
+	}
+	if dstSubnet != nil && result.Allowed && (result.Route == nil || result.Route.Local) {
+		result.Ingress = evaluateAcl(dstSubnet, vpc.ACL_RULE_DIRECTION_INGRESS, dstSubnet.ingress,
+			parsed)
+		result.Allowed = result.Ingress.Allowed
+	}
+	return result, nil
+}
+
+func evaluateAcl(s *subnet, direction vpc.AclRuleDirectionType, rules []*aclRule,
+	flow *parsedFlow) *AclDecision {
+	decision := &AclDecision{SubnetId: s.id, Direction: direction}
+	for _, rule := range rules {
+		if rule.match(flow) {
+			decision.Rule = rule.rule
+			decision.Allowed = rule.rule.Action == vpc.ACL_RULE_ACTION_ALLOW
+			return decision
+		}
+	}
+	return decision
+}
+
+func (n *Network) route(flow *parsedFlow) *RouteDecision {
+	if n.isLocal(flow.dst) {
+		return &RouteDecision{Local: true}
+	}
+	var best *routeRule
+	for _, rule := range n.routes {
+		if !rule.src.contains(flow.src) || !rule.dst.contains(flow.dst) {
+			continue
+		}
+		if best == nil || rule.dst.length() > best.dst.length() ||
+			(rule.dst.length() == best.dst.length() && rule.src.length() > best.src.length()) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return &RouteDecision{}
+	}
+	return &RouteDecision{Rule: best.rule}
+}
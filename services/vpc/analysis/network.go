@@ -0,0 +1,313 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// network.go - the in-memory model of the VPC ACL and route rules
+
+// Package analysis evaluates flows against the ACL and route rules of a VPC offline.
+//
+// The rules are loaded from the results of the VPC APIs or from a snapshot saved as JSON, so that
+// a change of the rules can be reviewed without accessing the VPC service.
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kougazhang/bce-sdk-go/services/vpc"
+)
+
+const (
+	ADDRESS_ALL  = "all"
+	PORT_ALL     = string(vpc.ACL_RULE_PORT_ALL)
+	PROTOCOL_ALL = "all"
+)
+
+// Snapshot holds the raw ACL and route rules of a VPC, it can be saved as JSON for later review.
+type Snapshot struct {
+	Acl        *vpc.ListAclEntrysResult `json:"acl"`
+	RouteTable *vpc.GetRouteTableResult `json:"routeTable"`
+}
+
+// CaptureSnapshot - get the ACL and route rules of the VPC from the VPC service
+//
+// PARAMS:
+//     - cli: the client of the VPC service
+//     - vpcId: the id of the VPC
+//     - routeTableId: the id of the route table, the one of the VPC is used if empty
+// RETURNS:
+//     - *Snapshot: the rules of the VPC
+//     - error: nil if success otherwise the specific error
+func CaptureSnapshot(cli *vpc.Client, vpcId, routeTableId string) (*Snapshot, error) {
+	acl, err := cli.ListAclEntrys(vpcId)
+	if err != nil {
+		return nil, err
+	}
+	routeTable, err := cli.GetRouteTableDetail(routeTableId, vpcId)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Acl: acl, RouteTable: routeTable}, nil
+}
+
+// LoadSnapshot - load the snapshot saved as JSON
+//
+// PARAMS:
+//     - r: the reader of the JSON content
+// RETURNS:
+//     - *Snapshot: the rules of the VPC
+//     - error: nil if success otherwise the specific error
+func LoadSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot as indented JSON.
+func (s *Snapshot) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(s)
+}
+
+type prefix struct {
+	network *net.IPNet // nil stands for all addresses
+}
+
+func parsePrefix(address string) (prefix, error) {
+	address = strings.TrimSpace(address)
+	if len(address) == 0 || strings.ToLower(address) == ADDRESS_ALL {
+		return prefix{}, nil
+	}
+	if !strings.Contains(address, "/") {
+		if strings.Contains(address, ":") {
+			address += "/128"
+		} else {
+			address += "/32"
+		}
+	}
+	_, network, err := net.ParseCIDR(address)
+	if err != nil {
+		return prefix{}, fmt.Errorf("invalid address %q", address)
+	}
+	if ones, _ := network.Mask.Size(); ones == 0 {
+		return prefix{}, nil
+	}
+	return prefix{network}, nil
+}
+
+func (p prefix) contains(ip net.IP) bool {
+	return p.network == nil || p.network.Contains(ip)
+}
+
+func (p prefix) length() int {
+	if p.network == nil {
+		return 0
+	}
+	ones, _ := p.network.Mask.Size()
+	return ones
+}
+
+type portRange struct {
+	all      bool
+	from, to int
+}
+
+func parsePortRange(ports string) (portRange, error) {
+	ports = strings.TrimSpace(ports)
+	if len(ports) == 0 || strings.ToLower(ports) == PORT_ALL {
+		return portRange{all: true}, nil
+	}
+	parts := strings.SplitN(ports, "-", 2)
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || port < 1 || port > 65535 {
+			return portRange{}, fmt.Errorf("invalid port range %q", ports)
+		}
+		values[i] = port
+	}
+	result := portRange{from: values[0], to: values[len(values)-1]}
+	if result.from > result.to {
+		return portRange{}, fmt.Errorf("invalid port range %q", ports)
+	}
+	return result, nil
+}
+
+// contains reports whether the port is in the range, the unspecified port 0 only matches "all".
+func (r portRange) contains(port int) bool {
+	return r.all || (port >= r.from && port <= r.to)
+}
+
+type aclRule struct {
+	rule     *vpc.AclRule
+	protocol string
+	src, dst prefix
+	srcPort  portRange
+	dstPort  portRange
+}
+
+func newAclRule(rule *vpc.AclRule) (*aclRule, error) {
+	result := &aclRule{rule: rule, protocol: strings.ToLower(string(rule.Protocol))}
+	if len(result.protocol) == 0 {
+		result.protocol = PROTOCOL_ALL
+	}
+	var err error
+	if result.src, err = parsePrefix(rule.SourceIpAddress); err != nil {
+		return nil, fmt.Errorf("acl rule %s: %v", rule.Id, err)
+	}
+	if result.dst, err = parsePrefix(rule.DestinationIpAddress); err != nil {
+		return nil, fmt.Errorf("acl rule %s: %v", rule.Id, err)
+	}
+	if result.srcPort, err = parsePortRange(rule.SourcePort); err != nil {
+		return nil, fmt.Errorf("acl rule %s: %v", rule.Id, err)
+	}
+	if result.dstPort, err = parsePortRange(rule.DestinationPort); err != nil {
+		return nil, fmt.Errorf("acl rule %s: %v", rule.Id, err)
+	}
+	return result, nil
+}
+
+func (r *aclRule) match(flow *parsedFlow) bool {
+	if r.protocol != PROTOCOL_ALL && r.protocol != flow.protocol {
+		return false
+	}
+	if !r.src.contains(flow.src) || !r.dst.contains(flow.dst) {
+		return false
+	}
+	if flow.protocol == string(vpc.ACL_RULE_PROTOCOL_TCP) ||
+		flow.protocol == string(vpc.ACL_RULE_PROTOCOL_UDP) {
+		return r.srcPort.contains(flow.SourcePort) && r.dstPort.contains(flow.DestinationPort)
+	}
+	return true
+}
+
+type subnet struct {
+	id, name string
+	cidr     prefix
+	ingress  []*aclRule
+	egress   []*aclRule
+}
+
+type routeRule struct {
+	rule     *vpc.RouteRule
+	src, dst prefix
+}
+
+// Network is the in-memory model of the ACL and route rules of a VPC.
+type Network struct {
+	vpcId   string
+	vpcCidr prefix
+	subnets []*subnet
+	routes  []*routeRule
+}
+
+// NewNetwork - build the model from the ACL entries and the route table of the VPC
+//
+// PARAMS:
+//     - acl: the result of ListAclEntrys
+//     - routeTable: the result of GetRouteTableDetail, may be nil if routing is not evaluated
+// RETURNS:
+//     - *Network: the model of the VPC
+//     - error: nil if success otherwise the specific error
+func NewNetwork(acl *vpc.ListAclEntrysResult, routeTable *vpc.GetRouteTableResult) (*Network, error) {
+	if acl == nil {
+		return nil, fmt.Errorf("the acl entries can not be nil")
+	}
+	network := &Network{vpcId: acl.VpcId}
+	var err error
+	if len(acl.VpcCidr) != 0 {
+		if network.vpcCidr, err = parsePrefix(acl.VpcCidr); err != nil {
+			return nil, fmt.Errorf("vpc %s: %v", acl.VpcId, err)
+		}
+	}
+	for i := range acl.AclEntrys {
+		entry := &acl.AclEntrys[i]
+		s := &subnet{id: entry.SubnetId, name: entry.SubnetName}
+		if s.cidr, err = parsePrefix(entry.SubnetCidr); err != nil || s.cidr.network == nil {
+			return nil, fmt.Errorf("subnet %s: invalid cidr %q", entry.SubnetId, entry.SubnetCidr)
+		}
+		for j := range entry.AclRules {
+			rule, err := newAclRule(&entry.AclRules[j])
+			if err != nil {
+				return nil, err
+			}
+			switch rule.rule.Direction {
+			case vpc.ACL_RULE_DIRECTION_INGRESS:
+				s.ingress = append(s.ingress, rule)
+			case vpc.ACL_RULE_DIRECTION_EGRESS:
+				s.egress = append(s.egress, rule)
+			default:
+				return nil, fmt.Errorf("acl rule %s: invalid direction %q", rule.rule.Id,
+					rule.rule.Direction)
+			}
+		}
+		sortAclRules(s.ingress)
+		sortAclRules(s.egress)
+		network.subnets = append(network.subnets, s)
+	}
+	if routeTable != nil {
+		for i := range routeTable.RouteRules {
+			rule := &routeRule{rule: &routeTable.RouteRules[i]}
+			if rule.src, err = parsePrefix(rule.rule.SourceAddress); err != nil {
+				return nil, fmt.Errorf("route rule %s: %v", rule.rule.RouteRuleId, err)
+			}
+			if rule.dst, err = parsePrefix(rule.rule.DestinationAddress); err != nil {
+				return nil, fmt.Errorf("route rule %s: %v", rule.rule.RouteRuleId, err)
+			}
+			network.routes = append(network.routes, rule)
+		}
+	}
+	return network, nil
+}
+
+// NewNetworkFromSnapshot - build the model from the snapshot
+//
+// PARAMS:
+//     - snapshot: the snapshot captured or loaded from JSON
+// RETURNS:
+//     - *Network: the model of the VPC
+//     - error: nil if success otherwise the specific error
+func NewNetworkFromSnapshot(snapshot *Snapshot) (*Network, error) {
+	return NewNetwork(snapshot.Acl, snapshot.RouteTable)
+}
+
+func sortAclRules(rules []*aclRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].rule.Position < rules[j].rule.Position
+	})
+}
+
+func (n *Network) findSubnet(ip net.IP) *subnet {
+	var found *subnet
+	for _, s := range n.subnets {
+		if s.cidr.contains(ip) && (found == nil || s.cidr.length() > found.cidr.length()) {
+			found = s
+		}
+	}
+	return found
+}
+
+func (n *Network) isLocal(ip net.IP) bool {
+	if n.vpcCidr.network != nil && n.vpcCidr.contains(ip) {
+		return true
+	}
+	return n.findSubnet(ip) != nil
+}
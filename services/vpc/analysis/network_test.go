@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testSnapshot = `
+{
+    "acl": {
+        "vpcId": "vpc-1",
+        "vpcCidr": "10.0.0.0/16",
+        "aclEntrys": [
+            {
+                "subnetId": "sbn-web",
+                "subnetCidr": "10.0.1.0/24",
+                "aclRules": [
+                    {"id": "web-out", "protocol": "all", "sourceIpAddress": "all",
+                        "destinationIpAddress": "all", "sourcePort": "all", "destinationPort": "all",
+                        "position": 10, "direction": "egress", "action": "allow"},
+                    {"id": "web-in-ssh", "protocol": "tcp", "sourceIpAddress": "0.0.0.0/0",
+                        "destinationIpAddress": "all", "sourcePort": "all", "destinationPort": "22",
+                        "position": 20, "direction": "ingress", "action": "deny"},
+                    {"id": "web-in", "protocol": "all", "sourceIpAddress": "all",
+                        "destinationIpAddress": "all", "sourcePort": "all", "destinationPort": "all",
+                        "position": 100, "direction": "ingress", "action": "allow"}
+                ]
+            },
+            {
+                "subnetId": "sbn-db",
+                "subnetCidr": "10.0.2.0/24",
+                "aclRules": [
+                    {"id": "db-out", "protocol": "all", "sourceIpAddress": "all",
+                        "destinationIpAddress": "10.0.0.0/16", "sourcePort": "all",
+                        "destinationPort": "all", "position": 10, "direction": "egress",
+                        "action": "allow"},
+                    {"id": "db-deny", "protocol": "all", "sourceIpAddress": "all",
+                        "destinationIpAddress": "all", "sourcePort": "all", "destinationPort": "all",
+                        "position": 200, "direction": "ingress", "action": "deny"},
+                    {"id": "db-https", "protocol": "tcp", "sourceIpAddress": "10.0.1.0/24",
+                        "destinationIpAddress": "10.0.2.0/24", "sourcePort": "all",
+                        "destinationPort": "443", "position": 100, "direction": "ingress",
+                        "action": "allow"}
+                ]
+            }
+        ]
+    },
+    "routeTable": {
+        "routeTableId": "rt-1",
+        "vpcId": "vpc-1",
+        "routeRules": [
+            {"routeRuleId": "default", "sourceAddress": "0.0.0.0/0", "destinationAddress": "0.0.0.0/0",
+                "nexthopId": "nat-1", "nexthopType": "nat"},
+            {"routeRuleId": "idc", "sourceAddress": "0.0.0.0/0", "destinationAddress": "192.168.0.0/16",
+                "nexthopId": "vpn-1", "nexthopType": "vpn"}
+        ]
+    }
+}
+`
+
+func loadTestNetwork(t *testing.T) *Network {
+	snapshot, err := LoadSnapshot(strings.NewReader(testSnapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := NewNetworkFromSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
+
+func TestEvaluateFlow(t *testing.T) {
+	network := loadTestNetwork(t)
+	cases := []struct {
+		flow    Flow
+		allowed bool
+		rule    string
+	}{
+		{Flow{"tcp", "10.0.1.5", 0, "10.0.2.9", 443}, true, "db-https"},
+		{Flow{"tcp", "10.0.1.5", 0, "10.0.2.9", 3306}, false, "db-deny"},
+		{Flow{"tcp", "10.0.2.9", 0, "10.0.1.5", 22}, false, "web-in-ssh"},
+		{Flow{"udp", "10.0.2.9", 0, "10.0.1.5", 53}, true, "web-in"},
+		{Flow{"icmp", "172.16.0.1", 0, "10.0.2.9", 0}, false, "db-deny"},
+		{Flow{"tcp", "10.0.1.5", 0, "10.0.1.6", 22}, true, ""},
+	}
+	for i, c := range cases {
+		result, err := network.Evaluate(c.flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != c.allowed {
+			t.Errorf("case %d: expect %v but\n%s", i, c.allowed, result)
+		}
+		rule := ""
+		if result.Ingress != nil && result.Ingress.Rule != nil {
+			rule = result.Ingress.Rule.Id
+		}
+		if rule != c.rule {
+			t.Errorf("case %d: expect rule %q but %q", i, c.rule, rule)
+		}
+	}
+
+	result, _ := network.Evaluate(Flow{"tcp", "10.0.1.5", 0, "192.168.3.3", 80})
+	if !result.Allowed || result.Route.Rule == nil || result.Route.Rule.NexthopId != "vpn-1" ||
+		result.Egress.Rule.Id != "web-out" {
+		t.Errorf("unexpected result of idc flow\n%s", result)
+	}
+	result, _ = network.Evaluate(Flow{"tcp", "10.0.1.5", 0, "8.8.8.8", 53})
+	if !result.Allowed || result.Route.Rule.NexthopId != "nat-1" || result.Ingress != nil {
+		t.Errorf("unexpected result of internet flow\n%s", result)
+	}
+
+	if _, err := network.Evaluate(Flow{"gre", "10.0.1.5", 0, "10.0.2.9", 0}); err == nil {
+		t.Errorf("expect error of invalid protocol")
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	snapshot, _ := LoadSnapshot(strings.NewReader(testSnapshot))
+	buf := &bytes.Buffer{}
+	if err := snapshot.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Acl.AclEntrys) != 2 || len(loaded.RouteTable.RouteRules) != 2 {
+		t.Errorf("unexpected snapshot %+v", loaded)
+	}
+	loaded.Acl.AclEntrys[0].AclRules[0].DestinationPort = "70000"
+	if _, err := NewNetworkFromSnapshot(loaded); err == nil {
+		t.Errorf("expect error of invalid port")
+	}
+}
@@ -41,7 +41,7 @@ func (c *Client) CreateNatGateway(args *CreateNatGatewayArgs) (*CreateNatGateway
 		WithURL(getURLForNat()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -113,7 +113,7 @@ func (c *Client) UpdateNatGateway(natId string, args *UpdateNatGatewayArgs) erro
 		WithURL(getURLForNatId(natId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -133,7 +133,7 @@ func (c *Client) BindEips(natId string, args *BindEipsArgs) error {
 		WithURL(getURLForNatId(natId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("bind", "").
 		Do()
 }
@@ -154,7 +154,7 @@ func (c *Client) UnBindEips(natId string, args *UnBindEipsArgs) error {
 		WithURL(getURLForNatId(natId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("unbind", "").
 		Do()
 }
@@ -170,7 +170,7 @@ func (c *Client) DeleteNatGateway(natId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForNatId(natId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -190,7 +190,7 @@ func (c *Client) RenewNatGateway(natId string, args *RenewNatGatewayArgs) error
 		WithURL(getURLForNatId(natId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("purchaseReserved", "").
 		Do()
 }
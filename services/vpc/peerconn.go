@@ -41,7 +41,7 @@ func (c *Client) CreatePeerConn(args *CreatePeerConnArgs) (*CreatePeerConnResult
 		WithURL(getURLForPeerConn()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -127,7 +127,7 @@ func (c *Client) AcceptPeerConnApply(peerConnId, clientToken string) error {
 		WithURL(getURLForPeerConnId(peerConnId)).
 		WithMethod(http.PUT).
 		WithQueryParam("accept", "").
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -143,7 +143,7 @@ func (c *Client) RejectPeerConnApply(peerConnId, clientToken string) error {
 		WithURL(getURLForPeerConnId(peerConnId)).
 		WithMethod(http.PUT).
 		WithQueryParam("reject", "").
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -158,7 +158,7 @@ func (c *Client) DeletePeerConn(peerConnId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForPeerConnId(peerConnId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -179,7 +179,7 @@ func (c *Client) ResizePeerConn(peerConnId string, args *ResizePeerConnArgs) err
 		WithMethod(http.PUT).
 		WithBody(args).
 		WithQueryParam("resize", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -200,7 +200,7 @@ func (c *Client) RenewPeerConn(peerConnId string, args *RenewPeerConnArgs) error
 		WithMethod(http.PUT).
 		WithBody(args).
 		WithQueryParam("purchaseReserved", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -221,7 +221,7 @@ func (c *Client) OpenPeerConnSyncDNS(peerConnId string, args *PeerConnSyncDNSArg
 		WithMethod(http.PUT).
 		WithQueryParam("open", "").
 		WithQueryParamFilter("role", string(args.Role)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithBody(args).
 		Do()
 }
@@ -243,6 +243,6 @@ func (c *Client) ClosePeerConnSyncDNS(peerConnId string, args *PeerConnSyncDNSAr
 		WithMethod(http.PUT).
 		WithQueryParam("close", "").
 		WithQueryParamFilter("role", string(args.Role)).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
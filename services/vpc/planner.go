@@ -0,0 +1,363 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// planner.go - the subnet CIDR planner of the VPC service
+
+package vpc
+
+import (
+	"fmt"
+
+	"github.com/kougazhang/bce-sdk-go/model"
+	"github.com/kougazhang/bce-sdk-go/services/vpn"
+	"github.com/kougazhang/bce-sdk-go/util"
+	"github.com/kougazhang/bce-sdk-go/util/log"
+)
+
+// ReservedCidr is a CIDR which the new subnets must not overlap, the Source tells where it comes
+// from, such as "subnet sbn-xxx", "peer connection peerconn-xxx" or "vpn connection vpnconn-xxx".
+type ReservedCidr struct {
+	Cidr   string
+	Source string
+}
+
+// CidrConflict is an existing subnet overlapping a CIDR reserved by other networks.
+type CidrConflict struct {
+	Subnet   Subnet
+	Reserved ReservedCidr
+}
+
+// SubnetRequest defines a subnet to be planned.
+type SubnetRequest struct {
+	Name         string
+	ZoneName     string
+	PrefixLength int
+	SubnetType   SubnetType
+	Description  string
+	Tags         []model.TagModel
+}
+
+type reservedBlock struct {
+	block    util.IPv4Block
+	external bool // reserved by other networks rather than the subnets of the VPC
+	ReservedCidr
+}
+
+// SubnetPlanner finds the free CIDR blocks in a VPC and plans the new subnets.
+type SubnetPlanner struct {
+	vpc      VPC
+	vpcCidrs []util.IPv4Block
+	subnets  []Subnet
+	used     []reservedBlock
+}
+
+// NewSubnetPlanner - create a planner from the results of ListVPC and ListSubnets
+//
+// PARAMS:
+//     - vpc: the VPC to plan the subnets in
+//     - subnets: the existing subnets of the VPC
+// RETURNS:
+//     - *SubnetPlanner: the planner
+//     - error: nil if success otherwise the specific error
+func NewSubnetPlanner(vpc VPC, subnets []Subnet) (*SubnetPlanner, error) {
+	planner := &SubnetPlanner{vpc: vpc, subnets: subnets}
+	for _, cidr := range append([]string{vpc.Cidr}, vpc.SecondaryCidr...) {
+		block, err := util.ParseIPv4Block(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("vpc %s: %v", vpc.VPCID, err)
+		}
+		planner.vpcCidrs = append(planner.vpcCidrs, block)
+	}
+	for _, subnet := range subnets {
+		if err := planner.reserve("subnet "+subnet.SubnetId, false, subnet.Cidr); err != nil {
+			return nil, err
+		}
+	}
+	return planner, nil
+}
+
+// Reserve - mark the CIDRs as used, so the planned subnets will not overlap them
+//
+// PARAMS:
+//     - source: where the CIDRs come from
+//     - cidrs: the CIDRs to reserve
+// RETURNS:
+//     - error: nil if success otherwise the specific error
+func (p *SubnetPlanner) Reserve(source string, cidrs ...string) error {
+	return p.reserve(source, true, cidrs...)
+}
+
+func (p *SubnetPlanner) reserve(source string, external bool, cidrs ...string) error {
+	for _, cidr := range cidrs {
+		block, err := util.ParseIPv4Block(cidr)
+		if err != nil {
+			return fmt.Errorf("%s: %v", source, err)
+		}
+		p.used = append(p.used, reservedBlock{block, external, ReservedCidr{block.String(), source}})
+	}
+	return nil
+}
+
+// Overlaps - find the reserved CIDRs overlapping the given one
+//
+// PARAMS:
+//     - cidr: the CIDR to check
+// RETURNS:
+//     - []ReservedCidr: the overlapping CIDRs, empty if the CIDR is free
+//     - error: nil if success otherwise the specific error
+func (p *SubnetPlanner) Overlaps(cidr string) ([]ReservedCidr, error) {
+	block, err := util.ParseIPv4Block(cidr)
+	if err != nil {
+		return nil, err
+	}
+	result := []ReservedCidr{}
+	for _, used := range p.used {
+		if used.block.Overlaps(block) {
+			result = append(result, used.ReservedCidr)
+		}
+	}
+	return result, nil
+}
+
+// Conflicts returns the existing subnets which overlap the CIDRs reserved by other networks such
+// as the peer VPCs and the remote subnets of the VPN connections.
+func (p *SubnetPlanner) Conflicts() []CidrConflict {
+	result := []CidrConflict{}
+	for _, subnet := range p.subnets {
+		block, _ := util.ParseIPv4Block(subnet.Cidr) // validated by NewSubnetPlanner
+		for _, used := range p.used {
+			if used.external && used.block.Overlaps(block) {
+				result = append(result, CidrConflict{subnet, used.ReservedCidr})
+			}
+		}
+	}
+	return result
+}
+
+// FreeBlocks - find the free CIDR blocks of the prefix length in the primary and secondary CIDRs
+// of the VPC
+//
+// PARAMS:
+//     - prefixLength: the prefix length of the blocks
+//     - limit: the maximum number of blocks to return, no limit if not positive
+// RETURNS:
+//     - []string: the free CIDR blocks
+//     - error: nil if success otherwise the specific error
+func (p *SubnetPlanner) FreeBlocks(prefixLength, limit int) ([]string, error) {
+	used := make([]util.IPv4Block, 0, len(p.used))
+	for _, reserved := range p.used {
+		used = append(used, reserved.block)
+	}
+	result := []string{}
+	for _, parent := range p.vpcCidrs {
+		if prefixLength < parent.PrefixLength {
+			continue
+		}
+		blocks, err := util.FreeIPv4Blocks(parent, used, prefixLength, limit-len(result))
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			result = append(result, block.String())
+		}
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Plan - allocate a free CIDR block for each request in order, the allocated blocks are reserved
+// so the following calls will not reuse them
+//
+// PARAMS:
+//     - requests: the subnets to plan
+// RETURNS:
+//     - []CreateSubnetArgs: the arguments to create the subnets
+//     - error: nil if success otherwise the specific error
+func (p *SubnetPlanner) Plan(requests []SubnetRequest) ([]CreateSubnetArgs, error) {
+	result := make([]CreateSubnetArgs, 0, len(requests))
+	for _, request := range requests {
+		if len(request.ZoneName) == 0 {
+			return nil, fmt.Errorf("the zone of subnet %s can not be empty", request.Name)
+		}
+		free, err := p.FreeBlocks(request.PrefixLength, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(free) == 0 {
+			return nil, fmt.Errorf("no free /%d block for subnet %s in vpc %s", request.PrefixLength,
+				request.Name, p.vpc.VPCID)
+		}
+		args := CreateSubnetArgs{
+			Name:        request.Name,
+			ZoneName:    request.ZoneName,
+			Cidr:        free[0],
+			VpcId:       p.vpc.VPCID,
+			SubnetType:  request.SubnetType,
+			Description: request.Description,
+			Tags:        request.Tags,
+		}
+		block, _ := util.ParseIPv4Block(free[0])
+		for i, parent := range p.vpcCidrs {
+			if i > 0 && parent.Contains(block) {
+				args.VpcSecondaryCidr = parent.String()
+			}
+		}
+		p.reserve("planned subnet "+request.Name, false, free[0])
+		result = append(result, args)
+	}
+	return result, nil
+}
+
+// LoadSubnetPlannerArgs defines the structure of the input parameters for the LoadSubnetPlanner api
+type LoadSubnetPlannerArgs struct {
+	VpcId string
+
+	// VpnClient is used to reserve the remote subnets of the VPN connections if not nil
+	VpnClient *vpn.Client
+}
+
+// LoadSubnetPlanner - load the VPC with its subnets and create a planner, the CIDRs of the peer
+// VPCs owned by the same account and the remote subnets of the VPN connections are reserved. Use
+// the package level NewSubnetPlanner to create a planner from already listed results offline.
+//
+// PARAMS:
+//     - args: the arguments to load the planner
+// RETURNS:
+//     - *SubnetPlanner: the planner
+//     - error: nil if success otherwise the specific error
+func (c *Client) LoadSubnetPlanner(args *LoadSubnetPlannerArgs) (*SubnetPlanner, error) {
+	if args == nil || len(args.VpcId) == 0 {
+		return nil, fmt.Errorf("the vpcId can not be empty")
+	}
+	vpcs := map[string]VPC{}
+	listVpcArgs := &ListVPCArgs{}
+	for {
+		result, err := c.ListVPC(listVpcArgs)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range result.VPCs {
+			vpcs[vpc.VPCID] = vpc
+		}
+		if !result.IsTruncated || len(result.NextMarker) == 0 {
+			break
+		}
+		listVpcArgs.Marker = result.NextMarker
+	}
+	vpc, ok := vpcs[args.VpcId]
+	if !ok {
+		return nil, fmt.Errorf("vpc %s not found", args.VpcId)
+	}
+
+	subnets := []Subnet{}
+	listSubnetArgs := &ListSubnetArgs{VpcId: args.VpcId}
+	for {
+		result, err := c.ListSubnets(listSubnetArgs)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, result.Subnets...)
+		if !result.IsTruncated || len(result.NextMarker) == 0 {
+			break
+		}
+		listSubnetArgs.Marker = result.NextMarker
+	}
+	planner, err := NewSubnetPlanner(vpc, subnets)
+	if err != nil {
+		return nil, err
+	}
+
+	listPeerConnArgs := &ListPeerConnsArgs{VpcId: args.VpcId}
+	for {
+		result, err := c.ListPeerConn(listPeerConnArgs)
+		if err != nil {
+			return nil, err
+		}
+		for _, conn := range result.PeerConns {
+			peerVpcId := conn.PeerVpcId
+			if peerVpcId == args.VpcId {
+				peerVpcId = conn.LocalVpcId
+			}
+			peer, ok := vpcs[peerVpcId]
+			if !ok {
+				log.Warnf("skip peer connection %s, the cidr of vpc %s is unknown",
+					conn.PeerConnId, peerVpcId)
+				continue
+			}
+			cidrs := append([]string{peer.Cidr}, peer.SecondaryCidr...)
+			if err := planner.Reserve("peer connection "+conn.PeerConnId, cidrs...); err != nil {
+				return nil, err
+			}
+		}
+		if !result.IsTruncated || len(result.NextMarker) == 0 {
+			break
+		}
+		listPeerConnArgs.Marker = result.NextMarker
+	}
+
+	if args.VpnClient != nil {
+		if err := reserveVpnRemoteSubnets(planner, args.VpnClient, args.VpcId); err != nil {
+			return nil, err
+		}
+	}
+	return planner, nil
+}
+
+func reserveVpnRemoteSubnets(planner *SubnetPlanner, cli *vpn.Client, vpcId string) error {
+	listVpnArgs := &vpn.ListVpnGatewayArgs{VpcId: vpcId}
+	for {
+		result, err := cli.ListVpnGateway(listVpnArgs)
+		if err != nil {
+			return err
+		}
+		for _, gateway := range result.Vpns {
+			conns, err := cli.ListVpnConn(gateway.VpnId)
+			if err != nil {
+				return err
+			}
+			for _, conn := range conns.VpnConns {
+				source := "vpn connection " + conn.VpnConnId
+				if err := planner.Reserve(source, conn.RemoteSubnets...); err != nil {
+					return err
+				}
+			}
+		}
+		if !result.IsTruncated || len(result.NextMarker) == 0 {
+			break
+		}
+		listVpnArgs.Marker = result.NextMarker
+	}
+	return nil
+}
+
+// CreatePlannedSubnets - create the subnets planned by the SubnetPlanner in order
+//
+// PARAMS:
+//     - planned: the arguments returned by SubnetPlanner.Plan
+// RETURNS:
+//     - []string: the ids of the created subnets
+//     - error: nil if success otherwise the specific error
+func (c *Client) CreatePlannedSubnets(planned []CreateSubnetArgs) ([]string, error) {
+	result := make([]string, 0, len(planned))
+	for i := range planned {
+		created, err := c.CreateSubnet(&planned[i])
+		if err != nil {
+			return result, fmt.Errorf("create subnet %s(%s): %v", planned[i].Name, planned[i].Cidr, err)
+		}
+		result = append(result, created.SubnetId)
+	}
+	return result, nil
+}
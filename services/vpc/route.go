@@ -65,7 +65,7 @@ func (c *Client) CreateRouteRule(args *CreateRouteRuleArgs) (*CreateRouteRuleRes
 		WithURL(getURLForRouteRule()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -83,6 +83,6 @@ func (c *Client) DeleteRouteRule(routeRuleId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForRouteRuleId(routeRuleId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
@@ -41,7 +41,7 @@ func (c *Client) CreateSubnet(args *CreateSubnetArgs) (*CreateSubnetResult, erro
 		WithURL(getURLForSubnet()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -122,7 +122,7 @@ func (c *Client) UpdateSubnet(subnetId string, args *UpdateSubnetArgs) error {
 		WithURL(getURLForSubnetId(subnetId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("modifyAttribute", "").
 		Do()
 }
@@ -142,6 +142,6 @@ func (c *Client) DeleteSubnet(subnetId string, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForSubnetId(subnetId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
@@ -41,7 +41,7 @@ func (c *Client) CreateVPC(args *CreateVPCArgs) (*CreateVPCResult, error) {
 		WithURL(getURLForVPC()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -117,7 +117,7 @@ func (c *Client) UpdateVPC(vpcId string, updateVPCArgs *UpdateVPCArgs) error {
 		WithMethod(http.PUT).
 		WithQueryParam("modifyAttribute", "").
 		WithBody(updateVPCArgs).
-		WithQueryParamFilter("clientToken", updateVPCArgs.ClientToken).
+		WithClientToken(updateVPCArgs.ClientToken).
 		Do()
 }
 
@@ -132,7 +132,7 @@ func (c *Client) DeleteVPC(vpcId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForVPCId(vpcId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
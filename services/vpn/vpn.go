@@ -41,7 +41,7 @@ func (c *Client) CreateVpnGateway(args *CreateVpnGatewayArgs) (*CreateVpnGateway
 		WithURL(getURLForVPN()).
 		WithMethod(http.POST).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithResult(result).
 		Do()
 
@@ -90,7 +90,7 @@ func (c *Client) DeleteVpn(vpnId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForVPNId(vpnId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -129,7 +129,7 @@ func (c *Client) UpdateVpnGateway(vpnId string, args *UpdateVpnGatewayArgs) erro
 		WithMethod(http.PUT).
 		WithBody(args).
 		WithQueryParam("modifyAttribute", "").
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		Do()
 }
 
@@ -148,7 +148,7 @@ func (c *Client) BindEip(vpnId string, args *BindEipArgs) error {
 		WithURL(getURLForVPNId(vpnId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("bind", "").
 		Do()
 }
@@ -163,7 +163,7 @@ func (c *Client) UnBindEip(vpnId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForVPNId(vpnId)).
 		WithMethod(http.PUT).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		WithQueryParam("unbind", "").
 		Do()
 }
@@ -179,7 +179,7 @@ func (c *Client) DeleteVpnGateway(vpcId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForVPNId(vpcId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
 
@@ -199,7 +199,7 @@ func (c *Client) RenewVpnGateway(vpnId string, args *RenewVpnGatewayArgs) error
 		WithURL(getURLForVPNId(vpnId)).
 		WithMethod(http.PUT).
 		WithBody(args).
-		WithQueryParamFilter("clientToken", args.ClientToken).
+		WithClientToken(args.ClientToken).
 		WithQueryParam("purchaseReserved", "").
 		Do()
 }
@@ -219,6 +219,7 @@ func (c *Client) CreateVpnConn(args *CreateVpnConnArgs) (*CreateVpnConnResult, e
 	err := bce.NewRequestBuilder(c).
 		WithURL(getURLForVPNId(args.VpnId) + "/vpnconn").
 		WithMethod(http.POST).
+		WithIdempotent(false).
 		WithBody(args).
 		WithResult(result).
 		Do()
@@ -267,6 +268,6 @@ func (c *Client) DeleteVpnConn(vpnConnId, clientToken string) error {
 	return bce.NewRequestBuilder(c).
 		WithURL(getURLForVpnConnId(vpnConnId)).
 		WithMethod(http.DELETE).
-		WithQueryParamFilter("clientToken", clientToken).
+		WithClientToken(clientToken).
 		Do()
 }
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2017 Baidu, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the
+ * License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions
+ * and limitations under the License.
+ */
+
+// cidr.go - define the IPv4 CIDR util function
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// IPv4Block is an IPv4 CIDR block stored as the first address and the prefix length.
+type IPv4Block struct {
+	First        uint32
+	PrefixLength int
+}
+
+// ParseIPv4Block - parse the IPv4 CIDR, the host bits must be zero
+//
+// PARAMS:
+//     - cidr: the CIDR string such as "192.168.0.0/16"
+// RETURNS:
+//     - IPv4Block: the parsed block
+//     - error: nil if success otherwise the specific error
+func ParseIPv4Block(cidr string) (IPv4Block, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return IPv4Block{}, fmt.Errorf("invalid IPv4 cidr %q", cidr)
+	}
+	if !ip.Equal(network.IP) {
+		return IPv4Block{}, fmt.Errorf("the host bits of cidr %q are not zero, use %s", cidr,
+			network.String())
+	}
+	ones, _ := network.Mask.Size()
+	return IPv4Block{binary.BigEndian.Uint32(network.IP.To4()), ones}, nil
+}
+
+// Size returns the number of addresses in the block.
+func (b IPv4Block) Size() uint64 { return 1 << uint(32-b.PrefixLength) }
+
+// Last returns the last address of the block.
+func (b IPv4Block) Last() uint32 { return b.First + uint32(b.Size()-1) }
+
+// Contains returns whether the other block is inside the block.
+func (b IPv4Block) Contains(other IPv4Block) bool {
+	return b.PrefixLength <= other.PrefixLength && b.First <= other.First && other.Last() <= b.Last()
+}
+
+// Overlaps returns whether the two blocks share any address.
+func (b IPv4Block) Overlaps(other IPv4Block) bool {
+	return b.First <= other.Last() && other.First <= b.Last()
+}
+
+func (b IPv4Block) String() string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, b.First)
+	return fmt.Sprintf("%s/%d", ip.String(), b.PrefixLength)
+}
+
+// FreeIPv4Blocks - find the free blocks of the given prefix length inside the parent block which do
+// not overlap any of the used blocks, in the order of the address
+//
+// PARAMS:
+//     - parent: the block to allocate from
+//     - used: the blocks already in use, they may be outside the parent
+//     - prefixLength: the prefix length of the blocks to find
+//     - limit: the maximum number of blocks to return, no limit if not positive
+// RETURNS:
+//     - []IPv4Block: the free blocks
+//     - error: nil if success otherwise the specific error
+func FreeIPv4Blocks(parent IPv4Block, used []IPv4Block,
+	prefixLength, limit int) ([]IPv4Block, error) {
+	if prefixLength < parent.PrefixLength || prefixLength > 32 {
+		return nil, fmt.Errorf("invalid prefix length %d inside %s", prefixLength, parent)
+	}
+	sorted := make([]IPv4Block, 0, len(used))
+	for _, block := range used {
+		if block.Overlaps(parent) {
+			sorted = append(sorted, block)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].First < sorted[j].First })
+
+	result := []IPv4Block{}
+	step := uint64(1) << uint(32-prefixLength)
+	next := uint64(parent.First)
+	// fill allocates the aligned blocks in the gap before the given address
+	fill := func(gapEnd uint64) bool {
+		for ; next+step <= gapEnd; next += step {
+			result = append(result, IPv4Block{uint32(next), prefixLength})
+			if limit > 0 && len(result) >= limit {
+				return false
+			}
+		}
+		return true
+	}
+	for _, block := range sorted {
+		if !fill(uint64(block.First)) {
+			return result, nil
+		}
+		if after := uint64(block.Last()) + 1; after > next {
+			next = (after + step - 1) / step * step
+		}
+	}
+	fill(uint64(parent.Last()) + 1)
+	return result, nil
+}
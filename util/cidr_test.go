@@ -0,0 +1,65 @@
+package util
+
+import "testing"
+
+func mustParseIPv4Blocks(t *testing.T, cidrs ...string) []IPv4Block {
+	result := make([]IPv4Block, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		block, err := ParseIPv4Block(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, block)
+	}
+	return result
+}
+
+func TestIPv4Block(t *testing.T) {
+	blocks := mustParseIPv4Blocks(t, "10.0.0.0/16", "10.0.3.0/24", "10.1.0.0/16")
+	if !blocks[0].Contains(blocks[1]) || blocks[1].Contains(blocks[0]) {
+		t.Errorf("unexpected contains result")
+	}
+	if !blocks[0].Overlaps(blocks[1]) || blocks[0].Overlaps(blocks[2]) {
+		t.Errorf("unexpected overlaps result")
+	}
+	if blocks[1].String() != "10.0.3.0/24" || blocks[1].Size() != 256 {
+		t.Errorf("unexpected block %s", blocks[1])
+	}
+	for _, cidr := range []string{"10.0.0.1/16", "10.0.0.0/33", "fc00::/7", "10.0.0.0"} {
+		if _, err := ParseIPv4Block(cidr); err == nil {
+			t.Errorf("expect error of %s", cidr)
+		}
+	}
+}
+
+func TestFreeIPv4Blocks(t *testing.T) {
+	parent := mustParseIPv4Blocks(t, "192.168.0.0/22")[0]
+	used := mustParseIPv4Blocks(t, "192.168.0.0/25", "192.168.1.128/25", "192.168.3.0/24",
+		"10.0.0.0/8")
+
+	free, err := FreeIPv4Blocks(parent, used, 24, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(free) != 1 || free[0].String() != "192.168.2.0/24" {
+		t.Errorf("unexpected free blocks %v", free)
+	}
+
+	free, _ = FreeIPv4Blocks(parent, used, 25, 0)
+	expected := []string{"192.168.0.128/25", "192.168.1.0/25", "192.168.2.0/25", "192.168.2.128/25"}
+	if len(free) != len(expected) {
+		t.Fatalf("unexpected free blocks %v", free)
+	}
+	for i, block := range free {
+		if block.String() != expected[i] {
+			t.Errorf("expect %s but %s", expected[i], block)
+		}
+	}
+
+	if free, _ = FreeIPv4Blocks(parent, used, 26, 2); len(free) != 2 {
+		t.Errorf("expect the limit to be respected but %v", free)
+	}
+	if _, err := FreeIPv4Blocks(parent, used, 20, 0); err == nil {
+		t.Errorf("expect error of prefix length shorter than the parent")
+	}
+}